@@ -0,0 +1,17 @@
+// Command echo-plugin is the reference llm-bridge provider plugin.
+// See docs/plugins.md.
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/anthropics/llm-bridge/internal/echoplugin"
+)
+
+func main() {
+	if err := echoplugin.Run(os.Stdin, os.Stdout, os.Stderr); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/anthropics/llm-bridge/internal/audit"
+	"github.com/anthropics/llm-bridge/internal/config"
+)
+
+var auditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "Inspect the audit log of bridge commands and prompts",
+}
+
+var auditVerifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Check the audit log's hash chain for tampering",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path, err := auditFile(cmd)
+		if err != nil {
+			return err
+		}
+		count, head, err := audit.Verify(path)
+		var torn *audit.TornRecordError
+		if err != nil && !errors.As(err, &torn) {
+			return fmt.Errorf("verify %s: %w", path, err)
+		}
+		fmt.Printf("%s: %d records OK\n", path, count)
+		if head != "" {
+			fmt.Printf("head: %s\n", head)
+		}
+		if torn != nil {
+			// The bridge moves the torn record aside when it next opens the log.
+			return fmt.Errorf("verify %s: %w", path, torn)
+		}
+		return nil
+	},
+}
+
+var auditQueryCmd = &cobra.Command{
+	Use:   "query",
+	Short: "List audit records by repo, user or time",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path, err := auditFile(cmd)
+		if err != nil {
+			return err
+		}
+		repoFlag, _ := cmd.Flags().GetString("repo")
+		userFlag, _ := cmd.Flags().GetString("user")
+		sinceFlag, _ := cmd.Flags().GetString("since")
+		jsonFlag, _ := cmd.Flags().GetBool("json")
+
+		filter := audit.Filter{Repo: repoFlag, User: userFlag}
+		if sinceFlag != "" {
+			if filter.Since, err = parseSince(sinceFlag); err != nil {
+				return err
+			}
+		}
+
+		recs, err := audit.Query(path, filter)
+		if err != nil {
+			return fmt.Errorf("query %s: %w", path, err)
+		}
+		enc := json.NewEncoder(os.Stdout)
+		for _, rec := range recs {
+			if jsonFlag {
+				if err := enc.Encode(rec); err != nil {
+					return err
+				}
+				continue
+			}
+			fmt.Println(audit.Format(rec))
+		}
+		return nil
+	},
+}
+
+// auditFile returns the --file flag, or the audit log configured in the
+// config file.
+func auditFile(cmd *cobra.Command) (string, error) {
+	if file, _ := cmd.Flags().GetString("file"); file != "" {
+		return file, nil
+	}
+	cfg, err := config.Load(cfgFile)
+	if err != nil {
+		return "", fmt.Errorf("load config: %w", err)
+	}
+	return config.ResolvePath(cfgFile, cfg.Defaults.Audit.GetFile()), nil
+}
+
+// parseSince accepts a duration before now (e.g. "24h") or an RFC 3339 time.
+func parseSince(s string) (time.Time, error) {
+	if d, err := time.ParseDuration(s); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid --since %q: want a duration like 24h or an RFC 3339 time", s)
+	}
+	return t, nil
+}
+
+func init() {
+	rootCmd.AddCommand(auditCmd)
+	auditCmd.AddCommand(auditVerifyCmd)
+	auditCmd.AddCommand(auditQueryCmd)
+
+	auditCmd.PersistentFlags().String("file", "", "Audit log path (default: the config's audit file)")
+	auditQueryCmd.Flags().String("repo", "", "Only records for this repo")
+	auditQueryCmd.Flags().String("user", "", "Only records by this author ID or name")
+	auditQueryCmd.Flags().String("since", "", "Only records since a duration ago (e.g. 24h) or an RFC 3339 time")
+	auditQueryCmd.Flags().Bool("json", false, "Print records as JSON lines")
+}
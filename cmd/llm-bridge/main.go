@@ -34,15 +34,23 @@ var serveCmd = &cobra.Command{
 		ctx, cancel := context.WithCancel(context.Background())
 		defer cancel()
 
+		b := bridge.New(cfg, cfgFile)
+
 		sigCh := make(chan os.Signal, 1)
-		signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+		signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
 		go func() {
-			<-sigCh
-			slog.Info("shutting down")
-			cancel()
+			for sig := range sigCh {
+				if sig == syscall.SIGHUP {
+					slog.Info("reloading config", "config", cfgFile)
+					b.Reload()
+					continue
+				}
+				slog.Info("shutting down")
+				cancel()
+				return
+			}
 		}()
 
-		b := bridge.New(cfg, cfgFile)
 		slog.Info("starting bridge", "config", cfgFile)
 		return b.Start(ctx)
 	},
@@ -86,7 +94,7 @@ func init() {
 	rootCmd.AddCommand(serveCmd)
 	rootCmd.AddCommand(addRepoCmd)
 
-	addRepoCmd.Flags().String("provider", "discord", "Chat provider (discord)")
+	addRepoCmd.Flags().String("provider", "discord", "Chat provider (discord or a configured plugin name)")
 	addRepoCmd.Flags().String("channel", "", "Channel ID")
 	addRepoCmd.Flags().String("llm", "claude", "LLM backend")
 	addRepoCmd.Flags().String("dir", ".", "Working directory")
@@ -0,0 +1,405 @@
+// Package adminapi serves a token-authenticated JSON API for operating the
+// bridge without a chat provider: listing repos and sessions, starting,
+// stopping, restarting and cancelling sessions, adding and removing repos,
+// and sending prompts. It listens on a loopback address or a Unix socket.
+package adminapi
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// Errors a Controller wraps to choose the response status.
+var (
+	ErrNotFound = errors.New("not found")       // 404
+	ErrConflict = errors.New("conflict")        // 409
+	ErrInvalid  = errors.New("invalid request") // 400
+)
+
+// maxBody bounds request bodies.
+const maxBody = 1 << 20
+
+// Repo is a configured repo.
+type Repo struct {
+	Name        string `json:"name"`
+	Provider    string `json:"provider"`
+	ChannelID   string `json:"channel_id,omitempty"`
+	LLM         string `json:"llm"`
+	WorkingDir  string `json:"working_dir"`
+	Branch      string `json:"branch,omitempty"`
+	SessionMode string `json:"session_mode"`
+	Active      bool   `json:"active"` // any of its sessions is running
+}
+
+// Channel is a chat channel a session posts output to.
+type Channel struct {
+	Provider  string `json:"provider"`
+	ChannelID string `json:"channel_id"`
+	Mode      string `json:"mode,omitempty"` // read-write, read-only or notify-only, for the repo's configured channels
+}
+
+// Session is an LLM session.
+type Session struct {
+	Key         string    `json:"key"`
+	Repo        string    `json:"repo"`
+	User        string    `json:"user,omitempty"`    // owner of a per_user session
+	UserID      string    `json:"user_id,omitempty"` // owner's AuthorID
+	LLM         string    `json:"llm"`
+	State       string    `json:"state"` // "idle", "busy", "waiting_permission" or "stopped"
+	IdleSeconds int64     `json:"idle_seconds"`
+	BusySeconds int64     `json:"busy_seconds,omitempty"`
+	BusyWith    string    `json:"busy_with,omitempty"` // author of the prompt in flight
+	Branch      string    `json:"branch,omitempty"`
+	Worktree    bool      `json:"worktree,omitempty"`
+	Queued      int       `json:"queued"`
+	Channels    []Channel `json:"channels"`
+	LastActive  time.Time `json:"last_active"`
+}
+
+// Session states.
+const (
+	StateIdle              = "idle"
+	StateBusy              = "busy"
+	StateWaitingPermission = "waiting_permission"
+	StateStopped           = "stopped"
+)
+
+// AddRepoRequest is the body of POST /v1/repos.
+type AddRepoRequest struct {
+	Name        string `json:"name"`
+	Provider    string `json:"provider"`
+	ChannelID   string `json:"channel_id"`
+	LLM         string `json:"llm"`
+	WorkingDir  string `json:"working_dir"`
+	GitRoot     string `json:"git_root"`
+	Branch      string `json:"branch"`
+	SessionMode string `json:"session_mode"`
+}
+
+// SessionRequest names a session: the repo's shared session, or with User
+// set, that AuthorID's session in a per_user repo.
+type SessionRequest struct {
+	Repo string `json:"repo"`
+	User string `json:"user,omitempty"`
+}
+
+// PromptRequest is the body of POST /v1/prompt.
+type PromptRequest struct {
+	SessionRequest
+	Text   string `json:"text"`
+	Author string `json:"author,omitempty"` // shown to the LLM and in the audit log (default: "admin-api")
+}
+
+// PromptResponse reports where a prompt went.
+type PromptResponse struct {
+	Session  Session `json:"session"`
+	Position int     `json:"position"` // 0 if sent now, else its place in the queue
+}
+
+// Controller carries out API requests. The bridge implements it.
+type Controller interface {
+	Repos() []Repo
+	Sessions() []Session
+	AddRepo(req AddRepoRequest) (Repo, error)
+	RemoveRepo(name string) error
+	StartSession(req SessionRequest) (Session, error)
+	StopSession(req SessionRequest) error
+	RestartSession(req SessionRequest) (Session, error)
+	CancelSession(req SessionRequest) error
+	SendPrompt(req PromptRequest) (PromptResponse, error)
+}
+
+// Server is the admin API server.
+type Server struct {
+	ctl    Controller
+	token  string
+	server *http.Server
+	socket string // Unix socket path to remove on Close, if any
+}
+
+// New returns a server that authenticates requests with a bearer token.
+func New(ctl Controller, token string) *Server {
+	return &Server{ctl: ctl, token: token}
+}
+
+// Start listens on addr, a loopback host:port or "unix:<path>", and serves
+// in the background.
+func (s *Server) Start(addr string) error {
+	ln, err := Listen(addr)
+	if err != nil {
+		return err
+	}
+	if path, ok := strings.CutPrefix(addr, "unix:"); ok {
+		s.socket = path
+	}
+	s.server = &http.Server{
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		if err := s.server.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("admin api server failed", "error", err)
+		}
+	}()
+	return nil
+}
+
+// Close shuts the server down.
+func (s *Server) Close() error {
+	if s.server == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err := s.server.Shutdown(ctx)
+	if s.socket != "" {
+		_ = os.Remove(s.socket)
+	}
+	return err
+}
+
+// Listen opens addr: "unix:<path>" for a Unix socket only its owner may
+// connect to, otherwise a TCP address that must be on a loopback interface.
+func Listen(addr string) (net.Listener, error) {
+	if path, ok := strings.CutPrefix(addr, "unix:"); ok {
+		// A socket left behind by an unclean exit would block the listen.
+		if info, err := os.Lstat(path); err == nil && info.Mode()&os.ModeSocket != 0 {
+			_ = os.Remove(path)
+		}
+		ln, err := net.Listen("unix", path)
+		if err != nil {
+			return nil, fmt.Errorf("listen: %w", err)
+		}
+		if err := os.Chmod(path, 0o600); err != nil {
+			ln.Close()
+			return nil, fmt.Errorf("chmod socket: %w", err)
+		}
+		return ln, nil
+	}
+
+	if err := CheckLoopback(addr); err != nil {
+		return nil, err
+	}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("listen: %w", err)
+	}
+	return ln, nil
+}
+
+// CheckLoopback returns an error unless addr is "unix:<path>" or a
+// host:port whose host is localhost or a loopback IP.
+func CheckLoopback(addr string) error {
+	if path, ok := strings.CutPrefix(addr, "unix:"); ok {
+		if path == "" {
+			return fmt.Errorf("invalid address %q: empty socket path", addr)
+		}
+		return nil
+	}
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Errorf("invalid address %q: %w", addr, err)
+	}
+	if host == "localhost" {
+		return nil
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+		return nil
+	}
+	return fmt.Errorf("invalid address %q: must be a loopback address or unix:<path>", addr)
+}
+
+// Handler returns the API's HTTP handler.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/repos", s.handleRepos)
+	mux.HandleFunc("/v1/repos/", s.handleRepo)
+	mux.HandleFunc("/v1/sessions", s.handleSessions)
+	mux.HandleFunc("/v1/sessions/", s.handleSessionAction)
+	mux.HandleFunc("/v1/prompt", s.handlePrompt)
+	return s.authenticate(mux)
+}
+
+// authenticate rejects requests without the bearer token.
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || s.token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+			slog.Warn("admin api request unauthorized", "method", r.Method, "path", r.URL.Path, "remote", r.RemoteAddr)
+			writeError(w, http.StatusUnauthorized, errors.New("missing or invalid token"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// handleRepos lists (GET) or adds (POST) repos.
+func (s *Server) handleRepos(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, s.ctl.Repos())
+	case http.MethodPost:
+		var req AddRepoRequest
+		if !readJSON(w, r, &req) {
+			return
+		}
+		repo, err := s.ctl.AddRepo(req)
+		if err != nil {
+			writeControllerError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, repo)
+	default:
+		methodNotAllowed(w, http.MethodGet, http.MethodPost)
+	}
+}
+
+// handleRepo removes (DELETE) the repo named by the rest of the path, which
+// may contain "/" for worktree repos.
+func (s *Server) handleRepo(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(r.URL.Path, "/v1/repos/")
+	if r.Method != http.MethodDelete {
+		methodNotAllowed(w, http.MethodDelete)
+		return
+	}
+	if name == "" {
+		writeError(w, http.StatusBadRequest, errors.New("missing repo name"))
+		return
+	}
+	if err := s.ctl.RemoveRepo(name); err != nil {
+		writeControllerError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleSessions lists sessions.
+func (s *Server) handleSessions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, http.MethodGet)
+		return
+	}
+	writeJSON(w, http.StatusOK, s.ctl.Sessions())
+}
+
+// handleSessionAction starts, stops, restarts or cancels the session named
+// in the body.
+func (s *Server) handleSessionAction(w http.ResponseWriter, r *http.Request) {
+	action := strings.TrimPrefix(r.URL.Path, "/v1/sessions/")
+	if r.Method != http.MethodPost {
+		methodNotAllowed(w, http.MethodPost)
+		return
+	}
+	var req SessionRequest
+	if !readJSON(w, r, &req) {
+		return
+	}
+	if req.Repo == "" {
+		writeError(w, http.StatusBadRequest, errors.New("missing repo"))
+		return
+	}
+
+	var session Session
+	var err error
+	switch action {
+	case "start":
+		session, err = s.ctl.StartSession(req)
+	case "restart":
+		session, err = s.ctl.RestartSession(req)
+	case "stop":
+		err = s.ctl.StopSession(req)
+	case "cancel":
+		err = s.ctl.CancelSession(req)
+	default:
+		writeError(w, http.StatusNotFound, fmt.Errorf("unknown session action %q", action))
+		return
+	}
+	if err != nil {
+		writeControllerError(w, err)
+		return
+	}
+	if action == "start" || action == "restart" {
+		writeJSON(w, http.StatusOK, session)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handlePrompt sends a prompt to a session, starting it if needed.
+func (s *Server) handlePrompt(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		methodNotAllowed(w, http.MethodPost)
+		return
+	}
+	var req PromptRequest
+	if !readJSON(w, r, &req) {
+		return
+	}
+	if req.Repo == "" || strings.TrimSpace(req.Text) == "" {
+		writeError(w, http.StatusBadRequest, errors.New("repo and text are required"))
+		return
+	}
+	resp, err := s.ctl.SendPrompt(req)
+	if err != nil {
+		writeControllerError(w, err)
+		return
+	}
+	status := http.StatusOK
+	if resp.Position > 0 {
+		status = http.StatusAccepted
+	}
+	writeJSON(w, status, resp)
+}
+
+// readJSON decodes the request body into v, writing a 400 and returning
+// false if it is not valid JSON.
+func readJSON(w http.ResponseWriter, r *http.Request, v any) bool {
+	dec := json.NewDecoder(io.LimitReader(r.Body, maxBody))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid JSON body: %w", err))
+		return false
+	}
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.Warn("admin api write response failed", "error", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+// writeControllerError maps a Controller error to a response status.
+func writeControllerError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, ErrNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, ErrConflict):
+		status = http.StatusConflict
+	case errors.Is(err, ErrInvalid):
+		status = http.StatusBadRequest
+	}
+	writeError(w, status, err)
+}
+
+func methodNotAllowed(w http.ResponseWriter, allowed ...string) {
+	w.Header().Set("Allow", strings.Join(allowed, ", "))
+	writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+}
@@ -0,0 +1,292 @@
+package adminapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeController records calls and returns canned results.
+type fakeController struct {
+	calls []string
+	err   error
+}
+
+func (f *fakeController) Repos() []Repo {
+	return []Repo{{Name: "app", Provider: "discord", ChannelID: "c1", LLM: "claude", SessionMode: "shared", Active: true}}
+}
+
+func (f *fakeController) Sessions() []Session {
+	return []Session{{Key: "app", Repo: "app", State: StateBusy, Channels: []Channel{{Provider: "discord", ChannelID: "c1"}}}}
+}
+
+func (f *fakeController) AddRepo(req AddRepoRequest) (Repo, error) {
+	f.calls = append(f.calls, "add "+req.Name)
+	return Repo{Name: req.Name}, f.err
+}
+
+func (f *fakeController) RemoveRepo(name string) error {
+	f.calls = append(f.calls, "remove "+name)
+	return f.err
+}
+
+func (f *fakeController) StartSession(req SessionRequest) (Session, error) {
+	f.calls = append(f.calls, "start "+req.Repo+" "+req.User)
+	return Session{Key: req.Repo, Repo: req.Repo, State: StateIdle}, f.err
+}
+
+func (f *fakeController) StopSession(req SessionRequest) error {
+	f.calls = append(f.calls, "stop "+req.Repo)
+	return f.err
+}
+
+func (f *fakeController) RestartSession(req SessionRequest) (Session, error) {
+	f.calls = append(f.calls, "restart "+req.Repo)
+	return Session{Key: req.Repo, Repo: req.Repo, State: StateIdle}, f.err
+}
+
+func (f *fakeController) CancelSession(req SessionRequest) error {
+	f.calls = append(f.calls, "cancel "+req.Repo)
+	return f.err
+}
+
+func (f *fakeController) SendPrompt(req PromptRequest) (PromptResponse, error) {
+	f.calls = append(f.calls, "prompt "+req.Repo+" "+req.Text)
+	position := 0
+	if req.Text == "queued" {
+		position = 2
+	}
+	return PromptResponse{Session: Session{Repo: req.Repo}, Position: position}, f.err
+}
+
+const testToken = "s3cret"
+
+// do sends a request with the test token to a server around ctl.
+func do(t *testing.T, ctl Controller, method, path, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	rec := httptest.NewRecorder()
+	New(ctl, testToken).Handler().ServeHTTP(rec, req)
+	return rec
+}
+
+func TestHandler_RequiresToken(t *testing.T) {
+	handler := New(&fakeController{}, testToken).Handler()
+	for _, header := range []string{"", "Bearer wrong", "Basic " + testToken, testToken} {
+		req := httptest.NewRequest(http.MethodGet, "/v1/repos", nil)
+		if header != "" {
+			req.Header.Set("Authorization", header)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("Authorization %q: status = %d, want 401", header, rec.Code)
+		}
+	}
+
+	// An empty configured token never matches.
+	req := httptest.NewRequest(http.MethodGet, "/v1/repos", nil)
+	req.Header.Set("Authorization", "Bearer ")
+	rec := httptest.NewRecorder()
+	New(&fakeController{}, "").Handler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("empty token: status = %d, want 401", rec.Code)
+	}
+}
+
+func TestHandler_List(t *testing.T) {
+	ctl := &fakeController{}
+
+	rec := do(t, ctl, http.MethodGet, "/v1/repos", "")
+	var repos []Repo
+	if err := json.Unmarshal(rec.Body.Bytes(), &repos); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("GET /v1/repos = %d %s", rec.Code, rec.Body)
+	}
+	if len(repos) != 1 || repos[0].Name != "app" || !repos[0].Active {
+		t.Errorf("repos = %+v", repos)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+
+	rec = do(t, ctl, http.MethodGet, "/v1/sessions", "")
+	var sessions []Session
+	if err := json.Unmarshal(rec.Body.Bytes(), &sessions); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("GET /v1/sessions = %d %s", rec.Code, rec.Body)
+	}
+	if len(sessions) != 1 || sessions[0].State != StateBusy || sessions[0].Channels[0].ChannelID != "c1" {
+		t.Errorf("sessions = %+v", sessions)
+	}
+}
+
+func TestHandler_Actions(t *testing.T) {
+	tests := []struct {
+		method, path, body string
+		wantStatus         int
+		wantCall           string
+	}{
+		{http.MethodPost, "/v1/repos", `{"name":"new","provider":"discord","channel_id":"c2","working_dir":"/src/new"}`, http.StatusCreated, "add new"},
+		{http.MethodDelete, "/v1/repos/app/feature", "", http.StatusNoContent, "remove app/feature"},
+		{http.MethodPost, "/v1/sessions/start", `{"repo":"app","user":"u1"}`, http.StatusOK, "start app u1"},
+		{http.MethodPost, "/v1/sessions/stop", `{"repo":"app"}`, http.StatusNoContent, "stop app"},
+		{http.MethodPost, "/v1/sessions/restart", `{"repo":"app"}`, http.StatusOK, "restart app"},
+		{http.MethodPost, "/v1/sessions/cancel", `{"repo":"app"}`, http.StatusNoContent, "cancel app"},
+		{http.MethodPost, "/v1/prompt", `{"repo":"app","text":"run tests"}`, http.StatusOK, "prompt app run tests"},
+		{http.MethodPost, "/v1/prompt", `{"repo":"app","text":"queued"}`, http.StatusAccepted, "prompt app queued"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			ctl := &fakeController{}
+			rec := do(t, ctl, tt.method, tt.path, tt.body)
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if len(ctl.calls) != 1 || ctl.calls[0] != tt.wantCall {
+				t.Errorf("calls = %q, want %q", ctl.calls, tt.wantCall)
+			}
+		})
+	}
+}
+
+func TestHandler_BadRequests(t *testing.T) {
+	tests := []struct {
+		method, path, body string
+		wantStatus         int
+	}{
+		{http.MethodPut, "/v1/repos", "", http.StatusMethodNotAllowed},
+		{http.MethodGet, "/v1/repos/app", "", http.StatusMethodNotAllowed},
+		{http.MethodDelete, "/v1/repos/", "", http.StatusBadRequest},
+		{http.MethodPost, "/v1/repos", "{", http.StatusBadRequest},
+		{http.MethodPost, "/v1/repos", `{"nmae":"typo"}`, http.StatusBadRequest},
+		{http.MethodGet, "/v1/sessions/start", "", http.StatusMethodNotAllowed},
+		{http.MethodPost, "/v1/sessions/start", `{}`, http.StatusBadRequest},
+		{http.MethodPost, "/v1/sessions/explode", `{"repo":"app"}`, http.StatusNotFound},
+		{http.MethodPost, "/v1/prompt", `{"repo":"app","text":"  "}`, http.StatusBadRequest},
+		{http.MethodGet, "/v1/unknown", "", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			ctl := &fakeController{}
+			rec := do(t, ctl, tt.method, tt.path, tt.body)
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if len(ctl.calls) != 0 {
+				t.Errorf("controller should not be called, got %q", ctl.calls)
+			}
+		})
+	}
+}
+
+func TestHandler_ControllerErrors(t *testing.T) {
+	tests := []struct {
+		err        error
+		wantStatus int
+	}{
+		{fmt.Errorf("%w: repo %q", ErrNotFound, "x"), http.StatusNotFound},
+		{fmt.Errorf("%w: already exists", ErrConflict), http.StatusConflict},
+		{fmt.Errorf("%w: bad name", ErrInvalid), http.StatusBadRequest},
+		{fmt.Errorf("disk full"), http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		rec := do(t, &fakeController{err: tt.err}, http.MethodPost, "/v1/sessions/cancel", `{"repo":"x"}`)
+		if rec.Code != tt.wantStatus {
+			t.Errorf("%v: status = %d, want %d", tt.err, rec.Code, tt.wantStatus)
+		}
+		var body map[string]string
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || body["error"] != tt.err.Error() {
+			t.Errorf("%v: body = %s", tt.err, rec.Body)
+		}
+	}
+}
+
+func TestCheckLoopback(t *testing.T) {
+	tests := []struct {
+		addr string
+		ok   bool
+	}{
+		{"127.0.0.1:9090", true},
+		{"[::1]:9090", true},
+		{"localhost:9090", true},
+		{"unix:/run/llm-bridge/admin.sock", true},
+		{"0.0.0.0:9090", false},
+		{":9090", false},
+		{"10.0.0.5:9090", false},
+		{"example.com:9090", false},
+		{"127.0.0.1", false},
+		{"unix:", false},
+	}
+	for _, tt := range tests {
+		if err := CheckLoopback(tt.addr); (err == nil) != tt.ok {
+			t.Errorf("CheckLoopback(%q) = %v, want ok=%v", tt.addr, err, tt.ok)
+		}
+	}
+}
+
+func TestServer_UnixSocket(t *testing.T) {
+	// Unix socket paths are limited to about 100 bytes, so avoid t.TempDir.
+	dir, err := os.MkdirTemp("", "adminapi")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "admin.sock")
+
+	// A stale socket from a previous run is replaced.
+	stale, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+
+	srv := New(&fakeController{}, testToken)
+	if err := srv.Start("unix:" + path); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Errorf("socket mode = %v, want 0600", perm)
+	}
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", path)
+		},
+	}}
+	req, _ := http.NewRequest(http.MethodGet, "http://admin/v1/sessions", nil)
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("request over socket: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("status = %d, want 200", resp.StatusCode)
+	}
+
+	if err := srv.Close(); err != nil {
+		t.Errorf("Close: %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("socket should be removed on Close, stat err = %v", err)
+	}
+}
+
+func TestListen_RejectsNonLoopback(t *testing.T) {
+	if ln, err := Listen("0.0.0.0:0"); err == nil {
+		ln.Close()
+		t.Fatal("Listen on all interfaces should fail")
+	}
+}
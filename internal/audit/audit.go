@@ -0,0 +1,374 @@
+// Package audit keeps an append-only, hash-chained record of who told the
+// bridge to do what. Each record carries the SHA-256 hash of the one before
+// it, so editing, reordering or deleting a record breaks the chain that
+// Verify checks.
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Record kinds.
+const (
+	KindCommand = "command" // a /command, typed or sent as a reaction
+	KindPrompt  = "prompt"  // text sent to the LLM
+	KindLog     = "log"     // something that happened to the log itself
+)
+
+// Outcomes.
+const (
+	OutcomeOK          = "ok"           // command ran or prompt was sent
+	OutcomeQueued      = "queued"       // prompt waits for the current turn
+	OutcomeDenied      = "denied"       // authorization refused it
+	OutcomeRateLimited = "rate_limited" // rate limiting refused it
+	OutcomeFailed      = "failed"       // the bridge could not carry it out
+	OutcomeRecovered   = "recovered"    // Open cut off a torn final record
+)
+
+// Record is one audited action.
+type Record struct {
+	Seq      uint64    `json:"seq"`
+	Time     time.Time `json:"time"`
+	Kind     string    `json:"kind"`
+	Provider string    `json:"provider"`
+	Channel  string    `json:"channel"`
+	Author   string    `json:"author,omitempty"`
+	AuthorID string    `json:"author_id,omitempty"`
+	Repo     string    `json:"repo,omitempty"`
+	Command  string    `json:"command,omitempty"` // without the "/"
+	Args     string    `json:"args,omitempty"`    // command arguments, e.g. a /clone URL
+	Prompt   string    `json:"prompt,omitempty"`  // prompt as typed
+	Outcome  string    `json:"outcome"`
+	Detail   string    `json:"detail,omitempty"` // command response or error
+	PrevHash string    `json:"prev_hash"`
+	Hash     string    `json:"hash"`
+}
+
+// computeHash returns the hash of r with its Hash field cleared.
+func (r Record) computeHash() (string, error) {
+	r.Hash = ""
+	data, err := json.Marshal(r)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// TornRecordError reports a final record that was cut off mid-write, e.g.
+// by a crash or a full disk. The records before it are intact.
+type TornRecordError struct {
+	Line   int
+	Offset int64  // where the torn record starts in the file
+	Saved  string // file Open moved the torn bytes to, if it did
+}
+
+func (e *TornRecordError) Error() string {
+	return fmt.Sprintf("line %d: incomplete final record", e.Line)
+}
+
+// Log appends records to a file. It is safe for concurrent use.
+type Log struct {
+	mu   sync.Mutex
+	f    *os.File
+	seq  uint64
+	head string           // hash of the last record
+	torn *TornRecordError // cut off by Open, if any
+}
+
+// Open opens the log at path for appending, creating it if needed. It picks
+// up the chain where the file's last complete record left off. A torn final
+// record is moved to <path>.torn-<offset> so the next record starts on its
+// own line, and a recovered record chained after the intact ones says so;
+// Torn reports it.
+func Open(path string) (*Log, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("create audit dir: %w", err)
+	}
+	l := &Log{}
+	err := scan(path, func(r Record) error {
+		l.seq, l.head = r.Seq, r.Hash
+		return nil
+	})
+	switch {
+	case errors.As(err, &l.torn):
+		if err := moveTorn(path, l.torn); err != nil {
+			return nil, err
+		}
+	case err != nil && !errors.Is(err, os.ErrNotExist):
+		return nil, err
+	}
+
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("open audit log: %w", err)
+	}
+	// A write cut off just before its newline leaves a complete record.
+	if err := terminateLastLine(f); err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("open audit log: %w", err)
+	}
+	l.f = f
+	if l.torn != nil {
+		err := l.Append(Record{
+			Kind:    KindLog,
+			Outcome: OutcomeRecovered,
+			Detail:  fmt.Sprintf("torn record at offset %d cut off and moved to %s", l.torn.Offset, l.torn.Saved),
+		})
+		if err != nil {
+			_ = l.Close()
+			return nil, err
+		}
+	}
+	return l, nil
+}
+
+// moveTorn copies the torn record at the end of the file at path to
+// <path>.torn-<offset>, then truncates it from the log.
+func moveTorn(path string, torn *TornRecordError) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("read torn audit record: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.Seek(torn.Offset, io.SeekStart); err != nil {
+		return fmt.Errorf("read torn audit record: %w", err)
+	}
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return fmt.Errorf("read torn audit record: %w", err)
+	}
+	saved := fmt.Sprintf("%s.torn-%d", path, torn.Offset)
+	if err := os.WriteFile(saved, data, 0o600); err != nil {
+		return fmt.Errorf("save torn audit record: %w", err)
+	}
+	if err := os.Truncate(path, torn.Offset); err != nil {
+		return fmt.Errorf("truncate torn audit record: %w", err)
+	}
+	torn.Saved = saved
+	return nil
+}
+
+// terminateLastLine appends a newline to f unless it is empty or already
+// ends with one.
+func terminateLastLine(f *os.File) error {
+	info, err := f.Stat()
+	if err != nil || info.Size() == 0 {
+		return err
+	}
+	last := make([]byte, 1)
+	if _, err := f.ReadAt(last, info.Size()-1); err != nil {
+		return err
+	}
+	if last[0] == '\n' {
+		return nil
+	}
+	_, err = f.Write([]byte("\n"))
+	return err
+}
+
+// Torn returns the torn final record Open moved aside, or nil.
+func (l *Log) Torn() *TornRecordError {
+	return l.torn
+}
+
+// Append chains r onto the log, filling in Seq, PrevHash, Hash and (if
+// unset) Time, and syncs it to disk.
+func (l *Log) Append(r Record) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.f == nil {
+		return errors.New("audit log is closed")
+	}
+	if r.Time.IsZero() {
+		r.Time = time.Now()
+	}
+	r.Time = r.Time.UTC()
+	r.Seq = l.seq + 1
+	r.PrevHash = l.head
+	hash, err := r.computeHash()
+	if err != nil {
+		return fmt.Errorf("hash audit record: %w", err)
+	}
+	r.Hash = hash
+
+	data, err := json.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("encode audit record: %w", err)
+	}
+	if _, err := l.f.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("write audit record: %w", err)
+	}
+	if err := l.f.Sync(); err != nil {
+		return fmt.Errorf("sync audit log: %w", err)
+	}
+	l.seq, l.head = r.Seq, r.Hash
+	return nil
+}
+
+// Close closes the log file.
+func (l *Log) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.f == nil {
+		return nil
+	}
+	err := l.f.Close()
+	l.f = nil
+	return err
+}
+
+// scan calls fn for each record in the file at path, in order.
+func scan(path string, fn func(Record) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return scanReader(f, fn)
+}
+
+// scanReader calls fn for each record read from r. A final line that has no
+// newline and does not parse is a torn write: it yields a *TornRecordError
+// after fn has seen every record before it.
+func scanReader(r io.Reader, fn func(Record) error) error {
+	br := bufio.NewReaderSize(r, 64*1024)
+	line := 0
+	var offset int64
+	for {
+		data, err := br.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return fmt.Errorf("read audit log: %w", err)
+		}
+		last := err == io.EOF
+		if last && len(data) == 0 {
+			return nil
+		}
+		line++
+		start := offset
+		offset += int64(len(data))
+
+		if len(bytes.TrimSpace(data)) > 0 {
+			var rec Record
+			if err := json.Unmarshal(data, &rec); err != nil {
+				if last {
+					return &TornRecordError{Line: line, Offset: start}
+				}
+				return fmt.Errorf("line %d: parse audit record: %w", line, err)
+			}
+			if err := fn(rec); err != nil {
+				return fmt.Errorf("line %d: %w", line, err)
+			}
+		}
+		if last {
+			return nil
+		}
+	}
+}
+
+// Verify checks the hash chain of the log at path. It returns the number of
+// records and the hash of the last one, which can be kept elsewhere to
+// detect records later removed from the end. If the file ends in a torn
+// record, the chain before it is still checked and returned along with a
+// *TornRecordError.
+func Verify(path string) (count uint64, head string, err error) {
+	err = scan(path, func(r Record) error {
+		if r.Seq != count+1 {
+			return fmt.Errorf("record %d: expected seq %d", r.Seq, count+1)
+		}
+		if r.PrevHash != head {
+			return fmt.Errorf("record %d: previous hash does not match record %d", r.Seq, count)
+		}
+		hash, err := r.computeHash()
+		if err != nil {
+			return err
+		}
+		if hash != r.Hash {
+			return fmt.Errorf("record %d: hash mismatch, record was modified", r.Seq)
+		}
+		count, head = r.Seq, r.Hash
+		return nil
+	})
+	return count, head, err
+}
+
+// Filter selects records. Zero fields match everything.
+type Filter struct {
+	Repo  string    // exact repo name
+	User  string    // author ID or display name
+	Since time.Time // records at or after this time
+}
+
+func (f Filter) match(r Record) bool {
+	if f.Repo != "" && r.Repo != f.Repo {
+		return false
+	}
+	if f.User != "" && r.AuthorID != f.User && r.Author != f.User {
+		return false
+	}
+	if !f.Since.IsZero() && r.Time.Before(f.Since) {
+		return false
+	}
+	return true
+}
+
+// Query returns the records of the log at path that match f, in order. A
+// torn final record is skipped.
+func Query(path string, f Filter) ([]Record, error) {
+	var out []Record
+	err := scan(path, func(r Record) error {
+		if f.match(r) {
+			out = append(out, r)
+		}
+		return nil
+	})
+	var torn *TornRecordError
+	if errors.As(err, &torn) {
+		err = nil
+	}
+	return out, err
+}
+
+// Format renders a record as one line for people to read.
+func Format(r Record) string {
+	who := r.Author
+	if r.AuthorID != "" && r.AuthorID != r.Author {
+		who = fmt.Sprintf("%s (%s)", r.Author, r.AuthorID)
+	}
+	if who == "" {
+		who = "-"
+	}
+	repo := r.Repo
+	if repo == "" {
+		repo = "-"
+	}
+
+	action := r.Prompt
+	if r.Kind == KindCommand {
+		action = strings.TrimSpace("/" + r.Command + " " + r.Args)
+	}
+	line := fmt.Sprintf("#%d %s %s %s/%s %s %s: %s [%s]",
+		r.Seq, r.Time.Format(time.RFC3339), repo, r.Provider, r.Channel, who, r.Kind, oneLine(action), r.Outcome)
+	if r.Detail != "" {
+		line += " " + oneLine(r.Detail)
+	}
+	return line
+}
+
+// oneLine collapses whitespace, including newlines, to single spaces.
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
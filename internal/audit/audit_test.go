@@ -0,0 +1,295 @@
+package audit
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeRecords(t *testing.T, path string, recs ...Record) {
+	t.Helper()
+	l, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	for _, r := range recs {
+		if err := l.Append(r); err != nil {
+			t.Fatalf("Append: %v", err)
+		}
+	}
+	if err := l.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+}
+
+func TestAppendAndVerify(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit", "log.jsonl")
+	writeRecords(t, path,
+		Record{Kind: KindCommand, Provider: "discord", Channel: "c1", AuthorID: "u1", Repo: "r1", Command: "clone", Args: "https://example.com/x.git x", Outcome: OutcomeOK},
+		Record{Kind: KindPrompt, Provider: "discord", Channel: "c1", AuthorID: "u1", Repo: "r1", Prompt: "fix it", Outcome: OutcomeOK},
+	)
+	// Reopening continues the chain.
+	writeRecords(t, path, Record{Kind: KindCommand, Provider: "discord", Channel: "c1", AuthorID: "u2", Repo: "r1", Command: "approve", Outcome: OutcomeOK})
+
+	count, head, err := Verify(path)
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if count != 3 {
+		t.Errorf("count = %d, want 3", count)
+	}
+	recs, err := Query(path, Filter{})
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	if len(recs) != 3 || recs[2].Hash != head || recs[2].PrevHash != recs[1].Hash || recs[0].PrevHash != "" {
+		t.Errorf("chain not linked: %+v", recs)
+	}
+	if recs[0].Time.Location() != time.UTC {
+		t.Errorf("time should be UTC, got %v", recs[0].Time.Location())
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Errorf("mode = %v, want 0600", perm)
+	}
+}
+
+func TestVerify_DetectsTampering(t *testing.T) {
+	recs := []Record{
+		{Kind: KindPrompt, AuthorID: "u1", Prompt: "one", Outcome: OutcomeOK},
+		{Kind: KindPrompt, AuthorID: "u1", Prompt: "two", Outcome: OutcomeOK},
+		{Kind: KindPrompt, AuthorID: "u1", Prompt: "three", Outcome: OutcomeOK},
+	}
+	tests := []struct {
+		name   string
+		tamper func(lines []string) []string
+		want   string
+	}{
+		{"modified", func(l []string) []string {
+			l[1] = strings.Replace(l[1], `"two"`, `"TWO"`, 1)
+			return l
+		}, "record 2: hash mismatch"},
+		{"deleted", func(l []string) []string {
+			return append(l[:1], l[2:]...)
+		}, "record 3: expected seq 2"},
+		{"reordered", func(l []string) []string {
+			l[1], l[2] = l[2], l[1]
+			return l
+		}, "record 3: expected seq 2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "log.jsonl")
+			writeRecords(t, path, recs...)
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+			if err := os.WriteFile(path, []byte(strings.Join(tt.tamper(lines), "\n")+"\n"), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			if _, _, err := Verify(path); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Verify error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerify_ForgedHashBreaksChain(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log.jsonl")
+	writeRecords(t, path,
+		Record{Kind: KindPrompt, Prompt: "one", Outcome: OutcomeOK},
+		Record{Kind: KindPrompt, Prompt: "two", Outcome: OutcomeOK},
+	)
+	recs, err := Query(path, Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Rewriting a record and fixing up its own hash still breaks the link
+	// from the record after it.
+	recs[0].Prompt = "forged"
+	recs[0].Hash, _ = recs[0].computeHash()
+	var buf strings.Builder
+	for _, r := range recs {
+		data, err := json.Marshal(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		buf.Write(append(data, '\n'))
+	}
+	if err := os.WriteFile(path, []byte(buf.String()), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := Verify(path); err == nil || !strings.Contains(err.Error(), "record 2: previous hash does not match") {
+		t.Errorf("Verify error = %v", err)
+	}
+}
+
+func TestOpen_CorruptFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log.jsonl")
+	if err := os.WriteFile(path, []byte("{not json\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Open(path); err == nil || !strings.Contains(err.Error(), "line 1") {
+		t.Errorf("Open error = %v, want parse error on line 1", err)
+	}
+}
+
+func TestAppend_Closed(t *testing.T) {
+	l, err := Open(filepath.Join(t.TempDir(), "log.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Close()
+	if err := l.Append(Record{Kind: KindPrompt}); err == nil {
+		t.Error("Append after Close should fail")
+	}
+}
+
+func TestQuery_Filter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log.jsonl")
+	old := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	writeRecords(t, path,
+		Record{Time: old, Kind: KindPrompt, Author: "alice", AuthorID: "id-alice", Repo: "r1", Outcome: OutcomeOK},
+		Record{Kind: KindPrompt, Author: "bob", AuthorID: "id-bob", Repo: "r1", Outcome: OutcomeOK},
+		Record{Kind: KindCommand, Author: "alice", AuthorID: "id-alice", Repo: "r2", Command: "status", Outcome: OutcomeOK},
+	)
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   []uint64
+	}{
+		{"all", Filter{}, []uint64{1, 2, 3}},
+		{"repo", Filter{Repo: "r1"}, []uint64{1, 2}},
+		{"user id", Filter{User: "id-alice"}, []uint64{1, 3}},
+		{"user name", Filter{User: "bob"}, []uint64{2}},
+		{"repo and user", Filter{Repo: "r1", User: "alice"}, []uint64{1}},
+		{"since", Filter{Since: old.Add(time.Hour)}, []uint64{2, 3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recs, err := Query(path, tt.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []uint64
+			for _, r := range recs {
+				got = append(got, r.Seq)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("seqs = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("seqs = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestFormat(t *testing.T) {
+	at := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		rec  Record
+		want string
+	}{
+		{"command", Record{Seq: 1, Time: at, Kind: KindCommand, Provider: "discord", Channel: "c1", Author: "alice", AuthorID: "id-alice", Repo: "r1", Command: "remove-repo", Args: "r1", Outcome: OutcomeOK, Detail: "Removed\nrepo"},
+			"#1 2024-05-01T12:00:00Z r1 discord/c1 alice (id-alice) command: /remove-repo r1 [ok] Removed repo"},
+		{"prompt", Record{Seq: 2, Time: at, Kind: KindPrompt, Provider: "terminal", Channel: "terminal", Author: "terminal", AuthorID: "terminal", Prompt: "fix\nit", Outcome: OutcomeQueued},
+			"#2 2024-05-01T12:00:00Z - terminal/terminal terminal prompt: fix it [queued]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Format(tt.rec); got != tt.want {
+				t.Errorf("Format = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOpen_TornFinalRecord(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log.jsonl")
+	writeRecords(t, path, Record{Kind: KindPrompt, Prompt: "one"}, Record{Kind: KindPrompt, Prompt: "two"})
+	intact, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A crash mid-append leaves part of a third record.
+	if err := os.WriteFile(path, append(intact, []byte(`{"seq":3,"kind":"pro`)...), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	count, head, err := Verify(path)
+	var torn *TornRecordError
+	if !errors.As(err, &torn) || torn.Line != 3 || torn.Offset != int64(len(intact)) {
+		t.Fatalf("Verify error = %v, want torn record at line 3", err)
+	}
+	if count != 2 || head == "" {
+		t.Errorf("Verify = %d, %q; want the intact chain of 2", count, head)
+	}
+	if recs, err := Query(path, Filter{}); err != nil || len(recs) != 2 {
+		t.Errorf("Query = %d records, %v; want the 2 intact ones", len(recs), err)
+	}
+
+	l, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open error = %v", err)
+	}
+	saved := fmt.Sprintf("%s.torn-%d", path, len(intact))
+	if l.Torn() == nil || l.Torn().Line != 3 || l.Torn().Saved != saved {
+		t.Errorf("Torn() = %+v, want line 3 saved to %s", l.Torn(), saved)
+	}
+	if err := l.Append(Record{Kind: KindPrompt, Prompt: "three"}); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+	l.Close()
+
+	if data, err := os.ReadFile(saved); err != nil || string(data) != `{"seq":3,"kind":"pro` {
+		t.Errorf("saved torn bytes = %q, %v", data, err)
+	}
+	if count, _, err := Verify(path); err != nil || count != 4 {
+		t.Errorf("after reopening, Verify = %d, %v; want 4 records", count, err)
+	}
+	recs, err := Query(path, Filter{})
+	if err != nil || len(recs) != 4 {
+		t.Fatalf("Query = %d records, %v", len(recs), err)
+	}
+	want := fmt.Sprintf("torn record at offset %d cut off and moved to %s", len(intact), saved)
+	if rec := recs[2]; rec.Kind != KindLog || rec.Outcome != OutcomeRecovered || rec.Detail != want {
+		t.Errorf("record 3 = %+v, want the recovery", rec)
+	}
+}
+
+func TestOpen_MissingFinalNewline(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log.jsonl")
+	writeRecords(t, path, Record{Kind: KindPrompt, Prompt: "one"})
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The record was written but its newline was not.
+	if err := os.WriteFile(path, data[:len(data)-1], 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	writeRecords(t, path, Record{Kind: KindPrompt, Prompt: "two"})
+	if count, _, err := Verify(path); err != nil || count != 2 {
+		t.Errorf("Verify = %d, %v; want both records kept", count, err)
+	}
+}
@@ -0,0 +1,190 @@
+// Package authz decides which bridge commands and prompts each user may send,
+// based on roles bound to user IDs and provider role IDs.
+//
+// IDs are only unique within a provider, so roles bind them as
+// "provider:id", e.g. "github:12345". An ID without a provider prefix is a
+// Discord ID, the only provider roles could name before plugins and GitHub.
+package authz
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// ErrDenied is matched by every error a Policy returns.
+var ErrDenied = errors.New("permission denied")
+
+// DefaultProvider is the provider of IDs written without a "provider:" prefix.
+const DefaultProvider = "discord"
+
+// Role is a named set of permissions and the users who hold it.
+type Role struct {
+	Name     string
+	Users    []string // "provider:id" AuthorIDs; "*" matches everyone
+	RoleIDs  []string // "provider:id" role IDs, e.g. Discord guild roles
+	Commands []string // bridge commands without the "/"; "*" for all
+	Repos    []string // repo name patterns (path.Match syntax); empty for all
+	Prompt   bool     // may send prompts to the LLM
+}
+
+// Subject is the user asking for permission. UserID and RoleIDs are
+// scoped to Provider.
+type Subject struct {
+	Provider string
+	UserID   string
+	RoleIDs  []string
+}
+
+// Policy grants the union of the permissions of every role a user holds.
+type Policy struct {
+	roles       []Role // in name order
+	defaultRole string
+}
+
+// New creates a Policy. Users that hold no role get defaultRole, if set.
+func New(roles []Role, defaultRole string) *Policy {
+	sorted := append([]Role(nil), roles...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+	return &Policy{roles: sorted, defaultRole: defaultRole}
+}
+
+// Roles returns the names of the roles s holds, in name order.
+func (p *Policy) Roles(s Subject) []string {
+	var names []string
+	for _, r := range p.held(s) {
+		names = append(names, r.Name)
+	}
+	return names
+}
+
+func (p *Policy) held(s Subject) []Role {
+	var held []Role
+	for _, r := range p.roles {
+		if r.holds(s) {
+			held = append(held, r)
+		}
+	}
+	if len(held) == 0 && p.defaultRole != "" {
+		for _, r := range p.roles {
+			if r.Name == p.defaultRole {
+				held = append(held, r)
+			}
+		}
+	}
+	return held
+}
+
+func (r Role) holds(s Subject) bool {
+	for _, u := range r.Users {
+		if u == "*" || (s.UserID != "" && qualify(u) == s.Provider+":"+s.UserID) {
+			return true
+		}
+	}
+	for _, id := range r.RoleIDs {
+		for _, have := range s.RoleIDs {
+			if qualify(id) == s.Provider+":"+have {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// qualify returns a configured ID in "provider:id" form.
+func qualify(id string) string {
+	if strings.Contains(id, ":") {
+		return id
+	}
+	return DefaultProvider + ":" + id
+}
+
+// appliesTo reports whether the role covers repo. Actions outside any repo
+// (repo == "") are covered by every role.
+func (r Role) appliesTo(repo string) bool {
+	if repo == "" || len(r.Repos) == 0 {
+		return true
+	}
+	for _, pattern := range r.Repos {
+		if ok, _ := path.Match(pattern, repo); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (r Role) allowsCommand(command string) bool {
+	for _, c := range r.Commands {
+		if c == "*" || c == command {
+			return true
+		}
+	}
+	return false
+}
+
+// CanCommand returns nil if s may run the bridge command (without "/") in
+// repo, which is "" for commands run outside a repo channel.
+func (p *Policy) CanCommand(s Subject, command, repo string) error {
+	held := p.held(s)
+	for _, r := range held {
+		if r.allowsCommand(command) && r.appliesTo(repo) {
+			return nil
+		}
+	}
+	return &DeniedError{Action: "use /" + command, Repo: repo, Roles: names(held)}
+}
+
+// CanPrompt returns nil if s may send prompts to the LLM of repo.
+func (p *Policy) CanPrompt(s Subject, repo string) error {
+	held := p.held(s)
+	for _, r := range held {
+		if r.Prompt && r.appliesTo(repo) {
+			return nil
+		}
+	}
+	return &DeniedError{Action: "send prompts", Repo: repo, Roles: names(held)}
+}
+
+func names(roles []Role) []string {
+	var out []string
+	for _, r := range roles {
+		out = append(out, r.Name)
+	}
+	return out
+}
+
+// DeniedError explains a denial: what was attempted and which roles the
+// user held.
+type DeniedError struct {
+	Action string // e.g. "use /clone"
+	Repo   string // "" outside a repo channel
+	Roles  []string
+}
+
+func (e *DeniedError) Error() string {
+	return "permission denied: " + e.Reason()
+}
+
+// Reason explains the denial to the user, e.g. "your role (viewer) may not
+// use /clone".
+func (e *DeniedError) Reason() string {
+	where := ""
+	if e.Repo != "" {
+		where = " in " + e.Repo
+	}
+	if len(e.Roles) == 0 {
+		return fmt.Sprintf("you have no role that may %s%s", e.Action, where)
+	}
+	label := "role"
+	if len(e.Roles) > 1 {
+		label = "roles"
+	}
+	return fmt.Sprintf("your %s (%s) may not %s%s", label, strings.Join(e.Roles, ", "), e.Action, where)
+}
+
+// Is makes errors.Is(err, ErrDenied) true.
+func (e *DeniedError) Is(target error) bool {
+	return target == ErrDenied
+}
@@ -0,0 +1,142 @@
+package authz
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func testPolicy(defaultRole string) *Policy {
+	return New([]Role{
+		{Name: "viewer", Users: []string{"*"}, Commands: []string{"status", "history"}},
+		{Name: "dev", RoleIDs: []string{"r-dev"}, Commands: []string{"status", "cancel", "restart"}, Repos: []string{"app", "app/*"}, Prompt: true},
+		{Name: "admin", Users: []string{"u-admin"}, Commands: []string{"*"}, Prompt: true},
+		{Name: "guest", Commands: []string{"help"}},
+	}, defaultRole)
+}
+
+func TestPolicy_Roles(t *testing.T) {
+	p := testPolicy("")
+	tests := []struct {
+		subject Subject
+		want    []string
+	}{
+		{Subject{Provider: "discord", UserID: "u1"}, []string{"viewer"}},
+		{Subject{Provider: "discord", UserID: "u1", RoleIDs: []string{"r-other", "r-dev"}}, []string{"dev", "viewer"}},
+		{Subject{Provider: "discord", UserID: "u-admin"}, []string{"admin", "viewer"}},
+	}
+	for _, tt := range tests {
+		if got := p.Roles(tt.subject); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Roles(%+v) = %v, want %v", tt.subject, got, tt.want)
+		}
+	}
+}
+
+func TestPolicy_DefaultRole(t *testing.T) {
+	p := New([]Role{
+		{Name: "admin", Users: []string{"u-admin"}, Commands: []string{"*"}},
+		{Name: "guest", Commands: []string{"help"}},
+	}, "guest")
+
+	if got := p.Roles(Subject{Provider: "discord", UserID: "u1"}); !reflect.DeepEqual(got, []string{"guest"}) {
+		t.Errorf("unbound user roles = %v, want [guest]", got)
+	}
+	if got := p.Roles(Subject{Provider: "discord", UserID: "u-admin"}); !reflect.DeepEqual(got, []string{"admin"}) {
+		t.Errorf("bound user should not also get the default role, got %v", got)
+	}
+	if got := New(nil, "").Roles(Subject{Provider: "discord", UserID: "u1"}); got != nil {
+		t.Errorf("no default role should leave users without roles, got %v", got)
+	}
+}
+
+func TestPolicy_CanCommand(t *testing.T) {
+	p := testPolicy("")
+	viewer := Subject{Provider: "discord", UserID: "u1"}
+	dev := Subject{Provider: "discord", UserID: "u2", RoleIDs: []string{"r-dev"}}
+	admin := Subject{Provider: "discord", UserID: "u-admin"}
+
+	tests := []struct {
+		name    string
+		subject Subject
+		command string
+		repo    string
+		allowed bool
+	}{
+		{"viewer status", viewer, "status", "app", true},
+		{"viewer cancel", viewer, "cancel", "app", false},
+		{"dev cancel own repo", dev, "cancel", "app", true},
+		{"dev cancel worktree", dev, "cancel", "app/feature", true},
+		{"dev cancel other repo", dev, "cancel", "infra", false},
+		{"dev command outside repo", dev, "restart", "", true},
+		{"dev clone", dev, "clone", "", false},
+		{"admin anything", admin, "remove-repo", "infra", true},
+	}
+	for _, tt := range tests {
+		err := p.CanCommand(tt.subject, tt.command, tt.repo)
+		if (err == nil) != tt.allowed {
+			t.Errorf("%s: CanCommand() = %v, allowed %v", tt.name, err, tt.allowed)
+		}
+		if err != nil && !errors.Is(err, ErrDenied) {
+			t.Errorf("%s: error %v should match ErrDenied", tt.name, err)
+		}
+	}
+}
+
+func TestPolicy_CanPrompt(t *testing.T) {
+	p := testPolicy("")
+
+	if err := p.CanPrompt(Subject{Provider: "discord", UserID: "u1"}, "app"); err == nil {
+		t.Error("read-only viewer should not prompt")
+	}
+	if err := p.CanPrompt(Subject{Provider: "discord", UserID: "u2", RoleIDs: []string{"r-dev"}}, "app"); err != nil {
+		t.Errorf("dev should prompt in app: %v", err)
+	}
+	if err := p.CanPrompt(Subject{Provider: "discord", UserID: "u2", RoleIDs: []string{"r-dev"}}, "infra"); err == nil {
+		t.Error("dev should not prompt outside its repos")
+	}
+}
+
+func TestDeniedError_Message(t *testing.T) {
+	p := New([]Role{{Name: "viewer", Users: []string{"u1"}}, {Name: "ops", Users: []string{"u1"}}}, "")
+
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{p.CanCommand(Subject{Provider: "discord", UserID: "u1"}, "clone", ""), "permission denied: your roles (ops, viewer) may not use /clone"},
+		{p.CanPrompt(Subject{Provider: "discord", UserID: "u1"}, "app"), "permission denied: your roles (ops, viewer) may not send prompts in app"},
+		{p.CanPrompt(Subject{Provider: "discord", UserID: "u9"}, "app"), "permission denied: you have no role that may send prompts in app"},
+		{New([]Role{{Name: "viewer", Users: []string{"*"}}}, "").CanCommand(Subject{}, "cancel", "app"), "permission denied: your role (viewer) may not use /cancel in app"},
+	}
+	for _, tt := range tests {
+		if tt.err == nil || tt.err.Error() != tt.want {
+			t.Errorf("error = %v, want %q", tt.err, tt.want)
+		}
+	}
+}
+
+func TestPolicy_IDsScopedByProvider(t *testing.T) {
+	p := New([]Role{
+		{Name: "admin", Users: []string{"12345"}, Commands: []string{"*"}, Prompt: true},
+		{Name: "reviewer", Users: []string{"github:12345"}, RoleIDs: []string{"matrix:@ops:example.org"}, Commands: []string{"status"}},
+	}, "")
+
+	tests := []struct {
+		subject Subject
+		want    []string
+	}{
+		{Subject{Provider: "discord", UserID: "12345"}, []string{"admin"}},
+		{Subject{Provider: "github", UserID: "12345"}, []string{"reviewer"}},
+		{Subject{Provider: "slack", UserID: "12345"}, nil},
+		{Subject{Provider: "matrix", UserID: "u1", RoleIDs: []string{"@ops:example.org"}}, []string{"reviewer"}},
+		{Subject{Provider: "discord", UserID: "u1", RoleIDs: []string{"@ops:example.org"}}, nil},
+	}
+	for _, tt := range tests {
+		if got := p.Roles(tt.subject); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Roles(%+v) = %v, want %v", tt.subject, got, tt.want)
+		}
+	}
+	if err := p.CanCommand(Subject{Provider: "github", UserID: "12345"}, "clone", ""); err == nil {
+		t.Error("a GitHub user must not get the admin role bound to the Discord user with the same ID")
+	}
+}
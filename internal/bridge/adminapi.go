@@ -0,0 +1,367 @@
+package bridge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/anthropics/llm-bridge/internal/adminapi"
+	"github.com/anthropics/llm-bridge/internal/audit"
+	"github.com/anthropics/llm-bridge/internal/config"
+	"github.com/anthropics/llm-bridge/internal/git"
+	"github.com/anthropics/llm-bridge/internal/llm"
+)
+
+// adminAPISource names the admin API as a prompt source and in the audit
+// log.
+const adminAPISource = "admin-api"
+
+// startAdminAPI starts the admin API server, if configured. Sessions it
+// starts live as long as ctx.
+func (b *Bridge) startAdminAPI(ctx context.Context) error {
+	cfg := b.currentConfig().AdminAPI
+	if !cfg.Enabled() {
+		return nil
+	}
+	srv := adminapi.New(&adminController{b: b, ctx: ctx}, cfg.Token)
+	if err := srv.Start(cfg.Listen); err != nil {
+		return fmt.Errorf("start admin api: %w", err)
+	}
+	b.adminAPI = srv
+	slog.Info("admin api started", "listen", cfg.Listen)
+	return nil
+}
+
+// closeAdminAPI stops the admin API server, if running.
+func (b *Bridge) closeAdminAPI() {
+	if b.adminAPI == nil {
+		return
+	}
+	if err := b.adminAPI.Close(); err != nil {
+		slog.Warn("close admin api failed", "error", err)
+	}
+}
+
+// adminController carries out admin API requests on the bridge. Every
+// request that changes something is audited.
+type adminController struct {
+	b   *Bridge
+	ctx context.Context
+}
+
+var _ adminapi.Controller = (*adminController)(nil)
+
+func (c *adminController) Repos() []adminapi.Repo {
+	b := c.b
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	repos := make([]adminapi.Repo, 0, len(b.cfg.Repos))
+	for _, name := range sortedKeys(b.cfg.Repos) {
+		repo := b.cfg.Repos[name]
+		llmName := repo.LLM
+		if llmName == "" {
+			llmName = b.cfg.Defaults.LLM
+		}
+		mode := repo.SessionMode
+		if mode == "" {
+			mode = config.SessionModeShared
+		}
+		repos = append(repos, adminapi.Repo{
+			Name:        name,
+			Provider:    repo.Provider,
+			ChannelID:   repo.ChannelID,
+			LLM:         llmName,
+			WorkingDir:  repo.WorkingDir,
+			Branch:      repo.Branch,
+			SessionMode: mode,
+			Active:      b.repoActiveLocked(name),
+		})
+	}
+	return repos
+}
+
+func (c *adminController) Sessions() []adminapi.Session {
+	b := c.b
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	sessions := make([]adminapi.Session, 0, len(b.repos))
+	for _, key := range sortedKeys(b.repos) {
+		sessions = append(sessions, sessionInfoLocked(key, b.repos[key]))
+	}
+	return sessions
+}
+
+// sessionInfoLocked describes a session for the admin API. Callers must
+// hold b.mu.
+func sessionInfoLocked(key string, session *repoSession) adminapi.Session {
+	info := adminapi.Session{
+		Key:      key,
+		Repo:     session.name,
+		User:     session.user.name,
+		UserID:   session.user.id,
+		State:    adminapi.StateStopped,
+		Queued:   len(session.queue),
+		Channels: make([]adminapi.Channel, 0, len(session.channels)),
+	}
+	for _, ch := range session.channels {
+		info.Channels = append(info.Channels, adminapi.Channel{Provider: ch.provider.Name(), ChannelID: ch.channelID, Mode: ch.mode})
+	}
+	if session.gitInfo != nil {
+		info.Branch = session.gitInfo.Branch
+		info.Worktree = session.gitInfo.IsWorktree
+	}
+	if session.llm == nil {
+		return info
+	}
+
+	info.LLM = session.llm.Name()
+	info.LastActive = session.llm.LastActivity().UTC()
+	info.IdleSeconds = int64(time.Since(session.llm.LastActivity()).Seconds())
+	busy, since, author := session.busy.snapshot()
+	switch {
+	case !session.llm.Running():
+	case session.permissionPending:
+		info.State = adminapi.StateWaitingPermission
+	case busy:
+		info.State = adminapi.StateBusy
+	default:
+		info.State = adminapi.StateIdle
+	}
+	if busy && session.llm.Running() {
+		info.BusySeconds = int64(time.Since(since).Seconds())
+		info.BusyWith = author
+	}
+	return info
+}
+
+func (c *adminController) AddRepo(req adminapi.AddRepoRequest) (adminapi.Repo, error) {
+	err := c.addRepo(req)
+	c.audit("add-repo", req.Name+" "+req.WorkingDir, req.Name, err)
+	if err != nil {
+		return adminapi.Repo{}, err
+	}
+	for _, repo := range c.Repos() {
+		if repo.Name == req.Name {
+			return repo, nil
+		}
+	}
+	return adminapi.Repo{}, fmt.Errorf("%w: repo %q was removed", adminapi.ErrNotFound, req.Name)
+}
+
+func (c *adminController) addRepo(req adminapi.AddRepoRequest) error {
+	switch {
+	case !git.IsSafeRepoName(req.Name):
+		return fmt.Errorf("%w: name must contain only letters, numbers, hyphens, and underscores", adminapi.ErrInvalid)
+	case req.Provider == "" || req.ChannelID == "" || req.WorkingDir == "":
+		return fmt.Errorf("%w: provider, channel_id and working_dir are required", adminapi.ErrInvalid)
+	}
+
+	repo := config.RepoConfig{
+		Provider:    req.Provider,
+		ChannelID:   req.ChannelID,
+		LLM:         req.LLM,
+		WorkingDir:  req.WorkingDir,
+		GitRoot:     req.GitRoot,
+		Branch:      req.Branch,
+		SessionMode: req.SessionMode,
+	}
+	if err := c.b.RuntimeAddRepo(req.Name, repo, true); err != nil {
+		if errors.Is(err, errRepoExists) || errors.Is(err, config.ErrDuplicateChannel) {
+			return fmt.Errorf("%w: %v", adminapi.ErrConflict, err)
+		}
+		return err
+	}
+	slog.Info("repo added via admin api", "repo", req.Name, "provider", req.Provider, "channel", req.ChannelID)
+	return nil
+}
+
+func (c *adminController) RemoveRepo(name string) error {
+	err := c.b.removeRepo(name)
+	if errors.Is(err, errRepoNotFound) {
+		err = fmt.Errorf("%w: repo %q", adminapi.ErrNotFound, name)
+	}
+	c.audit("remove-repo", name, name, err)
+	return err
+}
+
+func (c *adminController) StartSession(req adminapi.SessionRequest) (adminapi.Session, error) {
+	session, err := c.startSession(req)
+	c.audit("start", req.User, req.Repo, err)
+	return session, err
+}
+
+// startSession starts the session req names, attached to its repo's
+// channel, unless it is already running.
+func (c *adminController) startSession(req adminapi.SessionRequest) (adminapi.Session, error) {
+	repo, user, err := c.resolve(req)
+	if err != nil {
+		return adminapi.Session{}, err
+	}
+
+	b := c.b
+	b.mu.Lock()
+	prov, ok := b.providers[repo.Provider]
+	b.mu.Unlock()
+	if !ok || repo.ChannelID == "" {
+		return adminapi.Session{}, fmt.Errorf("%w: repo %q has no running channel to post output to", adminapi.ErrConflict, req.Repo)
+	}
+
+	session, err := b.getOrCreateSession(c.ctx, req.Repo, repo, prov, repo.ChannelID, user)
+	var full *poolFullError
+	if errors.As(err, &full) {
+		return adminapi.Session{}, fmt.Errorf("%w: %v", adminapi.ErrConflict, err)
+	}
+	if err != nil {
+		return adminapi.Session{}, err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return sessionInfoLocked(session.key(), session), nil
+}
+
+func (c *adminController) StopSession(req adminapi.SessionRequest) error {
+	stopped, err := c.stopSession(req, "stop")
+	if err == nil && !stopped {
+		err = fmt.Errorf("%w: no session for repo %q", adminapi.ErrNotFound, req.Repo)
+	}
+	c.audit("stop", req.User, req.Repo, err)
+	return err
+}
+
+// stopSession stops the session req names, reporting whether there was one.
+func (c *adminController) stopSession(req adminapi.SessionRequest, reason string) (bool, error) {
+	_, user, err := c.resolve(req)
+	if err != nil {
+		return false, err
+	}
+	b := c.b
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.stopSessionLocked(sessionKey(req.Repo, user), reason), nil
+}
+
+func (c *adminController) RestartSession(req adminapi.SessionRequest) (adminapi.Session, error) {
+	var session adminapi.Session
+	_, err := c.stopSession(req, "restart")
+	if err == nil {
+		session, err = c.startSession(req)
+	}
+	c.audit("restart", req.User, req.Repo, err)
+	return session, err
+}
+
+func (c *adminController) CancelSession(req adminapi.SessionRequest) error {
+	_, user, err := c.resolve(req)
+	if err == nil {
+		err = c.b.cancelSession(sessionKey(req.Repo, user))
+		if errors.Is(err, errSessionNotRunning) {
+			err = fmt.Errorf("%w: LLM of repo %q is not running", adminapi.ErrConflict, req.Repo)
+		}
+	}
+	c.audit("cancel", req.User, req.Repo, err)
+	return err
+}
+
+func (c *adminController) SendPrompt(req adminapi.PromptRequest) (adminapi.PromptResponse, error) {
+	author := req.Author
+	if author == "" {
+		author = adminAPISource
+	}
+	resp, err := c.sendPrompt(req, author)
+
+	rec := audit.Record{
+		Kind:     audit.KindPrompt,
+		Provider: adminAPISource,
+		Author:   author,
+		Repo:     req.Repo,
+		Prompt:   req.Text,
+		Outcome:  audit.OutcomeOK,
+	}
+	switch {
+	case err != nil:
+		rec.Outcome, rec.Detail = audit.OutcomeFailed, err.Error()
+	case resp.Position > 0:
+		rec.Outcome, rec.Detail = audit.OutcomeQueued, fmt.Sprintf("position %d", resp.Position)
+	}
+	c.b.audit(rec)
+	return resp, err
+}
+
+func (c *adminController) sendPrompt(req adminapi.PromptRequest, author string) (adminapi.PromptResponse, error) {
+	if _, err := c.startSession(req.SessionRequest); err != nil {
+		return adminapi.PromptResponse{}, err
+	}
+	_, user, err := c.resolve(req.SessionRequest)
+	if err != nil {
+		return adminapi.PromptResponse{}, err
+	}
+
+	b := c.b
+	key := sessionKey(req.Repo, user)
+	b.mu.Lock()
+	session, ok := b.repos[key]
+	var ch channelRef
+	if ok && len(session.channels) > 0 {
+		ch = session.channels[0]
+	}
+	b.mu.Unlock()
+	if ch.provider == nil {
+		return adminapi.PromptResponse{}, fmt.Errorf("%w: session of repo %q stopped", adminapi.ErrConflict, req.Repo)
+	}
+
+	position, err := b.submit(session, queuedPrompt{
+		msg: llm.Message{
+			Source:  adminAPISource,
+			Content: session.merger.FormatMessage(adminAPISource, req.Text),
+		},
+		author:    author,
+		text:      req.Text,
+		prov:      ch.provider,
+		channelID: ch.channelID,
+	})
+	if err != nil {
+		return adminapi.PromptResponse{}, err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return adminapi.PromptResponse{Session: sessionInfoLocked(key, session), Position: position}, nil
+}
+
+// resolve returns the config of the repo req names and whose session in it
+// req means.
+func (c *adminController) resolve(req adminapi.SessionRequest) (config.RepoConfig, sessionUser, error) {
+	b := c.b
+	b.mu.Lock()
+	repo, ok := b.cfg.Repos[req.Repo]
+	b.mu.Unlock()
+	if !ok {
+		return config.RepoConfig{}, sessionUser{}, fmt.Errorf("%w: repo %q", adminapi.ErrNotFound, req.Repo)
+	}
+	if req.User != "" && !repo.PerUser() {
+		return config.RepoConfig{}, sessionUser{}, fmt.Errorf("%w: repo %q does not have per_user sessions", adminapi.ErrInvalid, req.Repo)
+	}
+	return repo, sessionUserFor(repo, sessionUser{id: req.User, name: req.User}), nil
+}
+
+// audit records an admin API command.
+func (c *adminController) audit(command, args, repoName string, err error) {
+	rec := audit.Record{
+		Kind:     audit.KindCommand,
+		Provider: adminAPISource,
+		Author:   adminAPISource,
+		Repo:     repoName,
+		Command:  command,
+		Args:     strings.TrimSpace(args),
+		Outcome:  audit.OutcomeOK,
+	}
+	if err != nil {
+		rec.Outcome, rec.Detail = audit.OutcomeFailed, err.Error()
+	}
+	c.b.audit(rec)
+}
@@ -0,0 +1,201 @@
+package bridge
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/anthropics/llm-bridge/internal/adminapi"
+	"github.com/anthropics/llm-bridge/internal/audit"
+	"github.com/anthropics/llm-bridge/internal/config"
+)
+
+// adminBridge returns a bridge with a running Discord provider and the admin
+// API controller for it. It returns the LLMs it starts.
+func adminBridge(t *testing.T) (*Bridge, *adminController, *[]*mockLLM) {
+	t.Helper()
+	tb := newTestBridge(t)
+	return tb.b, &adminController{b: tb.b, ctx: context.Background()}, &tb.started
+}
+
+func TestAdminAPI_StartAndList(t *testing.T) {
+	b, ctl, started := adminBridge(t)
+
+	session, err := ctl.StartSession(adminapi.SessionRequest{Repo: "test-repo"})
+	if err != nil {
+		t.Fatalf("StartSession: %v", err)
+	}
+	if len(*started) != 1 {
+		t.Fatalf("started %d LLMs, want 1", len(*started))
+	}
+	if session.Key != "test-repo" || session.State != adminapi.StateIdle || session.LLM != "claude" ||
+		len(session.Channels) != 1 || session.Channels[0] != (adminapi.Channel{Provider: "discord", ChannelID: "channel-123", Mode: "read-write"}) {
+		t.Errorf("session = %+v", session)
+	}
+
+	// Starting a running session is a no-op.
+	if _, err := ctl.StartSession(adminapi.SessionRequest{Repo: "test-repo"}); err != nil || len(*started) != 1 {
+		t.Errorf("second StartSession: err = %v, started %d", err, len(*started))
+	}
+
+	repos := ctl.Repos()
+	if len(repos) != 2 || repos[0].Name != "other-repo" || repos[0].Active || repos[1].Name != "test-repo" || !repos[1].Active {
+		t.Errorf("repos = %+v", repos)
+	}
+	if repos[1].SessionMode != config.SessionModeShared || repos[1].LLM != "claude" {
+		t.Errorf("repo defaults = %+v", repos[1])
+	}
+
+	b.mu.Lock()
+	b.repos["test-repo"].permissionPending = true
+	b.mu.Unlock()
+	if sessions := ctl.Sessions(); len(sessions) != 1 || sessions[0].State != adminapi.StateWaitingPermission {
+		t.Errorf("sessions = %+v", sessions)
+	}
+}
+
+func TestAdminAPI_SendPrompt(t *testing.T) {
+	b, ctl, started := adminBridge(t)
+
+	resp, err := ctl.SendPrompt(adminapi.PromptRequest{SessionRequest: adminapi.SessionRequest{Repo: "test-repo"}, Text: "run the tests"})
+	if err != nil {
+		t.Fatalf("SendPrompt: %v", err)
+	}
+	if resp.Position != 0 || resp.Session.State != adminapi.StateBusy || resp.Session.BusyWith != adminAPISource {
+		t.Errorf("first prompt = %+v", resp)
+	}
+	if msgs := (*started)[0].getSentMessages(); len(msgs) != 1 || msgs[0].Content != "run the tests" || msgs[0].Source != adminAPISource {
+		t.Errorf("LLM got %+v", msgs)
+	}
+
+	resp, err = ctl.SendPrompt(adminapi.PromptRequest{SessionRequest: adminapi.SessionRequest{Repo: "test-repo"}, Text: "then lint", Author: "ci"})
+	if err != nil {
+		t.Fatalf("SendPrompt: %v", err)
+	}
+	if resp.Position != 1 || resp.Session.Queued != 1 {
+		t.Errorf("second prompt = %+v", resp)
+	}
+
+	endTurns(b)
+	if msgs := (*started)[0].getSentMessages(); len(msgs) != 2 {
+		t.Errorf("queued prompt should follow, LLM got %d messages", len(msgs))
+	}
+}
+
+func TestAdminAPI_StopRestartCancel(t *testing.T) {
+	_, ctl, started := adminBridge(t)
+	req := adminapi.SessionRequest{Repo: "test-repo"}
+
+	if err := ctl.CancelSession(req); !errors.Is(err, adminapi.ErrConflict) {
+		t.Errorf("cancel with no session: err = %v, want ErrConflict", err)
+	}
+	if err := ctl.StopSession(req); !errors.Is(err, adminapi.ErrNotFound) {
+		t.Errorf("stop with no session: err = %v, want ErrNotFound", err)
+	}
+
+	if _, err := ctl.RestartSession(req); err != nil {
+		t.Fatalf("restart with no session should start one: %v", err)
+	}
+	if _, err := ctl.RestartSession(req); err != nil {
+		t.Fatalf("RestartSession: %v", err)
+	}
+	if len(*started) != 2 || (*started)[0].Running() || !(*started)[1].Running() {
+		t.Errorf("restart should stop the old LLM and start a new one")
+	}
+
+	if err := ctl.CancelSession(req); err != nil {
+		t.Errorf("CancelSession: %v", err)
+	}
+	if err := ctl.StopSession(req); err != nil {
+		t.Errorf("StopSession: %v", err)
+	}
+	if (*started)[1].Running() || len(ctl.Sessions()) != 0 {
+		t.Error("stop should stop and forget the session")
+	}
+}
+
+func TestAdminAPI_Errors(t *testing.T) {
+	b, ctl, _ := adminBridge(t)
+
+	if _, err := ctl.StartSession(adminapi.SessionRequest{Repo: "nope"}); !errors.Is(err, adminapi.ErrNotFound) {
+		t.Errorf("unknown repo: err = %v", err)
+	}
+	if _, err := ctl.StartSession(adminapi.SessionRequest{Repo: "test-repo", User: "u1"}); !errors.Is(err, adminapi.ErrInvalid) {
+		t.Errorf("user in shared repo: err = %v", err)
+	}
+
+	b.mu.Lock()
+	delete(b.providers, "discord")
+	b.mu.Unlock()
+	if _, err := ctl.StartSession(adminapi.SessionRequest{Repo: "test-repo"}); !errors.Is(err, adminapi.ErrConflict) {
+		t.Errorf("provider not running: err = %v", err)
+	}
+
+	if _, err := ctl.AddRepo(adminapi.AddRepoRequest{Name: "../etc", Provider: "discord", ChannelID: "c9", WorkingDir: "/tmp/x"}); !errors.Is(err, adminapi.ErrInvalid) {
+		t.Errorf("unsafe name: err = %v", err)
+	}
+	if _, err := ctl.AddRepo(adminapi.AddRepoRequest{Name: "x"}); !errors.Is(err, adminapi.ErrInvalid) {
+		t.Errorf("missing fields: err = %v", err)
+	}
+	if err := ctl.RemoveRepo("nope"); !errors.Is(err, adminapi.ErrNotFound) {
+		t.Errorf("remove unknown repo: err = %v", err)
+	}
+}
+
+func TestAdminAPI_AddRepoConflicts(t *testing.T) {
+	b, ctl, _ := adminBridge(t)
+	b.cfgPath = filepath.Join(t.TempDir(), "llm-bridge.yaml")
+	writeReloadConfig(t, b.cfgPath, `repos:
+  test-repo: {provider: discord, channel_id: channel-123, working_dir: /tmp/test}
+  other-repo: {provider: discord, channel_id: channel-456, working_dir: /tmp/other}
+`)
+
+	if _, err := ctl.AddRepo(adminapi.AddRepoRequest{Name: "test-repo", Provider: "discord", ChannelID: "c9", WorkingDir: "/tmp/x"}); !errors.Is(err, adminapi.ErrConflict) {
+		t.Errorf("existing name: err = %v, want ErrConflict", err)
+	}
+	if _, err := ctl.AddRepo(adminapi.AddRepoRequest{Name: "x", Provider: "discord", ChannelID: "channel-456", WorkingDir: "/tmp/x"}); !errors.Is(err, adminapi.ErrConflict) {
+		t.Errorf("channel in use: err = %v, want ErrConflict", err)
+	}
+	if _, err := ctl.AddRepo(adminapi.AddRepoRequest{Name: "x", Provider: "discord", ChannelID: "c9", WorkingDir: "/tmp/x"}); err != nil {
+		t.Errorf("new repo: err = %v", err)
+	}
+}
+
+func TestAdminAPI_PerUser(t *testing.T) {
+	b, ctl, started := adminBridge(t)
+	repo := b.cfg.Repos["test-repo"]
+	repo.SessionMode = config.SessionModePerUser
+	b.cfg.Repos["test-repo"] = repo
+
+	session, err := ctl.StartSession(adminapi.SessionRequest{Repo: "test-repo", User: "u1"})
+	if err != nil {
+		t.Fatalf("StartSession: %v", err)
+	}
+	if session.Key != "test-repo@u1" || session.UserID != "u1" || len(*started) != 1 {
+		t.Errorf("session = %+v", session)
+	}
+}
+
+func TestAdminAPI_Audited(t *testing.T) {
+	b, ctl, _ := adminBridge(t)
+	path := withAuditLog(t, b)
+
+	_, _ = ctl.SendPrompt(adminapi.PromptRequest{SessionRequest: adminapi.SessionRequest{Repo: "test-repo"}, Text: "hello", Author: "ops-bot"})
+	_ = ctl.StopSession(adminapi.SessionRequest{Repo: "test-repo"})
+	_ = ctl.RemoveRepo("nope")
+
+	recs := auditRecords(t, path)
+	if len(recs) != 3 {
+		t.Fatalf("got %d records, want 3: %+v", len(recs), recs)
+	}
+	if r := recs[0]; r.Kind != audit.KindPrompt || r.Provider != adminAPISource || r.Author != "ops-bot" || r.Prompt != "hello" || r.Outcome != audit.OutcomeOK {
+		t.Errorf("prompt record = %+v", r)
+	}
+	if r := recs[1]; r.Command != "stop" || r.Repo != "test-repo" || r.Outcome != audit.OutcomeOK {
+		t.Errorf("stop record = %+v", r)
+	}
+	if r := recs[2]; r.Command != "remove-repo" || r.Outcome != audit.OutcomeFailed {
+		t.Errorf("remove record = %+v", r)
+	}
+}
@@ -0,0 +1,283 @@
+package bridge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/anthropics/llm-bridge/internal/config"
+	"github.com/anthropics/llm-bridge/internal/git"
+	"github.com/anthropics/llm-bridge/internal/llm"
+	"github.com/anthropics/llm-bridge/internal/provider"
+)
+
+// AttachmentFetcher downloads an attachment. Implementations must not return
+// more than maxBytes bytes; errAttachmentTooLarge signals the cap was hit.
+// Defaults to fetchAttachment.
+type AttachmentFetcher func(ctx context.Context, url string, maxBytes int64) ([]byte, error)
+
+var errAttachmentTooLarge = errors.New("attachment exceeds size limit")
+
+var errAttachmentDestination = errors.New("attachment host is not a public address")
+
+// attachmentHTTPClient bounds how long a single download may take and only
+// connects to public addresses, so an attachment URL (or a redirect) cannot
+// reach the bridge host's loopback, link-local or private networks.
+var attachmentHTTPClient = &http.Client{
+	Timeout: 30 * time.Second,
+	Transport: &http.Transport{
+		DialContext: (&net.Dialer{
+			Timeout: 10 * time.Second,
+			Control: func(_, address string, _ syscall.RawConn) error {
+				return checkAttachmentAddress(address)
+			},
+		}).DialContext,
+		TLSHandshakeTimeout: 10 * time.Second,
+	},
+}
+
+// unsafeFilenameChars matches anything outside the conservative set used for inbox filenames.
+var unsafeFilenameChars = regexp.MustCompile(`[^A-Za-z0-9._-]`)
+
+// fetchAttachment downloads rawURL over HTTP(S), reading at most maxBytes+1
+// bytes so oversize bodies are detected without buffering them fully.
+func fetchAttachment(ctx context.Context, rawURL string, maxBytes int64) ([]byte, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("parse url: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("unsupported url scheme %q", u.Scheme)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("build request: %w", err)
+	}
+
+	resp, err := attachmentHTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("download: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("download: unexpected status %s", resp.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("read body: %w", err)
+	}
+	if int64(len(data)) > maxBytes {
+		return nil, errAttachmentTooLarge
+	}
+	return data, nil
+}
+
+// checkAttachmentAddress rejects a resolved ip:port that is not a public
+// unicast address. It runs for every connection, including redirects.
+func checkAttachmentAddress(address string) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsMulticast() {
+		return fmt.Errorf("%w: %s", errAttachmentDestination, host)
+	}
+	return nil
+}
+
+// saveAttachments downloads a message's attachments into the repo's inbox
+// directory (inside WorkingDir) so the LLM can read them from disk.
+// Attachments that are too large, of a disallowed type, or fail to download
+// are skipped; a human-readable notice is returned for each one.
+func (b *Bridge) saveAttachments(ctx context.Context, repo config.RepoConfig, attachments []provider.Attachment) ([]llm.Attachment, []string) {
+	cfg := b.currentConfig().Defaults.Attachments
+	if !cfg.GetAttachmentsEnabled() {
+		return nil, []string{fmt.Sprintf("Attachments are disabled; ignored %d file(s)", len(attachments))}
+	}
+
+	maxBytes := cfg.GetMaxBytes()
+	allowed := cfg.GetAllowedTypes()
+
+	root, err := filepath.Abs(repo.WorkingDir)
+	if err != nil {
+		return nil, []string{fmt.Sprintf("Attachments skipped: %v", err)}
+	}
+	inbox := filepath.Join(root, cfg.GetInboxDir())
+	if err := mkdirInTree(root, inbox, 0700); err != nil {
+		slog.Error("create attachment inbox failed", "dir", inbox, "error", err)
+		return nil, []string{fmt.Sprintf("Attachments skipped: cannot create inbox: %v", err)}
+	}
+	excludeFromGit(root, inbox)
+
+	var saved []llm.Attachment
+	var notices []string
+	stamp := time.Now().Format("20060102-150405")
+
+	for i, att := range attachments {
+		if att.Size > maxBytes {
+			notices = append(notices, fmt.Sprintf("Skipped %s: too large (%d bytes, limit %d)", att.Filename, att.Size, maxBytes))
+			continue
+		}
+
+		contentType := attachmentContentType(att)
+		if contentType != "" && !attachmentTypeAllowed(contentType, allowed) {
+			notices = append(notices, fmt.Sprintf("Skipped %s: type %s not allowed", att.Filename, contentType))
+			continue
+		}
+
+		data, err := b.attachmentFetcher(ctx, att.URL, maxBytes)
+		if err != nil {
+			if errors.Is(err, errAttachmentTooLarge) {
+				notices = append(notices, fmt.Sprintf("Skipped %s: too large (limit %d bytes)", att.Filename, maxBytes))
+			} else {
+				slog.Warn("attachment download failed", "file", att.Filename, "error", err)
+				notices = append(notices, fmt.Sprintf("Skipped %s: download failed", att.Filename))
+			}
+			continue
+		}
+
+		// Sniff the body when the chat service did not report a type.
+		if contentType == "" {
+			contentType, _, _ = mime.ParseMediaType(http.DetectContentType(data))
+			if !attachmentTypeAllowed(contentType, allowed) {
+				notices = append(notices, fmt.Sprintf("Skipped %s: type %s not allowed", att.Filename, contentType))
+				continue
+			}
+		}
+
+		path := filepath.Join(inbox, fmt.Sprintf("%s-%d-%s", stamp, i, sanitizeAttachmentName(att.Filename)))
+		if err := writeNewFile(path, data); err != nil {
+			slog.Error("save attachment failed", "path", path, "error", err)
+			notices = append(notices, fmt.Sprintf("Skipped %s: could not save", att.Filename))
+			continue
+		}
+
+		slog.Info("saved attachment", "file", att.Filename, "path", path, "bytes", len(data), "type", contentType)
+		saved = append(saved, llm.Attachment{Path: path, ContentType: contentType})
+	}
+
+	return saved, notices
+}
+
+// attachmentContentType returns the bare MIME type reported for an attachment,
+// falling back to the filename extension. Returns "" if neither is known.
+func attachmentContentType(att provider.Attachment) string {
+	ct := att.ContentType
+	if ct == "" {
+		ct = mime.TypeByExtension(filepath.Ext(att.Filename))
+	}
+	if ct == "" {
+		return ""
+	}
+	mediaType, _, err := mime.ParseMediaType(ct)
+	if err != nil {
+		return ""
+	}
+	return mediaType
+}
+
+// attachmentTypeAllowed matches a MIME type against an allowlist of exact
+// types and "type/*" wildcards.
+func attachmentTypeAllowed(contentType string, allowed []string) bool {
+	for _, pattern := range allowed {
+		if pattern == contentType {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(pattern, "/*"); ok && strings.HasPrefix(contentType, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// sanitizeAttachmentName reduces an uploaded filename to a safe base name.
+func sanitizeAttachmentName(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	name = unsafeFilenameChars.ReplaceAllString(name, "_")
+	name = strings.TrimLeft(name, ".")
+	if name == "" {
+		return "attachment"
+	}
+	return name
+}
+
+// mkdirInTree creates dir, which must be inside root, one component at a
+// time. Unlike os.MkdirAll it refuses to follow a symlink, so a link planted
+// in the working tree (e.g. by the LLM) cannot redirect writes outside it.
+func mkdirInTree(root, dir string, perm os.FileMode) error {
+	rel, err := filepath.Rel(root, dir)
+	if err != nil {
+		return err
+	}
+	if rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("%s is outside %s", dir, root)
+	}
+	path := root
+	for _, part := range strings.Split(rel, string(filepath.Separator)) {
+		if part == "." {
+			continue
+		}
+		path = filepath.Join(path, part)
+		info, err := os.Lstat(path)
+		if errors.Is(err, os.ErrNotExist) {
+			if err := os.Mkdir(path, perm); err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			return fmt.Errorf("%s is a symlink", path)
+		}
+		if !info.IsDir() {
+			return fmt.Errorf("%s is not a directory", path)
+		}
+	}
+	return nil
+}
+
+// excludeFromGit lists dir, inside the working tree root, in the repo's
+// info/exclude so bridge files never show up as changes the LLM might
+// commit. Working dirs that are not git repos are left alone.
+func excludeFromGit(root, dir string) {
+	rel, err := filepath.Rel(root, dir)
+	if err != nil {
+		return
+	}
+	pattern := "/" + filepath.ToSlash(rel) + "/"
+	if err := git.Exclude(root, pattern); err != nil {
+		slog.Debug("git exclude skipped", "dir", root, "pattern", pattern, "error", err)
+	}
+}
+
+// writeNewFile writes data to path, failing if the file already exists.
+func writeNewFile(path string, data []byte) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,370 @@
+package bridge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anthropics/llm-bridge/internal/config"
+	"github.com/anthropics/llm-bridge/internal/provider"
+	"github.com/anthropics/llm-bridge/internal/router"
+)
+
+func attachmentTestBridge(t *testing.T) (*Bridge, config.RepoConfig) {
+	t.Helper()
+	cfg := testConfig()
+	repo := cfg.Repos["test-repo"]
+	repo.WorkingDir = t.TempDir()
+	cfg.Repos["test-repo"] = repo
+	return newTestBridge(t, withConfig(cfg)).b, repo
+}
+
+func TestBridge_SaveAttachments_Success(t *testing.T) {
+	b, repo := attachmentTestBridge(t)
+	b.attachmentFetcher = func(_ context.Context, url string, _ int64) ([]byte, error) {
+		return []byte("contents of " + url), nil
+	}
+
+	saved, notices := b.saveAttachments(context.Background(), repo, []provider.Attachment{
+		{Filename: "build.log", URL: "u1", ContentType: "text/plain; charset=utf-8", Size: 10},
+		{Filename: "shot.png", URL: "u2", ContentType: "image/png", Size: 10},
+	})
+
+	if len(notices) != 0 {
+		t.Errorf("unexpected notices: %v", notices)
+	}
+	if len(saved) != 2 {
+		t.Fatalf("expected 2 saved attachments, got %d", len(saved))
+	}
+
+	inbox := filepath.Join(repo.WorkingDir, ".llm-bridge", "inbox")
+	for _, a := range saved {
+		if filepath.Dir(a.Path) != inbox {
+			t.Errorf("attachment saved to %q, want inside %q", a.Path, inbox)
+		}
+	}
+	if saved[0].ContentType != "text/plain" {
+		t.Errorf("ContentType = %q, want text/plain", saved[0].ContentType)
+	}
+	if !saved[1].IsImage() {
+		t.Error("png attachment should be an image")
+	}
+
+	data, err := os.ReadFile(saved[0].Path)
+	if err != nil {
+		t.Fatalf("read saved file: %v", err)
+	}
+	if string(data) != "contents of u1" {
+		t.Errorf("saved content = %q", data)
+	}
+	if !strings.HasSuffix(saved[0].Path, "build.log") {
+		t.Errorf("saved path %q should keep original name", saved[0].Path)
+	}
+}
+
+func TestBridge_SaveAttachments_TooLarge(t *testing.T) {
+	b, repo := attachmentTestBridge(t)
+	b.cfg.Defaults.Attachments.MaxBytes = 100
+	fetched := false
+	b.attachmentFetcher = func(_ context.Context, _ string, _ int64) ([]byte, error) {
+		fetched = true
+		return nil, nil
+	}
+
+	saved, notices := b.saveAttachments(context.Background(), repo, []provider.Attachment{
+		{Filename: "huge.log", URL: "u", ContentType: "text/plain", Size: 101},
+	})
+
+	if len(saved) != 0 {
+		t.Errorf("oversize attachment should not be saved")
+	}
+	if fetched {
+		t.Error("oversize attachment should not be downloaded")
+	}
+	if len(notices) != 1 || !strings.Contains(notices[0], "too large") {
+		t.Errorf("expected too-large notice, got %v", notices)
+	}
+}
+
+func TestBridge_SaveAttachments_TooLargeDuringDownload(t *testing.T) {
+	b, repo := attachmentTestBridge(t)
+	b.attachmentFetcher = func(_ context.Context, _ string, _ int64) ([]byte, error) {
+		return nil, errAttachmentTooLarge
+	}
+
+	saved, notices := b.saveAttachments(context.Background(), repo, []provider.Attachment{
+		{Filename: "liar.log", URL: "u", ContentType: "text/plain", Size: 1},
+	})
+
+	if len(saved) != 0 {
+		t.Error("attachment should not be saved")
+	}
+	if len(notices) != 1 || !strings.Contains(notices[0], "too large") {
+		t.Errorf("expected too-large notice, got %v", notices)
+	}
+}
+
+func TestBridge_SaveAttachments_DisallowedType(t *testing.T) {
+	b, repo := attachmentTestBridge(t)
+	b.attachmentFetcher = func(_ context.Context, _ string, _ int64) ([]byte, error) {
+		t.Error("disallowed attachment should not be downloaded")
+		return nil, nil
+	}
+
+	saved, notices := b.saveAttachments(context.Background(), repo, []provider.Attachment{
+		{Filename: "tool.exe", URL: "u", ContentType: "application/x-msdownload", Size: 10},
+	})
+
+	if len(saved) != 0 {
+		t.Error("disallowed attachment should not be saved")
+	}
+	if len(notices) != 1 || !strings.Contains(notices[0], "not allowed") {
+		t.Errorf("expected not-allowed notice, got %v", notices)
+	}
+}
+
+func TestBridge_SaveAttachments_SniffsUnknownType(t *testing.T) {
+	b, repo := attachmentTestBridge(t)
+	b.attachmentFetcher = func(_ context.Context, _ string, _ int64) ([]byte, error) {
+		return []byte("\x7fELF\x02\x01\x01\x00binary"), nil
+	}
+
+	saved, notices := b.saveAttachments(context.Background(), repo, []provider.Attachment{
+		{Filename: "blob", URL: "u", Size: 10},
+	})
+
+	if len(saved) != 0 {
+		t.Error("binary attachment should not be saved")
+	}
+	if len(notices) != 1 || !strings.Contains(notices[0], "not allowed") {
+		t.Errorf("expected not-allowed notice, got %v", notices)
+	}
+}
+
+func TestBridge_SaveAttachments_DownloadError(t *testing.T) {
+	b, repo := attachmentTestBridge(t)
+	b.attachmentFetcher = func(_ context.Context, _ string, _ int64) ([]byte, error) {
+		return nil, fmt.Errorf("connection reset")
+	}
+
+	saved, notices := b.saveAttachments(context.Background(), repo, []provider.Attachment{
+		{Filename: "a.txt", URL: "u", ContentType: "text/plain", Size: 1},
+	})
+
+	if len(saved) != 0 {
+		t.Error("failed download should not be saved")
+	}
+	if len(notices) != 1 || !strings.Contains(notices[0], "download failed") {
+		t.Errorf("expected download-failed notice, got %v", notices)
+	}
+}
+
+func TestBridge_SaveAttachments_Disabled(t *testing.T) {
+	b, repo := attachmentTestBridge(t)
+	disabled := false
+	b.cfg.Defaults.Attachments.Enabled = &disabled
+
+	saved, notices := b.saveAttachments(context.Background(), repo, []provider.Attachment{
+		{Filename: "a.txt", URL: "u", ContentType: "text/plain", Size: 1},
+	})
+
+	if len(saved) != 0 {
+		t.Error("attachments should not be saved when disabled")
+	}
+	if len(notices) != 1 || !strings.Contains(notices[0], "disabled") {
+		t.Errorf("expected disabled notice, got %v", notices)
+	}
+}
+
+func TestBridge_HandleLLMMessage_WithAttachments(t *testing.T) {
+	b, _ := attachmentTestBridge(t)
+	b.attachmentFetcher = func(_ context.Context, _ string, _ int64) ([]byte, error) {
+		return []byte("panic: boom"), nil
+	}
+
+	mockLLM := newMockLLM("claude")
+	mockLLM.setRunning(true)
+	mockProv := provider.NewMockProvider("discord")
+	b.repos["test-repo"] = &repoSession{
+		name:     "test-repo",
+		llm:      mockLLM,
+		channels: []channelRef{{provider: mockProv, channelID: "channel-123"}},
+		merger:   NewMerger(2 * time.Second),
+	}
+
+	msg := provider.Message{
+		ChannelID: "channel-123",
+		Content:   "why does this crash?",
+		Source:    "discord",
+		Attachments: []provider.Attachment{
+			{Filename: "crash.log", URL: "u", ContentType: "text/plain", Size: 11},
+		},
+	}
+	b.handleLLMMessage(context.Background(), mockProv, msg, router.Parse(msg.Content))
+
+	sent := mockLLM.getSentMessages()
+	if len(sent) != 1 {
+		t.Fatalf("expected 1 LLM message, got %d", len(sent))
+	}
+	if sent[0].Content != "why does this crash?" {
+		t.Errorf("Content = %q", sent[0].Content)
+	}
+	if len(sent[0].Attachments) != 1 || !strings.HasSuffix(sent[0].Attachments[0].Path, "crash.log") {
+		t.Errorf("Attachments = %+v", sent[0].Attachments)
+	}
+}
+
+func TestBridge_HandleLLMMessage_OnlyRejectedAttachments(t *testing.T) {
+	b, _ := attachmentTestBridge(t)
+
+	mockLLM := newMockLLM("claude")
+	mockLLM.setRunning(true)
+	mockProv := provider.NewMockProvider("discord")
+	b.repos["test-repo"] = &repoSession{
+		name:     "test-repo",
+		llm:      mockLLM,
+		channels: []channelRef{{provider: mockProv, channelID: "channel-123"}},
+		merger:   NewMerger(2 * time.Second),
+	}
+
+	msg := provider.Message{
+		ChannelID: "channel-123",
+		Source:    "discord",
+		Attachments: []provider.Attachment{
+			{Filename: "tool.exe", URL: "u", ContentType: "application/x-msdownload", Size: 1},
+		},
+	}
+	b.handleLLMMessage(context.Background(), mockProv, msg, router.Parse(msg.Content))
+
+	if len(mockLLM.getSentMessages()) != 0 {
+		t.Error("empty prompt with no usable attachments should not reach the LLM")
+	}
+	msgs := mockProv.GetSentMessages()
+	if len(msgs) != 1 || !strings.Contains(msgs[0].Content, "not allowed") {
+		t.Errorf("expected rejection notice, got %v", msgs)
+	}
+}
+
+func TestFetchAttachment(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ok":
+			_, _ = w.Write([]byte("hello"))
+		case "/big":
+			_, _ = w.Write([]byte(strings.Repeat("x", 64)))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	// The test server listens on loopback, which the real client refuses.
+	defaultClient := attachmentHTTPClient
+	attachmentHTTPClient = srv.Client()
+	defer func() { attachmentHTTPClient = defaultClient }()
+
+	data, err := fetchAttachment(context.Background(), srv.URL+"/ok", 10)
+	if err != nil || string(data) != "hello" {
+		t.Errorf("fetchAttachment(ok) = %q, %v", data, err)
+	}
+
+	if _, err := fetchAttachment(context.Background(), srv.URL+"/big", 10); err != errAttachmentTooLarge {
+		t.Errorf("fetchAttachment(big) error = %v, want errAttachmentTooLarge", err)
+	}
+
+	if _, err := fetchAttachment(context.Background(), srv.URL+"/missing", 10); err == nil {
+		t.Error("fetchAttachment(missing) should fail on 404")
+	}
+}
+
+func TestFetchAttachment_RefusesInternalDestinations(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("secret"))
+	}))
+	defer srv.Close()
+
+	if _, err := fetchAttachment(context.Background(), srv.URL+"/ok", 10); !errors.Is(err, errAttachmentDestination) {
+		t.Errorf("fetchAttachment(loopback) error = %v, want errAttachmentDestination", err)
+	}
+	if _, err := fetchAttachment(context.Background(), "file:///etc/passwd", 10); err == nil {
+		t.Error("fetchAttachment(file://) should fail")
+	}
+
+	for _, addr := range []string{"127.0.0.1:80", "[::1]:443", "10.1.2.3:80", "192.168.0.1:80", "169.254.169.254:80", "[fe80::1]:80", "0.0.0.0:80"} {
+		if err := checkAttachmentAddress(addr); err == nil {
+			t.Errorf("checkAttachmentAddress(%s) allowed", addr)
+		}
+	}
+	if err := checkAttachmentAddress("203.0.113.7:443"); err != nil {
+		t.Errorf("checkAttachmentAddress(public) error = %v", err)
+	}
+}
+
+func TestBridge_SaveAttachments_RefusesSymlinkedInbox(t *testing.T) {
+	b, repo := attachmentTestBridge(t)
+	b.attachmentFetcher = func(_ context.Context, _ string, _ int64) ([]byte, error) {
+		return []byte("data"), nil
+	}
+	outside := t.TempDir()
+	if err := os.Symlink(outside, filepath.Join(repo.WorkingDir, ".llm-bridge")); err != nil {
+		t.Fatalf("symlink: %v", err)
+	}
+
+	saved, notices := b.saveAttachments(context.Background(), repo, []provider.Attachment{
+		{Filename: "a.txt", URL: "u1", ContentType: "text/plain", Size: 4},
+	})
+
+	if len(saved) != 0 || len(notices) != 1 || !strings.Contains(notices[0], "cannot create inbox") {
+		t.Errorf("saved = %v, notices = %v; want inbox refused", saved, notices)
+	}
+	if entries, _ := os.ReadDir(outside); len(entries) != 0 {
+		t.Errorf("wrote through the symlink: %v", entries)
+	}
+}
+
+func TestAttachmentTypeAllowed(t *testing.T) {
+	allowed := []string{"text/*", "image/png"}
+	tests := []struct {
+		contentType string
+		want        bool
+	}{
+		{"text/plain", true},
+		{"text/x-log", true},
+		{"image/png", true},
+		{"image/jpeg", false},
+		{"textual/plain", false},
+		{"application/octet-stream", false},
+	}
+	for _, tt := range tests {
+		if got := attachmentTypeAllowed(tt.contentType, allowed); got != tt.want {
+			t.Errorf("attachmentTypeAllowed(%q) = %v, want %v", tt.contentType, got, tt.want)
+		}
+	}
+}
+
+func TestSanitizeAttachmentName(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"build.log", "build.log"},
+		{"../../etc/passwd", "passwd"},
+		{`..\..\windows\evil.bat`, "evil.bat"},
+		{"my file (1).txt", "my_file__1_.txt"},
+		{".hidden", "hidden"},
+		{"..", "attachment"},
+		{"", "attachment"},
+	}
+	for _, tt := range tests {
+		if got := sanitizeAttachmentName(tt.in); got != tt.want {
+			t.Errorf("sanitizeAttachmentName(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package bridge
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/anthropics/llm-bridge/internal/audit"
+	"github.com/anthropics/llm-bridge/internal/config"
+	"github.com/anthropics/llm-bridge/internal/provider"
+	"github.com/anthropics/llm-bridge/internal/router"
+)
+
+// maxAuditDetail bounds the command response kept in an audit record.
+const maxAuditDetail = 200
+
+// auditPath returns the audit log path, or "" if auditing is off or the
+// bridge has no config file to keep it next to (e.g. in tests).
+func (b *Bridge) auditPath() string {
+	cfg := b.currentConfig()
+	if b.cfgPath == "" || !cfg.Defaults.Audit.GetAuditEnabled() {
+		return ""
+	}
+	return config.ResolvePath(b.cfgPath, cfg.Defaults.Audit.GetFile())
+}
+
+// openAuditLog opens the audit log, if enabled. It runs before providers
+// start so no command goes unrecorded.
+func (b *Bridge) openAuditLog() error {
+	path := b.auditPath()
+	if path == "" {
+		return nil
+	}
+	log, err := audit.Open(path)
+	if err != nil {
+		return fmt.Errorf("open audit log: %w", err)
+	}
+	if torn := log.Torn(); torn != nil {
+		slog.Warn("audit log ended in an incomplete record, moved it aside", "path", path, "saved", torn.Saved, "error", torn)
+	}
+	b.auditLog = log
+	slog.Info("audit log opened", "path", path)
+	return nil
+}
+
+// closeAuditLog closes the audit log, if open.
+func (b *Bridge) closeAuditLog() {
+	if b.auditLog == nil {
+		return
+	}
+	if err := b.auditLog.Close(); err != nil {
+		slog.Warn("close audit log failed", "error", err)
+	}
+}
+
+// audit appends rec to the audit log, if enabled. A failed write is logged
+// but does not block the action.
+func (b *Bridge) audit(rec audit.Record) {
+	if b.auditLog == nil {
+		return
+	}
+	if err := b.auditLog.Append(rec); err != nil {
+		slog.Error("audit log write failed", "kind", rec.Kind, "command", rec.Command, "repo", rec.Repo, "error", err)
+	}
+}
+
+// auditCommand records a /command that ran, whether it was carried out and
+// the first line of its response.
+func (b *Bridge) auditCommand(prov provider.Provider, channelID string, author sessionUser, route router.Route, result commandResult) {
+	repoName := commandTarget(route)
+	if repoName == "" {
+		repoName = b.repoForChannel(channelID)
+	}
+	b.audit(audit.Record{
+		Kind:     audit.KindCommand,
+		Provider: prov.Name(),
+		Channel:  channelID,
+		Author:   author.name,
+		AuthorID: author.id,
+		Repo:     repoName,
+		Command:  route.Command,
+		Args:     route.Args,
+		Outcome:  commandOutcome(result),
+		Detail:   auditDetail(result.response),
+	})
+}
+
+// auditPrompt records a prompt from msg to repoName.
+func (b *Bridge) auditPrompt(prov provider.Provider, msg provider.Message, repoName, prompt, outcome, detail string) {
+	b.audit(audit.Record{
+		Kind:     audit.KindPrompt,
+		Provider: prov.Name(),
+		Channel:  msg.ChannelID,
+		Author:   msg.Author,
+		AuthorID: msg.AuthorID,
+		Repo:     repoName,
+		Prompt:   prompt,
+		Outcome:  outcome,
+		Detail:   detail,
+	})
+}
+
+// auditRefused records a command or prompt that was refused before it ran.
+func (b *Bridge) auditRefused(prov provider.Provider, channelID, author, authorID string, route router.Route, repoName, outcome, reason string) {
+	rec := audit.Record{
+		Provider: prov.Name(),
+		Channel:  channelID,
+		Author:   author,
+		AuthorID: authorID,
+		Repo:     repoName,
+		Outcome:  outcome,
+		Detail:   reason,
+	}
+	if route.Type == router.RouteToLLM || route.Type == router.RouteToMacro {
+		rec.Kind, rec.Prompt = audit.KindPrompt, route.Raw
+	} else {
+		rec.Kind, rec.Command, rec.Args = audit.KindCommand, route.Command, route.Args
+	}
+	b.audit(rec)
+}
+
+// commandTarget returns the repo a command names in its arguments, such as
+// the repo /clone registers or /remove-repo removes, or "".
+func commandTarget(route router.Route) string {
+	fields := strings.Fields(route.Args)
+	switch route.Command {
+	case "remove-repo", "select":
+		if len(fields) > 0 {
+			return fields[0]
+		}
+	case "clone":
+		if len(fields) > 1 {
+			return fields[1]
+		}
+	}
+	return ""
+}
+
+// commandOutcome returns the audit outcome of a command's result.
+func commandOutcome(result commandResult) string {
+	if result.failed {
+		return audit.OutcomeFailed
+	}
+	return audit.OutcomeOK
+}
+
+// auditDetail returns the first line of a response, truncated.
+func auditDetail(response string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(response), "\n")
+	return truncateLine(line, maxAuditDetail)
+}
@@ -0,0 +1,206 @@
+package bridge
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/anthropics/llm-bridge/internal/audit"
+	"github.com/anthropics/llm-bridge/internal/config"
+	"github.com/anthropics/llm-bridge/internal/provider"
+	"github.com/anthropics/llm-bridge/internal/router"
+)
+
+// withAuditLog gives b an audit log in a temp dir and returns its path.
+func withAuditLog(t *testing.T, b *Bridge) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	log, err := audit.Open(path)
+	if err != nil {
+		t.Fatalf("open audit log: %v", err)
+	}
+	b.auditLog = log
+	t.Cleanup(b.closeAuditLog)
+	return path
+}
+
+// auditRecords returns the records of the audit log at path after checking
+// its hash chain.
+func auditRecords(t *testing.T, path string) []audit.Record {
+	t.Helper()
+	if _, _, err := audit.Verify(path); err != nil {
+		t.Fatalf("verify audit log: %v", err)
+	}
+	recs, err := audit.Query(path, audit.Filter{})
+	if err != nil {
+		t.Fatalf("query audit log: %v", err)
+	}
+	return recs
+}
+
+func TestAudit_Prompts(t *testing.T) {
+	b, _, mockProv, _ := queueBridge(t)
+	path := withAuditLog(t, b)
+
+	sendPrompt(b, mockProv, "alice", "first")
+	sendPrompt(b, mockProv, "bob", "second")
+
+	recs := auditRecords(t, path)
+	if len(recs) != 2 {
+		t.Fatalf("got %d records, want 2", len(recs))
+	}
+	want := []struct{ authorID, prompt, outcome string }{
+		{"id-alice", "first", audit.OutcomeOK},
+		{"id-bob", "second", audit.OutcomeQueued},
+	}
+	for i, w := range want {
+		r := recs[i]
+		if r.Kind != audit.KindPrompt || r.AuthorID != w.authorID || r.Prompt != w.prompt || r.Outcome != w.outcome ||
+			r.Repo != "test-repo" || r.Provider != "discord" || r.Channel != "channel-123" {
+			t.Errorf("record %d = %+v, want %+v", i, r, w)
+		}
+	}
+}
+
+func TestAudit_Commands(t *testing.T) {
+	b, _, mockProv, _ := queueBridge(t)
+	path := withAuditLog(t, b)
+	ctx := context.Background()
+
+	b.processMessage(ctx, mockProv, provider.Message{ChannelID: "channel-123", Content: "/status", Author: "alice", AuthorID: "id-alice", Source: "discord"})
+	b.processMessage(ctx, mockProv, provider.Message{ChannelID: "channel-123", Content: "/remove-repo other-repo", Author: "alice", AuthorID: "id-alice", Source: "discord"})
+
+	recs := auditRecords(t, path)
+	if len(recs) != 2 {
+		t.Fatalf("got %d records, want 2", len(recs))
+	}
+	if r := recs[0]; r.Kind != audit.KindCommand || r.Command != "status" || r.Repo != "test-repo" || r.Outcome != audit.OutcomeOK {
+		t.Errorf("/status record = %+v", r)
+	}
+	// The bridge has no config file to remove the repo from.
+	if r := recs[1]; r.Command != "remove-repo" || r.Args != "other-repo" || r.Repo != "other-repo" || r.Outcome != audit.OutcomeFailed ||
+		!strings.HasPrefix(r.Detail, "Failed to remove repo") {
+		t.Errorf("/remove-repo record = %+v", r)
+	}
+}
+
+func TestAudit_FailedCommands(t *testing.T) {
+	b, _, mockProv, _ := queueBridge(t)
+	path := withAuditLog(t, b)
+	b.cloneRepo = func(url, destDir string) error {
+		return errors.New("repository not found")
+	}
+	ctx := context.Background()
+
+	for _, m := range []provider.Message{
+		{ChannelID: "channel-123", Content: "/clone https://example.com/x.git x chan-x"},
+		{ChannelID: "channel-none", Content: "/restart"},
+		{ChannelID: "channel-123", Content: "/queue drop 5"},
+	} {
+		m.Author, m.AuthorID, m.Source = "alice", "id-alice", "discord"
+		b.processMessage(ctx, mockProv, m)
+	}
+
+	recs := auditRecords(t, path)
+	if len(recs) != 3 {
+		t.Fatalf("got %d records, want 3: %+v", len(recs), recs)
+	}
+	want := []struct{ command, detail string }{
+		{"clone", "Clone failed: repository not found"},
+		{"restart", "No repo configured"},
+		{"queue", "No queued prompt 5 (0 queued)"},
+	}
+	for i, w := range want {
+		if r := recs[i]; r.Command != w.command || r.Outcome != audit.OutcomeFailed || r.Detail != w.detail {
+			t.Errorf("record %d = %+v, want failed %s", i, r, w.command)
+		}
+	}
+}
+
+func TestAudit_ReactionApproval(t *testing.T) {
+	b, _, mockProv, session := queueBridge(t)
+	path := withAuditLog(t, b)
+	pl := &permissionLLM{mockLLM: newMockLLM("claude")}
+	pl.setRunning(true)
+	session.llm = pl
+	session.permissionPending = true
+
+	b.processReaction(mockProv, provider.Reaction{ChannelID: "channel-123", Emoji: "✅", User: "alice", UserID: "id-alice"})
+
+	recs := auditRecords(t, path)
+	if len(recs) != 1 {
+		t.Fatalf("got %d records, want 1", len(recs))
+	}
+	if r := recs[0]; r.Command != "approve" || r.AuthorID != "id-alice" || r.Outcome != audit.OutcomeOK || r.Detail != "Approved" {
+		t.Errorf("approval record = %+v", r)
+	}
+}
+
+func TestAudit_Refused(t *testing.T) {
+	b, mockProv, _ := authzBridge(t)
+	path := withAuditLog(t, b)
+	ctx := context.Background()
+
+	b.processMessage(ctx, mockProv, provider.Message{ChannelID: "channel-123", Content: "/clone https://example.com/x.git x", Author: "eve", AuthorID: "id-eve", Source: "discord"})
+
+	b.mu.Lock()
+	b.setRateLimits(config.RateLimitConfig{UserRate: 0.001, UserBurst: 1, ChannelRate: 100, ChannelBurst: 100})
+	b.mu.Unlock()
+	for i := 0; i < 2; i++ {
+		b.processMessage(ctx, mockProv, provider.Message{ChannelID: "channel-123", Content: "go", Author: "dana", AuthorID: "id-dana", Roles: []string{"role-dev"}, Source: "discord"})
+	}
+
+	recs := auditRecords(t, path)
+	if len(recs) != 3 {
+		t.Fatalf("got %d records, want 3: %+v", len(recs), recs)
+	}
+	if r := recs[0]; r.Command != "clone" || r.Args != "https://example.com/x.git x" || r.Repo != "test-repo" || r.Outcome != audit.OutcomeDenied {
+		t.Errorf("denied record = %+v", r)
+	}
+	if r := recs[1]; r.Kind != audit.KindPrompt || r.Outcome != audit.OutcomeOK {
+		t.Errorf("allowed prompt record = %+v", r)
+	}
+	if r := recs[2]; r.Kind != audit.KindPrompt || r.Prompt != "go" || r.Outcome != audit.OutcomeRateLimited {
+		t.Errorf("rate limited record = %+v", r)
+	}
+}
+
+func TestAudit_Path(t *testing.T) {
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "llm-bridge.yaml")
+
+	b := New(testConfig(), cfgPath)
+	if got, want := b.auditPath(), filepath.Join(dir, "llm-bridge.audit.jsonl"); got != want {
+		t.Errorf("default path = %q, want %q", got, want)
+	}
+
+	cfg := testConfig()
+	disabled := false
+	cfg.Defaults.Audit.Enabled = &disabled
+	if got := New(cfg, cfgPath).auditPath(); got != "" {
+		t.Errorf("disabled path = %q, want empty", got)
+	}
+	if got := New(testConfig(), "").auditPath(); got != "" {
+		t.Errorf("path without config file = %q, want empty", got)
+	}
+}
+
+func TestCommandTarget(t *testing.T) {
+	tests := []struct {
+		content string
+		want    string
+	}{
+		{"/remove-repo old", "old"},
+		{"/select other", "other"},
+		{"/clone https://example.com/x.git x 123", "x"},
+		{"/clone https://example.com/x.git", ""},
+		{"/status", ""},
+	}
+	for _, tt := range tests {
+		if got := commandTarget(router.Parse(tt.content)); got != tt.want {
+			t.Errorf("commandTarget(%q) = %q, want %q", tt.content, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package bridge
+
+import (
+	"errors"
+	"log/slog"
+	"strings"
+
+	"github.com/anthropics/llm-bridge/internal/audit"
+	"github.com/anthropics/llm-bridge/internal/authz"
+	"github.com/anthropics/llm-bridge/internal/config"
+	"github.com/anthropics/llm-bridge/internal/provider"
+	"github.com/anthropics/llm-bridge/internal/router"
+)
+
+// newPolicy builds the authorization policy, or returns nil if no roles are
+// configured and everyone may do everything.
+func newPolicy(cfg config.AuthzConfig) *authz.Policy {
+	if !cfg.Enabled() {
+		return nil
+	}
+	var roles []authz.Role
+	for _, name := range sortedKeys(cfg.Roles) {
+		rc := cfg.Roles[name]
+		for _, cmd := range rc.Commands {
+			if cmd != "*" && !router.BridgeCommands[cmd] {
+				slog.Warn("authz role grants unknown command", "role", name, "command", cmd)
+			}
+		}
+		roles = append(roles, authz.Role{
+			Name:     name,
+			Users:    rc.Users,
+			RoleIDs:  rc.RoleIDs,
+			Commands: rc.Commands,
+			Repos:    rc.Repos,
+			Prompt:   rc.Prompt,
+		})
+	}
+	return authz.New(roles, cfg.DefaultRole)
+}
+
+// messageSubject returns who sent msg through prov, for authorization.
+func messageSubject(prov provider.Provider, msg provider.Message) authz.Subject {
+	return authz.Subject{Provider: prov.Name(), UserID: msg.AuthorID, RoleIDs: msg.Roles}
+}
+
+// authorize reports whether subject may take route in repoName (the repo
+// bound to the channel, or "" if none). A denied user is told why. /help is
+// always allowed.
+func (b *Bridge) authorize(prov provider.Provider, channelID, author string, subject authz.Subject, route router.Route, repoName string) bool {
+	b.mu.Lock()
+	policy := b.authz
+	b.mu.Unlock()
+	if policy == nil {
+		return true
+	}
+
+	var err error
+	switch {
+	case route.Type == router.RouteToLLM, route.Type == router.RouteToMacro: // macros expand into prompts
+		err = policy.CanPrompt(subject, repoName)
+	case route.Command == "help":
+		return true
+	default:
+		// Commands that name a repo are checked against that repo, and
+		// those that name a schedule against the schedule's repo.
+		if route.Command == "remove-repo" || route.Command == "select" {
+			if target := strings.TrimSpace(route.Args); target != "" {
+				repoName = target
+			}
+		}
+		fields := strings.Fields(route.Args)
+		if route.Command == "schedules" && len(fields) == 2 {
+			if sc, ok := b.currentConfig().Schedules[fields[1]]; ok {
+				repoName = sc.Repo
+			}
+		}
+		err = policy.CanCommand(subject, route.Command, repoName)
+		// Triggering a schedule also sends its prompt to that repo.
+		if err == nil && route.Command == "schedules" && len(fields) == 2 && fields[0] == "trigger" {
+			err = policy.CanPrompt(subject, repoName)
+		}
+	}
+	if err == nil {
+		return true
+	}
+
+	slog.Warn("authorization denied", "user", author, "author_id", subject.UserID, "command", route.Command, "repo", repoName, "provider", prov.Name(), "error", err)
+	b.auditRefused(prov, channelID, author, subject.UserID, route, repoName, audit.OutcomeDenied, err.Error())
+	var denied *authz.DeniedError
+	if errors.As(err, &denied) {
+		b.reply(prov, channelID, "Permission denied: "+denied.Reason()+".")
+	} else {
+		b.reply(prov, channelID, "Permission denied.")
+	}
+	return false
+}
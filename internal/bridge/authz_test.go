@@ -0,0 +1,162 @@
+package bridge
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/anthropics/llm-bridge/internal/config"
+	"github.com/anthropics/llm-bridge/internal/provider"
+	"github.com/anthropics/llm-bridge/internal/router"
+)
+
+// authzBridge returns a bridge with a read-only viewer role for everyone, a
+// dev role (by Discord role ID) that may prompt in test-repo, and an admin
+// user. It returns the LLMs it starts.
+func authzBridge(t *testing.T) (*Bridge, *provider.MockProvider, *[]*mockLLM) {
+	t.Helper()
+	cfg := testConfig()
+	cfg.Authz = config.AuthzConfig{Roles: map[string]config.RoleConfig{
+		"viewer": {Users: []string{"*"}, Commands: []string{"status", "history"}},
+		"dev":    {RoleIDs: []string{"role-dev"}, Commands: []string{"status", "cancel", "select"}, Repos: []string{"test-repo"}, Prompt: true},
+		"admin":  {Users: []string{"id-admin"}, Commands: []string{"*"}, Prompt: true},
+	}}
+	tb := newTestBridge(t, withConfig(cfg))
+	return tb.b, tb.prov, &tb.started
+}
+
+func TestAuthz_ReadOnlyCannotPrompt(t *testing.T) {
+	b, mockProv, started := authzBridge(t)
+
+	b.processMessage(context.Background(), mockProv, provider.Message{
+		ChannelID: "channel-123", Content: "delete everything", Author: "eve", AuthorID: "id-eve", Source: "discord",
+	})
+
+	if len(*started) != 0 {
+		t.Error("a read-only user's prompt should not start the LLM")
+	}
+	want := "Permission denied: your role (viewer) may not send prompts in test-repo."
+	if got := lastSent(mockProv, "channel-123"); got != want {
+		t.Errorf("denial = %q, want %q", got, want)
+	}
+}
+
+func TestAuthz_RoleIDsGrantPrompt(t *testing.T) {
+	b, mockProv, started := authzBridge(t)
+	ctx := context.Background()
+
+	b.processMessage(ctx, mockProv, provider.Message{
+		ChannelID: "channel-123", Content: "fix the bug", Author: "dana", AuthorID: "id-dana", Roles: []string{"role-dev"}, Source: "discord",
+	})
+	if len(*started) != 1 {
+		t.Errorf("dev prompt should start the LLM, started %d", len(*started))
+	}
+
+	// The dev role is scoped to test-repo.
+	b.processMessage(ctx, mockProv, provider.Message{
+		ChannelID: "channel-456", Content: "fix the bug", Author: "dana", AuthorID: "id-dana", Roles: []string{"role-dev"}, Source: "discord",
+	})
+	if len(*started) != 1 {
+		t.Error("dev prompt outside its repos should be denied")
+	}
+	if got := lastSent(mockProv, "channel-456"); !strings.Contains(got, "may not send prompts in other-repo") {
+		t.Errorf("denial = %q", got)
+	}
+}
+
+func TestAuthz_Commands(t *testing.T) {
+	b, mockProv, _ := authzBridge(t)
+	ctx := context.Background()
+	send := func(authorID, content string) string {
+		b.processMessage(ctx, mockProv, provider.Message{ChannelID: "channel-123", Content: content, Author: authorID, AuthorID: authorID, Source: "discord"})
+		return lastSent(mockProv, "channel-123")
+	}
+
+	if got := send("id-eve", "/status"); strings.Contains(got, "Permission denied") {
+		t.Errorf("viewer /status = %q", got)
+	}
+	if got := send("id-eve", "/help"); strings.Contains(got, "Permission denied") {
+		t.Errorf("/help should always be allowed, got %q", got)
+	}
+	if got := send("id-eve", "/clone https://example.com/x.git x"); got != "Permission denied: your role (viewer) may not use /clone in test-repo." {
+		t.Errorf("viewer /clone = %q", got)
+	}
+
+	// /remove-repo is checked against the repo it removes.
+	if got := send("id-eve", "/remove-repo other-repo"); !strings.Contains(got, "may not use /remove-repo in other-repo") {
+		t.Errorf("viewer /remove-repo = %q", got)
+	}
+	if _, ok := b.cfg.Repos["other-repo"]; !ok {
+		t.Error("denied /remove-repo should not remove the repo")
+	}
+}
+
+func TestAuthz_NoRoleDenied(t *testing.T) {
+	b, mockProv, _ := authzBridge(t)
+	viewer := b.cfg.Authz.Roles["viewer"]
+	viewer.Users = []string{"id-viewer"}
+	b.cfg.Authz.Roles["viewer"] = viewer
+	b.authz = newPolicy(b.cfg.Authz)
+
+	b.processMessage(context.Background(), mockProv, provider.Message{ChannelID: "channel-123", Content: "/status", Author: "stranger", AuthorID: "id-stranger", Source: "discord"})
+	if got := lastSent(mockProv, "channel-123"); got != "Permission denied: you have no role that may use /status in test-repo." {
+		t.Errorf("denial = %q", got)
+	}
+}
+
+func TestAuthz_Reactions(t *testing.T) {
+	b, mockProv, _ := authzBridge(t)
+	mockLLM := newMockLLM("claude")
+	mockLLM.setRunning(true)
+	b.repos["test-repo"] = &repoSession{name: "test-repo", llm: mockLLM, channels: []channelRef{{provider: mockProv, channelID: "channel-123"}}}
+
+	b.processReaction(mockProv, provider.Reaction{ChannelID: "channel-123", MessageID: "m1", Emoji: "🛑", User: "eve", UserID: "id-eve"})
+	if got := lastSent(mockProv, "channel-123"); got != "Permission denied: your role (viewer) may not use /cancel in test-repo." {
+		t.Errorf("viewer reaction = %q", got)
+	}
+
+	b.processReaction(mockProv, provider.Reaction{ChannelID: "channel-123", MessageID: "m1", Emoji: "🛑", User: "dana", UserID: "id-dana", Roles: []string{"role-dev"}})
+	if got := lastSent(mockProv, "channel-123"); got != "Sent interrupt signal" {
+		t.Errorf("dev reaction = %q", got)
+	}
+}
+
+func TestAuthz_DirectMessageSelect(t *testing.T) {
+	b, mockProv, _ := authzBridge(t)
+	dm := func(content string) string {
+		b.processMessage(context.Background(), mockProv, provider.Message{
+			ChannelID: "dm-1", Content: content, Author: "dana", AuthorID: "id-dana", Roles: []string{"role-dev"}, Source: "discord", DirectMessage: true,
+		})
+		return lastSent(mockProv, "dm-1")
+	}
+
+	if got := dm("/select other-repo"); got != "Permission denied: your roles (dev, viewer) may not use /select in other-repo." {
+		t.Errorf("/select other-repo = %q", got)
+	}
+	if got := dm("/select test-repo"); got != "Selected repo: test-repo" {
+		t.Errorf("/select test-repo = %q", got)
+	}
+}
+
+func TestAuthz_DisabledAllowsEveryone(t *testing.T) {
+	b := New(testConfig(), "")
+	if b.authz != nil {
+		t.Fatal("no roles should disable authorization")
+	}
+	mockProv := provider.NewMockProvider("discord")
+	if !b.authorize(mockProv, "channel-123", "eve", messageSubject(mockProv, provider.Message{AuthorID: "id-eve"}), router.Parse("/remove-repo test-repo"), "test-repo") {
+		t.Error("everything should be allowed without roles")
+	}
+}
+
+func TestAuthz_UserIDsScopedByProvider(t *testing.T) {
+	b, _, _ := authzBridge(t)
+	plugin := provider.NewMockProvider("chat")
+
+	// id-admin is bound as a Discord user; the same ID on another provider
+	// is someone else.
+	b.processMessage(context.Background(), plugin, provider.Message{ChannelID: "channel-123", Content: "/clone https://example.com/x.git x", Author: "mallory", AuthorID: "id-admin", Source: "chat"})
+	if got := lastSent(plugin, "channel-123"); got != "Permission denied: your role (viewer) may not use /clone in test-repo." {
+		t.Errorf("same ID on another provider = %q", got)
+	}
+}
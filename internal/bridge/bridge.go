@@ -7,12 +7,17 @@ import (
 	"fmt"
 	"io"
 	"log/slog"
+	"net/http"
 	"path/filepath"
 	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/anthropics/llm-bridge/internal/adminapi"
+	"github.com/anthropics/llm-bridge/internal/audit"
+	"github.com/anthropics/llm-bridge/internal/authz"
 	"github.com/anthropics/llm-bridge/internal/config"
 	"github.com/anthropics/llm-bridge/internal/git"
 	"github.com/anthropics/llm-bridge/internal/llm"
@@ -20,6 +25,8 @@ import (
 	"github.com/anthropics/llm-bridge/internal/provider"
 	"github.com/anthropics/llm-bridge/internal/ratelimit"
 	"github.com/anthropics/llm-bridge/internal/router"
+	"github.com/anthropics/llm-bridge/internal/transcript"
+	"github.com/anthropics/llm-bridge/internal/webhook"
 )
 
 // LLMFactory creates LLM instances. Defaults to llm.New.
@@ -28,6 +35,12 @@ type LLMFactory func(backend, workingDir, claudePath string, resume bool) (llm.L
 // DiscordFactory creates Discord provider instances. Defaults to provider.NewDiscord.
 type DiscordFactory func(token string, channelIDs []string) provider.Provider
 
+// GitHubFactory creates GitHub provider instances. Defaults to provider.NewGitHub.
+type GitHubFactory func(cfg config.GitHubConfig) provider.Provider
+
+// PluginFactory creates plugin provider instances. Defaults to provider.NewPlugin.
+type PluginFactory func(name string, cfg config.PluginConfig, channelIDs []string) provider.Provider
+
 // TerminalFactory creates Terminal provider instances. Defaults to provider.NewTerminal.
 type TerminalFactory func(channelID string) *provider.Terminal
 
@@ -44,86 +57,211 @@ type CloneRepoFunc func(url, destDir string) error
 type AddWorktreeFunc func(repoDir, wtDir, branch string) error
 
 type Bridge struct {
-	cfg             *config.Config
-	cfgPath         string
-	providers       map[string]provider.Provider
-	repos           map[string]*repoSession
-	output          *output.Handler
-	discordFactory  DiscordFactory
-	terminalFactory TerminalFactory
-	llmFactory      LLMFactory
-	gitDetector     GitDetector
-	worktreeLister  WorktreeLister
-	cloneRepo       CloneRepoFunc
-	addWorktree     AddWorktreeFunc
+	cfg               *config.Config
+	cfgPath           string
+	providers         map[string]provider.Provider
+	repos             map[string]*repoSession
+	output            *output.Handler
+	discordFactory    DiscordFactory
+	terminalFactory   TerminalFactory
+	pluginFactory     PluginFactory
+	githubFactory     GitHubFactory
+	llmFactory        LLMFactory
+	gitDetector       GitDetector
+	worktreeLister    WorktreeLister
+	cloneRepo         CloneRepoFunc
+	addWorktree       AddWorktreeFunc
+	attachmentFetcher AttachmentFetcher
+	remoteResolver    RemoteResolver
+	emitEvent         EventEmitter
+	webhooks          *webhook.Dispatcher // nil if no webhooks configured; closed by Stop
+	auditLog          *audit.Log          // nil if auditing is off; opened by Start, closed by Stop
+	adminAPI          *adminapi.Server    // nil if the admin API is off; started by Start, closed by Stop
+	metrics           *bridgeMetrics
+	metricsServer     *http.Server // nil if metrics are not served; started by Start, closed by Stop
 
 	userLimiter    *ratelimit.Limiter
 	channelLimiter *ratelimit.Limiter
+	authz          *authz.Policy // nil if no roles are configured
+
+	busyQuietPeriod time.Duration
+	typingInterval  time.Duration
+
+	outboxMu        sync.Mutex
+	outboxes        map[string]*outbox // provider name + channel ID -> delivery queue
+	deliveryBackoff time.Duration
+	deliveryDropped atomic.Int64 // output messages given up on since start
+	stopCh          chan struct{}
+	stopOnce        sync.Once
+
+	// sessionsCtx is the parent of every LLM's context. Stop cancels it
+	// after saving state, so sessions outlive the contexts of the requests
+	// that started them and are still running when state is recorded.
+	sessionsCtx    context.Context
+	cancelSessions context.CancelFunc
+
+	stateMu    sync.Mutex    // serializes state file writes
+	worktreeMu sync.Mutex    // serializes creating per-user worktrees
+	stateDirty chan struct{} // signals stateLoop to save
+
+	reloadCh           chan struct{} // signals configWatchLoop to reload
+	configPollInterval time.Duration // how often to check the config file for changes
+	scheduleInterval   time.Duration // how often to check for due scheduled runs
 
 	mu               sync.Mutex
 	terminalRepoName string
+	dmRepos          map[string]string          // AuthorID -> repo selected in DMs
+	dmChannels       map[string]string          // DM channel ID -> AuthorID
+	repoChannels     map[string]string          // repository-routed channel ID -> repo
+	repoRemotes      map[string]string          // working dir -> GitHub full name of origin
+	transcripts      map[string]*transcript.Log // transcript dir -> log
+	llmSessions      map[string]string          // session key -> LLM conversation ID, for resuming
+	schedulesPaused  map[string]bool            // schedule name -> paused, as set by /schedules
+	scheduleRuns     map[string]scheduleRun     // schedule name -> most recent run
+	scheduleCtx      context.Context            // sessions started by schedules live as long as this
+	pendingStarts    []pendingStart             // sessions waiting for room under max_active_sessions
 }
 
 type repoSession struct {
+	id        string // identifies the session in transcripts
 	name      string
 	llm       llm.LLM
 	channels  []channelRef
+	ctx       context.Context // the LLM's context; done once it is stopped or the bridge shuts down
 	cancelCtx context.CancelFunc
-	merger    *Merger        // per-repo conflict detection
-	gitInfo   *git.RepoInfo  // nil if not a git repo
+	merger    *Merger       // per-repo conflict detection
+	gitInfo   *git.RepoInfo // nil if not a git repo
+	busy      busyState     // prompt in flight (has its own lock)
+	user      sessionUser   // owner of a per_user session; zero for the shared session
+
+	// Guarded by Bridge.mu.
+	lastOutput        string         // most recent broadcast chunk, for /last
+	permissionPending bool           // LLM is waiting on a permission prompt
+	replyTo           *replyTarget   // prompt awaiting its first output in reply mode
+	queue             []queuedPrompt // prompts waiting for the current turn to finish
+	keepAliveUntil    time.Time      // set by /keepalive; no idle timeout before then
+	idleWarnedFor     time.Time      // idle deadline the channels were last warned about
 }
 
 type channelRef struct {
 	provider  provider.Provider
 	channelID string
+	mode      string // config.ChannelMode*, for the repo's configured channels; "" otherwise
+}
+
+// replyTarget identifies the prompt message that output should reply to
+// when output_mode is "reply".
+type replyTarget struct {
+	provider  string // provider name
+	channelID string // channel the prompt was routed by
+	threadID  string // thread the prompt was posted in, if any
+	messageID string
 }
 
 func New(cfg *config.Config, cfgPath string) *Bridge {
 	b := &Bridge{
-		cfg:       cfg,
-		cfgPath:   cfgPath,
-		providers: make(map[string]provider.Provider),
-		repos:     make(map[string]*repoSession),
-		output:    output.NewHandler(cfg.Defaults.OutputThreshold),
-		llmFactory: llm.New,
+		cfg:              cfg,
+		cfgPath:          cfgPath,
+		providers:        make(map[string]provider.Provider),
+		repos:            make(map[string]*repoSession),
+		dmRepos:          make(map[string]string),
+		dmChannels:       make(map[string]string),
+		repoChannels:     make(map[string]string),
+		repoRemotes:      make(map[string]string),
+		transcripts:      make(map[string]*transcript.Log),
+		llmSessions:      make(map[string]string),
+		schedulesPaused:  make(map[string]bool),
+		scheduleRuns:     make(map[string]scheduleRun),
+		scheduleCtx:      context.Background(),
+		scheduleInterval: defaultScheduleInterval,
+		output:           output.NewHandler(cfg.Defaults.OutputThreshold),
+		llmFactory:       llm.New,
 		discordFactory: func(token string, channelIDs []string) provider.Provider {
 			return provider.NewDiscord(token, channelIDs)
 		},
 		terminalFactory: provider.NewTerminal,
-		gitDetector:     git.DetectRepo,
-		worktreeLister:  git.ListWorktrees,
-		cloneRepo:       git.CloneRepo,
-		addWorktree:     git.AddWorktree,
+		pluginFactory: func(name string, cfg config.PluginConfig, channelIDs []string) provider.Provider {
+			return provider.NewPlugin(name, cfg.Command, cfg.Args, cfg.EnvList(), channelIDs)
+		},
+		githubFactory: func(cfg config.GitHubConfig) provider.Provider {
+			return provider.NewGitHub(provider.GitHubOptions{
+				WebhookSecret: cfg.WebhookSecret,
+				Token:         cfg.Token,
+				Listen:        cfg.GetListen(),
+				Path:          cfg.GetPath(),
+				APIURL:        cfg.GetAPIURL(),
+				Mention:       cfg.GetMention(),
+				QuietPeriod:   cfg.GetQuietPeriod(),
+				ReplyWindow:   cfg.GetReplyWindow(),
+				AllowedUsers:  cfg.AllowedUsers,
+			})
+		},
+		gitDetector:        git.DetectRepo,
+		worktreeLister:     git.ListWorktrees,
+		cloneRepo:          git.CloneRepo,
+		addWorktree:        git.AddWorktree,
+		attachmentFetcher:  fetchAttachment,
+		remoteResolver:     resolveGitHubRemote,
+		busyQuietPeriod:    defaultBusyQuietPeriod,
+		typingInterval:     defaultTypingInterval,
+		outboxes:           make(map[string]*outbox),
+		deliveryBackoff:    defaultDeliveryBackoff,
+		stopCh:             make(chan struct{}),
+		stateDirty:         make(chan struct{}, 1),
+		reloadCh:           make(chan struct{}, 1),
+		configPollInterval: defaultConfigPollInterval,
 	}
 
-	if cfg.Defaults.RateLimit.GetRateLimitEnabled() {
-		b.userLimiter = ratelimit.NewLimiter(ratelimit.Config{
-			Rate:  cfg.Defaults.RateLimit.GetUserRate(),
-			Burst: cfg.Defaults.RateLimit.GetUserBurst(),
-		})
-		b.channelLimiter = ratelimit.NewLimiter(ratelimit.Config{
-			Rate:  cfg.Defaults.RateLimit.GetChannelRate(),
-			Burst: cfg.Defaults.RateLimit.GetChannelBurst(),
-		})
+	b.sessionsCtx, b.cancelSessions = context.WithCancel(context.Background())
+	b.metrics = newBridgeMetrics(b)
+
+	if b.webhooks = newWebhookDispatcher(cfg.Webhooks); b.webhooks != nil {
+		b.emitEvent = b.webhooks.Emit
 	}
 
+	b.setRateLimits(cfg.Defaults.RateLimit)
+	b.authz = newPolicy(cfg.Authz)
+
 	return b
 }
 
+// setRateLimits replaces the rate limiters, resetting their buckets.
+// Callers must hold b.mu once the bridge is running.
+func (b *Bridge) setRateLimits(rl config.RateLimitConfig) {
+	b.userLimiter, b.channelLimiter = nil, nil
+	if !rl.GetRateLimitEnabled() {
+		return
+	}
+	b.userLimiter = ratelimit.NewLimiter(ratelimit.Config{
+		Rate:  rl.GetUserRate(),
+		Burst: rl.GetUserBurst(),
+	})
+	b.channelLimiter = ratelimit.NewLimiter(ratelimit.Config{
+		Rate:  rl.GetChannelRate(),
+		Burst: rl.GetChannelBurst(),
+	})
+}
+
 func (b *Bridge) Start(ctx context.Context) error {
-	// Initialize Discord if configured
-	token := b.cfg.Providers.Discord.GetBotToken()
-	if token != "" {
-		channelIDs := b.channelIDsForProvider("discord")
-		if len(channelIDs) > 0 {
-			discord := b.discordFactory(token, channelIDs)
-			if err := discord.Start(ctx); err != nil {
-				return fmt.Errorf("start discord: %w", err)
-			}
-			b.providers["discord"] = discord
-			go b.handleMessages(ctx, discord)
-			slog.Info("discord provider started", "channels", len(channelIDs))
-		}
+	if err := b.openAuditLog(); err != nil {
+		return err
+	}
+
+	// Initialize Discord if configured. It starts even with no Discord repos
+	// so that repos added at runtime (/clone, /add-worktree) can subscribe.
+	if err := b.startDiscord(ctx); err != nil {
+		return err
+	}
+
+	// Initialize GitHub if configured. Its channels are issues, routed to
+	// repos by git remote rather than by channel_id.
+	if err := b.startGitHub(ctx); err != nil {
+		return err
+	}
+
+	// Initialize out-of-process provider plugins.
+	if err := b.startPlugins(ctx); err != nil {
+		return err
 	}
 
 	// Initialize Terminal (always enabled for local interaction)
@@ -131,18 +269,133 @@ func (b *Bridge) Start(ctx context.Context) error {
 	if err := terminal.Start(ctx); err != nil {
 		return fmt.Errorf("start terminal: %w", err)
 	}
+	b.mu.Lock()
 	b.providers["terminal"] = terminal
+	b.mu.Unlock()
 	go b.handleTerminalMessages(ctx, terminal)
 	slog.Info("terminal provider started")
 
+	// Resume the sessions that were running before the last shutdown, then
+	// keep the state file current.
+	b.resumeState(ctx)
+	if b.statePath() != "" {
+		go b.stateLoop()
+	}
+
+	// Pick up edits to the config file without a restart.
+	if b.cfgPath != "" {
+		go b.configWatchLoop(ctx)
+	}
+
+	// Serve the admin API once sessions can be started.
+	if err := b.startAdminAPI(ctx); err != nil {
+		return err
+	}
+	if err := b.startMetrics(); err != nil {
+		return err
+	}
+
 	// Start idle timeout checker
 	go b.idleTimeoutLoop(ctx)
 
+	// Run scheduled prompts as they come due. Sessions they start, including
+	// from /schedules trigger, live as long as ctx.
+	b.mu.Lock()
+	b.scheduleCtx = ctx
+	b.mu.Unlock()
+	go b.scheduleLoop(ctx)
+
 	<-ctx.Done()
 	return b.Stop()
 }
 
+// startDiscord starts the Discord provider if a bot token is configured.
+func (b *Bridge) startDiscord(ctx context.Context) error {
+	token := b.currentConfig().Providers.Discord.GetBotToken()
+	if token == "" {
+		return nil
+	}
+	channelIDs := b.channelIDsForProvider("discord")
+	discord := b.discordFactory(token, channelIDs)
+	if err := discord.Start(ctx); err != nil {
+		return fmt.Errorf("start discord: %w", err)
+	}
+	b.mu.Lock()
+	b.providers["discord"] = discord
+	b.mu.Unlock()
+	go b.handleMessages(ctx, discord)
+	if reactor, ok := discord.(provider.Reactor); ok {
+		go b.handleReactions(ctx, discord, reactor)
+	}
+	slog.Info("discord provider started", "channels", len(channelIDs))
+	return nil
+}
+
+// startGitHub starts the GitHub provider if a token is configured.
+func (b *Bridge) startGitHub(ctx context.Context) error {
+	gh := b.currentConfig().Providers.GitHub
+	if !gh.Enabled() {
+		return nil
+	}
+	github := b.githubFactory(gh)
+	if err := github.Start(ctx); err != nil {
+		return fmt.Errorf("start github: %w", err)
+	}
+	b.mu.Lock()
+	b.providers["github"] = github
+	b.mu.Unlock()
+	go b.handleMessages(ctx, github)
+	slog.Info("github provider started", "listen", gh.GetListen(), "path", gh.GetPath())
+	return nil
+}
+
+// startPlugins launches each configured plugin in name order. Plugins
+// start even with no repos bound to them, like Discord.
+func (b *Bridge) startPlugins(ctx context.Context) error {
+	plugins := b.currentConfig().Providers.Plugins
+	names := make([]string, 0, len(plugins))
+	for name := range plugins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if err := b.startPlugin(ctx, name, plugins[name]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// startPlugin launches one plugin provider.
+func (b *Bridge) startPlugin(ctx context.Context, name string, cfg config.PluginConfig) error {
+	channelIDs := b.channelIDsForProvider(name)
+	plugin := b.pluginFactory(name, cfg, channelIDs)
+	if err := plugin.Start(ctx); err != nil {
+		return fmt.Errorf("start plugin %s: %w", name, err)
+	}
+	b.mu.Lock()
+	b.providers[name] = plugin
+	b.mu.Unlock()
+	go b.handleMessages(ctx, plugin)
+	if reactor, ok := plugin.(provider.Reactor); ok {
+		go b.handleReactions(ctx, plugin, reactor)
+	}
+	slog.Info("plugin provider started", "plugin", name, "channels", len(channelIDs))
+	return nil
+}
+
 func (b *Bridge) Stop() error {
+	// Record the running sessions before stopping them so the next start
+	// resumes them; their contexts are only cancelled below.
+	b.saveState()
+	b.stopOnce.Do(func() { close(b.stopCh) })
+	defer b.cancelSessions()
+	defer b.closeWebhooks()
+	defer b.closeAuditLog()
+	b.closeAdminAPI()
+	b.closeMetrics()
+
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
@@ -157,6 +410,8 @@ func (b *Bridge) Stop() error {
 		if repo.cancelCtx != nil {
 			repo.cancelCtx()
 		}
+		b.metrics.sessionStops.Inc(repo.name, "shutdown")
+		b.emit(webhook.SessionStopped, repo.name, map[string]any{"reason": "shutdown"})
 	}
 
 	for name, prov := range b.providers {
@@ -169,10 +424,14 @@ func (b *Bridge) Stop() error {
 }
 
 func (b *Bridge) channelIDsForProvider(providerName string) []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
 	var ids []string
 	for _, repo := range b.cfg.Repos {
-		if repo.Provider == providerName {
-			ids = append(ids, repo.ChannelID)
+		for _, ch := range repo.AllChannels() {
+			if ch.Provider == providerName {
+				ids = append(ids, ch.ChannelID)
+			}
 		}
 	}
 	return ids
@@ -193,13 +452,34 @@ func (b *Bridge) handleMessages(ctx context.Context, prov provider.Provider) {
 }
 
 func (b *Bridge) processMessage(ctx context.Context, prov provider.Provider, msg provider.Message) {
-	route := router.Parse(msg.Content)
+	b.metrics.messagesIn.Inc(prov.Name())
+	if msg.DirectMessage {
+		b.processDirectMessage(ctx, prov, msg)
+		return
+	}
+	if msg.Repository != "" {
+		b.processRepositoryMessage(ctx, prov, msg)
+		return
+	}
+
+	repoName := b.repoForChannel(msg.ChannelID)
+	route := b.parseMessage(msg.Content, repoName)
+	if !b.authorize(prov, msg.ChannelID, msg.Author, messageSubject(prov, msg), route, repoName) {
+		return
+	}
+	route, ok := b.expandMacro(prov, msg, repoName, route)
+	if !ok {
+		return
+	}
 
 	switch route.Type {
 	case router.RouteToBridge:
-		b.handleBridgeCommand(prov, msg.ChannelID, route)
+		if b.refuseReadOnlyCommand(prov, msg.ChannelID, messageAuthor(msg), route) {
+			return
+		}
+		b.handleBridgeCommand(prov, msg.ChannelID, messageAuthor(msg), route)
 	case router.RouteToLLM:
-		if b.isRateLimited(prov, msg) {
+		if b.refuseReadOnlyPrompt(prov, msg, repoName, route.Raw) || b.isRateLimited(prov, msg) {
 			return
 		}
 		b.handleLLMMessage(ctx, prov, msg, route)
@@ -211,56 +491,113 @@ func (b *Bridge) processMessage(ctx context.Context, prov provider.Provider, msg
 // User rate limiting uses AuthorID (stable unique ID), so terminal messages
 // (which have empty AuthorID) are implicitly not user-rate-limited.
 func (b *Bridge) isRateLimited(prov provider.Provider, msg provider.Message) bool {
-	if b.userLimiter == nil || b.channelLimiter == nil {
+	b.mu.Lock()
+	userLimiter, channelLimiter := b.userLimiter, b.channelLimiter
+	b.mu.Unlock()
+	if userLimiter == nil || channelLimiter == nil {
 		return false
 	}
 
-	if msg.AuthorID != "" && !b.userLimiter.Allow(msg.AuthorID) {
+	if msg.AuthorID != "" && !userLimiter.Allow(msg.AuthorID) {
 		if err := prov.Send(msg.ChannelID, fmt.Sprintf("Rate limited: too many messages from user %s. Please wait.", msg.Author)); err != nil {
 			slog.Warn("send rate limit notice failed", "error", err, "channel", msg.ChannelID, "provider", prov.Name())
 		}
 		slog.Warn("rate limited user", "user", msg.Author, "author_id", msg.AuthorID, "channel", msg.ChannelID)
+		b.metrics.rateLimited.Inc("user")
+		b.auditPrompt(prov, msg, b.repoForChannel(msg.ChannelID), msg.Content, audit.OutcomeRateLimited, "user rate limit")
+		b.emit(webhook.RateLimitHit, b.repoForChannel(msg.ChannelID), map[string]any{
+			"scope": "user", "user": msg.Author, "author_id": msg.AuthorID, "channel": msg.ChannelID, "provider": prov.Name(),
+		})
 		return true
 	}
 
-	if !b.channelLimiter.Allow(msg.ChannelID) {
+	if !channelLimiter.Allow(msg.ChannelID) {
 		if err := prov.Send(msg.ChannelID, "Rate limited: too many messages in this channel. Please wait."); err != nil {
 			slog.Warn("send rate limit notice failed", "error", err, "channel", msg.ChannelID, "provider", prov.Name())
 		}
 		slog.Warn("rate limited channel", "channel", msg.ChannelID)
+		b.metrics.rateLimited.Inc("channel")
+		b.auditPrompt(prov, msg, b.repoForChannel(msg.ChannelID), msg.Content, audit.OutcomeRateLimited, "channel rate limit")
+		b.emit(webhook.RateLimitHit, b.repoForChannel(msg.ChannelID), map[string]any{
+			"scope": "channel", "user": msg.Author, "author_id": msg.AuthorID, "channel": msg.ChannelID, "provider": prov.Name(),
+		})
 		return true
 	}
 
 	return false
 }
 
-func (b *Bridge) handleBridgeCommand(prov provider.Provider, channelID string, route router.Route) {
-	var response string
+// commandResult is what a bridge command did: the response to send, if
+// any, and whether the command could not be carried out.
+type commandResult struct {
+	response string
+	failed   bool
+}
+
+// commandOK returns the result of a command that was carried out.
+func commandOK(response string) commandResult {
+	return commandResult{response: response}
+}
+
+// commandFailed returns the result of a command that could not be carried
+// out, with a response saying why.
+func commandFailed(response string) commandResult {
+	return commandResult{response: response, failed: true}
+}
+
+// handleBridgeCommand runs a /command. author selects the session that
+// session commands act on in per_user repos.
+func (b *Bridge) handleBridgeCommand(prov provider.Provider, channelID string, author sessionUser, route router.Route) {
+	var result commandResult
+	known := true
 
 	switch route.Command {
 	case "status":
-		response = b.getStatus(channelID)
+		result = b.getStatus(channelID, author)
 	case "cancel":
-		response = b.cancelLLM(channelID)
+		result = b.cancelLLM(channelID, author)
 	case "restart":
-		response = b.restartLLM(channelID)
+		result = b.restartLLM(channelID, author)
 	case "worktrees":
-		response = b.listWorktrees(channelID)
+		result = b.listWorktrees(channelID)
 	case "list-repos":
-		response = b.handleListRepos()
+		result = b.handleListRepos()
 	case "remove-repo":
-		response = b.handleRemoveRepo(route.Args)
+		result = b.handleRemoveRepo(route.Args)
 	case "clone":
-		response = b.handleClone(prov.Name(), route.Args)
+		result = b.handleClone(prov.Name(), route.Args)
 	case "add-worktree":
-		response = b.handleAddWorktree(prov.Name(), channelID, route.Args)
+		result = b.handleAddWorktree(prov.Name(), channelID, route.Args)
+	case "last":
+		result = b.resendLastOutput(prov, channelID, author)
+	case "approve":
+		result = b.answerPermission(channelID, author, true)
+	case "deny":
+		result = b.answerPermission(channelID, author, false)
+	case "queue":
+		result = b.handleQueue(channelID, author, route.Args)
+	case "history":
+		result = b.handleHistory(channelID, author, route.Args)
+	case "export":
+		result = b.handleExport(prov, channelID, author, route.Args)
+	case "schedules":
+		result = b.handleSchedules(route.Args)
+	case "keepalive":
+		result = b.handleKeepAlive(channelID, author, route.Args)
 	case "help":
-		response = `Commands:
+		result = commandOK(`Commands:
   /help                                  - Show this help
-  /status                                - Show LLM status and idle time
+  /status                                - Show LLM status, idle and busy time
   /cancel                                - Send SIGINT to LLM
   /restart                               - Restart LLM process
-  /select <repo>                         - Select repo for terminal
+  /keepalive [duration]                  - Keep the LLM running when idle (default: one idle timeout)
+  /select <repo>                         - Select repo for terminal or DM
+  /last                                  - Re-send the last output (as a file if supported)
+  /approve, /deny                        - Answer a pending permission prompt
+  /queue [drop <n> | clear]              - List, drop or clear prompts waiting for the LLM
+  /history [n]                           - Show the last n prompts and replies (default 5)
+  /export [md|html|jsonl]                - Upload the current session's transcript
+  /schedules [pause|resume|trigger <name>] - List, pause, resume or run scheduled prompts
 
 Repo Management:
   /list-repos                            - List all configured repos
@@ -271,109 +608,217 @@ Worktrees:
   /worktrees                             - List git worktrees for current repo
   /add-worktree <name> <branch> [channel-id] - Create worktree from current repo
 
-Skills: ::commit, ::review-pr, etc.`
+Skills: ::commit, ::review-pr, etc.` + b.macroHelp(b.commandRepo(prov, channelID)))
 	default:
-		response = fmt.Sprintf("Unknown command: %s", route.Command)
+		result = commandFailed(fmt.Sprintf("Unknown command: %s", route.Command))
+		known = false
 	}
 
-	if err := prov.Send(channelID, response); err != nil {
-		slog.Warn("send command response failed", "error", err, "channel", channelID, "provider", prov.Name())
+	b.auditCommand(prov, channelID, author, route, result)
+	if known {
+		b.emit(webhook.CommandExecuted, b.repoForChannel(channelID), map[string]any{
+			"command": route.Command, "channel": channelID, "provider": prov.Name(),
+		})
 	}
+
+	if result.response == "" {
+		return
+	}
+
+	b.respond(prov, channelID, result.response, route.Command == "help")
 }
 
 func (b *Bridge) handleLLMMessage(ctx context.Context, prov provider.Provider, msg provider.Message, route router.Route) {
 	repoName := b.repoForChannel(msg.ChannelID)
 	if repoName == "" {
+		b.auditPrompt(prov, msg, "", route.Raw, audit.OutcomeFailed, "no repo configured for this channel")
 		if err := prov.Send(msg.ChannelID, "No repo configured for this channel"); err != nil {
 			slog.Warn("send error failed", "error", err, "channel", msg.ChannelID, "provider", prov.Name())
 		}
 		return
 	}
 
-	repo := b.cfg.Repos[repoName]
-	session, err := b.getOrCreateSession(ctx, repoName, repo, prov)
+	cfg := b.currentConfig()
+	repo := cfg.Repos[repoName]
+
+	var attachments []llm.Attachment
+	if len(msg.Attachments) > 0 {
+		var notices []string
+		attachments, notices = b.saveAttachments(ctx, repo, msg.Attachments)
+		for _, notice := range notices {
+			if err := prov.Send(msg.ChannelID, notice); err != nil {
+				slog.Warn("send attachment notice failed", "error", err, "channel", msg.ChannelID, "provider", prov.Name())
+			}
+		}
+		if len(attachments) == 0 && strings.TrimSpace(route.Raw) == "" {
+			return
+		}
+	}
+
+	prompt := queuedPrompt{
+		msg:       llm.Message{Source: prov.Name(), Attachments: attachments},
+		author:    msg.Author,
+		authorID:  msg.AuthorID,
+		text:      route.Raw,
+		prov:      prov,
+		channelID: msg.ChannelID,
+	}
+	if msg.ID != "" && cfg.Defaults.GetOutputMode() == config.OutputModeReply {
+		prompt.replyTo = &replyTarget{
+			provider:  prov.Name(),
+			channelID: msg.ChannelID,
+			threadID:  msg.ThreadID,
+			messageID: msg.ID,
+		}
+	}
+
+	user := sessionUserFor(repo, messageAuthor(msg))
+	if msg.DirectMessage {
+		// DM output is private: each user chats with their own session, even
+		// in repos whose channel shares one.
+		user = messageAuthor(msg)
+	}
+	session, err := b.getOrCreateSession(ctx, repoName, repo, prov, msg.ChannelID, user)
+	var full *poolFullError
+	if errors.As(err, &full) {
+		b.handlePoolFull(ctx, prov, msg, repoName, user, prompt, full)
+		return
+	}
 	if err != nil {
 		slog.Error("failed to create session", "error", err, "repo", repoName)
+		b.auditPrompt(prov, msg, repoName, route.Raw, audit.OutcomeFailed, fmt.Sprintf("start llm: %v", err))
 		if sendErr := prov.Send(msg.ChannelID, fmt.Sprintf("Error starting LLM: %v", err)); sendErr != nil {
 			slog.Warn("send error failed", "error", sendErr, "channel", msg.ChannelID, "provider", prov.Name())
 		}
 		return
 	}
+	prompt.msg.Content = session.merger.FormatMessage(prov.Name(), route.Raw)
 
-	formatted := session.merger.FormatMessage(prov.Name(), route.Raw)
-
-	llmMsg := llm.Message{
-		Source:  prov.Name(),
-		Content: formatted,
-	}
-
-	if err := session.llm.Send(llmMsg); err != nil {
+	position, err := b.submit(session, prompt)
+	if err != nil {
 		slog.Error("send to llm failed", "error", err, "repo", repoName)
+		b.auditPrompt(prov, msg, repoName, route.Raw, audit.OutcomeFailed, err.Error())
 		if sendErr := prov.Send(msg.ChannelID, fmt.Sprintf("Error: %v", err)); sendErr != nil {
 			slog.Warn("send error failed", "error", sendErr, "channel", msg.ChannelID, "provider", prov.Name())
 		}
+		return
 	}
+	if position > 0 {
+		b.auditPrompt(prov, msg, repoName, route.Raw, audit.OutcomeQueued, fmt.Sprintf("position %d", position))
+		if err := prov.Send(msg.ChannelID, queueNotice(position)); err != nil {
+			slog.Warn("send queue notice failed", "error", err, "channel", msg.ChannelID, "provider", prov.Name())
+		}
+		return
+	}
+	b.auditPrompt(prov, msg, repoName, route.Raw, audit.OutcomeOK, "")
 }
 
-func (b *Bridge) getOrCreateSession(ctx context.Context, repoName string, repo config.RepoConfig, prov provider.Provider) (*repoSession, error) {
+// getOrCreateSession returns the running session for repoName and user
+// (zero for the shared session), starting one if needed, and attaches
+// (prov, channelID) so the channel receives its output. Starting a session
+// returns a *poolFullError if max_active_sessions is reached and no idle
+// session can be evicted.
+func (b *Bridge) getOrCreateSession(ctx context.Context, repoName string, repo config.RepoConfig, prov provider.Provider, channelID string, user sessionUser) (*repoSession, error) {
+	// A per-user worktree is created before taking b.mu so its checkout
+	// does not hold up the rest of the bridge.
+	workingDir, err := b.userWorkingDir(repoName, repo, user)
+	if err != nil {
+		return nil, err
+	}
+
 	b.mu.Lock()
 	defer b.mu.Unlock()
+	return b.getOrCreateSessionLocked(ctx, repoName, repo, workingDir, prov, channelID, user, false)
+}
 
-	if session, ok := b.repos[repoName]; ok && session.llm.Running() {
-		b.addChannelToSession(session, prov, repo.ChannelID)
+// getOrCreateSessionLocked implements getOrCreateSession, starting a new
+// LLM in workingDir. fromQueue is set for starts taken from b.pendingStarts.
+// A new LLM runs under b.sessionsCtx, not ctx, which only stops a start for
+// a request that is already gone. Callers must hold b.mu.
+func (b *Bridge) getOrCreateSessionLocked(ctx context.Context, repoName string, repo config.RepoConfig, workingDir string, prov provider.Provider, channelID string, user sessionUser, fromQueue bool) (*repoSession, error) {
+	key := sessionKey(repoName, user)
+	if session, ok := b.repos[key]; ok && session.llm.Running() {
+		b.addChannelToSession(session, prov, channelID)
 		return session, nil
 	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := b.makeRoomLocked(fromQueue); err != nil {
+		return nil, err
+	}
 
 	llmBackend := repo.LLM
 	if llmBackend == "" {
 		llmBackend = b.cfg.Defaults.LLM
 	}
 
-	llmInstance, err := b.llmFactory(llmBackend, repo.WorkingDir, b.cfg.Defaults.GetClaudePath(), b.cfg.Defaults.GetResumeSession())
+	llmInstance, err := b.llmFactory(llmBackend, workingDir, b.cfg.Defaults.GetClaudePath(), b.cfg.Defaults.GetResumeSession())
 	if err != nil {
 		return nil, fmt.Errorf("create llm: %w", err)
 	}
+	resumer, canResume := llmInstance.(llm.SessionResumer)
+	if id := b.llmSessions[key]; canResume && id != "" {
+		resumer.ResumeSession(id)
+	}
 
-	sessionCtx, cancel := context.WithCancel(ctx)
+	sessionCtx, cancel := context.WithCancel(b.sessionsCtx)
 
 	if err := llmInstance.Start(sessionCtx); err != nil {
 		cancel()
 		return nil, fmt.Errorf("start llm: %w", err)
 	}
+	if canResume {
+		if id := resumer.SessionID(); id != "" {
+			b.llmSessions[key] = id
+		}
+	}
 
 	var gitInfo *git.RepoInfo
 	if b.gitDetector != nil {
-		info, err := b.gitDetector(repo.WorkingDir)
+		info, err := b.gitDetector(workingDir)
 		if err != nil {
-			slog.Warn("git detection failed", "repo", repoName, "dir", repo.WorkingDir, "error", err)
+			slog.Warn("git detection failed", "repo", repoName, "dir", workingDir, "error", err)
 		} else {
 			gitInfo = info
 		}
 	}
 
 	session := &repoSession{
+		id:        newSessionID(),
 		name:      repoName,
 		llm:       llmInstance,
-		channels:  []channelRef{{provider: prov, channelID: repo.ChannelID}},
+		ctx:       sessionCtx,
 		cancelCtx: cancel,
 		merger:    NewMerger(2 * time.Second),
 		gitInfo:   gitInfo,
+		user:      user,
 	}
-	b.repos[repoName] = session
+	notifyOnly := b.addChannelToSession(session, prov, channelID)
+	b.repos[key] = session
+	b.markStateChanged()
 
 	go b.readOutput(session, repoName)
+	if len(notifyOnly) > 0 {
+		notice := fmt.Sprintf("LLM session started for %s", repoLabel(repoName, user))
+		go func() {
+			for _, ch := range notifyOnly {
+				b.reply(ch.provider, ch.channelID, notice)
+			}
+		}()
+	}
 
-	slog.Info("started llm session", "repo", repoName, "llm", llmBackend, "dir", repo.WorkingDir)
-	return session, nil
-}
-
-func (b *Bridge) addChannelToSession(session *repoSession, prov provider.Provider, channelID string) {
-	for _, ch := range session.channels {
-		if ch.provider.Name() == prov.Name() && ch.channelID == channelID {
-			return
-		}
+	slog.Info("started llm session", "repo", repoName, "user", user.name, "llm", llmBackend, "dir", workingDir)
+	b.metrics.sessionStarts.Inc(repoName)
+	data := map[string]any{
+		"llm": llmBackend, "dir": workingDir, "provider": prov.Name(), "channel": channelID,
+	}
+	if user.id != "" {
+		data["user"] = user.name
+		data["author_id"] = user.id
 	}
-	session.channels = append(session.channels, channelRef{provider: prov, channelID: channelID})
+	b.emit(webhook.SessionStarted, repoName, data)
+	return session, nil
 }
 
 func (b *Bridge) readOutput(session *repoSession, repoName string) {
@@ -395,6 +840,7 @@ func (b *Bridge) readOutput(session *repoSession, repoName string) {
 		err  error
 	}
 	lines := make(chan readResult, 100) // Buffer to prevent blocking on slow broadcasts
+	defer session.busy.clear()
 	go func() {
 		defer close(lines)
 		for {
@@ -413,6 +859,12 @@ func (b *Bridge) readOutput(session *repoSession, repoName string) {
 				b.broadcastOutput(session, buffer)
 				buffer = ""
 			}
+			if session.busy.settle(b.busyQuietPeriod) {
+				b.dispatchQueued(session)
+				b.mu.Lock()
+				b.retryPendingStartsLocked()
+				b.mu.Unlock()
+			}
 		case result, ok := <-lines:
 			if !ok {
 				// Channel closed
@@ -434,12 +886,22 @@ func (b *Bridge) readOutput(session *repoSession, repoName string) {
 				} else {
 					slog.Warn("llm read error", "repo", repoName, "error", result.err)
 				}
+				b.outputEnded(session, repoName, result.err)
 				return
 			}
 			buffer += result.line
 			session.llm.UpdateActivity()
+			if wait, first := session.busy.touch(); first {
+				b.metrics.firstOutput.Observe(wait.Seconds(), repoName)
+			}
+
+			if responder, ok := session.llm.(llm.PermissionResponder); ok && responder.IsPermissionPrompt(result.line) {
+				b.mu.Lock()
+				session.permissionPending = true
+				b.mu.Unlock()
+			}
 
-			if len(buffer) > b.cfg.Defaults.OutputThreshold {
+			if len(buffer) > b.currentConfig().Defaults.OutputThreshold {
 				b.broadcastOutput(session, buffer)
 				buffer = ""
 			}
@@ -455,19 +917,87 @@ func (b *Bridge) broadcastOutput(session *repoSession, content string) {
 	b.mu.Lock()
 	channels := make([]channelRef, len(session.channels))
 	copy(channels, session.channels)
+	session.lastOutput = content
+	reply := session.replyTo
+	session.replyTo = nil
 	b.mu.Unlock()
 
+	b.recordOutput(session, content)
+	b.metrics.broadcastBytes.Add(float64(len(content)), session.name)
+
 	for _, ch := range channels {
-		if b.output.ShouldAttach(content) {
-			filename, data := b.output.FormatFile(content)
-			if err := ch.provider.SendFile(ch.channelID, filename, data); err != nil {
-				slog.Error("send file failed", "error", err, "provider", ch.provider.Name())
-			}
-		} else {
-			if err := ch.provider.Send(ch.channelID, content); err != nil {
-				slog.Error("send failed", "error", err, "provider", ch.provider.Name())
-			}
+		if !ch.receivesOutput() {
+			continue
+		}
+		if reply != nil && reply.provider == ch.provider.Name() && reply.channelID == ch.channelID && b.sendReply(ch.provider, *reply, content) {
+			continue
+		}
+		b.sendOutput(ch.provider, ch.channelID, addressOutput(ch.provider, session.user, content))
+	}
+}
+
+// sendReply posts content as a reply to the prompt that produced it.
+// Returns false if the provider cannot reply or the content is too long for
+// a single message, so the caller falls back to sendOutput.
+func (b *Bridge) sendReply(prov provider.Provider, target replyTarget, content string) bool {
+	replier, ok := prov.(provider.Replier)
+	if !ok {
+		return false
+	}
+	caps := provider.CapabilitiesOf(prov)
+	if !caps.Replies || len(content) > b.output.Limit(caps.MaxMessageLength) {
+		return false
+	}
+	channelID := target.channelID
+	if target.threadID != "" {
+		channelID = target.threadID
+	}
+	// Earlier output still waiting to be delivered must not be overtaken.
+	if b.outboxBusy(prov, target.channelID) {
+		return false
+	}
+
+	if err := replier.Reply(channelID, target.messageID, content); err != nil {
+		slog.Warn("reply failed, sending as a new message", "error", err, "provider", prov.Name())
+		return false
+	}
+	b.metrics.messagesOut.Inc(prov.Name())
+	return true
+}
+
+// sendOutput delivers LLM output to one channel in the form its provider
+// handles best: inline when it fits, as a file when the provider supports
+// uploads, and otherwise split into messages that fit. Delivery goes through
+// the channel's outbox so failed sends are retried in order.
+func (b *Bridge) sendOutput(prov provider.Provider, channelID, content string) {
+	caps := provider.CapabilitiesOf(prov)
+
+	if len(content) > b.output.Limit(caps.MaxMessageLength) && caps.Files {
+		filename, data := b.output.FormatFile(content)
+		b.deliver(prov, channelID, outboxItem{filename: filename, data: data})
+		return
+	}
+
+	for _, chunk := range output.Split(content, caps.MaxMessageLength) {
+		b.deliver(prov, channelID, outboxItem{content: chunk})
+	}
+}
+
+// respond sends a command response, splitting it to fit the provider's
+// message limit. Help-style tables are wrapped in a code block for providers
+// that render markdown so their column alignment survives.
+func (b *Bridge) respond(prov provider.Provider, channelID, content string, preformatted bool) {
+	caps := provider.CapabilitiesOf(prov)
+	if preformatted && caps.Markdown != provider.MarkdownNone {
+		content = "```\n" + content + "\n```"
+	}
+
+	for _, chunk := range output.Split(content, caps.MaxMessageLength) {
+		if err := prov.Send(channelID, chunk); err != nil {
+			slog.Warn("send command response failed", "error", err, "channel", channelID, "provider", prov.Name())
+			return
 		}
+		b.metrics.messagesOut.Inc(prov.Name())
 	}
 }
 
@@ -486,25 +1016,30 @@ func (b *Bridge) handleTerminalMessages(ctx context.Context, term *provider.Term
 }
 
 func (b *Bridge) processTerminalMessage(ctx context.Context, term *provider.Terminal, msg provider.Message) {
-	route := router.Parse(msg.Content)
+	b.metrics.messagesIn.Inc(term.Name())
+	route := b.parseMessage(msg.Content, b.getTerminalRepo())
 
+	cfg := b.currentConfig()
 	if route.Type == router.RouteToBridge && route.Command == "select" {
 		if route.Args == "" {
 			var repos []string
-			for name := range b.cfg.Repos {
+			for name := range cfg.Repos {
 				repos = append(repos, name)
 			}
 			_ = term.Send("", fmt.Sprintf("Usage: /select <repo-name>\nAvailable repos: %v\nCurrently selected: %s", repos, b.getTerminalRepo()))
 			return
 		}
-		if _, ok := b.cfg.Repos[route.Args]; !ok {
+		if _, ok := cfg.Repos[route.Args]; !ok {
 			_ = term.Send("", fmt.Sprintf("Unknown repo: %s", route.Args))
 			return
 		}
 		b.mu.Lock()
 		b.terminalRepoName = route.Args
+		b.markStateChanged()
 		b.mu.Unlock()
-		_ = term.Send("", fmt.Sprintf("Selected repo: %s", route.Args))
+		result := commandOK(fmt.Sprintf("Selected repo: %s", route.Args))
+		b.auditCommand(term, term.ChannelID(), messageAuthor(msg), route, result)
+		_ = term.Send("", result.response)
 		return
 	}
 
@@ -514,29 +1049,51 @@ func (b *Bridge) processTerminalMessage(ctx context.Context, term *provider.Term
 		return
 	}
 
-	repo := b.cfg.Repos[repoName]
+	repo := cfg.Repos[repoName]
+	route, ok := b.expandMacro(term, msg, repoName, route)
+	if !ok {
+		return
+	}
 
 	switch route.Type {
 	case router.RouteToBridge:
-		b.handleBridgeCommand(term, term.ChannelID(), route)
+		b.handleBridgeCommand(term, term.ChannelID(), sessionUser{}, route)
 	case router.RouteToLLM:
-		session, err := b.getOrCreateSession(ctx, repoName, repo, term)
+		prompt := queuedPrompt{
+			msg:       llm.Message{Source: term.Name()},
+			author:    msg.Author,
+			authorID:  msg.AuthorID,
+			text:      route.Raw,
+			prov:      term,
+			channelID: repo.ChannelID,
+		}
+		session, err := b.getOrCreateSession(ctx, repoName, repo, term, repo.ChannelID, sessionUser{})
+		var full *poolFullError
+		if errors.As(err, &full) {
+			b.handlePoolFull(ctx, term, msg, repoName, sessionUser{}, prompt, full)
+			return
+		}
 		if err != nil {
 			slog.Error("failed to create session", "error", err, "repo", repoName)
+			b.auditPrompt(term, msg, repoName, route.Raw, audit.OutcomeFailed, fmt.Sprintf("start llm: %v", err))
 			_ = term.Send("", fmt.Sprintf("Error starting LLM: %v", err))
 			return
 		}
+		prompt.msg.Content = session.merger.FormatMessage(term.Name(), route.Raw)
 
-		formatted := session.merger.FormatMessage(term.Name(), route.Raw)
-		llmMsg := llm.Message{
-			Source:  term.Name(),
-			Content: formatted,
-		}
-
-		if err := session.llm.Send(llmMsg); err != nil {
+		position, err := b.submit(session, prompt)
+		if err != nil {
 			slog.Error("send to llm failed", "error", err, "repo", repoName)
+			b.auditPrompt(term, msg, repoName, route.Raw, audit.OutcomeFailed, err.Error())
 			_ = term.Send("", fmt.Sprintf("Error: %v", err))
+			return
 		}
+		if position > 0 {
+			b.auditPrompt(term, msg, repoName, route.Raw, audit.OutcomeQueued, fmt.Sprintf("position %d", position))
+			_ = term.Send("", queueNotice(position))
+			return
+		}
+		b.auditPrompt(term, msg, repoName, route.Raw, audit.OutcomeOK, "")
 	}
 }
 
@@ -556,21 +1113,29 @@ func (b *Bridge) getTerminalRepo() string {
 }
 
 func (b *Bridge) idleTimeoutLoop(ctx context.Context) {
-	ticker := time.NewTicker(1 * time.Minute)
+	interval := idleCheckInterval(b.currentConfig().Defaults.GetIdleWarningDuration())
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
-	timeout := b.cfg.Defaults.GetIdleTimeoutDuration()
-
 	for {
 		select {
 		case <-ctx.Done():
 			return
 		case <-ticker.C:
-			b.checkIdleTimeouts(timeout)
+			// Read each tick so a config reload applies to running sessions.
+			defaults := b.currentConfig().Defaults
+			b.checkIdleTimeouts(defaults.GetIdleTimeoutDuration())
+			if next := idleCheckInterval(defaults.GetIdleWarningDuration()); next != interval {
+				interval = next
+				ticker.Reset(interval)
+			}
 		}
 	}
 }
 
+// checkIdleTimeouts stops sessions idle past their repo's idle timeout,
+// defaulting to timeout, and warns their channels shortly before. Sessions
+// working on a prompt are never idle.
 func (b *Bridge) checkIdleTimeouts(timeout time.Duration) {
 	// Collect sessions to stop under lock, then release lock before stopping
 	// to avoid blocking message handling and session creation
@@ -578,35 +1143,69 @@ func (b *Bridge) checkIdleTimeouts(timeout time.Duration) {
 		name     string
 		session  *repoSession
 		channels []channelRef
+		timeout  time.Duration
+		left     time.Duration // until the deadline, for warnings
 	}
-	var toStop []idleSession
+	var toStop, toWarn []idleSession
 
 	b.mu.Lock()
+	warning := b.cfg.Defaults.GetIdleWarningDuration()
 	for name, session := range b.repos {
 		if session.llm == nil || !session.llm.Running() {
 			continue
 		}
+		if busy, _, _ := session.busy.snapshot(); busy || len(session.queue) > 0 {
+			continue
+		}
+		repoTimeout := b.cfg.Repos[session.name].GetIdleTimeout(timeout)
+		if repoTimeout <= 0 {
+			continue
+		}
 
-		if time.Since(session.llm.LastActivity()) > timeout {
-			// Copy channels slice while holding lock
-			channels := make([]channelRef, len(session.channels))
-			copy(channels, session.channels)
-			toStop = append(toStop, idleSession{name, session, channels})
+		deadline := idleDeadline(session, repoTimeout)
+		left := time.Until(deadline)
+		// Copy channels slice while holding lock
+		channels := make([]channelRef, len(session.channels))
+		copy(channels, session.channels)
+		if left >= 0 && warning > 0 && left <= warning && !session.idleWarnedFor.Equal(deadline) {
+			session.idleWarnedFor = deadline
+			toWarn = append(toWarn, idleSession{name, session, channels, repoTimeout, left})
+		}
+		if left < 0 {
+			toStop = append(toStop, idleSession{name, session, channels, repoTimeout, 0})
 			delete(b.repos, name)
+			b.markStateChanged()
 		}
 	}
+	if len(toStop) > 0 {
+		b.retryPendingStartsLocked()
+	}
 	b.mu.Unlock()
 
+	for _, idle := range toWarn {
+		notice := idleWarning(repoLabel(idle.session.name, idle.session.user), idle.left, idle.timeout)
+		for _, ch := range idle.channels {
+			b.reply(ch.provider, ch.channelID, notice)
+		}
+	}
+
 	// Stop sessions and notify channels outside the lock
 	for _, idle := range toStop {
-		slog.Info("stopping idle llm", "repo", idle.name, "idle", time.Since(idle.session.llm.LastActivity()))
+		slog.Info("stopping idle llm", "repo", idle.session.name, "user", idle.session.user.name, "idle", time.Since(idle.session.llm.LastActivity()))
 		_ = idle.session.llm.Stop()
 		if idle.session.cancelCtx != nil {
 			idle.session.cancelCtx()
 		}
 
+		b.metrics.idleTimeouts.Inc(idle.session.name)
+		b.emit(webhook.SessionIdle, idle.session.name, map[string]any{"timeout": idle.timeout.String()})
+
+		notice := fmt.Sprintf("LLM stopped due to idle timeout (%v)", idle.timeout)
+		if idle.session.user.id != "" {
+			notice = fmt.Sprintf("LLM session for %s stopped due to idle timeout (%v)", idle.session.user.name, idle.timeout)
+		}
 		for _, ch := range idle.channels {
-			_ = ch.provider.Send(ch.channelID, fmt.Sprintf("LLM stopped due to idle timeout (%v)", timeout))
+			_ = ch.provider.Send(ch.channelID, notice)
 		}
 	}
 }
@@ -615,11 +1214,14 @@ func (b *Bridge) repoForChannel(channelID string) string {
 	b.mu.Lock()
 	defer b.mu.Unlock()
 	for name, repo := range b.cfg.Repos {
-		if repo.ChannelID == channelID {
+		if _, ok := repo.ChannelMode(channelID); ok {
 			return name
 		}
 	}
-	return ""
+	if name := b.dmRepoForChannelLocked(channelID); name != "" {
+		return name
+	}
+	return b.repositoryRepoForChannelLocked(channelID)
 }
 
 // repoConfigForChannel returns both the repo name and a copy of its config.
@@ -628,92 +1230,219 @@ func (b *Bridge) repoConfigForChannel(channelID string) (string, config.RepoConf
 	b.mu.Lock()
 	defer b.mu.Unlock()
 	for name, repo := range b.cfg.Repos {
-		if repo.ChannelID == channelID {
+		if _, ok := repo.ChannelMode(channelID); ok {
 			return name, repo, true
 		}
 	}
+	if name := b.dmRepoForChannelLocked(channelID); name != "" {
+		return name, b.cfg.Repos[name], true
+	}
+	if name := b.repositoryRepoForChannelLocked(channelID); name != "" {
+		return name, b.cfg.Repos[name], true
+	}
 	return "", config.RepoConfig{}, false
 }
 
-func (b *Bridge) getStatus(channelID string) string {
-	repoName := b.repoForChannel(channelID)
+func (b *Bridge) getStatus(channelID string, author sessionUser) commandResult {
+	repoName, key, user := b.sessionKeyForChannel(channelID, author)
 	if repoName == "" {
-		return "No repo configured for this channel"
+		return commandFailed("No repo configured for this channel")
 	}
 
 	b.mu.Lock()
-	session, ok := b.repos[repoName]
+	session, ok := b.repos[key]
 	b.mu.Unlock()
 
+	label := repoLabel(repoName, user)
+	pool := b.poolStatus()
+	if pool != "" {
+		pool = "\n" + pool
+	}
 	if !ok || session.llm == nil || !session.llm.Running() {
-		return fmt.Sprintf("LLM: not running (repo: %s)", repoName)
+		if position := b.pendingPosition(key); position > 0 {
+			return commandOK(fmt.Sprintf("LLM: waiting to start (repo: %s, position %d)%s", label, position, pool))
+		}
+		return commandOK(fmt.Sprintf("LLM: not running (repo: %s)%s", label, pool))
 	}
 
 	idle := time.Since(session.llm.LastActivity())
+	var status string
 	if session.gitInfo != nil && session.gitInfo.Branch != "" {
 		if session.gitInfo.IsWorktree {
-			return fmt.Sprintf("LLM: %s running (repo: %s, branch: %s, worktree, idle: %v)", session.llm.Name(), repoName, session.gitInfo.Branch, idle.Round(time.Second))
+			status = fmt.Sprintf("LLM: %s running (repo: %s, branch: %s, worktree, idle: %v)", session.llm.Name(), label, session.gitInfo.Branch, idle.Round(time.Second))
+		} else {
+			status = fmt.Sprintf("LLM: %s running (repo: %s, branch: %s, idle: %v)", session.llm.Name(), label, session.gitInfo.Branch, idle.Round(time.Second))
 		}
-		return fmt.Sprintf("LLM: %s running (repo: %s, branch: %s, idle: %v)", session.llm.Name(), repoName, session.gitInfo.Branch, idle.Round(time.Second))
+	} else {
+		status = fmt.Sprintf("LLM: %s running (repo: %s, idle: %v)", session.llm.Name(), label, idle.Round(time.Second))
+	}
+
+	if busy := session.busy.describe(); busy != "" {
+		status += " - " + busy
 	}
-	return fmt.Sprintf("LLM: %s running (repo: %s, idle: %v)", session.llm.Name(), repoName, idle.Round(time.Second))
+	b.mu.Lock()
+	queued := len(session.queue)
+	b.mu.Unlock()
+	if queued > 0 {
+		status += fmt.Sprintf(", %d queued", queued)
+	}
+	return commandOK(status + pool)
 }
 
-func (b *Bridge) cancelLLM(channelID string) string {
-	repoName := b.repoForChannel(channelID)
+func (b *Bridge) cancelLLM(channelID string, author sessionUser) commandResult {
+	repoName, key, _ := b.sessionKeyForChannel(channelID, author)
 	if repoName == "" {
-		return "No repo configured"
+		return commandFailed("No repo configured")
 	}
 
+	if err := b.cancelSession(key); err != nil {
+		if errors.Is(err, errSessionNotRunning) {
+			return commandFailed("LLM not running")
+		}
+		return commandFailed(fmt.Sprintf("Cancel failed: %v", err))
+	}
+	return commandOK("Sent interrupt signal")
+}
+
+// errSessionNotRunning is returned for actions on a session that is not
+// running.
+var errSessionNotRunning = errors.New("LLM not running")
+
+// cancelSession interrupts the LLM of the session with key.
+func (b *Bridge) cancelSession(key string) error {
 	b.mu.Lock()
-	session, ok := b.repos[repoName]
+	session, ok := b.repos[key]
 	b.mu.Unlock()
 
 	if !ok || session.llm == nil || !session.llm.Running() {
-		return "LLM not running"
+		return errSessionNotRunning
 	}
-
-	if err := session.llm.Cancel(); err != nil {
-		return fmt.Sprintf("Cancel failed: %v", err)
-	}
-	return "Sent interrupt signal"
+	return session.llm.Cancel()
 }
 
-func (b *Bridge) restartLLM(channelID string) string {
-	repoName := b.repoForChannel(channelID)
+func (b *Bridge) restartLLM(channelID string, author sessionUser) commandResult {
+	repoName, key, _ := b.sessionKeyForChannel(channelID, author)
 	if repoName == "" {
-		return "No repo configured"
+		return commandFailed("No repo configured")
 	}
 
 	b.mu.Lock()
-	session, ok := b.repos[repoName]
+	b.stopSessionLocked(key, "restart")
+	b.mu.Unlock()
+
+	return commandOK("LLM stopped. Will restart on next message.")
+}
+
+// stopSessionLocked stops and forgets the session with key, reporting
+// whether there was one. Callers must hold b.mu.
+func (b *Bridge) stopSessionLocked(key, reason string) bool {
+	session, ok := b.repos[key]
 	if ok && session.llm != nil {
 		_ = session.llm.Stop()
 		if session.cancelCtx != nil {
 			session.cancelCtx()
 		}
+		b.metrics.sessionStops.Inc(session.name, reason)
+		b.emit(webhook.SessionStopped, session.name, map[string]any{"reason": reason})
+	}
+	delete(b.repos, key)
+	b.markStateChanged()
+	if ok {
+		b.retryPendingStartsLocked()
+	}
+	return ok
+}
+
+// resendLastOutput re-sends the session's most recent output chunk as a file.
+// Returns an empty string on success since the file itself is the response.
+// Providers without file uploads get the output back as the response text.
+func (b *Bridge) resendLastOutput(prov provider.Provider, channelID string, author sessionUser) commandResult {
+	repoName, key, _ := b.sessionKeyForChannel(channelID, author)
+	if repoName == "" {
+		return commandFailed("No repo configured")
+	}
+
+	b.mu.Lock()
+	var last string
+	if session, ok := b.repos[key]; ok {
+		last = session.lastOutput
+	}
+	b.mu.Unlock()
+
+	if last == "" {
+		return commandFailed("No output to re-send")
+	}
+
+	if !provider.CapabilitiesOf(prov).Files {
+		return commandOK(last)
+	}
+
+	filename, data := b.output.FormatFile(last)
+	if err := prov.SendFile(channelID, filename, data); err != nil {
+		return commandFailed(fmt.Sprintf("Re-send failed: %v", err))
+	}
+	return commandOK("")
+}
+
+// answerPermission approves or denies the LLM's pending permission prompt.
+func (b *Bridge) answerPermission(channelID string, author sessionUser, allow bool) commandResult {
+	repoName, key, _ := b.sessionKeyForChannel(channelID, author)
+	if repoName == "" {
+		return commandFailed("No repo configured")
+	}
+
+	b.mu.Lock()
+	session, ok := b.repos[key]
+	b.mu.Unlock()
+
+	if !ok || session.llm == nil || !session.llm.Running() {
+		return commandFailed("LLM not running")
 	}
-	delete(b.repos, repoName)
+
+	responder, ok := session.llm.(llm.PermissionResponder)
+	if !ok {
+		return commandFailed(fmt.Sprintf("%s does not support permission prompts", session.llm.Name()))
+	}
+
+	b.mu.Lock()
+	pending := session.permissionPending
+	session.permissionPending = false
 	b.mu.Unlock()
 
-	return "LLM stopped. Will restart on next message."
+	if !pending {
+		return commandFailed("No pending permission prompt")
+	}
+
+	if err := responder.RespondPermission(allow); err != nil {
+		return commandFailed(fmt.Sprintf("Permission response failed: %v", err))
+	}
+	// The turn goes on after the answer; readOutput sends queued prompts
+	// once its output goes quiet again.
+	if session.busy.resume() {
+		go b.typingLoop(session)
+	}
+	if allow {
+		return commandOK("Approved")
+	}
+	return commandOK("Denied")
 }
 
-func (b *Bridge) listWorktrees(channelID string) string {
+func (b *Bridge) listWorktrees(channelID string) commandResult {
 	repoName := b.repoForChannel(channelID)
 	if repoName == "" {
-		return "No repo configured for this channel"
+		return commandFailed("No repo configured for this channel")
 	}
 
-	repo := b.cfg.Repos[repoName]
+	cfg := b.currentConfig()
+	repo := cfg.Repos[repoName]
 
 	worktrees, err := b.worktreeLister(repo.WorkingDir)
 	if err != nil {
-		return fmt.Sprintf("Not a git repository or git error: %v", err)
+		return commandFailed(fmt.Sprintf("Not a git repository or git error: %v", err))
 	}
 
 	if len(worktrees) <= 1 {
-		return fmt.Sprintf("No linked worktrees for %s", repoName)
+		return commandOK(fmt.Sprintf("No linked worktrees for %s", repoName))
 	}
 
 	var sb strings.Builder
@@ -726,7 +1455,7 @@ func (b *Bridge) listWorktrees(channelID string) string {
 
 		// Check if this worktree has a configured repo
 		configuredRepo := ""
-		for name, r := range b.cfg.Repos {
+		for name, r := range cfg.Repos {
 			if r.WorkingDir == wt.Path {
 				configuredRepo = name
 				break
@@ -737,8 +1466,7 @@ func (b *Bridge) listWorktrees(channelID string) string {
 		if configuredRepo != "" {
 			// Check if session exists
 			b.mu.Lock()
-			session, active := b.repos[configuredRepo]
-			isActive := active && session.llm != nil && session.llm.Running()
+			isActive := b.repoActiveLocked(configuredRepo)
 			b.mu.Unlock()
 
 			if isActive {
@@ -751,7 +1479,7 @@ func (b *Bridge) listWorktrees(channelID string) string {
 		}
 		sb.WriteString(line + "\n")
 	}
-	return strings.TrimRight(sb.String(), "\n")
+	return commandOK(strings.TrimRight(sb.String(), "\n"))
 }
 
 // RuntimeAddRepo adds a repo to memory and persists it to the config file.
@@ -764,7 +1492,7 @@ func (b *Bridge) RuntimeAddRepo(name string, repo config.RepoConfig, checkExists
 	// Check existence while holding lock (prevents TOCTOU race)
 	if checkExists {
 		if _, exists := b.cfg.Repos[name]; exists {
-			return fmt.Errorf("repo %q already exists", name)
+			return fmt.Errorf("repo %q %w", name, errRepoExists)
 		}
 	}
 
@@ -774,20 +1502,40 @@ func (b *Bridge) RuntimeAddRepo(name string, repo config.RepoConfig, checkExists
 	}
 
 	// Update memory only after successful persistence
-	if b.cfg.Repos == nil {
-		b.cfg.Repos = make(map[string]config.RepoConfig)
-	}
-	b.cfg.Repos[name] = repo
+	cfg := b.withReposLocked()
+	cfg.Repos[name] = repo
+	b.cfg = cfg
+
+	// Start receiving messages from the new channel immediately
+	b.subscribeRepoLocked(repo)
 
 	return nil
 }
 
-func (b *Bridge) handleListRepos() string {
+// subscribeChannel asks the named provider to deliver messages from channelID,
+// if it filters by channel. Callers must hold b.mu.
+func (b *Bridge) subscribeChannel(providerName, channelID string) {
+	if sub, ok := b.providers[providerName].(provider.Subscriber); ok && channelID != "" {
+		sub.Subscribe(channelID)
+		slog.Info("subscribed channel", "provider", providerName, "channel", channelID)
+	}
+}
+
+// unsubscribeChannel asks the named provider to stop delivering messages from
+// channelID. Callers must hold b.mu.
+func (b *Bridge) unsubscribeChannel(providerName, channelID string) {
+	if sub, ok := b.providers[providerName].(provider.Subscriber); ok && channelID != "" {
+		sub.Unsubscribe(channelID)
+		slog.Info("unsubscribed channel", "provider", providerName, "channel", channelID)
+	}
+}
+
+func (b *Bridge) handleListRepos() commandResult {
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
 	if len(b.cfg.Repos) == 0 {
-		return "No repos configured"
+		return commandOK("No repos configured")
 	}
 
 	// Collect and sort repo names alphabetically
@@ -811,7 +1559,7 @@ func (b *Bridge) handleListRepos() string {
 
 		// Check if session is active
 		status := "inactive"
-		if session, ok := b.repos[name]; ok && session.llm != nil && session.llm.Running() {
+		if b.repoActiveLocked(name) {
 			status = "active"
 		}
 
@@ -824,47 +1572,82 @@ func (b *Bridge) handleListRepos() string {
 		sb.WriteString(line + "\n")
 	}
 
-	return strings.TrimRight(sb.String(), "\n")
+	return commandOK(strings.TrimRight(sb.String(), "\n"))
 }
 
 // handleRemoveRepo removes a repo from config and stops its active session.
 // This does NOT delete any files on disk - only the config entry.
-// Order: persist removal first, then stop session (ensures consistency if config write fails).
-func (b *Bridge) handleRemoveRepo(args string) string {
+func (b *Bridge) handleRemoveRepo(args string) commandResult {
 	name := strings.TrimSpace(args)
 	if name == "" {
-		return "Usage: /remove-repo <repo-name>"
+		return commandFailed("Usage: /remove-repo <repo-name>")
 	}
 
+	if err := b.removeRepo(name); err != nil {
+		if errors.Is(err, errRepoNotFound) {
+			return commandFailed(fmt.Sprintf("Repo %q not found", name))
+		}
+		return commandFailed(fmt.Sprintf("Failed to remove repo: %v", err))
+	}
+	return commandOK(fmt.Sprintf("Removed repo %q (files on disk were not deleted)", name))
+}
+
+// errRepoNotFound is returned for actions on a repo that is not configured.
+var errRepoNotFound = errors.New("repo not found")
+
+// errRepoExists is returned by RuntimeAddRepo for a name already in use.
+var errRepoExists = errors.New("already exists")
+
+// removeRepo removes a repo from the config file and memory, then stops its
+// sessions. Order: persist removal first, then stop session (ensures
+// consistency if config write fails).
+func (b *Bridge) removeRepo(name string) error {
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
 	// Check if repo exists in config
-	if _, ok := b.cfg.Repos[name]; !ok {
-		return fmt.Sprintf("Repo %q not found", name)
+	repo, ok := b.cfg.Repos[name]
+	if !ok {
+		return errRepoNotFound
 	}
 
 	// Persist removal to config file FIRST (before stopping session)
 	// If this fails, session remains intact and config is consistent
 	if err := config.RemoveRepo(b.cfgPath, name); err != nil {
-		return fmt.Sprintf("Failed to remove repo: %v", err)
+		return err
 	}
 
 	// Remove from memory after successful persistence
-	delete(b.cfg.Repos, name)
-
-	// Stop active session LAST (after config is consistent)
-	if session, ok := b.repos[name]; ok {
+	cfg := b.withReposLocked()
+	delete(cfg.Repos, name)
+	b.cfg = cfg
+	b.unsubscribeRepoLocked(repo)
+
+	// Stop active sessions LAST (after config is consistent), including
+	// per-user ones.
+	stopped := false
+	for key, session := range b.repos {
+		if session.name != name {
+			continue
+		}
 		if session.llm != nil {
 			_ = session.llm.Stop()
 		}
 		if session.cancelCtx != nil {
 			session.cancelCtx()
 		}
-		delete(b.repos, name)
+		delete(b.repos, key)
+		b.markStateChanged()
+		b.metrics.sessionStops.Inc(name, "removed")
+		b.emit(webhook.SessionStopped, name, map[string]any{"reason": "removed"})
+		stopped = true
 	}
-
-	return fmt.Sprintf("Removed repo %q (files on disk were not deleted)", name)
+	b.dropPendingStartsLocked(name)
+	if stopped {
+		b.retryPendingStartsLocked()
+	}
+	b.emit(webhook.RepoRemoved, name, map[string]any{"provider": repo.Provider, "channel": repo.ChannelID})
+	return nil
 }
 
 // handleClone clones an external repo and registers it as a new llm-bridge repo.
@@ -872,10 +1655,10 @@ func (b *Bridge) handleRemoveRepo(args string) string {
 // - url: Git repository URL (required, must use https/git/ssh scheme)
 // - name: Repo name (required, alphanumeric with hyphens/underscores only)
 // - channel-id: For Discord, required; for Terminal, defaults to "terminal-<name>"
-func (b *Bridge) handleClone(providerName string, args string) string {
+func (b *Bridge) handleClone(providerName string, args string) commandResult {
 	parts := strings.Fields(args)
 	if len(parts) < 2 {
-		return "Usage: /clone <url> <name> [channel-id]"
+		return commandFailed("Usage: /clone <url> <name> [channel-id]")
 	}
 
 	repoURL := parts[0]
@@ -887,50 +1670,58 @@ func (b *Bridge) handleClone(providerName string, args string) string {
 
 	// Security: validate URL scheme (prevents malicious URLs like ext::, file://)
 	if !git.IsAllowedGitURL(repoURL) {
-		return "Error: URL must use https, git, or ssh scheme"
+		return commandFailed("Error: URL must use https, git, or ssh scheme")
 	}
 
 	// Security: validate name uses only safe characters (alphanumeric, hyphen, underscore)
 	if !git.IsSafeRepoName(name) {
-		return "Error: name must contain only letters, numbers, hyphens, and underscores"
+		return commandFailed("Error: name must contain only letters, numbers, hyphens, and underscores")
 	}
 
 	// Determine destination directory (always relative to BaseDir)
-	baseDir := b.cfg.Defaults.GetBaseDir()
+	defaults := b.currentConfig().Defaults
+	baseDir := defaults.GetBaseDir()
 	destDir := filepath.Join(baseDir, name)
 
 	// Determine channel ID based on provider
 	if channelID == "" {
 		if providerName == "discord" {
-			return "Error: channel-id is required for Discord"
+			return commandFailed("Error: channel-id is required for Discord")
 		}
 		// Terminal: default to "terminal-<name>"
 		channelID = "terminal-" + name
 	}
 
 	// Clone the repository
-	if err := b.cloneRepo(repoURL, destDir); err != nil {
-		return fmt.Sprintf("Clone failed: %v", err)
+	start := time.Now()
+	err := b.cloneRepo(repoURL, destDir)
+	b.metrics.cloneDuration.Observe(time.Since(start).Seconds())
+	if err != nil {
+		b.metrics.cloneFailures.Inc()
+		return commandFailed(fmt.Sprintf("Clone failed: %v", err))
 	}
 
 	// Create RepoConfig
 	repo := config.RepoConfig{
 		Provider:   providerName,
 		ChannelID:  channelID,
-		LLM:        b.cfg.Defaults.LLM,
+		LLM:        defaults.LLM,
 		WorkingDir: destDir,
 	}
 
 	// Persist to config (checkExists=true for atomic duplicate check)
 	if err := b.RuntimeAddRepo(name, repo, true); err != nil {
 		// Check if it's a duplicate error vs other errors
-		if strings.Contains(err.Error(), "already exists") {
-			return fmt.Sprintf("Error: repo %q already exists", name)
+		if errors.Is(err, errRepoExists) {
+			return commandFailed(fmt.Sprintf("Error: repo %q already exists", name))
 		}
-		return fmt.Sprintf("Cloned but failed to register: %v", err)
+		return commandFailed(fmt.Sprintf("Cloned but failed to register: %v", err))
 	}
 
-	return fmt.Sprintf("Cloned and registered repo %q (channel: %s)", name, channelID)
+	b.emit(webhook.RepoCloned, name, map[string]any{
+		"url": redactURL(repoURL), "provider": providerName, "channel": channelID, "dir": destDir,
+	})
+	return commandOK(fmt.Sprintf("Cloned and registered repo %q (channel: %s)", name, channelID))
 }
 
 // handleAddWorktree creates a new git worktree from the current repo and registers it.
@@ -938,10 +1729,10 @@ func (b *Bridge) handleClone(providerName string, args string) string {
 // - name: Worktree name (required, alphanumeric with hyphens/underscores only)
 // - branch: Branch name to create (required)
 // - channel-id: For Discord, required; for Terminal, defaults to "terminal-<parent>/<name>"
-func (b *Bridge) handleAddWorktree(providerName string, parentChannelID string, args string) string {
+func (b *Bridge) handleAddWorktree(providerName string, parentChannelID string, args string) commandResult {
 	parts := strings.Fields(args)
 	if len(parts) < 2 {
-		return "Usage: /add-worktree <name> <branch> [channel-id]"
+		return commandFailed("Usage: /add-worktree <name> <branch> [channel-id]")
 	}
 
 	name := parts[0]
@@ -953,18 +1744,18 @@ func (b *Bridge) handleAddWorktree(providerName string, parentChannelID string,
 
 	// Security: validate name uses only safe characters (alphanumeric, hyphen, underscore)
 	if !git.IsSafeRepoName(name) {
-		return "Error: name must contain only letters, numbers, hyphens, and underscores"
+		return commandFailed("Error: name must contain only letters, numbers, hyphens, and underscores")
 	}
 
 	// Validate branch is also safe (alphanumeric, hyphen, underscore, slash for feature branches)
 	if !git.IsSafeRepoName(strings.ReplaceAll(branch, "/", "-")) {
-		return "Error: branch must contain only letters, numbers, hyphens, underscores, and slashes"
+		return commandFailed("Error: branch must contain only letters, numbers, hyphens, underscores, and slashes")
 	}
 
 	// Find current repo from channel (atomically get name and config copy)
 	parentRepoName, parentRepo, found := b.repoConfigForChannel(parentChannelID)
 	if !found {
-		return "Error: no repo configured for this channel"
+		return commandFailed("Error: no repo configured for this channel")
 	}
 
 	// Determine git root: if current repo has GitRoot set (is worktree), use GitRoot; otherwise use WorkingDir
@@ -982,7 +1773,7 @@ func (b *Bridge) handleAddWorktree(providerName string, parentChannelID string,
 	// Determine channel ID based on provider
 	if newChannelID == "" {
 		if providerName == "discord" {
-			return "Error: channel-id is required for Discord"
+			return commandFailed("Error: channel-id is required for Discord")
 		}
 		// Terminal: default to "terminal-<childName>"
 		newChannelID = "terminal-" + childName
@@ -990,7 +1781,7 @@ func (b *Bridge) handleAddWorktree(providerName string, parentChannelID string,
 
 	// Create the worktree
 	if err := b.addWorktree(parentGitRoot, wtDir, branch); err != nil {
-		return fmt.Sprintf("Failed to create worktree: %v", err)
+		return commandFailed(fmt.Sprintf("Failed to create worktree: %v", err))
 	}
 
 	// Create RepoConfig
@@ -1006,11 +1797,14 @@ func (b *Bridge) handleAddWorktree(providerName string, parentChannelID string,
 	// Persist to config (checkExists=true for atomic duplicate check)
 	if err := b.RuntimeAddRepo(childName, repo, true); err != nil {
 		// Check if it's a duplicate error vs other errors
-		if strings.Contains(err.Error(), "already exists") {
-			return fmt.Sprintf("Error: repo %q already exists", childName)
+		if errors.Is(err, errRepoExists) {
+			return commandFailed(fmt.Sprintf("Error: repo %q already exists", childName))
 		}
-		return fmt.Sprintf("Worktree created but failed to register: %v", err)
+		return commandFailed(fmt.Sprintf("Worktree created but failed to register: %v", err))
 	}
 
-	return fmt.Sprintf("Created worktree %q (channel: %s, branch: %s)", childName, newChannelID, branch)
+	b.emit(webhook.WorktreeCreated, childName, map[string]any{
+		"parent": parentRepoName, "branch": branch, "provider": repo.Provider, "channel": newChannelID, "dir": wtDir,
+	})
+	return commandOK(fmt.Sprintf("Created worktree %q (channel: %s, branch: %s)", childName, newChannelID, branch))
 }
@@ -2,11 +2,13 @@ package bridge
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -37,6 +39,110 @@ func testConfig() *config.Config {
 	}
 }
 
+// testBridge is a bridge built by newTestBridge and the mocks behind it.
+type testBridge struct {
+	b         *Bridge
+	prov      *provider.MockProvider // the first mock provider
+	providers map[string]*provider.MockProvider
+	session   *repoSession // running test-repo session, with withRunningSession
+	llm       *mockLLM     // the running session's LLM
+
+	mu      sync.Mutex
+	started []*mockLLM // LLMs the factory created, in order
+	dirs    []string   // the working dir of each
+}
+
+type testBridgeSetup struct {
+	cfg       *config.Config
+	cfgPath   string
+	providers []string
+	session   bool
+}
+
+// testBridgeOption configures newTestBridge.
+type testBridgeOption func(*testBridgeSetup)
+
+// withConfig builds the bridge from cfg instead of testConfig.
+func withConfig(cfg *config.Config) testBridgeOption {
+	return func(s *testBridgeSetup) { s.cfg = cfg }
+}
+
+// withConfigPath gives the bridge a config file path, which enables the
+// state file and transcripts next to it.
+func withConfigPath(path string) testBridgeOption {
+	return func(s *testBridgeSetup) { s.cfgPath = path }
+}
+
+// withProviders registers running mock providers with these names instead
+// of a single "discord" one.
+func withProviders(names ...string) testBridgeOption {
+	return func(s *testBridgeSetup) { s.providers = names }
+}
+
+// withRunningSession adds a running test-repo session, with ID "s1", on the
+// first provider's channel-123.
+func withRunningSession() testBridgeOption {
+	return func(s *testBridgeSetup) { s.session = true }
+}
+
+// newTestBridge returns a bridge without git detection whose LLM factory
+// creates mock LLMs and records them.
+func newTestBridge(t *testing.T, opts ...testBridgeOption) *testBridge {
+	t.Helper()
+	setup := testBridgeSetup{providers: []string{"discord"}}
+	for _, opt := range opts {
+		opt(&setup)
+	}
+	if setup.cfg == nil {
+		setup.cfg = testConfig()
+	}
+
+	tb := &testBridge{b: New(setup.cfg, setup.cfgPath), providers: make(map[string]*provider.MockProvider)}
+	tb.b.gitDetector = nil
+	tb.b.llmFactory = func(backend, workDir, claudePath string, resume bool) (llm.LLM, error) {
+		tb.mu.Lock()
+		defer tb.mu.Unlock()
+		m := newMockLLM("claude")
+		tb.started = append(tb.started, m)
+		tb.dirs = append(tb.dirs, workDir)
+		return m, nil
+	}
+	for _, name := range setup.providers {
+		p := provider.NewMockProvider(name)
+		tb.b.providers[name] = p
+		tb.providers[name] = p
+		if tb.prov == nil {
+			tb.prov = p
+		}
+	}
+
+	if setup.session {
+		tb.llm = newMockLLM("claude")
+		tb.llm.setRunning(true)
+		tb.session = &repoSession{
+			id:       "s1",
+			name:     "test-repo",
+			llm:      tb.llm,
+			channels: []channelRef{{provider: tb.prov, channelID: "channel-123"}},
+			merger:   NewMerger(2 * time.Second),
+		}
+		tb.b.repos["test-repo"] = tb.session
+	}
+	return tb
+}
+
+// llmFor returns the last LLM the factory started in dir, or nil.
+func (tb *testBridge) llmFor(dir string) *mockLLM {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+	for i := len(tb.dirs) - 1; i >= 0; i-- {
+		if tb.dirs[i] == dir {
+			return tb.started[i]
+		}
+	}
+	return nil
+}
+
 func TestNew(t *testing.T) {
 	cfg := testConfig()
 	b := New(cfg, "")
@@ -104,7 +210,7 @@ func TestBridge_GetStatus_NoRepo(t *testing.T) {
 	cfg := testConfig()
 	b := New(cfg, "")
 
-	status := b.getStatus("unknown-channel")
+	status := b.getStatus("unknown-channel", sessionUser{}).response
 	if status != "No repo configured for this channel" {
 		t.Errorf("unexpected status: %q", status)
 	}
@@ -114,7 +220,7 @@ func TestBridge_GetStatus_NotRunning(t *testing.T) {
 	cfg := testConfig()
 	b := New(cfg, "")
 
-	status := b.getStatus("channel-123")
+	status := b.getStatus("channel-123", sessionUser{}).response
 	if status != "LLM: not running (repo: test-repo)" {
 		t.Errorf("unexpected status: %q", status)
 	}
@@ -124,7 +230,7 @@ func TestBridge_CancelLLM_NoRepo(t *testing.T) {
 	cfg := testConfig()
 	b := New(cfg, "")
 
-	result := b.cancelLLM("unknown-channel")
+	result := b.cancelLLM("unknown-channel", sessionUser{}).response
 	if result != "No repo configured" {
 		t.Errorf("unexpected result: %q", result)
 	}
@@ -134,7 +240,7 @@ func TestBridge_CancelLLM_NotRunning(t *testing.T) {
 	cfg := testConfig()
 	b := New(cfg, "")
 
-	result := b.cancelLLM("channel-123")
+	result := b.cancelLLM("channel-123", sessionUser{}).response
 	if result != "LLM not running" {
 		t.Errorf("unexpected result: %q", result)
 	}
@@ -144,7 +250,7 @@ func TestBridge_RestartLLM_NoRepo(t *testing.T) {
 	cfg := testConfig()
 	b := New(cfg, "")
 
-	result := b.restartLLM("unknown-channel")
+	result := b.restartLLM("unknown-channel", sessionUser{}).response
 	if result != "No repo configured" {
 		t.Errorf("unexpected result: %q", result)
 	}
@@ -154,7 +260,7 @@ func TestBridge_RestartLLM_NotRunning(t *testing.T) {
 	cfg := testConfig()
 	b := New(cfg, "")
 
-	result := b.restartLLM("channel-123")
+	result := b.restartLLM("channel-123", sessionUser{}).response
 	if result != "LLM stopped. Will restart on next message." {
 		t.Errorf("unexpected result: %q", result)
 	}
@@ -229,7 +335,7 @@ func TestBridge_HandleBridgeCommand_Help(t *testing.T) {
 		Command: "help",
 	}
 
-	b.handleBridgeCommand(mockProv, "channel-123", route)
+	b.handleBridgeCommand(mockProv, "channel-123", sessionUser{}, route)
 
 	msgs := mockProv.GetSentMessages()
 	if len(msgs) != 1 {
@@ -299,6 +405,90 @@ func TestBridge_BroadcastOutput_Long(t *testing.T) {
 	}
 }
 
+func TestBridge_BroadcastOutput_ProviderLimitCapsThreshold(t *testing.T) {
+	cfg := testConfig()
+	b := New(cfg, "")
+
+	mockProv := provider.NewMockProvider("discord")
+	mockProv.SetCapabilities(provider.Capabilities{MaxMessageLength: 20, Files: true})
+	session := &repoSession{
+		name:     "test-repo",
+		channels: []channelRef{{provider: mockProv, channelID: "channel-123"}},
+		merger:   NewMerger(2 * time.Second),
+	}
+
+	b.broadcastOutput(session, strings.Repeat("x", 21))
+
+	if len(mockProv.GetSentFiles()) != 1 {
+		t.Errorf("output over the provider limit should be attached even below output_threshold")
+	}
+}
+
+func TestBridge_BroadcastOutput_SplitsWithoutFiles(t *testing.T) {
+	cfg := testConfig()
+	cfg.Defaults.OutputThreshold = 10
+	b := New(cfg, "")
+
+	mockProv := provider.NewMockProvider("chat")
+	mockProv.SetCapabilities(provider.Capabilities{MaxMessageLength: 12})
+	session := &repoSession{
+		name:     "test-repo",
+		channels: []channelRef{{provider: mockProv, channelID: "channel-123"}},
+		merger:   NewMerger(2 * time.Second),
+	}
+
+	b.broadcastOutput(session, "first line\nsecond line\n")
+
+	if len(mockProv.GetSentFiles()) != 0 {
+		t.Error("provider without file support should not receive files")
+	}
+	msgs := mockProv.GetSentMessages()
+	if len(msgs) != 2 || msgs[0].Content != "first line\n" || msgs[1].Content != "second line\n" {
+		t.Errorf("expected output split at newlines, got %+v", msgs)
+	}
+}
+
+func TestBridge_BroadcastOutput_UnlimitedWithoutFiles(t *testing.T) {
+	cfg := testConfig()
+	cfg.Defaults.OutputThreshold = 10
+	b := New(cfg, "")
+
+	mockProv := provider.NewMockProvider("terminal")
+	mockProv.SetCapabilities(provider.Capabilities{})
+	session := &repoSession{
+		name:     "test-repo",
+		channels: []channelRef{{provider: mockProv, channelID: "channel-123"}},
+		merger:   NewMerger(2 * time.Second),
+	}
+
+	content := strings.Repeat("y", 100)
+	b.broadcastOutput(session, content)
+
+	msgs := mockProv.GetSentMessages()
+	if len(msgs) != 1 || msgs[0].Content != content {
+		t.Errorf("expected output sent inline in one message, got %d messages", len(msgs))
+	}
+}
+
+func TestBridge_HandleBridgeCommand_HelpAdaptsToMarkdown(t *testing.T) {
+	b := New(testConfig(), "")
+
+	md := provider.NewMockProvider("discord")
+	b.handleBridgeCommand(md, "channel-123", sessionUser{}, router.Route{Type: router.RouteToBridge, Command: "help"})
+	msgs := md.GetSentMessages()
+	if len(msgs) != 1 || !strings.HasPrefix(msgs[0].Content, "```") {
+		t.Errorf("help should be a code block for markdown providers, got %+v", msgs)
+	}
+
+	plain := provider.NewMockProvider("terminal")
+	plain.SetCapabilities(provider.Capabilities{})
+	b.handleBridgeCommand(plain, "channel-123", sessionUser{}, router.Route{Type: router.RouteToBridge, Command: "help"})
+	msgs = plain.GetSentMessages()
+	if len(msgs) != 1 || strings.Contains(msgs[0].Content, "```") {
+		t.Errorf("help should be plain text for providers without markdown, got %+v", msgs)
+	}
+}
+
 func TestBridge_BroadcastOutput_Empty(t *testing.T) {
 	cfg := testConfig()
 	b := New(cfg, "")
@@ -381,7 +571,7 @@ func TestBridge_HandleBridgeCommand_Status(t *testing.T) {
 
 	mockProv := provider.NewMockProvider("discord")
 	route := router.Route{Type: router.RouteToBridge, Command: "status"}
-	b.handleBridgeCommand(mockProv, "channel-123", route)
+	b.handleBridgeCommand(mockProv, "channel-123", sessionUser{}, route)
 
 	msgs := mockProv.GetSentMessages()
 	if len(msgs) != 1 {
@@ -398,7 +588,7 @@ func TestBridge_HandleBridgeCommand_Cancel(t *testing.T) {
 
 	mockProv := provider.NewMockProvider("discord")
 	route := router.Route{Type: router.RouteToBridge, Command: "cancel"}
-	b.handleBridgeCommand(mockProv, "channel-123", route)
+	b.handleBridgeCommand(mockProv, "channel-123", sessionUser{}, route)
 
 	msgs := mockProv.GetSentMessages()
 	if len(msgs) != 1 {
@@ -412,7 +602,7 @@ func TestBridge_HandleBridgeCommand_Restart(t *testing.T) {
 
 	mockProv := provider.NewMockProvider("discord")
 	route := router.Route{Type: router.RouteToBridge, Command: "restart"}
-	b.handleBridgeCommand(mockProv, "channel-123", route)
+	b.handleBridgeCommand(mockProv, "channel-123", sessionUser{}, route)
 
 	msgs := mockProv.GetSentMessages()
 	if len(msgs) != 1 {
@@ -429,7 +619,7 @@ func TestBridge_HandleBridgeCommand_Unknown(t *testing.T) {
 
 	mockProv := provider.NewMockProvider("discord")
 	route := router.Route{Type: router.RouteToBridge, Command: "foobar"}
-	b.handleBridgeCommand(mockProv, "channel-123", route)
+	b.handleBridgeCommand(mockProv, "channel-123", sessionUser{}, route)
 
 	msgs := mockProv.GetSentMessages()
 	if len(msgs) != 1 {
@@ -452,7 +642,7 @@ func TestBridge_GetStatus_Running(t *testing.T) {
 		llm:  mockLLM,
 	}
 
-	status := b.getStatus("channel-123")
+	status := b.getStatus("channel-123", sessionUser{}).response
 	if !strings.Contains(status, "claude running") {
 		t.Errorf("expected running status, got %q", status)
 	}
@@ -473,7 +663,7 @@ func TestBridge_CancelLLM_Running(t *testing.T) {
 		llm:  mockLLM,
 	}
 
-	result := b.cancelLLM("channel-123")
+	result := b.cancelLLM("channel-123", sessionUser{}).response
 	if result != "Sent interrupt signal" {
 		t.Errorf("expected 'Sent interrupt signal', got %q", result)
 	}
@@ -493,7 +683,7 @@ func TestBridge_RestartLLM_Running(t *testing.T) {
 		cancelCtx: func() { cancelled = true },
 	}
 
-	result := b.restartLLM("channel-123")
+	result := b.restartLLM("channel-123", sessionUser{}).response
 	if result != "LLM stopped. Will restart on next message." {
 		t.Errorf("unexpected result: %q", result)
 	}
@@ -929,7 +1119,7 @@ func TestBridge_GetOrCreateSession_NewSession(t *testing.T) {
 	mockProv := provider.NewMockProvider("discord")
 	repo := cfg.Repos["test-repo"]
 
-	session, err := b.getOrCreateSession(context.Background(), "test-repo", repo, mockProv)
+	session, err := b.getOrCreateSession(context.Background(), "test-repo", repo, mockProv, repo.ChannelID, sessionUser{})
 	if err != nil {
 		t.Fatalf("getOrCreateSession error = %v", err)
 	}
@@ -957,7 +1147,7 @@ func TestBridge_GetOrCreateSession_ExistingSession(t *testing.T) {
 	}
 
 	repo := cfg.Repos["test-repo"]
-	session, err := b.getOrCreateSession(context.Background(), "test-repo", repo, mockProv)
+	session, err := b.getOrCreateSession(context.Background(), "test-repo", repo, mockProv, repo.ChannelID, sessionUser{})
 	if err != nil {
 		t.Fatalf("getOrCreateSession error = %v", err)
 	}
@@ -977,7 +1167,7 @@ func TestBridge_GetOrCreateSession_FactoryError(t *testing.T) {
 	mockProv := provider.NewMockProvider("discord")
 	repo := cfg.Repos["test-repo"]
 
-	_, err := b.getOrCreateSession(context.Background(), "test-repo", repo, mockProv)
+	_, err := b.getOrCreateSession(context.Background(), "test-repo", repo, mockProv, repo.ChannelID, sessionUser{})
 	if err == nil {
 		t.Error("expected error from factory")
 	}
@@ -1175,10 +1365,13 @@ func TestBridge_Start_NoChannelsForDiscord(t *testing.T) {
 
 	b := New(cfg, "")
 
-	// Discord factory should not be called since no channels use discord
+	// Discord still starts with an empty allowlist so that repos added at
+	// runtime can subscribe their channels.
+	var gotChannels []string
 	discordFactoryCalled := false
 	b.discordFactory = func(token string, channelIDs []string) provider.Provider {
 		discordFactoryCalled = true
+		gotChannels = channelIDs
 		return provider.NewMockProvider("discord")
 	}
 
@@ -1187,8 +1380,69 @@ func TestBridge_Start_NoChannelsForDiscord(t *testing.T) {
 
 	_ = b.Start(ctx)
 
-	if discordFactoryCalled {
-		t.Error("discord factory should not be called when no channels use discord")
+	if !discordFactoryCalled {
+		t.Error("discord factory should be called even when no channels use discord")
+	}
+	if len(gotChannels) != 0 {
+		t.Errorf("expected empty channel list, got %v", gotChannels)
+	}
+}
+
+func TestBridge_Start_Plugins(t *testing.T) {
+	cfg := testConfig()
+	cfg.Providers.Plugins = map[string]config.PluginConfig{
+		"slack": {Command: "slack-plugin"},
+	}
+	repo := cfg.Repos["test-repo"]
+	repo.Provider = "slack"
+	cfg.Repos["test-repo"] = repo
+
+	b := New(cfg, "")
+
+	mockPlugin := provider.NewMockProvider("slack")
+	var gotName string
+	var gotChannels []string
+	b.pluginFactory = func(name string, pc config.PluginConfig, channelIDs []string) provider.Provider {
+		gotName = name
+		gotChannels = channelIDs
+		return mockPlugin
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	_ = b.Start(ctx)
+
+	if gotName != "slack" {
+		t.Errorf("plugin factory name = %q, want slack", gotName)
+	}
+	if len(gotChannels) != 1 || gotChannels[0] != repo.ChannelID {
+		t.Errorf("plugin channels = %v, want [%s]", gotChannels, repo.ChannelID)
+	}
+	if !mockPlugin.WasStartCalled() {
+		t.Error("plugin should be started")
+	}
+	if !mockPlugin.WasStopCalled() {
+		t.Error("plugin should be stopped with the bridge")
+	}
+}
+
+func TestBridge_Start_PluginError(t *testing.T) {
+	cfg := testConfig()
+	cfg.Providers.Plugins = map[string]config.PluginConfig{
+		"slack": {Command: "slack-plugin"},
+	}
+	b := New(cfg, "")
+
+	mockPlugin := provider.NewMockProvider("slack")
+	mockPlugin.SetStartError(errors.New("handshake failed"))
+	b.pluginFactory = func(string, config.PluginConfig, []string) provider.Provider {
+		return mockPlugin
+	}
+
+	err := b.Start(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "start plugin slack") {
+		t.Errorf("Start() error = %v, want plugin start failure", err)
 	}
 }
 
@@ -1606,6 +1860,7 @@ func TestBridge_RateLimitDisabled(t *testing.T) {
 			Source:    "discord",
 		}
 		b.processMessage(ctx, mockProv, msg)
+		endTurns(b)
 	}
 
 	sentMsgs := mockLLM.getSentMessages()
@@ -1682,6 +1937,7 @@ func TestBridge_RateLimitDifferentUsersIndependent(t *testing.T) {
 		Source:    "discord",
 	}
 	b.processMessage(ctx, mockProv, msgA)
+	endTurns(b)
 
 	// User B sends a message - should also pass (independent bucket)
 	msgB := provider.Message{
@@ -1692,6 +1948,7 @@ func TestBridge_RateLimitDifferentUsersIndependent(t *testing.T) {
 		Source:    "discord",
 	}
 	b.processMessage(ctx, mockProv, msgB)
+	endTurns(b)
 
 	sentMsgs := mockLLM.getSentMessages()
 	if len(sentMsgs) != 2 {
@@ -1749,7 +2006,7 @@ func TestBridge_GetStatus_RunningWithGitInfo(t *testing.T) {
 		},
 	}
 
-	status := b.getStatus("channel-123")
+	status := b.getStatus("channel-123", sessionUser{}).response
 	if !strings.Contains(status, "claude running") {
 		t.Errorf("expected running status, got %q", status)
 	}
@@ -1777,7 +2034,7 @@ func TestBridge_GetStatus_RunningWithWorktreeGitInfo(t *testing.T) {
 		},
 	}
 
-	status := b.getStatus("channel-123")
+	status := b.getStatus("channel-123", sessionUser{}).response
 	if !strings.Contains(status, "claude running") {
 		t.Errorf("expected running status, got %q", status)
 	}
@@ -1802,7 +2059,7 @@ func TestBridge_GetStatus_RunningWithoutGitInfo(t *testing.T) {
 		gitInfo: nil,
 	}
 
-	status := b.getStatus("channel-123")
+	status := b.getStatus("channel-123", sessionUser{}).response
 	if !strings.Contains(status, "claude running") {
 		t.Errorf("expected running status, got %q", status)
 	}
@@ -1838,7 +2095,7 @@ func TestBridge_GetOrCreateSession_DetectsGit(t *testing.T) {
 	mockProv := provider.NewMockProvider("discord")
 	repo := cfg.Repos["test-repo"]
 
-	session, err := b.getOrCreateSession(context.Background(), "test-repo", repo, mockProv)
+	session, err := b.getOrCreateSession(context.Background(), "test-repo", repo, mockProv, repo.ChannelID, sessionUser{})
 	if err != nil {
 		t.Fatalf("getOrCreateSession error = %v", err)
 	}
@@ -1871,7 +2128,7 @@ func TestBridge_GetOrCreateSession_GitDetectionFailure(t *testing.T) {
 	mockProv := provider.NewMockProvider("discord")
 	repo := cfg.Repos["test-repo"]
 
-	session, err := b.getOrCreateSession(context.Background(), "test-repo", repo, mockProv)
+	session, err := b.getOrCreateSession(context.Background(), "test-repo", repo, mockProv, repo.ChannelID, sessionUser{})
 	if err != nil {
 		t.Fatalf("getOrCreateSession should succeed even if git detection fails, got error = %v", err)
 	}
@@ -1896,7 +2153,7 @@ func TestBridge_HandleBridgeCommand_Worktrees_NoRepo(t *testing.T) {
 		Command: "worktrees",
 	}
 
-	b.handleBridgeCommand(mockProv, "unknown-channel", route)
+	b.handleBridgeCommand(mockProv, "unknown-channel", sessionUser{}, route)
 
 	msgs := mockProv.GetSentMessages()
 	if len(msgs) != 1 {
@@ -1920,7 +2177,7 @@ func TestBridge_ListWorktrees_WithWorktrees(t *testing.T) {
 
 	mockProv := provider.NewMockProvider("discord")
 	route := router.Route{Type: router.RouteToBridge, Command: "worktrees"}
-	b.handleBridgeCommand(mockProv, "channel-123", route)
+	b.handleBridgeCommand(mockProv, "channel-123", sessionUser{}, route)
 
 	msgs := mockProv.GetSentMessages()
 	if len(msgs) != 1 {
@@ -1951,7 +2208,7 @@ func TestBridge_ListWorktrees_GitError(t *testing.T) {
 
 	mockProv := provider.NewMockProvider("discord")
 	route := router.Route{Type: router.RouteToBridge, Command: "worktrees"}
-	b.handleBridgeCommand(mockProv, "channel-123", route)
+	b.handleBridgeCommand(mockProv, "channel-123", sessionUser{}, route)
 
 	msgs := mockProv.GetSentMessages()
 	if len(msgs) != 1 {
@@ -1974,7 +2231,7 @@ func TestBridge_ListWorktrees_NoLinkedWorktrees(t *testing.T) {
 
 	mockProv := provider.NewMockProvider("discord")
 	route := router.Route{Type: router.RouteToBridge, Command: "worktrees"}
-	b.handleBridgeCommand(mockProv, "channel-123", route)
+	b.handleBridgeCommand(mockProv, "channel-123", sessionUser{}, route)
 
 	msgs := mockProv.GetSentMessages()
 	if len(msgs) != 1 {
@@ -2005,7 +2262,7 @@ func TestBridge_ListWorktrees_WithConfiguredRepo(t *testing.T) {
 
 	mockProv := provider.NewMockProvider("discord")
 	route := router.Route{Type: router.RouteToBridge, Command: "worktrees"}
-	b.handleBridgeCommand(mockProv, "channel-123", route)
+	b.handleBridgeCommand(mockProv, "channel-123", sessionUser{}, route)
 
 	msgs := mockProv.GetSentMessages()
 	if len(msgs) != 1 {
@@ -2042,7 +2299,7 @@ func TestBridge_ListWorktrees_WithActiveSession(t *testing.T) {
 
 	mockProv := provider.NewMockProvider("discord")
 	route := router.Route{Type: router.RouteToBridge, Command: "worktrees"}
-	b.handleBridgeCommand(mockProv, "channel-123", route)
+	b.handleBridgeCommand(mockProv, "channel-123", sessionUser{}, route)
 
 	msgs := mockProv.GetSentMessages()
 	if len(msgs) != 1 {
@@ -2066,7 +2323,7 @@ func TestBridge_ListWorktrees_UnconfiguredWorktree(t *testing.T) {
 
 	mockProv := provider.NewMockProvider("discord")
 	route := router.Route{Type: router.RouteToBridge, Command: "worktrees"}
-	b.handleBridgeCommand(mockProv, "channel-123", route)
+	b.handleBridgeCommand(mockProv, "channel-123", sessionUser{}, route)
 
 	msgs := mockProv.GetSentMessages()
 	if len(msgs) != 1 {
@@ -2088,7 +2345,7 @@ func TestBridge_HandleBridgeCommand_Help_IncludesWorktrees(t *testing.T) {
 		Command: "help",
 	}
 
-	b.handleBridgeCommand(mockProv, "channel-123", route)
+	b.handleBridgeCommand(mockProv, "channel-123", sessionUser{}, route)
 
 	msgs := mockProv.GetSentMessages()
 	if len(msgs) != 1 {
@@ -2355,7 +2612,7 @@ func TestBridge_HandleListRepos_NoRepos(t *testing.T) {
 
 	mockProv := provider.NewMockProvider("discord")
 	route := router.Route{Type: router.RouteToBridge, Command: "list-repos"}
-	b.handleBridgeCommand(mockProv, "any-channel", route)
+	b.handleBridgeCommand(mockProv, "any-channel", sessionUser{}, route)
 
 	msgs := mockProv.GetSentMessages()
 	if len(msgs) != 1 {
@@ -2382,7 +2639,7 @@ func TestBridge_HandleListRepos_SingleRepo(t *testing.T) {
 
 	mockProv := provider.NewMockProvider("discord")
 	route := router.Route{Type: router.RouteToBridge, Command: "list-repos"}
-	b.handleBridgeCommand(mockProv, "any-channel", route)
+	b.handleBridgeCommand(mockProv, "any-channel", sessionUser{}, route)
 
 	msgs := mockProv.GetSentMessages()
 	if len(msgs) != 1 {
@@ -2427,7 +2684,7 @@ func TestBridge_HandleListRepos_MultipleRepos_Sorted(t *testing.T) {
 
 	mockProv := provider.NewMockProvider("discord")
 	route := router.Route{Type: router.RouteToBridge, Command: "list-repos"}
-	b.handleBridgeCommand(mockProv, "any-channel", route)
+	b.handleBridgeCommand(mockProv, "any-channel", sessionUser{}, route)
 
 	msgs := mockProv.GetSentMessages()
 	if len(msgs) != 1 {
@@ -2461,7 +2718,7 @@ func TestBridge_HandleListRepos_WithActiveSession(t *testing.T) {
 
 	mockProv := provider.NewMockProvider("discord")
 	route := router.Route{Type: router.RouteToBridge, Command: "list-repos"}
-	b.handleBridgeCommand(mockProv, "any-channel", route)
+	b.handleBridgeCommand(mockProv, "any-channel", sessionUser{}, route)
 
 	msgs := mockProv.GetSentMessages()
 	if len(msgs) != 1 {
@@ -2505,7 +2762,7 @@ func TestBridge_HandleListRepos_LongWorkingDir_Truncated(t *testing.T) {
 
 	mockProv := provider.NewMockProvider("discord")
 	route := router.Route{Type: router.RouteToBridge, Command: "list-repos"}
-	b.handleBridgeCommand(mockProv, "any-channel", route)
+	b.handleBridgeCommand(mockProv, "any-channel", sessionUser{}, route)
 
 	msgs := mockProv.GetSentMessages()
 	if len(msgs) != 1 {
@@ -2539,7 +2796,7 @@ func TestBridge_HandleListRepos_WithBranch(t *testing.T) {
 
 	mockProv := provider.NewMockProvider("discord")
 	route := router.Route{Type: router.RouteToBridge, Command: "list-repos"}
-	b.handleBridgeCommand(mockProv, "any-channel", route)
+	b.handleBridgeCommand(mockProv, "any-channel", sessionUser{}, route)
 
 	msgs := mockProv.GetSentMessages()
 	if len(msgs) != 1 {
@@ -2560,7 +2817,7 @@ func TestBridge_HandleListRepos_WorksFromAnyChannel(t *testing.T) {
 	route := router.Route{Type: router.RouteToBridge, Command: "list-repos"}
 
 	// Call from a channel that is not configured for any repo
-	b.handleBridgeCommand(mockProv, "unknown-channel-xyz", route)
+	b.handleBridgeCommand(mockProv, "unknown-channel-xyz", sessionUser{}, route)
 
 	msgs := mockProv.GetSentMessages()
 	if len(msgs) != 1 {
@@ -2579,7 +2836,7 @@ func TestBridge_HandleListRepos_BulletListFormat(t *testing.T) {
 
 	mockProv := provider.NewMockProvider("discord")
 	route := router.Route{Type: router.RouteToBridge, Command: "list-repos"}
-	b.handleBridgeCommand(mockProv, "any-channel", route)
+	b.handleBridgeCommand(mockProv, "any-channel", sessionUser{}, route)
 
 	msgs := mockProv.GetSentMessages()
 	if len(msgs) != 1 {
@@ -2608,7 +2865,7 @@ func TestBridge_HandleBridgeCommand_Help_IncludesListRepos(t *testing.T) {
 
 	mockProv := provider.NewMockProvider("discord")
 	route := router.Route{Type: router.RouteToBridge, Command: "help"}
-	b.handleBridgeCommand(mockProv, "channel-123", route)
+	b.handleBridgeCommand(mockProv, "channel-123", sessionUser{}, route)
 
 	msgs := mockProv.GetSentMessages()
 	if len(msgs) != 1 {
@@ -2970,7 +3227,7 @@ func TestBridge_HandleRemoveRepo_NoArgs(t *testing.T) {
 	cfg := testConfig()
 	b := New(cfg, "")
 
-	result := b.handleRemoveRepo("")
+	result := b.handleRemoveRepo("").response
 	if result != "Usage: /remove-repo <repo-name>" {
 		t.Errorf("expected usage message, got %q", result)
 	}
@@ -2980,7 +3237,7 @@ func TestBridge_HandleRemoveRepo_NotFound(t *testing.T) {
 	cfg := testConfig()
 	b := New(cfg, "")
 
-	result := b.handleRemoveRepo("nonexistent-repo")
+	result := b.handleRemoveRepo("nonexistent-repo").response
 	if !strings.Contains(result, "not found") {
 		t.Errorf("expected 'not found' in response, got %q", result)
 	}
@@ -3020,7 +3277,7 @@ func TestBridge_HandleRemoveRepo_StopsSessionAndRemovesFromMemory(t *testing.T)
 		cancelCtx: func() { cancelled = true },
 	}
 
-	result := b.handleRemoveRepo("test-repo")
+	result := b.handleRemoveRepo("test-repo").response
 	if !strings.Contains(result, "Removed repo") {
 		t.Errorf("expected success message, got %q", result)
 	}
@@ -3077,7 +3334,7 @@ func TestBridge_HandleRemoveRepo_NoActiveSession(t *testing.T) {
 	b := New(cfg, cfgPath)
 
 	// No active session - test-repo exists in config but not in b.repos
-	result := b.handleRemoveRepo("test-repo")
+	result := b.handleRemoveRepo("test-repo").response
 	if !strings.Contains(result, "Removed repo") {
 		t.Errorf("expected success message, got %q", result)
 	}
@@ -3114,7 +3371,7 @@ func TestBridge_HandleRemoveRepo_UpdatesConfigFile(t *testing.T) {
 	cfg := testConfig()
 	b := New(cfg, cfgPath)
 
-	result := b.handleRemoveRepo("test-repo")
+	result := b.handleRemoveRepo("test-repo").response
 	if !strings.Contains(result, "Removed repo") {
 		t.Fatalf("expected success message, got %q", result)
 	}
@@ -3153,7 +3410,7 @@ func TestBridge_HandleBridgeCommand_RemoveRepo(t *testing.T) {
 
 	mockProv := provider.NewMockProvider("discord")
 	route := router.Route{Type: router.RouteToBridge, Command: "remove-repo", Args: "test-repo"}
-	b.handleBridgeCommand(mockProv, "channel-123", route)
+	b.handleBridgeCommand(mockProv, "channel-123", sessionUser{}, route)
 
 	msgs := mockProv.GetSentMessages()
 	if len(msgs) != 1 {
@@ -3181,7 +3438,7 @@ func TestBridge_HandleClone_MissingArgs(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := b.handleClone("discord", tt.args)
+			result := b.handleClone("discord", tt.args).response
 			if result != "Usage: /clone <url> <name> [channel-id]" {
 				t.Errorf("expected usage message, got %q", result)
 			}
@@ -3194,12 +3451,12 @@ func TestBridge_HandleClone_PathTraversal(t *testing.T) {
 	b := New(cfg, "")
 
 	// Names with special characters are rejected by IsSafeRepoName
-	result := b.handleClone("discord", "https://github.com/example/repo ../../../etc/passwd 123")
+	result := b.handleClone("discord", "https://github.com/example/repo ../../../etc/passwd 123").response
 	if !strings.Contains(result, "must contain only letters, numbers, hyphens, and underscores") {
 		t.Errorf("expected safe name error, got %q", result)
 	}
 
-	result = b.handleClone("terminal", "https://github.com/example/repo foo/../bar")
+	result = b.handleClone("terminal", "https://github.com/example/repo foo/../bar").response
 	if !strings.Contains(result, "must contain only letters, numbers, hyphens, and underscores") {
 		t.Errorf("expected safe name error, got %q", result)
 	}
@@ -3214,7 +3471,7 @@ func TestBridge_HandleClone_DiscordRequiresChannelID(t *testing.T) {
 		return nil
 	}
 
-	result := b.handleClone("discord", "https://github.com/example/repo myrepo")
+	result := b.handleClone("discord", "https://github.com/example/repo myrepo").response
 	if !strings.Contains(result, "channel-id is required for Discord") {
 		t.Errorf("expected channel-id required error, got %q", result)
 	}
@@ -3239,7 +3496,7 @@ func TestBridge_HandleClone_TerminalDefaultsChannelID(t *testing.T) {
 		return nil
 	}
 
-	result := b.handleClone("terminal", "https://github.com/example/repo myrepo")
+	result := b.handleClone("terminal", "https://github.com/example/repo myrepo").response
 	if !cloneCalled {
 		t.Error("cloneRepo mock was not called")
 	}
@@ -3259,7 +3516,7 @@ func TestBridge_HandleClone_CloneError(t *testing.T) {
 		return fmt.Errorf("git clone failed")
 	}
 
-	result := b.handleClone("discord", "https://github.com/example/repo myrepo channel-999")
+	result := b.handleClone("discord", "https://github.com/example/repo myrepo channel-999").response
 	// Error message is sanitized to avoid exposing paths
 	if !strings.Contains(result, "Clone failed") {
 		t.Errorf("expected clone failed message, got %q", result)
@@ -3283,7 +3540,7 @@ func TestBridge_HandleClone_Success_Discord(t *testing.T) {
 		return nil
 	}
 
-	result := b.handleClone("discord", "https://github.com/example/repo myrepo channel-999")
+	result := b.handleClone("discord", "https://github.com/example/repo myrepo channel-999").response
 
 	// Verify clone was called with correct args
 	if clonedURL != "https://github.com/example/repo" {
@@ -3334,7 +3591,7 @@ func TestBridge_HandleClone_Success_Terminal(t *testing.T) {
 		return nil
 	}
 
-	result := b.handleClone("terminal", "https://github.com/example/repo myrepo my-channel")
+	result := b.handleClone("terminal", "https://github.com/example/repo myrepo my-channel").response
 
 	if !strings.Contains(result, "Cloned and registered repo") {
 		t.Errorf("expected success message, got %q", result)
@@ -3366,7 +3623,7 @@ func TestBridge_HandleClone_AbsolutePathRejected(t *testing.T) {
 	b := New(cfg, cfgPath)
 
 	// Absolute paths are now rejected - names must be safe alphanumeric
-	result := b.handleClone("terminal", "https://github.com/example/repo "+targetDir)
+	result := b.handleClone("terminal", "https://github.com/example/repo "+targetDir).response
 
 	if !strings.Contains(result, "must contain only letters, numbers, hyphens, and underscores") {
 		t.Errorf("expected safe name error, got %q", result)
@@ -3393,7 +3650,7 @@ func TestBridge_HandleClone_CustomBaseDir(t *testing.T) {
 		return nil
 	}
 
-	result := b.handleClone("terminal", "https://github.com/example/repo myrepo")
+	result := b.handleClone("terminal", "https://github.com/example/repo myrepo").response
 
 	expectedDir := filepath.Join(baseDir, "myrepo")
 	if clonedDir != expectedDir {
@@ -3416,7 +3673,7 @@ func TestBridge_HandleClone_RuntimeAddRepoError(t *testing.T) {
 		return nil
 	}
 
-	result := b.handleClone("terminal", "https://github.com/example/repo myrepo")
+	result := b.handleClone("terminal", "https://github.com/example/repo myrepo").response
 
 	if !strings.Contains(result, "Cloned but failed to register") {
 		t.Errorf("expected register error message, got %q", result)
@@ -3441,7 +3698,7 @@ func TestBridge_HandleBridgeCommand_Clone(t *testing.T) {
 
 	mockProv := provider.NewMockProvider("discord")
 	route := router.Route{Type: router.RouteToBridge, Command: "clone", Args: "https://github.com/example/repo myrepo channel-999"}
-	b.handleBridgeCommand(mockProv, "channel-123", route)
+	b.handleBridgeCommand(mockProv, "channel-123", sessionUser{}, route)
 
 	if !cloneCalled {
 		t.Error("clone was not called")
@@ -3462,7 +3719,7 @@ func TestBridge_HandleAddWorktree_NoArgs(t *testing.T) {
 	cfg := testConfig()
 	b := New(cfg, "")
 
-	result := b.handleAddWorktree("discord", "channel-123", "")
+	result := b.handleAddWorktree("discord", "channel-123", "").response
 	if result != "Usage: /add-worktree <name> <branch> [channel-id]" {
 		t.Errorf("expected usage message, got %q", result)
 	}
@@ -3472,7 +3729,7 @@ func TestBridge_HandleAddWorktree_OnlyName(t *testing.T) {
 	cfg := testConfig()
 	b := New(cfg, "")
 
-	result := b.handleAddWorktree("discord", "channel-123", "myworktree")
+	result := b.handleAddWorktree("discord", "channel-123", "myworktree").response
 	if result != "Usage: /add-worktree <name> <branch> [channel-id]" {
 		t.Errorf("expected usage message, got %q", result)
 	}
@@ -3482,7 +3739,7 @@ func TestBridge_HandleAddWorktree_NoRepoForChannel(t *testing.T) {
 	cfg := testConfig()
 	b := New(cfg, "")
 
-	result := b.handleAddWorktree("discord", "unknown-channel", "myworktree feature-branch")
+	result := b.handleAddWorktree("discord", "unknown-channel", "myworktree feature-branch").response
 	if !strings.Contains(result, "no repo configured for this channel") {
 		t.Errorf("expected 'no repo configured' error, got %q", result)
 	}
@@ -3493,7 +3750,7 @@ func TestBridge_HandleAddWorktree_PathTraversalDotDot(t *testing.T) {
 	b := New(cfg, "")
 
 	// Names with special characters are rejected by IsSafeRepoName
-	result := b.handleAddWorktree("discord", "channel-123", "../escape feature-branch channel-999")
+	result := b.handleAddWorktree("discord", "channel-123", "../escape feature-branch channel-999").response
 	if !strings.Contains(result, "must contain only letters, numbers, hyphens, and underscores") {
 		t.Errorf("expected safe name error, got %q", result)
 	}
@@ -3504,7 +3761,7 @@ func TestBridge_HandleAddWorktree_PathTraversalSlash(t *testing.T) {
 	b := New(cfg, "")
 
 	// Names with slashes are rejected by IsSafeRepoName
-	result := b.handleAddWorktree("discord", "channel-123", "path/escape feature-branch channel-999")
+	result := b.handleAddWorktree("discord", "channel-123", "path/escape feature-branch channel-999").response
 	if !strings.Contains(result, "must contain only letters, numbers, hyphens, and underscores") {
 		t.Errorf("expected safe name error, got %q", result)
 	}
@@ -3514,7 +3771,7 @@ func TestBridge_HandleAddWorktree_DiscordNoChannelID(t *testing.T) {
 	cfg := testConfig()
 	b := New(cfg, "")
 
-	result := b.handleAddWorktree("discord", "channel-123", "myworktree feature-branch")
+	result := b.handleAddWorktree("discord", "channel-123", "myworktree feature-branch").response
 	if !strings.Contains(result, "channel-id is required for Discord") {
 		t.Errorf("expected channel-id required error, got %q", result)
 	}
@@ -3547,7 +3804,7 @@ func TestBridge_HandleAddWorktree_TerminalAutoChannelID(t *testing.T) {
 		return nil
 	}
 
-	result := b.handleAddWorktree("terminal", "terminal", "myworktree feature-branch")
+	result := b.handleAddWorktree("terminal", "terminal", "myworktree feature-branch").response
 	if !addWorktreeCalled {
 		t.Fatal("addWorktree was not called")
 	}
@@ -3591,7 +3848,7 @@ func TestBridge_HandleAddWorktree_Success(t *testing.T) {
 		return nil
 	}
 
-	result := b.handleAddWorktree("discord", "channel-123", "myworktree feature-branch channel-999")
+	result := b.handleAddWorktree("discord", "channel-123", "myworktree feature-branch channel-999").response
 	if !addWorktreeCalled {
 		t.Fatal("addWorktree was not called")
 	}
@@ -3638,7 +3895,7 @@ func TestBridge_HandleAddWorktree_WorktreeError(t *testing.T) {
 		return fmt.Errorf("git error: branch already exists")
 	}
 
-	result := b.handleAddWorktree("discord", "channel-123", "myworktree feature-branch channel-999")
+	result := b.handleAddWorktree("discord", "channel-123", "myworktree feature-branch channel-999").response
 	// Error message is sanitized to avoid exposing paths
 	if !strings.Contains(result, "Failed to create worktree") {
 		t.Errorf("expected worktree error message, got %q", result)
@@ -3672,7 +3929,7 @@ func TestBridge_HandleAddWorktree_UsesGitRootFromWorktreeRepo(t *testing.T) {
 		return nil
 	}
 
-	result := b.handleAddWorktree("discord", "channel-123", "feature2 feature2-branch channel-456")
+	result := b.handleAddWorktree("discord", "channel-123", "feature2 feature2-branch channel-456").response
 	if capturedGitRoot != "/repos/myproject" {
 		t.Errorf("should use GitRoot from parent, got git root = %q, want %q", capturedGitRoot, "/repos/myproject")
 	}
@@ -3715,7 +3972,7 @@ func TestBridge_HandleAddWorktree_PersistError(t *testing.T) {
 		return nil
 	}
 
-	result := b.handleAddWorktree("discord", "channel-123", "myworktree feature-branch channel-999")
+	result := b.handleAddWorktree("discord", "channel-123", "myworktree feature-branch channel-999").response
 	if !strings.Contains(result, "Worktree created but failed to register") {
 		t.Errorf("expected persist error message, got %q", result)
 	}
@@ -3744,7 +4001,7 @@ func TestBridge_HandleBridgeCommand_AddWorktree(t *testing.T) {
 
 	mockProv := provider.NewMockProvider("discord")
 	route := router.Route{Type: router.RouteToBridge, Command: "add-worktree", Args: "myworktree feature-branch channel-999"}
-	b.handleBridgeCommand(mockProv, "channel-123", route)
+	b.handleBridgeCommand(mockProv, "channel-123", sessionUser{}, route)
 
 	msgs := mockProv.GetSentMessages()
 	if len(msgs) != 1 {
@@ -3754,3 +4011,128 @@ func TestBridge_HandleBridgeCommand_AddWorktree(t *testing.T) {
 		t.Errorf("expected success message, got %q", msgs[0].Content)
 	}
 }
+
+func TestBridge_HandleLLMMessage_ReplyMode(t *testing.T) {
+	cfg := testConfig()
+	cfg.Defaults.OutputMode = config.OutputModeReply
+	b := New(cfg, "")
+
+	mockLLM := newMockLLM("claude")
+	mockLLM.setRunning(true)
+	mockProv := provider.NewMockProvider("discord")
+	caps := provider.DefaultCapabilities()
+	caps.Replies = true
+	mockProv.SetCapabilities(caps)
+	other := provider.NewMockProvider("terminal")
+	session := &repoSession{
+		name:     "test-repo",
+		llm:      mockLLM,
+		channels: []channelRef{{provider: mockProv, channelID: "channel-123"}, {provider: other, channelID: "term"}},
+		merger:   NewMerger(2 * time.Second),
+	}
+	b.repos["test-repo"] = session
+
+	msg := provider.Message{ID: "msg-1", ChannelID: "channel-123", Content: "hello", Source: "discord"}
+	b.handleLLMMessage(context.Background(), mockProv, msg, router.Parse(msg.Content))
+
+	b.broadcastOutput(session, "first")
+	b.broadcastOutput(session, "second")
+
+	msgs := mockProv.GetSentMessages()
+	if len(msgs) != 2 {
+		t.Fatalf("expected 2 messages, got %+v", msgs)
+	}
+	if msgs[0].ReplyToID != "msg-1" {
+		t.Errorf("first output should reply to the prompt, got %+v", msgs[0])
+	}
+	if msgs[1].ReplyToID != "" {
+		t.Errorf("later output should not be a reply, got %+v", msgs[1])
+	}
+
+	for _, m := range other.GetSentMessages() {
+		if m.ReplyToID != "" {
+			t.Errorf("other channels should get plain output, got %+v", m)
+		}
+	}
+	if len(other.GetSentMessages()) != 2 {
+		t.Errorf("other channels should still receive output")
+	}
+}
+
+func TestBridge_HandleLLMMessage_ReplyModeThread(t *testing.T) {
+	cfg := testConfig()
+	cfg.Defaults.OutputMode = config.OutputModeReply
+	b := New(cfg, "")
+
+	mockLLM := newMockLLM("claude")
+	mockLLM.setRunning(true)
+	mockProv := provider.NewMockProvider("discord")
+	mockProv.SetCapabilities(provider.Capabilities{MaxMessageLength: 2000, Replies: true})
+	session := &repoSession{
+		name:     "test-repo",
+		llm:      mockLLM,
+		channels: []channelRef{{provider: mockProv, channelID: "channel-123"}},
+		merger:   NewMerger(2 * time.Second),
+	}
+	b.repos["test-repo"] = session
+
+	msg := provider.Message{ID: "msg-1", ChannelID: "channel-123", ThreadID: "thread-9", Content: "hello", Source: "discord"}
+	b.handleLLMMessage(context.Background(), mockProv, msg, router.Parse(msg.Content))
+	b.broadcastOutput(session, "answer")
+
+	msgs := mockProv.GetSentMessages()
+	if len(msgs) != 1 || msgs[0].ChannelID != "thread-9" || msgs[0].ReplyToID != "msg-1" {
+		t.Errorf("expected reply in the prompt's thread, got %+v", msgs)
+	}
+}
+
+func TestBridge_BroadcastOutput_DefaultModeDoesNotReply(t *testing.T) {
+	b := New(testConfig(), "")
+
+	mockLLM := newMockLLM("claude")
+	mockLLM.setRunning(true)
+	mockProv := provider.NewMockProvider("discord")
+	mockProv.SetCapabilities(provider.Capabilities{MaxMessageLength: 2000, Replies: true})
+	session := &repoSession{
+		name:     "test-repo",
+		llm:      mockLLM,
+		channels: []channelRef{{provider: mockProv, channelID: "channel-123"}},
+		merger:   NewMerger(2 * time.Second),
+	}
+	b.repos["test-repo"] = session
+
+	msg := provider.Message{ID: "msg-1", ChannelID: "channel-123", Content: "hello", Source: "discord"}
+	b.handleLLMMessage(context.Background(), mockProv, msg, router.Parse(msg.Content))
+	b.broadcastOutput(session, "answer")
+
+	msgs := mockProv.GetSentMessages()
+	if len(msgs) != 1 || msgs[0].ReplyToID != "" {
+		t.Errorf("broadcast mode should not reply, got %+v", msgs)
+	}
+}
+
+func TestBridge_Start_GitHub(t *testing.T) {
+	cfg := testConfig()
+	cfg.Providers.GitHub = config.GitHubConfig{Token: "tok", WebhookSecret: "s", Mention: "@bot"}
+
+	b := New(cfg, "")
+
+	mockGitHub := provider.NewMockProvider("github")
+	var got config.GitHubConfig
+	b.githubFactory = func(gc config.GitHubConfig) provider.Provider {
+		got = gc
+		return mockGitHub
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	_ = b.Start(ctx)
+
+	if got.GetMention() != "@bot" {
+		t.Errorf("github factory config = %+v", got)
+	}
+	if !mockGitHub.WasStartCalled() || !mockGitHub.WasStopCalled() {
+		t.Error("github provider should be started and stopped with the bridge")
+	}
+}
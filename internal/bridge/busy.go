@@ -0,0 +1,159 @@
+package bridge
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/anthropics/llm-bridge/internal/provider"
+)
+
+const (
+	// defaultBusyQuietPeriod is how long output must stay silent after a
+	// prompt before the LLM's turn is considered finished.
+	defaultBusyQuietPeriod = 5 * time.Second
+
+	// defaultTypingInterval refreshes typing indicators before they expire
+	// (Discord's indicator lasts about 10 seconds).
+	defaultTypingInterval = 8 * time.Second
+)
+
+// busyState tracks whether the LLM is working on a prompt: input was sent and
+// output is still flowing or has not yet started. The zero value is idle.
+type busyState struct {
+	mu         sync.Mutex
+	busy       bool
+	since      time.Time
+	author     string
+	lastOutput time.Time
+	promptAt   time.Time // when the prompt awaiting its first output was sent
+}
+
+// start marks the session busy on behalf of author. Returns true if the
+// session was idle before, i.e. a new busy period began.
+func (s *busyState) start(author string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.lastOutput = now
+	if s.promptAt.IsZero() {
+		s.promptAt = now
+	}
+	if s.busy {
+		return false
+	}
+	s.busy = true
+	s.since = now
+	s.author = author
+	return true
+}
+
+// touch records that output arrived, extending the busy period. If this is
+// the first output since a prompt was sent, it returns how long it took.
+func (s *busyState) touch() (time.Duration, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastOutput = time.Now()
+	if s.promptAt.IsZero() {
+		return 0, false
+	}
+	wait := s.lastOutput.Sub(s.promptAt)
+	s.promptAt = time.Time{}
+	return wait, true
+}
+
+// resume marks the session busy again without starting a new prompt, e.g.
+// when a turn continues after a permission prompt is answered. Returns true
+// if the session was idle.
+func (s *busyState) resume() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastOutput = time.Now()
+	if s.busy {
+		return false
+	}
+	s.busy = true
+	return true
+}
+
+// settle clears the busy state once output has been silent for quiet.
+// Returns true if the session transitioned to idle.
+func (s *busyState) settle(quiet time.Duration) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.busy || time.Since(s.lastOutput) < quiet {
+		return false
+	}
+	s.busy = false
+	return true
+}
+
+// clear forces the session idle (e.g. when output ends).
+func (s *busyState) clear() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.busy = false
+	s.promptAt = time.Time{}
+}
+
+// snapshot returns the current busy state.
+func (s *busyState) snapshot() (busy bool, since time.Time, author string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.busy, s.since, s.author
+}
+
+// describe renders the busy state for /status, or "" when idle.
+func (s *busyState) describe() string {
+	busy, since, author := s.snapshot()
+	if !busy {
+		return ""
+	}
+	if author == "" {
+		return fmt.Sprintf("busy for %v", time.Since(since).Round(time.Second))
+	}
+	return fmt.Sprintf("busy for %v since %s's prompt", time.Since(since).Round(time.Second), author)
+}
+
+// markBusy records that author's prompt was sent to the LLM and, if the
+// session was idle, starts driving typing indicators on its channels.
+func (b *Bridge) markBusy(session *repoSession, author string) {
+	if session.busy.start(author) {
+		go b.typingLoop(session)
+	}
+}
+
+// typingLoop shows typing indicators on every channel whose provider declares
+// the typing capability, refreshing until the session is no longer busy.
+func (b *Bridge) typingLoop(session *repoSession) {
+	ticker := time.NewTicker(b.typingInterval)
+	defer ticker.Stop()
+
+	for {
+		if busy, _, _ := session.busy.snapshot(); !busy || !session.llm.Running() {
+			return
+		}
+
+		b.mu.Lock()
+		channels := make([]channelRef, len(session.channels))
+		copy(channels, session.channels)
+		b.mu.Unlock()
+
+		for _, ch := range channels {
+			if !ch.receivesOutput() {
+				continue
+			}
+			if !provider.CapabilitiesOf(ch.provider).Typing {
+				continue
+			}
+			if typer, ok := ch.provider.(provider.Typer); ok {
+				_ = typer.Typing(ch.channelID)
+			}
+		}
+
+		<-ticker.C
+	}
+}
@@ -0,0 +1,229 @@
+package bridge
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anthropics/llm-bridge/internal/provider"
+	"github.com/anthropics/llm-bridge/internal/router"
+)
+
+func TestBusyState_Lifecycle(t *testing.T) {
+	var s busyState
+
+	if busy, _, _ := s.snapshot(); busy {
+		t.Fatal("zero value should be idle")
+	}
+	if s.describe() != "" {
+		t.Error("idle state should describe as empty")
+	}
+
+	if !s.start("alice") {
+		t.Error("first start should begin a busy period")
+	}
+	if s.start("bob") {
+		t.Error("start while busy should not begin a new period")
+	}
+	if _, _, author := s.snapshot(); author != "alice" {
+		t.Errorf("author = %q, want alice (first prompt of the turn)", author)
+	}
+
+	if s.settle(time.Hour) {
+		t.Error("should stay busy within the quiet period")
+	}
+	if !s.settle(0) {
+		t.Error("should settle once the quiet period has passed")
+	}
+	if busy, _, _ := s.snapshot(); busy {
+		t.Error("should be idle after settling")
+	}
+	if s.settle(0) {
+		t.Error("settling an idle state should be a no-op")
+	}
+}
+
+func TestBusyState_TouchExtendsBusy(t *testing.T) {
+	var s busyState
+	s.start("alice")
+	time.Sleep(20 * time.Millisecond)
+	s.touch()
+
+	if s.settle(15 * time.Millisecond) {
+		t.Error("recent output should keep the session busy")
+	}
+}
+
+func TestBusyState_TouchReportsFirstOutput(t *testing.T) {
+	var s busyState
+	if _, first := s.touch(); first {
+		t.Error("output with no prompt sent is not a first output")
+	}
+
+	s.start("alice")
+	time.Sleep(10 * time.Millisecond)
+	wait, first := s.touch()
+	if !first || wait < 10*time.Millisecond {
+		t.Errorf("touch() = %v, %v; want the wait since the prompt", wait, first)
+	}
+	if _, first := s.touch(); first {
+		t.Error("only the first output after a prompt counts")
+	}
+
+	s.start("bob")
+	s.clear()
+	if _, first := s.touch(); first {
+		t.Error("clear should forget the prompt awaiting output")
+	}
+}
+
+func TestBusyState_Describe(t *testing.T) {
+	var s busyState
+	s.start("alice")
+	s.mu.Lock()
+	s.since = time.Now().Add(-(2*time.Minute + 13*time.Second))
+	s.mu.Unlock()
+
+	if got := s.describe(); got != "busy for 2m13s since alice's prompt" {
+		t.Errorf("describe() = %q", got)
+	}
+
+	s.clear()
+	s.start("")
+	if got := s.describe(); !strings.HasPrefix(got, "busy for ") || strings.Contains(got, "since") {
+		t.Errorf("describe() without author = %q", got)
+	}
+}
+
+func TestBridge_HandleLLMMessage_MarksBusyAndTypes(t *testing.T) {
+	b := New(testConfig(), "")
+	b.typingInterval = 10 * time.Millisecond
+
+	mockLLM := newMockLLM("claude")
+	mockLLM.setRunning(true)
+	mockProv := provider.NewMockProvider("discord")
+	caps := provider.DefaultCapabilities()
+	caps.Typing = true
+	mockProv.SetCapabilities(caps)
+	// Providers that do not declare typing get no indicators
+	plainProv := provider.NewMockProvider("plain")
+	session := &repoSession{
+		name:     "test-repo",
+		llm:      mockLLM,
+		channels: []channelRef{{provider: mockProv, channelID: "channel-123"}, {provider: plainProv, channelID: "plain-1"}},
+		merger:   NewMerger(2 * time.Second),
+	}
+	b.repos["test-repo"] = session
+
+	msg := provider.Message{ChannelID: "channel-123", Content: "run the tests", Author: "alice", Source: "discord"}
+	b.handleLLMMessage(context.Background(), mockProv, msg, router.Parse(msg.Content))
+
+	if busy, _, author := session.busy.snapshot(); !busy || author != "alice" {
+		t.Fatalf("session busy=%v author=%q, want busy by alice", busy, author)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for len(mockProv.GetTypingChannels()) < 2 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	typing := mockProv.GetTypingChannels()
+	if len(typing) < 2 || typing[0] != "channel-123" {
+		t.Errorf("expected repeated typing indicators on channel-123, got %v", typing)
+	}
+	if got := plainProv.GetTypingChannels(); len(got) != 0 {
+		t.Errorf("provider without typing capability got indicators on %v", got)
+	}
+
+	// Once the turn settles, the typing loop stops
+	session.busy.clear()
+	time.Sleep(30 * time.Millisecond)
+	count := len(mockProv.GetTypingChannels())
+	time.Sleep(30 * time.Millisecond)
+	if len(mockProv.GetTypingChannels()) != count {
+		t.Error("typing indicators should stop once the session is idle")
+	}
+}
+
+func TestBridge_HandleLLMMessage_SendErrorNotBusy(t *testing.T) {
+	b := New(testConfig(), "")
+
+	mockLLM := newMockLLM("claude")
+	mockLLM.setRunning(true)
+	mockLLM.setSendError(io.ErrClosedPipe)
+	mockProv := provider.NewMockProvider("discord")
+	session := &repoSession{
+		name:     "test-repo",
+		llm:      mockLLM,
+		channels: []channelRef{{provider: mockProv, channelID: "channel-123"}},
+		merger:   NewMerger(2 * time.Second),
+	}
+	b.repos["test-repo"] = session
+
+	msg := provider.Message{ChannelID: "channel-123", Content: "hi", Author: "alice", Source: "discord"}
+	b.handleLLMMessage(context.Background(), mockProv, msg, router.Parse(msg.Content))
+
+	if busy, _, _ := session.busy.snapshot(); busy {
+		t.Error("failed send should not mark the session busy")
+	}
+}
+
+func TestBridge_ReadOutput_SettlesBusy(t *testing.T) {
+	b := New(testConfig(), "")
+	b.busyQuietPeriod = 0
+
+	mockLLM := newMockLLM("claude")
+	mockLLM.setRunning(true)
+	pr, pw := io.Pipe()
+	mockLLM.SetOutput(pr)
+	mockProv := provider.NewMockProvider("discord")
+	session := &repoSession{
+		name:     "test-repo",
+		llm:      mockLLM,
+		channels: []channelRef{{provider: mockProv, channelID: "channel-123"}},
+		merger:   NewMerger(2 * time.Second),
+	}
+	session.busy.start("alice")
+
+	done := make(chan struct{})
+	go func() {
+		b.readOutput(session, "test-repo")
+		close(done)
+	}()
+
+	_, _ = pw.Write([]byte("working...\n"))
+
+	// The 500ms flush ticker settles the busy state once output is quiet
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if busy, _, _ := session.busy.snapshot(); !busy {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if busy, _, _ := session.busy.snapshot(); busy {
+		t.Error("session should settle to idle after output goes quiet")
+	}
+
+	_ = pw.Close()
+	<-done
+}
+
+func TestBridge_GetStatus_Busy(t *testing.T) {
+	b := New(testConfig(), "")
+
+	mockLLM := newMockLLM("claude")
+	mockLLM.setRunning(true)
+	session := &repoSession{name: "test-repo", llm: mockLLM, merger: NewMerger(2 * time.Second)}
+	session.busy.start("alice")
+	session.busy.mu.Lock()
+	session.busy.since = time.Now().Add(-(2*time.Minute + 13*time.Second))
+	session.busy.mu.Unlock()
+	b.repos["test-repo"] = session
+
+	status := b.getStatus("channel-123", sessionUser{}).response
+	if !strings.HasSuffix(status, " - busy for 2m13s since alice's prompt") {
+		t.Errorf("status = %q, want busy suffix", status)
+	}
+}
@@ -0,0 +1,136 @@
+package bridge
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/anthropics/llm-bridge/internal/audit"
+	"github.com/anthropics/llm-bridge/internal/config"
+	"github.com/anthropics/llm-bridge/internal/provider"
+	"github.com/anthropics/llm-bridge/internal/router"
+)
+
+// receivesOutput reports whether LLM output and typing indicators go to the
+// channel. Notify-only channels get session notices only.
+func (c channelRef) receivesOutput() bool {
+	return c.mode != config.ChannelModeNotifyOnly
+}
+
+// addChannelToSession attaches (prov, channelID) to a session so it
+// receives its output. A repo's configured channels are attached together:
+// attaching any of them attaches all whose provider is running, each with
+// its mode. Channels the repo does not configure, such as DMs, are attached
+// alone. Returns the notify-only channels newly attached. Callers must hold
+// b.mu.
+func (b *Bridge) addChannelToSession(session *repoSession, prov provider.Provider, channelID string) []channelRef {
+	repo := b.cfg.Repos[session.name]
+	mode, configured := "", false
+	for _, ch := range repo.AllChannels() {
+		if ch.Provider == prov.Name() && ch.ChannelID == channelID {
+			mode, configured = ch.GetMode(), true
+			break
+		}
+	}
+
+	var notifyOnly []channelRef
+	add := func(ref channelRef) {
+		if b.addChannelLocked(session, ref) && !ref.receivesOutput() {
+			notifyOnly = append(notifyOnly, ref)
+		}
+	}
+	add(channelRef{provider: prov, channelID: channelID, mode: mode})
+	if !configured {
+		return notifyOnly
+	}
+	for _, ch := range repo.AllChannels() {
+		chProv, running := b.providers[ch.Provider]
+		if !running {
+			slog.Debug("not attaching channel: provider not running", "repo", session.name, "provider", ch.Provider, "channel", ch.ChannelID)
+			continue
+		}
+		add(channelRef{provider: chProv, channelID: ch.ChannelID, mode: ch.GetMode()})
+	}
+	return notifyOnly
+}
+
+// addChannelLocked adds ref to a session unless it is already attached.
+// Returns whether it was added. Callers must hold b.mu.
+func (b *Bridge) addChannelLocked(session *repoSession, ref channelRef) bool {
+	for _, ch := range session.channels {
+		if ch.provider.Name() == ref.provider.Name() && ch.channelID == ref.channelID {
+			return false
+		}
+	}
+	session.channels = append(session.channels, ref)
+	b.markStateChanged()
+	return true
+}
+
+// channelMode returns the mode of a repo's configured channel, or read-write
+// for channels no repo configures, such as DMs.
+func (b *Bridge) channelMode(channelID string) string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, repo := range b.cfg.Repos {
+		if mode, ok := repo.ChannelMode(channelID); ok {
+			return mode
+		}
+	}
+	return config.ChannelModeReadWrite
+}
+
+// refuseReadOnlyPrompt tells the author of a prompt in a read-only or
+// notify-only channel that it was not sent. Returns true if refused.
+func (b *Bridge) refuseReadOnlyPrompt(prov provider.Provider, msg provider.Message, repoName, prompt string) bool {
+	mode := b.channelMode(msg.ChannelID)
+	if mode == config.ChannelModeReadWrite {
+		return false
+	}
+	slog.Info("prompt refused in channel", "mode", mode, "repo", repoName, "user", msg.Author, "channel", msg.ChannelID, "provider", prov.Name())
+	b.auditPrompt(prov, msg, repoName, prompt, audit.OutcomeDenied, mode+" channel")
+	b.reply(prov, msg.ChannelID, fmt.Sprintf("This channel is %s for %s; send prompts in one of its read-write channels.", mode, repoName))
+	return true
+}
+
+// readOnlyCommands are the bridge commands that only report on a session,
+// and so may be run in read-only and notify-only channels.
+var readOnlyCommands = map[string]bool{
+	"help":    true,
+	"status":  true,
+	"queue":   true, // listing only; see refuseReadOnlyCommand
+	"history": true,
+}
+
+// refuseReadOnlyCommand tells the author of a command that would change a
+// session from a read-only or notify-only channel that it was not run.
+// Returns true if refused.
+func (b *Bridge) refuseReadOnlyCommand(prov provider.Provider, channelID string, author sessionUser, route router.Route) bool {
+	mode := b.channelMode(channelID)
+	if mode == config.ChannelModeReadWrite {
+		return false
+	}
+	if readOnlyCommands[route.Command] && (route.Command != "queue" || strings.TrimSpace(route.Args) == "") {
+		return false
+	}
+	repoName := b.repoForChannel(channelID)
+	slog.Info("command refused in channel", "mode", mode, "command", route.Command, "repo", repoName, "user", author.name, "channel", channelID, "provider", prov.Name())
+	b.auditRefused(prov, channelID, author.name, author.id, route, repoName, audit.OutcomeDenied, mode+" channel")
+	b.reply(prov, channelID, fmt.Sprintf("This channel is %s for %s; /%s can only be used in one of its read-write channels.", mode, repoName, route.Command))
+	return true
+}
+
+// subscribeRepoLocked asks providers to deliver messages from all of a
+// repo's channels. Callers must hold b.mu.
+func (b *Bridge) subscribeRepoLocked(repo config.RepoConfig) {
+	for _, ch := range repo.AllChannels() {
+		b.subscribeChannel(ch.Provider, ch.ChannelID)
+	}
+}
+
+// unsubscribeRepoLocked undoes subscribeRepoLocked. Callers must hold b.mu.
+func (b *Bridge) unsubscribeRepoLocked(repo config.RepoConfig) {
+	for _, ch := range repo.AllChannels() {
+		b.unsubscribeChannel(ch.Provider, ch.ChannelID)
+	}
+}
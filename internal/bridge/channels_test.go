@@ -0,0 +1,222 @@
+package bridge
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anthropics/llm-bridge/internal/config"
+	"github.com/anthropics/llm-bridge/internal/provider"
+)
+
+// mirrorConfig is testConfig with test-repo mirrored to a read-write Slack
+// channel, a read-only Slack channel and a notify-only Discord channel.
+func mirrorConfig() *config.Config {
+	cfg := testConfig()
+	repo := cfg.Repos["test-repo"]
+	repo.Channels = []config.ChannelConfig{
+		{Provider: "slack", ChannelID: "C-team"},
+		{Provider: "slack", ChannelID: "C-watch", Mode: config.ChannelModeReadOnly},
+		{Provider: "discord", ChannelID: "channel-notify", Mode: config.ChannelModeNotifyOnly},
+	}
+	cfg.Repos["test-repo"] = repo
+	return cfg
+}
+
+// mirrorBridge returns a bridge with mirrorConfig and Discord and Slack
+// providers running.
+func mirrorBridge(t *testing.T) (*Bridge, *provider.MockProvider, *provider.MockProvider) {
+	t.Helper()
+	tb := newTestBridge(t, withConfig(mirrorConfig()), withProviders("discord", "slack"))
+	return tb.b, tb.providers["discord"], tb.providers["slack"]
+}
+
+func sessionChannels(b *Bridge, key string) map[string]string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	channels := make(map[string]string)
+	for _, ch := range b.repos[key].channels {
+		channels[ch.provider.Name()+"/"+ch.channelID] = ch.mode
+	}
+	return channels
+}
+
+func TestChannels_AttachedAtSessionStart(t *testing.T) {
+	b, discord, slack := mirrorBridge(t)
+
+	b.processMessage(context.Background(), discord, provider.Message{ChannelID: "channel-123", Content: "hi", Author: "alice", AuthorID: "id-alice", Source: "discord"})
+
+	want := map[string]string{
+		"discord/channel-123":    config.ChannelModeReadWrite,
+		"slack/C-team":           config.ChannelModeReadWrite,
+		"slack/C-watch":          config.ChannelModeReadOnly,
+		"discord/channel-notify": config.ChannelModeNotifyOnly,
+	}
+	got := sessionChannels(b, "test-repo")
+	if len(got) != len(want) {
+		t.Fatalf("session channels = %v, want %v", got, want)
+	}
+	for ch, mode := range want {
+		if got[ch] != mode {
+			t.Errorf("channel %s mode = %q, want %q", ch, got[ch], mode)
+		}
+	}
+
+	// Notify-only channels hear that the session started, but get no output.
+	deadline := time.Now().Add(time.Second)
+	for lastSent(discord, "channel-notify") == "" && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if got := lastSent(discord, "channel-notify"); got != "LLM session started for test-repo" {
+		t.Errorf("notify-only channel got %q", got)
+	}
+
+	b.mu.Lock()
+	session := b.repos["test-repo"]
+	b.mu.Unlock()
+	b.broadcastOutput(session, "answer")
+
+	if got := lastSent(discord, "channel-123"); got != "answer" {
+		t.Errorf("primary channel got %q", got)
+	}
+	for _, ch := range []string{"C-team", "C-watch"} {
+		if got := lastSent(slack, ch); got != "answer" {
+			t.Errorf("slack %s got %q", ch, got)
+		}
+	}
+	if got := lastSent(discord, "channel-notify"); got == "answer" {
+		t.Error("notify-only channel should not get output")
+	}
+}
+
+func TestChannels_MirrorStartsSession(t *testing.T) {
+	b, _, slack := mirrorBridge(t)
+
+	b.processMessage(context.Background(), slack, provider.Message{ChannelID: "C-team", Content: "hi", Author: "bob", AuthorID: "id-bob", Source: "slack"})
+
+	got := sessionChannels(b, "test-repo")
+	if _, ok := got["discord/channel-123"]; !ok || len(got) != 4 {
+		t.Errorf("a prompt from a mirror should attach all the repo's channels, got %v", got)
+	}
+}
+
+func TestChannels_ReadOnlyRefusesPrompts(t *testing.T) {
+	for _, tt := range []struct {
+		prov, channel, mode string
+	}{
+		{"slack", "C-watch", config.ChannelModeReadOnly},
+		{"discord", "channel-notify", config.ChannelModeNotifyOnly},
+	} {
+		t.Run(tt.mode, func(t *testing.T) {
+			b, discord, slack := mirrorBridge(t)
+			prov := map[string]*provider.MockProvider{"discord": discord, "slack": slack}[tt.prov]
+			path := withAuditLog(t, b)
+
+			b.processMessage(context.Background(), prov, provider.Message{ChannelID: tt.channel, Content: "delete everything", Author: "eve", AuthorID: "id-eve", Source: tt.prov})
+
+			b.mu.Lock()
+			_, started := b.repos["test-repo"]
+			b.mu.Unlock()
+			if started {
+				t.Error("a prompt in a " + tt.mode + " channel should not start a session")
+			}
+			want := "This channel is " + tt.mode + " for test-repo; send prompts in one of its read-write channels."
+			if got := lastSent(prov, tt.channel); got != want {
+				t.Errorf("reply = %q, want %q", got, want)
+			}
+			if recs := auditRecords(t, path); len(recs) != 1 || recs[0].Outcome != "denied" {
+				t.Errorf("audit = %+v, want one denied prompt", recs)
+			}
+
+			// Commands that only report still work.
+			b.processMessage(context.Background(), prov, provider.Message{ChannelID: tt.channel, Content: "/status", Author: "eve", AuthorID: "id-eve", Source: tt.prov})
+			if got := lastSent(prov, tt.channel); !strings.Contains(got, "test-repo") {
+				t.Errorf("/status reply = %q", got)
+			}
+			b.processMessage(context.Background(), prov, provider.Message{ChannelID: tt.channel, Content: "/queue", Author: "eve", AuthorID: "id-eve", Source: tt.prov})
+			if got := lastSent(prov, tt.channel); got != "No queued prompts for test-repo" {
+				t.Errorf("/queue reply = %q", got)
+			}
+		})
+	}
+}
+
+func TestChannels_ReadOnlyRefusesCommands(t *testing.T) {
+	tb := newTestBridge(t, withConfig(mirrorConfig()), withProviders("discord", "slack"), withRunningSession())
+	b, slack := tb.b, tb.providers["slack"]
+	path := withAuditLog(t, b)
+
+	for _, cmd := range []string{"/restart", "/cancel", "/queue clear", "/remove-repo test-repo"} {
+		b.processMessage(context.Background(), slack, provider.Message{ChannelID: "C-watch", Content: cmd, Author: "eve", AuthorID: "id-eve", Source: "slack"})
+		name := strings.Fields(cmd)[0]
+		want := "This channel is read-only for test-repo; " + name + " can only be used in one of its read-write channels."
+		if got := lastSent(slack, "C-watch"); got != want {
+			t.Errorf("%s reply = %q, want %q", cmd, got, want)
+		}
+	}
+	if len(tb.started) != 0 {
+		t.Error("/restart in a read-only channel should not restart the session")
+	}
+	if _, ok := b.currentConfig().Repos["test-repo"]; !ok {
+		t.Error("/remove-repo in a read-only channel should not remove the repo")
+	}
+	recs := auditRecords(t, path)
+	if len(recs) != 4 {
+		t.Fatalf("audit = %+v, want four denied commands", recs)
+	}
+	for _, rec := range recs {
+		if rec.Outcome != "denied" || rec.Detail != "read-only channel" {
+			t.Errorf("audit record = %+v, want denied in read-only channel", rec)
+		}
+	}
+}
+
+func TestChannels_NotifyOnlyRefusesReactions(t *testing.T) {
+	tb := newTestBridge(t, withConfig(mirrorConfig()), withProviders("discord", "slack"), withRunningSession())
+	b, discord := tb.b, tb.providers["discord"]
+
+	b.processReaction(discord, provider.Reaction{ChannelID: "channel-notify", MessageID: "m1", Emoji: "🔁", User: "eve", UserID: "id-eve"})
+
+	want := "This channel is notify-only for test-repo; /restart can only be used in one of its read-write channels."
+	if got := lastSent(discord, "channel-notify"); got != want {
+		t.Errorf("reply = %q, want %q", got, want)
+	}
+	if len(tb.started) != 0 {
+		t.Error("a reaction in a notify-only channel should not restart the session")
+	}
+}
+
+func TestChannels_UnconfiguredChannelAttachedAlone(t *testing.T) {
+	b, discord, _ := mirrorBridge(t)
+	b.mu.Lock()
+	b.dmChannels["dm-1"] = "id-alice"
+	b.dmRepos["id-alice"] = "test-repo"
+	b.mu.Unlock()
+
+	b.processMessage(context.Background(), discord, provider.Message{ChannelID: "dm-1", Content: "hi", Author: "alice", AuthorID: "id-alice", Source: "discord", DirectMessage: true})
+
+	if got := sessionChannels(b, "test-repo@id-alice"); len(got) != 1 || got["discord/dm-1"] != "" {
+		t.Errorf("a DM-started session should only attach the DM, got %v", got)
+	}
+}
+
+func TestChannels_Subscriptions(t *testing.T) {
+	b, discord, slack := mirrorBridge(t)
+
+	if ids := b.channelIDsForProvider("slack"); len(ids) != 2 {
+		t.Errorf("slack channel IDs = %v, want the two mirrors", ids)
+	}
+
+	ctx := context.Background()
+	b.applyConfig(ctx, testConfig())
+	b.applyConfig(ctx, mirrorConfig())
+	if !slack.IsSubscribed("C-team") || !slack.IsSubscribed("C-watch") || !discord.IsSubscribed("channel-notify") {
+		t.Error("channels added by a reload should be subscribed")
+	}
+
+	b.applyConfig(ctx, testConfig())
+	if slack.IsSubscribed("C-team") || discord.IsSubscribed("channel-notify") {
+		t.Error("channels removed by a reload should be unsubscribed")
+	}
+}
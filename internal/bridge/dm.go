@@ -0,0 +1,200 @@
+package bridge
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/anthropics/llm-bridge/internal/config"
+	"github.com/anthropics/llm-bridge/internal/provider"
+	"github.com/anthropics/llm-bridge/internal/router"
+)
+
+// processDirectMessage handles a private message to the bot. DMs behave like
+// a personal terminal: each user picks a repo with /select and then chats with
+// their own session of that repo, with output sent to their DM channel.
+func (b *Bridge) processDirectMessage(ctx context.Context, prov provider.Provider, msg provider.Message) {
+	if msg.AuthorID == "" {
+		return
+	}
+
+	b.mu.Lock()
+	if b.dmChannels[msg.ChannelID] != msg.AuthorID {
+		b.dmChannels[msg.ChannelID] = msg.AuthorID
+		b.markStateChanged()
+	}
+	b.mu.Unlock()
+
+	repoName := b.dmRepoForChannel(msg.ChannelID)
+	if repoName != "" && !b.canSelectRepo(prov, msg, repoName) {
+		// Access may have been revoked since the repo was selected.
+		repoName = ""
+	}
+	route := b.parseMessage(msg.Content, repoName)
+	if !b.authorize(prov, msg.ChannelID, msg.Author, messageSubject(prov, msg), route, repoName) {
+		return
+	}
+
+	if route.Type == router.RouteToBridge && route.Command == "select" {
+		result := b.handleDMSelect(prov, msg, strings.TrimSpace(route.Args))
+		b.auditCommand(prov, msg.ChannelID, messageAuthor(msg), route, result)
+		b.reply(prov, msg.ChannelID, result.response)
+		return
+	}
+
+	if repoName == "" {
+		b.reply(prov, msg.ChannelID, "No repo selected. "+b.dmSelectUsage(prov, msg, ""))
+		return
+	}
+	route, ok := b.expandMacro(prov, msg, repoName, route)
+	if !ok {
+		return
+	}
+
+	switch route.Type {
+	case router.RouteToBridge:
+		b.handleBridgeCommand(prov, msg.ChannelID, messageAuthor(msg), route)
+	case router.RouteToLLM:
+		if b.isRateLimited(prov, msg) {
+			return
+		}
+		b.handleLLMMessage(ctx, prov, msg, route)
+	}
+}
+
+// handleDMSelect records a user's repo selection for their DM channel.
+// Switching repos detaches the DM from the previous repo's session output.
+func (b *Bridge) handleDMSelect(prov provider.Provider, msg provider.Message, repoName string) commandResult {
+	b.mu.Lock()
+	current := b.dmRepos[msg.AuthorID]
+	_, known := b.cfg.Repos[repoName]
+	b.mu.Unlock()
+
+	if repoName == "" {
+		return commandFailed(b.dmSelectUsage(prov, msg, current))
+	}
+	// Repos the user may not use are reported like unknown ones, so DMs
+	// don't reveal their names.
+	if !known || !b.canSelectRepo(prov, msg, repoName) {
+		return commandFailed(fmt.Sprintf("Unknown repo: %s", repoName))
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if current != "" && current != repoName {
+		for _, session := range b.repos {
+			if session.name == current {
+				b.removeChannelFromSession(session, prov, msg.ChannelID)
+			}
+		}
+	}
+	b.dmRepos[msg.AuthorID] = repoName
+	b.markStateChanged()
+
+	slog.Info("dm repo selected", "user", msg.Author, "author_id", msg.AuthorID, "repo", repoName)
+	return commandOK(fmt.Sprintf("Selected repo: %s", repoName))
+}
+
+// dmSelectUsage lists the repos a DM user can select. Callers must not hold
+// b.mu.
+func (b *Bridge) dmSelectUsage(prov provider.Provider, msg provider.Message, current string) string {
+	b.mu.Lock()
+	names := sortedKeys(b.cfg.Repos)
+	b.mu.Unlock()
+
+	repos := []string{}
+	for _, name := range names {
+		if b.canSelectRepo(prov, msg, name) {
+			repos = append(repos, name)
+		}
+	}
+	usage := fmt.Sprintf("Usage: /select <repo-name>\nAvailable repos: %v", repos)
+	if current != "" {
+		usage += fmt.Sprintf("\nCurrently selected: %s", current)
+	}
+	return usage
+}
+
+// canSelectRepo reports whether a DM user may use a repo. With authz roles
+// configured, that is whether they may prompt in it; otherwise they must be
+// able to read one of its read-write channels on the DM's provider, so DMs
+// never reach repos the user could not use in a channel. Callers must not
+// hold b.mu: checking access may call the provider's API.
+func (b *Bridge) canSelectRepo(prov provider.Provider, msg provider.Message, repoName string) bool {
+	b.mu.Lock()
+	policy := b.authz
+	repo, ok := b.cfg.Repos[repoName]
+	b.mu.Unlock()
+	if !ok {
+		return false
+	}
+	if policy != nil {
+		return policy.CanPrompt(messageSubject(prov, msg), repoName) == nil
+	}
+
+	checker, ok := prov.(provider.AccessChecker)
+	if !ok {
+		return false
+	}
+	for _, ch := range repo.AllChannels() {
+		if ch.Provider != prov.Name() || ch.GetMode() != config.ChannelModeReadWrite {
+			continue
+		}
+		visible, err := checker.CanView(msg.AuthorID, ch.ChannelID)
+		if err != nil {
+			slog.Warn("check channel access failed", "error", err, "user", msg.Author, "channel", ch.ChannelID, "provider", prov.Name())
+			continue
+		}
+		if visible {
+			return true
+		}
+	}
+	return false
+}
+
+// isDMChannel reports whether channelID is a known DM channel.
+func (b *Bridge) isDMChannel(channelID string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	_, ok := b.dmChannels[channelID]
+	return ok
+}
+
+// dmRepoForChannel returns the repo selected by the owner of a DM channel,
+// or "" if the channel is not a known DM or nothing valid is selected.
+func (b *Bridge) dmRepoForChannel(channelID string) string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.dmRepoForChannelLocked(channelID)
+}
+
+// dmRepoForChannelLocked is dmRepoForChannel for callers holding b.mu.
+func (b *Bridge) dmRepoForChannelLocked(channelID string) string {
+	authorID, ok := b.dmChannels[channelID]
+	if !ok {
+		return ""
+	}
+	name := b.dmRepos[authorID]
+	if _, ok := b.cfg.Repos[name]; !ok {
+		return ""
+	}
+	return name
+}
+
+// removeChannelFromSession detaches a channel from a session's output.
+// Callers must hold b.mu.
+func (b *Bridge) removeChannelFromSession(session *repoSession, prov provider.Provider, channelID string) {
+	for i, ch := range session.channels {
+		if ch.provider.Name() == prov.Name() && ch.channelID == channelID {
+			session.channels = append(session.channels[:i], session.channels[i+1:]...)
+			b.markStateChanged()
+			return
+		}
+	}
+}
+
+// reply sends a response to a channel, logging (not returning) failures.
+func (b *Bridge) reply(prov provider.Provider, channelID, content string) {
+	b.respond(prov, channelID, content, false)
+}
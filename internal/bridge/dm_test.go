@@ -0,0 +1,248 @@
+package bridge
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anthropics/llm-bridge/internal/config"
+	"github.com/anthropics/llm-bridge/internal/llm"
+	"github.com/anthropics/llm-bridge/internal/provider"
+)
+
+func dmMessage(authorID, content string) provider.Message {
+	return provider.Message{
+		ChannelID:     "dm-" + authorID,
+		Content:       content,
+		Author:        "user-" + authorID,
+		AuthorID:      authorID,
+		Source:        "discord",
+		DirectMessage: true,
+	}
+}
+
+func TestBridge_DM_NoSelection(t *testing.T) {
+	b := New(testConfig(), "")
+	mockProv := provider.NewMockProvider("discord")
+
+	b.processMessage(context.Background(), mockProv, dmMessage("u1", "hello"))
+
+	msgs := mockProv.GetSentMessages()
+	if len(msgs) != 1 {
+		t.Fatalf("expected 1 message, got %d", len(msgs))
+	}
+	if msgs[0].ChannelID != "dm-u1" {
+		t.Errorf("reply sent to %q, want dm-u1", msgs[0].ChannelID)
+	}
+	if !strings.Contains(msgs[0].Content, "No repo selected") || !strings.Contains(msgs[0].Content, "[other-repo test-repo]") {
+		t.Errorf("unexpected reply: %q", msgs[0].Content)
+	}
+}
+
+func TestBridge_DM_Select(t *testing.T) {
+	b := New(testConfig(), "")
+	mockProv := provider.NewMockProvider("discord")
+
+	b.processMessage(context.Background(), mockProv, dmMessage("u1", "/select"))
+	b.processMessage(context.Background(), mockProv, dmMessage("u1", "/select nope"))
+	b.processMessage(context.Background(), mockProv, dmMessage("u1", "/select other-repo"))
+	b.processMessage(context.Background(), mockProv, dmMessage("u1", "/select"))
+
+	msgs := mockProv.GetSentMessages()
+	if len(msgs) != 4 {
+		t.Fatalf("expected 4 messages, got %d", len(msgs))
+	}
+	if !strings.HasPrefix(msgs[0].Content, "Usage: /select") {
+		t.Errorf("no-arg select: %q", msgs[0].Content)
+	}
+	if msgs[1].Content != "Unknown repo: nope" {
+		t.Errorf("unknown repo: %q", msgs[1].Content)
+	}
+	if msgs[2].Content != "Selected repo: other-repo" {
+		t.Errorf("valid select: %q", msgs[2].Content)
+	}
+	if !strings.Contains(msgs[3].Content, "Currently selected: other-repo") {
+		t.Errorf("usage should show current selection: %q", msgs[3].Content)
+	}
+
+	if got := b.repoForChannel("dm-u1"); got != "other-repo" {
+		t.Errorf("repoForChannel(dm-u1) = %q, want other-repo", got)
+	}
+}
+
+func TestBridge_DM_SelectionIsPerUser(t *testing.T) {
+	b := New(testConfig(), "")
+	mockProv := provider.NewMockProvider("discord")
+
+	b.processMessage(context.Background(), mockProv, dmMessage("u1", "/select test-repo"))
+	b.processMessage(context.Background(), mockProv, dmMessage("u2", "/select other-repo"))
+
+	if got := b.repoForChannel("dm-u1"); got != "test-repo" {
+		t.Errorf("u1 repo = %q, want test-repo", got)
+	}
+	if got := b.repoForChannel("dm-u2"); got != "other-repo" {
+		t.Errorf("u2 repo = %q, want other-repo", got)
+	}
+}
+
+func TestBridge_DM_PromptMirrorsOutputToDMOnly(t *testing.T) {
+	b := New(testConfig(), "")
+	mockLLM := newMockLLM("claude")
+	b.llmFactory = func(backend, workDir, claudePath string, resume bool) (llm.LLM, error) {
+		return mockLLM, nil
+	}
+	mockProv := provider.NewMockProvider("discord")
+
+	b.processMessage(context.Background(), mockProv, dmMessage("u1", "/select test-repo"))
+	b.processMessage(context.Background(), mockProv, dmMessage("u1", "fix the bug"))
+
+	sent := mockLLM.getSentMessages()
+	if len(sent) != 1 || sent[0].Content != "fix the bug" {
+		t.Fatalf("LLM messages = %v", sent)
+	}
+
+	b.mu.Lock()
+	session := b.repos["test-repo@u1"]
+	_, shared := b.repos["test-repo"]
+	channels := append([]channelRef(nil), session.channels...)
+	b.mu.Unlock()
+
+	if shared {
+		t.Error("a DM prompt should not use the repo's shared session")
+	}
+	if len(channels) != 1 || channels[0].channelID != "dm-u1" {
+		t.Errorf("session channels = %v, want only the DM", channels)
+	}
+}
+
+func TestBridge_DM_SessionsArePerUser(t *testing.T) {
+	b := New(testConfig(), "")
+	b.llmFactory = func(backend, workDir, claudePath string, resume bool) (llm.LLM, error) {
+		return newMockLLM("claude"), nil
+	}
+	mockProv := provider.NewMockProvider("discord")
+
+	for _, id := range []string{"u1", "u2"} {
+		b.processMessage(context.Background(), mockProv, dmMessage(id, "/select test-repo"))
+		b.processMessage(context.Background(), mockProv, dmMessage(id, "hello"))
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, id := range []string{"u1", "u2"} {
+		session, ok := b.repos["test-repo@"+id]
+		if !ok || len(session.channels) != 1 || session.channels[0].channelID != "dm-"+id {
+			t.Errorf("user %s should have a session attached only to their DM", id)
+		}
+	}
+}
+
+func TestBridge_DM_SelectRequiresChannelAccess(t *testing.T) {
+	b := New(testConfig(), "")
+	mockProv := provider.NewMockProvider("discord")
+	mockProv.SetCanView(func(userID, channelID string) bool {
+		return channelID == "channel-456"
+	})
+
+	b.processMessage(context.Background(), mockProv, dmMessage("u1", "/select test-repo"))
+	b.processMessage(context.Background(), mockProv, dmMessage("u1", "/select"))
+
+	msgs := mockProv.GetSentMessages()
+	if len(msgs) != 2 || msgs[0].Content != "Unknown repo: test-repo" {
+		t.Fatalf("selecting a repo whose channel the user cannot see should fail, got %v", msgs)
+	}
+	if !strings.Contains(msgs[1].Content, "Available repos: [other-repo]") {
+		t.Errorf("usage should only list accessible repos, got %q", msgs[1].Content)
+	}
+
+	// Losing access after selecting also loses the selection.
+	b.processMessage(context.Background(), mockProv, dmMessage("u1", "/select other-repo"))
+	mockProv.SetCanView(func(userID, channelID string) bool { return false })
+	b.processMessage(context.Background(), mockProv, dmMessage("u1", "hello"))
+	if got := lastSent(mockProv, "dm-u1"); !strings.HasPrefix(got, "No repo selected.") {
+		t.Errorf("reply after losing access = %q", got)
+	}
+}
+
+func TestBridge_DM_SelectChecksAuthz(t *testing.T) {
+	cfg := testConfig()
+	cfg.Authz = config.AuthzConfig{
+		DefaultRole: "dev",
+		Roles: map[string]config.RoleConfig{
+			"dev": {Commands: []string{"*"}, Repos: []string{"other-repo"}, Prompt: true},
+		},
+	}
+	b := New(cfg, "")
+	mockProv := provider.NewMockProvider("discord")
+
+	b.processMessage(context.Background(), mockProv, dmMessage("u1", "/select"))
+	if got := lastSent(mockProv, "dm-u1"); !strings.Contains(got, "Available repos: [other-repo]") {
+		t.Errorf("usage should only list repos authz allows, got %q", got)
+	}
+}
+
+func TestBridge_DM_BridgeCommandUsesSelection(t *testing.T) {
+	b := New(testConfig(), "")
+	mockProv := provider.NewMockProvider("discord")
+
+	b.processMessage(context.Background(), mockProv, dmMessage("u1", "/select test-repo"))
+	b.processMessage(context.Background(), mockProv, dmMessage("u1", "/status"))
+
+	msgs := mockProv.GetSentMessages()
+	if len(msgs) != 2 || msgs[1].Content != "LLM: not running (repo: test-repo, user: user-u1)" {
+		t.Errorf("unexpected status reply: %v", msgs)
+	}
+}
+
+func TestBridge_DM_ReselectDetachesPreviousSession(t *testing.T) {
+	b := New(testConfig(), "")
+	mockProv := provider.NewMockProvider("discord")
+
+	mockLLM := newMockLLM("claude")
+	mockLLM.setRunning(true)
+	b.repos["test-repo"] = &repoSession{
+		name:   "test-repo",
+		llm:    mockLLM,
+		merger: NewMerger(2 * time.Second),
+		channels: []channelRef{
+			{provider: mockProv, channelID: "channel-123"},
+			{provider: mockProv, channelID: "dm-u1"},
+		},
+	}
+
+	b.processMessage(context.Background(), mockProv, dmMessage("u1", "/select test-repo"))
+	b.processMessage(context.Background(), mockProv, dmMessage("u1", "/select other-repo"))
+
+	b.mu.Lock()
+	channels := append([]channelRef(nil), b.repos["test-repo"].channels...)
+	b.mu.Unlock()
+
+	if len(channels) != 1 || channels[0].channelID != "channel-123" {
+		t.Errorf("DM should be detached from previous session, channels = %v", channels)
+	}
+}
+
+func TestBridge_DM_RemovedRepoSelection(t *testing.T) {
+	b := New(testConfig(), "")
+	mockProv := provider.NewMockProvider("discord")
+
+	b.processMessage(context.Background(), mockProv, dmMessage("u1", "/select test-repo"))
+	delete(b.cfg.Repos, "test-repo")
+
+	if got := b.repoForChannel("dm-u1"); got != "" {
+		t.Errorf("selection of a removed repo should not resolve, got %q", got)
+	}
+}
+
+func TestBridge_DM_NoAuthorIgnored(t *testing.T) {
+	b := New(testConfig(), "")
+	mockProv := provider.NewMockProvider("discord")
+
+	msg := dmMessage("", "hello")
+	b.processMessage(context.Background(), mockProv, msg)
+
+	if msgs := mockProv.GetSentMessages(); len(msgs) != 0 {
+		t.Errorf("DM without author should be ignored, got %v", msgs)
+	}
+}
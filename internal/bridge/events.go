@@ -0,0 +1,99 @@
+package bridge
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/url"
+	"time"
+
+	"github.com/anthropics/llm-bridge/internal/config"
+	"github.com/anthropics/llm-bridge/internal/webhook"
+)
+
+// EventEmitter receives bridge activity events. Defaults to a
+// webhook.Dispatcher for the configured webhooks, or nil if there are none.
+type EventEmitter func(webhook.Event)
+
+// webhookCloseTimeout bounds how long Stop waits for queued events.
+const webhookCloseTimeout = 5 * time.Second
+
+// newWebhookDispatcher returns a dispatcher for the configured webhooks,
+// or nil if none are configured.
+func newWebhookDispatcher(hooks []config.WebhookConfig) *webhook.Dispatcher {
+	if len(hooks) == 0 {
+		return nil
+	}
+	sinks := make([]webhook.Sink, len(hooks))
+	for i, h := range hooks {
+		sinks[i] = webhook.Sink{URL: h.URL, Secret: h.Secret, Events: h.Events}
+	}
+	return webhook.NewDispatcher(sinks)
+}
+
+// emit publishes an event. It never blocks, so it is safe under b.mu.
+func (b *Bridge) emit(eventType, repo string, data map[string]any) {
+	if b.emitEvent == nil {
+		return
+	}
+	b.emitEvent(webhook.Event{Type: eventType, Repo: repo, Data: data})
+}
+
+// closeWebhooks flushes queued events on shutdown.
+func (b *Bridge) closeWebhooks() {
+	if b.webhooks == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), webhookCloseTimeout)
+	defer cancel()
+	if err := b.webhooks.Close(ctx); err != nil {
+		slog.Warn("webhook events not delivered before shutdown", "error", err)
+	}
+}
+
+// outputEnded reports a session whose output stopped although the bridge
+// did not stop it (idle timeout, /restart, /remove-repo and shutdown all
+// unregister the session, close stopCh or cancel its context first), i.e.
+// the LLM exited.
+func (b *Bridge) outputEnded(session *repoSession, repoName string, err error) {
+	select {
+	case <-b.stopCh:
+		return
+	default:
+	}
+	if session.ctx != nil && session.ctx.Err() != nil {
+		// Cancelling the context killed the LLM; that is a shutdown, not a crash.
+		slog.Info("llm stopped with its context", "repo", repoName, "user", session.user.name)
+		return
+	}
+
+	b.mu.Lock()
+	current := b.repos[session.key()] == session
+	if current {
+		b.retryPendingStartsLocked()
+	}
+	b.mu.Unlock()
+	if !current {
+		return
+	}
+
+	data := map[string]any{}
+	if err != nil && err != io.EOF {
+		data["error"] = err.Error()
+	}
+	slog.Warn("llm exited unexpectedly", "repo", repoName)
+	b.markStateChanged()
+	b.metrics.sessionCrashes.Inc(repoName)
+	b.emit(webhook.SessionCrashed, repoName, data)
+}
+
+// redactURL removes credentials from a clone URL before it leaves the bridge.
+// URLs that do not parse (e.g. git@host:path) carry none and are returned as is.
+func redactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.User == nil {
+		return raw
+	}
+	u.User = nil
+	return u.String()
+}
@@ -0,0 +1,84 @@
+package bridge
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// idleCheckInterval is how often sessions are checked for idleness: every
+// minute, or twice per idle warning window if that is shorter, so no
+// warning is missed between checks.
+func idleCheckInterval(warning time.Duration) time.Duration {
+	if warning > 0 && warning/2 < time.Minute {
+		return max(warning/2, time.Millisecond)
+	}
+	return time.Minute
+}
+
+// idleDeadline returns when a session with the given idle timeout stops
+// unless there is more activity or /keepalive extends it. Callers must hold
+// b.mu.
+func idleDeadline(session *repoSession, timeout time.Duration) time.Time {
+	deadline := session.llm.LastActivity().Add(timeout)
+	if session.keepAliveUntil.After(deadline) {
+		return session.keepAliveUntil
+	}
+	return deadline
+}
+
+// idleTimeoutFor returns the idle timeout of a repo's sessions, or 0 if
+// they never time out. Callers must hold b.mu.
+func (b *Bridge) idleTimeoutFor(repoName string) time.Duration {
+	return b.cfg.Repos[repoName].GetIdleTimeout(b.cfg.Defaults.GetIdleTimeoutDuration())
+}
+
+// formatIdleTimeout renders an idle timeout for messages.
+func formatIdleTimeout(timeout time.Duration) string {
+	if timeout <= 0 {
+		return "never"
+	}
+	return timeout.String()
+}
+
+// idleWarning tells a session's channels it is about to stop.
+func idleWarning(label string, left, timeout time.Duration) string {
+	return fmt.Sprintf("LLM session for %s stops in %v unless there is activity (idle timeout %v). Send /keepalive [duration] to keep it running.",
+		label, left.Round(time.Second), timeout)
+}
+
+// handleKeepAlive implements /keepalive [duration]: the session author uses
+// in the channel's repo does not time out for duration, by default its idle
+// timeout, and after that times out as usual.
+func (b *Bridge) handleKeepAlive(channelID string, author sessionUser, args string) commandResult {
+	repoName, key, user := b.sessionKeyForChannel(channelID, author)
+	if repoName == "" {
+		return commandFailed("No repo configured")
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	label := repoLabel(repoName, user)
+	session, ok := b.repos[key]
+	if !ok || session.llm == nil || !session.llm.Running() {
+		return commandFailed(fmt.Sprintf("LLM not running (repo: %s)", label))
+	}
+	timeout := b.idleTimeoutFor(repoName)
+	if timeout <= 0 {
+		return commandFailed(fmt.Sprintf("LLM session for %s has no idle timeout", label))
+	}
+
+	extend := timeout
+	if args = strings.TrimSpace(args); args != "" {
+		d, err := time.ParseDuration(args)
+		if err != nil || d <= 0 {
+			return commandFailed("Usage: /keepalive [duration], e.g. /keepalive 2h")
+		}
+		extend = d
+	}
+	if until := time.Now().Add(extend); until.After(session.keepAliveUntil) {
+		session.keepAliveUntil = until
+	}
+	return commandOK(fmt.Sprintf("LLM session for %s kept alive for %v; it then stops after %v idle", label, extend, timeout))
+}
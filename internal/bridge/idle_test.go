@@ -0,0 +1,179 @@
+package bridge
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func sessionExists(b *Bridge, key string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	_, ok := b.repos[key]
+	return ok
+}
+
+func TestIdle_WarnsBeforeTimeout(t *testing.T) {
+	b, mockLLM, mockProv, _ := queueBridge(t)
+	mockLLM.setLastActivity(time.Now().Add(-9 * time.Minute))
+
+	b.checkIdleTimeouts(10 * time.Minute)
+	b.checkIdleTimeouts(10 * time.Minute)
+
+	if !sessionExists(b, "test-repo") {
+		t.Fatal("session should not stop before its deadline")
+	}
+	msgs := mockProv.GetSentMessages()
+	if len(msgs) != 1 || !strings.HasPrefix(msgs[0].Content, "LLM session for test-repo stops in ") || !strings.Contains(msgs[0].Content, "/keepalive") {
+		t.Fatalf("want one idle warning, got %+v", msgs)
+	}
+
+	// Activity moves the deadline, so the next one is warned about again.
+	mockLLM.setLastActivity(time.Now().Add(-9*time.Minute + time.Second))
+	b.checkIdleTimeouts(10 * time.Minute)
+	if got := len(mockProv.GetSentMessages()); got != 2 {
+		t.Errorf("messages = %d, want a second warning for the new deadline", got)
+	}
+}
+
+func TestIdle_CheckIntervalFitsWarning(t *testing.T) {
+	for _, tt := range []struct {
+		warning, want time.Duration
+	}{
+		{0, time.Minute},
+		{2 * time.Minute, time.Minute},
+		{30 * time.Second, 15 * time.Second},
+	} {
+		if got := idleCheckInterval(tt.warning); got != tt.want {
+			t.Errorf("idleCheckInterval(%v) = %v, want %v", tt.warning, got, tt.want)
+		}
+	}
+}
+
+func TestIdle_WarnsWhenWindowIsShorterThanMinute(t *testing.T) {
+	b, mockLLM, mockProv, _ := queueBridge(t)
+	b.cfg.Defaults.IdleWarning = "200ms"
+	repo := b.cfg.Repos["test-repo"]
+	repo.IdleTimeout = "1s"
+	b.cfg.Repos["test-repo"] = repo
+	// The warning window opens in 100ms and closes 200ms later, long before
+	// a once-a-minute check would run.
+	mockLLM.setLastActivity(time.Now().Add(-700 * time.Millisecond))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go b.idleTimeoutLoop(ctx)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for len(mockProv.GetSentMessages()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	msgs := mockProv.GetSentMessages()
+	if len(msgs) == 0 || !strings.HasPrefix(msgs[0].Content, "LLM session for test-repo stops in ") {
+		t.Errorf("want an idle warning before the timeout, got %+v", msgs)
+	}
+}
+
+func TestIdle_NoWarningWhenDisabled(t *testing.T) {
+	b, mockLLM, mockProv, _ := queueBridge(t)
+	b.cfg.Defaults.IdleWarning = "0"
+	mockLLM.setLastActivity(time.Now().Add(-9 * time.Minute))
+
+	b.checkIdleTimeouts(10 * time.Minute)
+
+	if msgs := mockProv.GetSentMessages(); len(msgs) != 0 {
+		t.Errorf("idle_warning 0 should not warn, got %+v", msgs)
+	}
+}
+
+func TestIdle_BusySessionDoesNotTimeOut(t *testing.T) {
+	b, mockLLM, _, session := queueBridge(t)
+	mockLLM.setLastActivity(time.Now().Add(-time.Hour))
+	session.busy.start("alice")
+
+	b.checkIdleTimeouts(10 * time.Minute)
+
+	if !sessionExists(b, "test-repo") {
+		t.Error("a session working on a prompt should not time out")
+	}
+}
+
+func TestIdle_PerRepoTimeout(t *testing.T) {
+	b, mockLLM, _, _ := queueBridge(t)
+	repo := b.cfg.Repos["test-repo"]
+	repo.IdleTimeout = "never"
+	b.cfg.Repos["test-repo"] = repo
+	mockLLM.setLastActivity(time.Now().Add(-24 * time.Hour))
+
+	b.checkIdleTimeouts(10 * time.Minute)
+	if !sessionExists(b, "test-repo") {
+		t.Fatal(`idle_timeout "never" should keep the session running`)
+	}
+
+	repo.IdleTimeout = "48h"
+	b.cfg.Repos["test-repo"] = repo
+	b.checkIdleTimeouts(10 * time.Minute)
+	if !sessionExists(b, "test-repo") {
+		t.Fatal("the repo's idle_timeout should override the default")
+	}
+
+	repo.IdleTimeout = "1h"
+	b.cfg.Repos["test-repo"] = repo
+	b.checkIdleTimeouts(48 * time.Hour)
+	if sessionExists(b, "test-repo") {
+		t.Error("the repo's idle_timeout should apply even if shorter than the default")
+	}
+}
+
+func TestIdle_KeepAlive(t *testing.T) {
+	b, mockLLM, mockProv, _ := queueBridge(t)
+
+	sendMessage(b, mockProv, "channel-123", "/keepalive 2h")
+	if got := lastSent(mockProv, "channel-123"); got != "LLM session for test-repo kept alive for 2h0m0s; it then stops after 10m0s idle" {
+		t.Errorf("reply = %q", got)
+	}
+
+	mockLLM.setLastActivity(time.Now().Add(-time.Hour))
+	b.checkIdleTimeouts(10 * time.Minute)
+	if !sessionExists(b, "test-repo") {
+		t.Fatal("/keepalive should hold off the idle timeout")
+	}
+	if got := lastSent(mockProv, "channel-123"); strings.Contains(got, "stops in") {
+		t.Errorf("no warning expected until near the keepalive deadline, got %q", got)
+	}
+
+	b.mu.Lock()
+	b.repos["test-repo"].keepAliveUntil = time.Now().Add(-time.Second)
+	b.mu.Unlock()
+	b.checkIdleTimeouts(10 * time.Minute)
+	if sessionExists(b, "test-repo") {
+		t.Error("session should time out once the keepalive has passed")
+	}
+}
+
+func TestIdle_KeepAliveErrors(t *testing.T) {
+	b, mockLLM, _, _ := queueBridge(t)
+
+	for _, tt := range []struct{ args, want string }{
+		{"soon", "Usage: /keepalive [duration], e.g. /keepalive 2h"},
+		{"-1h", "Usage: /keepalive [duration], e.g. /keepalive 2h"},
+	} {
+		if got := b.handleKeepAlive("channel-123", sessionUser{}, tt.args).response; got != tt.want {
+			t.Errorf("/keepalive %s = %q, want %q", tt.args, got, tt.want)
+		}
+	}
+
+	b.cfg.Defaults.IdleTimeout = "never"
+	if got := b.handleKeepAlive("channel-123", sessionUser{}, "").response; got != "LLM session for test-repo has no idle timeout" {
+		t.Errorf("/keepalive with no timeout = %q", got)
+	}
+
+	mockLLM.setRunning(false)
+	if got := b.handleKeepAlive("channel-123", sessionUser{}, "").response; got != "LLM not running (repo: test-repo)" {
+		t.Errorf("/keepalive without a session = %q", got)
+	}
+	if got := b.handleKeepAlive("channel-999", sessionUser{}, "").response; got != "No repo configured" {
+		t.Errorf("/keepalive in an unknown channel = %q", got)
+	}
+}
@@ -0,0 +1,92 @@
+package bridge
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/anthropics/llm-bridge/internal/audit"
+	"github.com/anthropics/llm-bridge/internal/provider"
+	"github.com/anthropics/llm-bridge/internal/router"
+)
+
+// parseMessage routes a message from a channel of repoName, recognising
+// the repo's macros as commands.
+func (b *Bridge) parseMessage(content, repoName string) router.Route {
+	macros := b.currentConfig().MacrosFor(repoName)
+	names := make(map[string]bool, len(macros))
+	for name := range macros {
+		names[name] = true
+	}
+	return router.ParseWithMacros(content, names)
+}
+
+// expandMacro turns a macro route into the prompt it stands for; other
+// routes pass through. It returns false if the macro's arguments did not
+// fit, after showing the author its usage.
+func (b *Bridge) expandMacro(prov provider.Provider, msg provider.Message, repoName string, route router.Route) (router.Route, bool) {
+	if route.Type != router.RouteToMacro {
+		return route, true
+	}
+
+	mc, ok := b.currentConfig().MacrosFor(repoName)[route.Command]
+	if !ok { // removed by a reload since parsing
+		return router.Route{Type: router.RouteToLLM, Raw: route.Raw}, true
+	}
+	m, err := mc.Macro(route.Command)
+	if err == nil {
+		var prompt string
+		if prompt, err = m.Expand(route.Args); err == nil {
+			slog.Info("macro expanded", "macro", route.Command, "repo", repoName, "user", msg.Author)
+			return router.Route{Type: router.RouteToLLM, Command: route.Command, Raw: prompt}, true
+		}
+	}
+
+	b.auditPrompt(prov, msg, repoName, route.Raw, audit.OutcomeFailed, fmt.Sprintf("macro /%s: %v", route.Command, err))
+	if m == nil { // rejected at load time
+		b.reply(prov, msg.ChannelID, fmt.Sprintf("Error: macro /%s: %v", route.Command, err))
+	} else {
+		b.reply(prov, msg.ChannelID, fmt.Sprintf("Error: %v\nUsage: %s", err, m.Usage()))
+	}
+	return route, false
+}
+
+// macroHelp lists the macros available in a repo for /help, or returns ""
+// if there are none.
+func (b *Bridge) macroHelp(repoName string) string {
+	macros := b.currentConfig().MacrosFor(repoName)
+	if len(macros) == 0 {
+		return ""
+	}
+
+	type entry struct{ usage, description string }
+	var entries []entry
+	width := 0
+	for _, name := range sortedKeys(macros) {
+		m, err := macros[name].Macro(name)
+		if err != nil {
+			continue
+		}
+		entries = append(entries, entry{m.Usage(), m.Description})
+		width = max(width, len(m.Usage()))
+	}
+
+	var sb strings.Builder
+	sb.WriteString("\n\nMacros:")
+	for _, e := range entries {
+		if e.description == "" {
+			fmt.Fprintf(&sb, "\n  %s", e.usage)
+			continue
+		}
+		fmt.Fprintf(&sb, "\n  %-*s - %s", width, e.usage, e.description)
+	}
+	return sb.String()
+}
+
+// commandRepo returns the repo a bridge command from a channel applies to.
+func (b *Bridge) commandRepo(prov provider.Provider, channelID string) string {
+	if _, ok := prov.(*provider.Terminal); ok {
+		return b.getTerminalRepo()
+	}
+	return b.repoForChannel(channelID)
+}
@@ -0,0 +1,130 @@
+package bridge
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/anthropics/llm-bridge/internal/audit"
+	"github.com/anthropics/llm-bridge/internal/config"
+	"github.com/anthropics/llm-bridge/internal/provider"
+)
+
+// macroBridge is queueBridge with a top-level /review macro and a /triage
+// macro that test-repo overrides.
+func macroBridge(t *testing.T) (*Bridge, *mockLLM, *provider.MockProvider) {
+	t.Helper()
+	b, mockLLM, mockProv, _ := queueBridge(t)
+	b.cfg.Macros = map[string]config.MacroConfig{
+		"review": {
+			Description: "Review a branch",
+			Args:        []string{"branch", "focus"},
+			Defaults:    map[string]string{"focus": "correctness"},
+			Prompt:      "Review {branch} for {focus}.",
+		},
+		"triage": {Args: []string{"issue"}, Prompt: "Triage {issue}."},
+	}
+	repo := b.cfg.Repos["test-repo"]
+	repo.Macros = map[string]config.MacroConfig{
+		"triage": {Args: []string{"issue"}, Prompt: "Triage {issue} using our runbook."},
+	}
+	b.cfg.Repos["test-repo"] = repo
+	return b, mockLLM, mockProv
+}
+
+func sendMessage(b *Bridge, prov provider.Provider, channelID, content string) {
+	b.processMessage(context.Background(), prov, provider.Message{
+		ChannelID: channelID, Content: content, Author: "alice", AuthorID: "id-alice", Source: "discord",
+	})
+}
+
+func TestMacros_ExpandIntoPrompt(t *testing.T) {
+	b, mockLLM, mockProv := macroBridge(t)
+	path := withAuditLog(t, b)
+
+	sendMessage(b, mockProv, "channel-123", `/review feature-x focus="error handling"`)
+
+	got := mockLLM.getSentMessages()
+	if len(got) != 1 || got[0].Content != "Review feature-x for error handling." {
+		t.Fatalf("LLM got %v", got)
+	}
+	recs := auditRecords(t, path)
+	if len(recs) != 1 || recs[0].Kind != audit.KindPrompt || recs[0].Prompt != "Review feature-x for error handling." {
+		t.Errorf("audit = %+v, want the expanded prompt", recs)
+	}
+}
+
+func TestMacros_RepoOverride(t *testing.T) {
+	b, mockLLM, mockProv := macroBridge(t)
+
+	sendMessage(b, mockProv, "channel-123", "/triage #42")
+
+	got := mockLLM.getSentMessages()
+	if len(got) != 1 || got[0].Content != "Triage #42 using our runbook." {
+		t.Fatalf("LLM got %v, want the repo's override", got)
+	}
+}
+
+func TestMacros_UsageOnMissingArgs(t *testing.T) {
+	b, mockLLM, mockProv := macroBridge(t)
+
+	sendMessage(b, mockProv, "channel-123", "/review")
+
+	if got := mockLLM.getSentMessages(); len(got) != 0 {
+		t.Fatalf("a macro missing arguments should not reach the LLM, got %v", got)
+	}
+	want := "Error: missing <branch>\nUsage: /review <branch> [focus=correctness]"
+	if got := lastSent(mockProv, "channel-123"); got != want {
+		t.Errorf("reply = %q, want %q", got, want)
+	}
+}
+
+func TestMacros_UnknownCommandGoesToLLM(t *testing.T) {
+	b, mockLLM, mockProv := macroBridge(t)
+
+	sendMessage(b, mockProv, "channel-123", "/commit")
+
+	if got := mockLLM.getSentMessages(); len(got) != 1 || got[0].Content != "/commit" {
+		t.Fatalf("LLM got %v, want /commit passed through", got)
+	}
+}
+
+func TestMacros_DeniedWithoutPromptPermission(t *testing.T) {
+	b, mockLLM, mockProv := macroBridge(t)
+	b.cfg.Authz = config.AuthzConfig{Roles: map[string]config.RoleConfig{
+		"viewer": {Users: []string{"*"}, Commands: []string{"status"}},
+	}}
+	b.authz = newPolicy(b.cfg.Authz)
+
+	sendMessage(b, mockProv, "channel-123", "/review main")
+
+	if got := mockLLM.getSentMessages(); len(got) != 0 {
+		t.Fatalf("a viewer's macro should not reach the LLM, got %v", got)
+	}
+	if got := lastSent(mockProv, "channel-123"); !strings.Contains(got, "may not send prompts") {
+		t.Errorf("reply = %q, want a prompt denial", got)
+	}
+}
+
+func TestMacros_Help(t *testing.T) {
+	b, _, mockProv := macroBridge(t)
+
+	sendMessage(b, mockProv, "channel-123", "/help")
+
+	got := lastSent(mockProv, "channel-123")
+	for _, want := range []string{
+		"Macros:",
+		"/review <branch> [focus=correctness] - Review a branch",
+		"/triage <issue>",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("help missing %q:\n%s", want, got)
+		}
+	}
+
+	b.cfg.Macros, b.cfg.Repos["test-repo"] = nil, testConfig().Repos["test-repo"]
+	sendMessage(b, mockProv, "channel-123", "/help")
+	if got := lastSent(mockProv, "channel-123"); strings.Contains(got, "Macros:") {
+		t.Errorf("help without macros should not list any:\n%s", got)
+	}
+}
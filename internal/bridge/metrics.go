@@ -0,0 +1,115 @@
+package bridge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/anthropics/llm-bridge/internal/metrics"
+)
+
+// bridgeMetrics are the bridge's Prometheus metrics. They are always
+// collected; the endpoint serving them is optional.
+type bridgeMetrics struct {
+	registry *metrics.Registry
+
+	sessionStarts  *metrics.Counter   // repo
+	sessionStops   *metrics.Counter   // repo, reason
+	sessionCrashes *metrics.Counter   // repo
+	idleTimeouts   *metrics.Counter   // repo
+	messagesIn     *metrics.Counter   // provider
+	messagesOut    *metrics.Counter   // provider
+	rateLimited    *metrics.Counter   // scope: user or channel
+	sendFailures   *metrics.Counter   // provider
+	outputDropped  *metrics.Counter   // provider
+	broadcastBytes *metrics.Counter   // repo
+	attachments    *metrics.Counter   // provider
+	firstOutput    *metrics.Histogram // repo
+	cloneDuration  *metrics.Histogram
+	cloneFailures  *metrics.Counter
+	activeSessions *metrics.GaugeFunc // repo
+	startsWaiting  *metrics.GaugeFunc
+}
+
+func newBridgeMetrics(b *Bridge) *bridgeMetrics {
+	r := metrics.NewRegistry()
+	return &bridgeMetrics{
+		registry:       r,
+		sessionStarts:  r.Counter("llm_bridge_session_starts_total", "LLM sessions started.", "repo"),
+		sessionStops:   r.Counter("llm_bridge_session_stops_total", "LLM sessions stopped by the bridge, by reason.", "repo", "reason"),
+		sessionCrashes: r.Counter("llm_bridge_session_crashes_total", "LLM processes that exited unexpectedly.", "repo"),
+		idleTimeouts:   r.Counter("llm_bridge_session_idle_timeouts_total", "LLM sessions stopped for being idle.", "repo"),
+		messagesIn:     r.Counter("llm_bridge_messages_received_total", "Messages received from chat providers.", "provider"),
+		messagesOut:    r.Counter("llm_bridge_messages_sent_total", "Messages and files delivered to chat providers.", "provider"),
+		rateLimited:    r.Counter("llm_bridge_rate_limited_total", "Prompts rejected by rate limiting, by user or channel limit.", "scope"),
+		sendFailures:   r.Counter("llm_bridge_send_failures_total", "Failed attempts to deliver output.", "provider"),
+		outputDropped:  r.Counter("llm_bridge_output_dropped_total", "Output messages given up on after failed deliveries.", "provider"),
+		broadcastBytes: r.Counter("llm_bridge_broadcast_bytes_total", "Bytes of LLM output broadcast to channels.", "repo"),
+		attachments:    r.Counter("llm_bridge_attachments_sent_total", "Output delivered as file attachments.", "provider"),
+		firstOutput:    r.Histogram("llm_bridge_first_output_seconds", "Time from sending a prompt to the LLM's first output.", nil, "repo"),
+		cloneDuration:  r.Histogram("llm_bridge_clone_duration_seconds", "Time taken by /clone to clone a repo.", nil),
+		cloneFailures:  r.Counter("llm_bridge_clone_failures_total", "Failed /clone git clones."),
+		activeSessions: r.GaugeFunc("llm_bridge_active_sessions", "Running LLM sessions.", []string{"repo"}, b.collectActiveSessions),
+		startsWaiting:  r.GaugeFunc("llm_bridge_session_starts_waiting", "Session starts waiting for room under max_active_sessions.", nil, b.collectStartsWaiting),
+	}
+}
+
+// collectActiveSessions reports running sessions per repo. Repos without
+// one are reported as zero so the series does not vanish between sessions.
+func (b *Bridge) collectActiveSessions(set func(v float64, values ...string)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for name := range b.cfg.Repos {
+		set(0, name)
+	}
+	for _, session := range b.repos {
+		if session.llm != nil && session.llm.Running() {
+			set(1, session.name)
+		}
+	}
+}
+
+// collectStartsWaiting reports how many session starts are queued.
+func (b *Bridge) collectStartsWaiting(set func(v float64, values ...string)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	set(float64(len(b.pendingStarts)))
+}
+
+// startMetrics serves metrics over HTTP, if configured.
+func (b *Bridge) startMetrics() error {
+	cfg := b.currentConfig().Metrics
+	if !cfg.Enabled() {
+		return nil
+	}
+	ln, err := net.Listen("tcp", cfg.Listen)
+	if err != nil {
+		return fmt.Errorf("start metrics: %w", err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle(cfg.GetPath(), b.metrics.registry.Handler())
+	b.metricsServer = &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		if err := b.metricsServer.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("metrics server failed", "error", err)
+		}
+	}()
+	slog.Info("metrics server started", "listen", ln.Addr().String(), "path", cfg.GetPath())
+	return nil
+}
+
+// closeMetrics stops the metrics server, if running.
+func (b *Bridge) closeMetrics() {
+	if b.metricsServer == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := b.metricsServer.Shutdown(ctx); err != nil {
+		slog.Warn("close metrics server failed", "error", err)
+	}
+}
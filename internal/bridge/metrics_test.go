@@ -0,0 +1,175 @@
+package bridge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anthropics/llm-bridge/internal/adminapi"
+	"github.com/anthropics/llm-bridge/internal/config"
+	"github.com/anthropics/llm-bridge/internal/provider"
+)
+
+// scrape returns the bridge's metrics in the exposition format.
+func scrape(t *testing.T, b *Bridge) string {
+	t.Helper()
+	var sb strings.Builder
+	if _, err := b.metrics.registry.WriteTo(&sb); err != nil {
+		t.Fatal(err)
+	}
+	return sb.String()
+}
+
+func TestMetrics_Sessions(t *testing.T) {
+	b, ctl, _ := adminBridge(t)
+
+	if _, err := ctl.StartSession(adminapi.SessionRequest{Repo: "test-repo"}); err != nil {
+		t.Fatal(err)
+	}
+	out := scrape(t, b)
+	for _, want := range []string{
+		`llm_bridge_session_starts_total{repo="test-repo"} 1`,
+		`llm_bridge_active_sessions{repo="test-repo"} 1`,
+		`llm_bridge_active_sessions{repo="other-repo"} 0`,
+	} {
+		if !strings.Contains(out, want+"\n") {
+			t.Errorf("metrics missing %q:\n%s", want, out)
+		}
+	}
+
+	if err := ctl.StopSession(adminapi.SessionRequest{Repo: "test-repo"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := b.metrics.sessionStops.Value("test-repo", "stop"); got != 1 {
+		t.Errorf("stops = %v, want 1", got)
+	}
+	if out := scrape(t, b); !strings.Contains(out, `llm_bridge_active_sessions{repo="test-repo"} 0`+"\n") {
+		t.Errorf("stopped session still active:\n%s", out)
+	}
+}
+
+func TestMetrics_CrashAndIdle(t *testing.T) {
+	b, mockLLM, _, session := queueBridge(t)
+
+	b.outputEnded(session, "test-repo", io.ErrUnexpectedEOF)
+	if got := b.metrics.sessionCrashes.Value("test-repo"); got != 1 {
+		t.Errorf("crashes = %v, want 1", got)
+	}
+
+	mockLLM.setLastActivity(time.Now().Add(-time.Hour))
+	b.checkIdleTimeouts(time.Minute)
+	if got := b.metrics.idleTimeouts.Value("test-repo"); got != 1 {
+		t.Errorf("idle timeouts = %v, want 1", got)
+	}
+}
+
+func TestMetrics_MessagesAndOutput(t *testing.T) {
+	b, mockLLM, mockProv, session := queueBridge(t)
+	pr, pw := io.Pipe()
+	mockLLM.SetOutput(pr)
+
+	b.processMessage(context.Background(), mockProv, provider.Message{ChannelID: "channel-123", Content: "hello", Author: "alice", AuthorID: "id-alice", Source: "discord"})
+	if got := b.metrics.messagesIn.Value("discord"); got != 1 {
+		t.Errorf("messages in = %v, want 1", got)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		b.readOutput(session, "test-repo")
+		close(done)
+	}()
+	_, _ = pw.Write([]byte("answer\n"))
+	_ = pw.Close()
+	<-done
+
+	if got := b.metrics.firstOutput.Count("test-repo"); got != 1 {
+		t.Errorf("first output observations = %d, want 1", got)
+	}
+	if got := b.metrics.broadcastBytes.Value("test-repo"); got != float64(len("answer\n")) {
+		t.Errorf("broadcast bytes = %v", got)
+	}
+	if got := b.metrics.messagesOut.Value("discord"); got != float64(len(mockProv.GetSentMessages())) || got == 0 {
+		t.Errorf("messages out = %v, provider got %d", got, len(mockProv.GetSentMessages()))
+	}
+}
+
+func TestMetrics_SendFailures(t *testing.T) {
+	b, _, mockProv, _ := queueBridge(t)
+	mockProv.SetSendError(&provider.DeliveryError{Err: errors.New("forbidden"), Permanent: true})
+
+	b.sendOutput(mockProv, "channel-123", "lost")
+	waitForIdle(t, b, mockProv, "channel-123")
+
+	// The output and its drop notice each fail once and are dropped.
+	if got := b.metrics.sendFailures.Value("discord"); got != 2 {
+		t.Errorf("send failures = %v, want 2", got)
+	}
+	if got := b.metrics.outputDropped.Value("discord"); got != 2 {
+		t.Errorf("dropped = %v, want 2", got)
+	}
+}
+
+func TestMetrics_RateLimited(t *testing.T) {
+	b, _, mockProv, _ := queueBridge(t)
+	b.mu.Lock()
+	b.setRateLimits(config.RateLimitConfig{UserRate: 0.001, UserBurst: 1, ChannelRate: 0.001, ChannelBurst: 2})
+	b.mu.Unlock()
+
+	ctx := context.Background()
+	for _, author := range []string{"alice", "alice", "bob", "carol"} {
+		b.processMessage(ctx, mockProv, provider.Message{ChannelID: "channel-123", Content: "go", Author: author, AuthorID: "id-" + author, Source: "discord"})
+	}
+	if got := b.metrics.rateLimited.Value("user"); got != 1 {
+		t.Errorf("user rejections = %v, want 1", got)
+	}
+	if got := b.metrics.rateLimited.Value("channel"); got != 1 {
+		t.Errorf("channel rejections = %v, want 1", got)
+	}
+}
+
+func TestMetrics_Clone(t *testing.T) {
+	cfg := &config.Config{Repos: make(map[string]config.RepoConfig), Defaults: config.NewDefaults()}
+	b := New(cfg, "")
+	b.cloneRepo = func(url, destDir string) error { return errors.New("auth required") }
+
+	b.handleClone("terminal", "https://example.com/x.git x")
+	if got := b.metrics.cloneDuration.Count(); got != 1 {
+		t.Errorf("clone observations = %d, want 1", got)
+	}
+	if got := b.metrics.cloneFailures.Value(); got != 1 {
+		t.Errorf("clone failures = %v, want 1", got)
+	}
+}
+
+func TestMetrics_Endpoint(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	cfg := testConfig()
+	cfg.Metrics = config.MetricsConfig{Listen: addr, Path: "/prom"}
+	b := New(cfg, "")
+	if err := b.startMetrics(); err != nil {
+		t.Fatalf("startMetrics: %v", err)
+	}
+	defer b.closeMetrics()
+
+	resp, err := http.Get(fmt.Sprintf("http://%s/prom", addr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || !strings.Contains(string(body), "# TYPE llm_bridge_session_starts_total counter") {
+		t.Errorf("GET /prom = %d:\n%s", resp.StatusCode, body)
+	}
+}
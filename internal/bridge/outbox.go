@@ -0,0 +1,227 @@
+package bridge
+
+import (
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/anthropics/llm-bridge/internal/provider"
+)
+
+const (
+	defaultDeliveryBackoff = time.Second
+	maxDeliveryBackoff     = 30 * time.Second
+)
+
+// outboxItem is one pending message: text, or a file when filename is set.
+type outboxItem struct {
+	content  string
+	filename string
+	data     []byte
+	attempts int  // failed sends so far
+	notice   bool // drop notice; never triggers another notice
+}
+
+// outbox delivers output to one channel in order. While nothing is pending,
+// sends happen inline in the caller; once a send fails, later output queues
+// behind it and a goroutine drains the queue with retries.
+type outbox struct {
+	prov      provider.Provider
+	channelID string
+
+	mu      sync.Mutex
+	queue   []outboxItem
+	active  bool // a send or drain is in progress; new items must queue
+	dropped int  // items dropped since the last notice
+}
+
+func outboxKey(prov provider.Provider, channelID string) string {
+	return prov.Name() + "\x00" + channelID
+}
+
+func (b *Bridge) outboxFor(prov provider.Provider, channelID string) *outbox {
+	b.outboxMu.Lock()
+	defer b.outboxMu.Unlock()
+
+	key := outboxKey(prov, channelID)
+	o, ok := b.outboxes[key]
+	if !ok {
+		o = &outbox{prov: prov, channelID: channelID}
+		b.outboxes[key] = o
+	}
+	return o
+}
+
+// outboxBusy reports whether a channel has output waiting to be delivered.
+func (b *Bridge) outboxBusy(prov provider.Provider, channelID string) bool {
+	o := b.outboxFor(prov, channelID)
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.active
+}
+
+// deliver sends item to a channel, queueing it behind any pending output.
+func (b *Bridge) deliver(prov provider.Provider, channelID string, item outboxItem) {
+	o := b.outboxFor(prov, channelID)
+	maxQueue := b.currentConfig().Defaults.Delivery.GetMaxQueue()
+
+	o.mu.Lock()
+	if o.active {
+		b.enqueueLocked(o, item, maxQueue)
+		o.mu.Unlock()
+		return
+	}
+	o.active = true
+	o.mu.Unlock()
+
+	var wait time.Duration
+	if err := b.sendItem(o, item); err != nil && b.sendFailed(o, item, err) {
+		wait = b.retryDelay(err, item.attempts)
+	}
+	b.continueOutbox(o, wait)
+}
+
+// enqueueLocked appends item, dropping it if the queue already holds
+// maxQueue items. Callers must hold o.mu.
+func (b *Bridge) enqueueLocked(o *outbox, item outboxItem, maxQueue int) {
+	if len(o.queue) >= maxQueue {
+		b.dropLocked(o, item, "queue full")
+		return
+	}
+	o.queue = append(o.queue, item)
+}
+
+// dropLocked records an undeliverable item. Callers must hold o.mu.
+func (b *Bridge) dropLocked(o *outbox, item outboxItem, reason string) {
+	b.deliveryDropped.Add(1)
+	b.metrics.outputDropped.Inc(o.prov.Name())
+	slog.Error("output dropped", "reason", reason, "provider", o.prov.Name(), "channel", o.channelID, "bytes", len(item.content)+len(item.data))
+	if !item.notice {
+		o.dropped++
+	}
+}
+
+// sendFailed puts a failed item back at the head of the queue, or drops it
+// if the failure is permanent or it has used all its attempts. Returns
+// whether the item will be retried.
+func (b *Bridge) sendFailed(o *outbox, item outboxItem, err error) bool {
+	item.attempts++
+	b.metrics.sendFailures.Inc(o.prov.Name())
+	maxAttempts := b.currentConfig().Defaults.Delivery.GetMaxAttempts()
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if provider.IsPermanent(err) || item.attempts >= maxAttempts {
+		b.dropLocked(o, item, err.Error())
+		return false
+	}
+	slog.Warn("send failed, will retry", "error", err, "attempt", item.attempts, "provider", o.prov.Name(), "channel", o.channelID)
+	o.queue = append([]outboxItem{item}, o.queue...)
+	return true
+}
+
+// retryDelay returns how long to wait before retrying after err: the
+// provider's requested delay if any, else exponential backoff.
+// attempts counts failures before this one.
+func (b *Bridge) retryDelay(err error, attempts int) time.Duration {
+	if wait := provider.RetryAfter(err); wait > 0 {
+		return wait
+	}
+	wait := b.deliveryBackoff << attempts
+	if wait > maxDeliveryBackoff || wait <= 0 {
+		wait = maxDeliveryBackoff
+	}
+	return wait
+}
+
+// continueOutbox starts a drain after wait if anything is pending, or marks
+// the outbox idle.
+func (b *Bridge) continueOutbox(o *outbox, wait time.Duration) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if len(o.queue) == 0 && o.dropped == 0 {
+		o.active = false
+		return
+	}
+	go b.drainOutbox(o, wait)
+}
+
+// drainOutbox delivers queued items in order until the queue is empty,
+// waiting between attempts as the provider requests or with exponential
+// backoff. Drops are reported to the channel once the queue catches up.
+func (b *Bridge) drainOutbox(o *outbox, wait time.Duration) {
+	for {
+		if wait > 0 {
+			select {
+			case <-time.After(wait):
+			case <-b.stopCh:
+				return
+			}
+			wait = 0
+		}
+
+		o.mu.Lock()
+		if len(o.queue) == 0 {
+			if o.dropped == 0 {
+				o.active = false
+				o.mu.Unlock()
+				return
+			}
+			o.queue = append(o.queue, outboxItem{
+				content: fmt.Sprintf("⚠️ %d output message(s) could not be delivered and were dropped", o.dropped),
+				notice:  true,
+			})
+			o.dropped = 0
+		}
+		item := b.coalesceLocked(o)
+		o.mu.Unlock()
+
+		if err := b.sendItem(o, item); err != nil && b.sendFailed(o, item, err) {
+			wait = b.retryDelay(err, item.attempts)
+		}
+	}
+}
+
+// coalesceLocked pops the head of the queue, merging following text items
+// into it while the result still fits in one message. Callers must hold o.mu.
+func (b *Bridge) coalesceLocked(o *outbox) outboxItem {
+	item := o.queue[0]
+	o.queue = o.queue[1:]
+	if item.filename != "" || item.notice {
+		return item
+	}
+
+	limit := b.output.Limit(provider.CapabilitiesOf(o.prov).MaxMessageLength)
+	for len(o.queue) > 0 {
+		next := o.queue[0]
+		if next.filename != "" || next.notice || len(item.content)+len(next.content) > limit {
+			break
+		}
+		item.content += next.content
+		o.queue = o.queue[1:]
+	}
+	return item
+}
+
+func (b *Bridge) sendItem(o *outbox, item outboxItem) error {
+	var err error
+	ts, try := o.prov.(provider.TrySender)
+	switch {
+	case item.filename != "" && try:
+		err = ts.TrySendFile(o.channelID, item.filename, item.data)
+	case item.filename != "":
+		err = o.prov.SendFile(o.channelID, item.filename, item.data)
+	case try:
+		err = ts.TrySend(o.channelID, item.content)
+	default:
+		err = o.prov.Send(o.channelID, item.content)
+	}
+	if err == nil {
+		b.metrics.messagesOut.Inc(o.prov.Name())
+		if item.filename != "" {
+			b.metrics.attachments.Inc(o.prov.Name())
+		}
+	}
+	return err
+}
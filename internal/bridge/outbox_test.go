@@ -0,0 +1,222 @@
+package bridge
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anthropics/llm-bridge/internal/provider"
+)
+
+func outboxTestBridge(t *testing.T) *Bridge {
+	t.Helper()
+	b := New(testConfig(), "")
+	b.deliveryBackoff = time.Millisecond
+	t.Cleanup(func() { _ = b.Stop() })
+	return b
+}
+
+// waitForSent polls until prov has sent n messages or the deadline passes.
+func waitForSent(t *testing.T, prov *provider.MockProvider, n int) []provider.SentMessage {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if msgs := prov.GetSentMessages(); len(msgs) >= n {
+			return msgs
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	msgs := prov.GetSentMessages()
+	t.Fatalf("expected %d sent messages, got %d: %+v", n, len(msgs), msgs)
+	return nil
+}
+
+func waitForIdle(t *testing.T, b *Bridge, prov provider.Provider, channelID string) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for b.outboxBusy(prov, channelID) {
+		if time.Now().After(deadline) {
+			t.Fatal("outbox did not drain")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestOutbox_InlineWhenIdle(t *testing.T) {
+	b := outboxTestBridge(t)
+	prov := provider.NewMockProvider("discord")
+
+	b.deliver(prov, "ch", outboxItem{content: "hello"})
+
+	msgs := prov.GetSentMessages()
+	if len(msgs) != 1 || msgs[0].Content != "hello" {
+		t.Errorf("expected synchronous delivery, got %+v", msgs)
+	}
+	if b.outboxBusy(prov, "ch") {
+		t.Error("outbox should be idle after a successful send")
+	}
+}
+
+// trySendProvider records whether the outbox used the TrySender variants.
+type trySendProvider struct {
+	*provider.MockProvider
+	tries int
+}
+
+func (p *trySendProvider) TrySend(channelID, content string) error {
+	p.tries++
+	return p.Send(channelID, content)
+}
+
+func (p *trySendProvider) TrySendFile(channelID, filename string, content []byte) error {
+	p.tries++
+	return p.SendFile(channelID, filename, content)
+}
+
+func TestOutbox_UsesTrySend(t *testing.T) {
+	b := outboxTestBridge(t)
+	prov := &trySendProvider{MockProvider: provider.NewMockProvider("discord")}
+
+	b.deliver(prov, "ch", outboxItem{content: "hello"})
+	b.deliver(prov, "ch", outboxItem{filename: "out.md", data: []byte("long")})
+	b.respond(prov, "ch", "/status reply", false)
+
+	if prov.tries != 2 {
+		t.Errorf("TrySend calls = %d, want 2: only queued output should skip waiting out rate limits", prov.tries)
+	}
+	if msgs, files := prov.GetSentMessages(), prov.GetSentFiles(); len(msgs) != 2 || len(files) != 1 {
+		t.Errorf("sent %d messages and %d files, want 2 and 1", len(msgs), len(files))
+	}
+}
+
+func TestOutbox_RetriesInOrderAndCoalesces(t *testing.T) {
+	b := outboxTestBridge(t)
+	prov := provider.NewMockProvider("discord")
+	prov.QueueSendErrors(errors.New("connection reset"))
+
+	b.deliver(prov, "ch", outboxItem{content: "first\n"})
+	b.deliver(prov, "ch", outboxItem{content: "second\n"})
+
+	waitForSent(t, prov, 1)
+	waitForIdle(t, b, prov, "ch")
+	msgs := prov.GetSentMessages()
+	if len(msgs) != 1 || msgs[0].Content != "first\nsecond\n" {
+		t.Errorf("expected queued chunks coalesced in order, got %+v", msgs)
+	}
+	if b.deliveryDropped.Load() != 0 {
+		t.Errorf("nothing should be dropped, got %d", b.deliveryDropped.Load())
+	}
+}
+
+func TestOutbox_HonorsRetryAfter(t *testing.T) {
+	b := outboxTestBridge(t)
+	b.deliveryBackoff = time.Hour // only the provider's hint can make this finish
+	prov := provider.NewMockProvider("discord")
+	prov.QueueSendErrors(&provider.DeliveryError{Err: errors.New("429"), RetryAfter: 20 * time.Millisecond})
+
+	start := time.Now()
+	b.deliver(prov, "ch", outboxItem{content: "hello"})
+
+	waitForSent(t, prov, 1)
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+		t.Errorf("retried after %v, before the requested 20ms", elapsed)
+	}
+}
+
+func TestOutbox_PermanentErrorDropsWithNotice(t *testing.T) {
+	b := outboxTestBridge(t)
+	prov := provider.NewMockProvider("discord")
+	prov.QueueSendErrors(&provider.DeliveryError{Err: errors.New("403"), Permanent: true})
+
+	b.deliver(prov, "ch", outboxItem{content: "lost"})
+
+	msgs := waitForSent(t, prov, 1)
+	if !strings.Contains(msgs[0].Content, "1 output message(s) could not be delivered") {
+		t.Errorf("expected drop notice, got %+v", msgs)
+	}
+	if b.deliveryDropped.Load() != 1 {
+		t.Errorf("deliveryDropped = %d, want 1", b.deliveryDropped.Load())
+	}
+}
+
+func TestOutbox_GivesUpAfterMaxAttempts(t *testing.T) {
+	b := outboxTestBridge(t)
+	b.cfg.Defaults.Delivery.MaxAttempts = 2
+	prov := provider.NewMockProvider("discord")
+	prov.SetSendError(errors.New("down"))
+
+	b.deliver(prov, "ch", outboxItem{content: "lost"})
+	waitForIdle(t, b, prov, "ch")
+
+	// The message and its drop notice both fail; the notice does not
+	// generate another notice.
+	if b.deliveryDropped.Load() != 2 {
+		t.Errorf("deliveryDropped = %d, want 2", b.deliveryDropped.Load())
+	}
+}
+
+func TestOutbox_BoundedQueue(t *testing.T) {
+	b := outboxTestBridge(t)
+	b.cfg.Defaults.Delivery.MaxQueue = 2
+	prov := provider.NewMockProvider("discord")
+
+	// Simulate a send in progress so everything queues.
+	o := b.outboxFor(prov, "ch")
+	o.mu.Lock()
+	o.active = true
+	o.mu.Unlock()
+
+	for _, s := range []string{"a", "b", "c", "d"} {
+		b.deliver(prov, "ch", outboxItem{content: s})
+	}
+	if b.deliveryDropped.Load() != 2 {
+		t.Fatalf("deliveryDropped = %d, want 2", b.deliveryDropped.Load())
+	}
+
+	b.continueOutbox(o, 0)
+	msgs := waitForSent(t, prov, 2)
+	if msgs[0].Content != "ab" {
+		t.Errorf("first message = %q, want queued items coalesced", msgs[0].Content)
+	}
+	if !strings.Contains(msgs[1].Content, "2 output message(s)") {
+		t.Errorf("second message = %q, want drop notice", msgs[1].Content)
+	}
+}
+
+func TestOutbox_CoalesceRespectsLimit(t *testing.T) {
+	b := outboxTestBridge(t)
+	prov := provider.NewMockProvider("discord")
+	prov.SetCapabilities(provider.Capabilities{MaxMessageLength: 5})
+
+	o := b.outboxFor(prov, "ch")
+	o.queue = []outboxItem{{content: "abc"}, {content: "de"}, {content: "f"}, {filename: "x.md"}}
+
+	if got := b.coalesceLocked(o); got.content != "abcde" {
+		t.Errorf("first coalesced item = %q, want %q", got.content, "abcde")
+	}
+	if got := b.coalesceLocked(o); got.content != "f" {
+		t.Errorf("second coalesced item = %q, want %q", got.content, "f")
+	}
+	if got := b.coalesceLocked(o); got.filename != "x.md" {
+		t.Errorf("files should not be coalesced, got %+v", got)
+	}
+}
+
+func TestBridge_BroadcastOutput_RetriesFailedSend(t *testing.T) {
+	b := outboxTestBridge(t)
+	prov := provider.NewMockProvider("discord")
+	prov.QueueSendErrors(errors.New("502 Bad Gateway"))
+	session := &repoSession{
+		name:     "test-repo",
+		channels: []channelRef{{provider: prov, channelID: "channel-123"}},
+		merger:   NewMerger(2 * time.Second),
+	}
+
+	b.broadcastOutput(session, "answer")
+
+	msgs := waitForSent(t, prov, 1)
+	if msgs[0].Content != "answer" {
+		t.Errorf("retried content = %q, want %q", msgs[0].Content, "answer")
+	}
+}
@@ -0,0 +1,255 @@
+package bridge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/anthropics/llm-bridge/internal/audit"
+	"github.com/anthropics/llm-bridge/internal/provider"
+)
+
+// poolFullError is returned when starting a session would exceed
+// max_active_sessions and no running session is idle enough to evict.
+type poolFullError struct {
+	max int
+}
+
+func (e *poolFullError) Error() string {
+	return fmt.Sprintf("all %d LLM sessions are busy", e.max)
+}
+
+// pendingStart is a session waiting for a slot in the pool, with the
+// prompts to send once it starts.
+type pendingStart struct {
+	ctx       context.Context
+	repoName  string
+	prov      provider.Provider
+	channelID string
+	user      sessionUser
+	prompts   []queuedPrompt // msg.Content is formatted when the session starts
+}
+
+func (p *pendingStart) key() string {
+	return sessionKey(p.repoName, p.user)
+}
+
+// activeSessionsLocked counts sessions whose LLM is running. Callers must
+// hold b.mu.
+func (b *Bridge) activeSessionsLocked() int {
+	n := 0
+	for _, session := range b.repos {
+		if session.llm != nil && session.llm.Running() {
+			n++
+		}
+	}
+	return n
+}
+
+// makeRoomLocked ensures a new session fits under max_active_sessions,
+// evicting the least recently active idle session if needed. Starts from
+// the pending queue may go ahead of it; others wait behind it. Callers must
+// hold b.mu.
+func (b *Bridge) makeRoomLocked(fromQueue bool) error {
+	limit := b.cfg.Defaults.MaxSessions
+	if limit <= 0 {
+		return nil
+	}
+	if !fromQueue && len(b.pendingStarts) > 0 {
+		return &poolFullError{max: limit}
+	}
+	if b.activeSessionsLocked() < limit {
+		return nil
+	}
+	if b.evictIdleLocked() {
+		return nil
+	}
+	return &poolFullError{max: limit}
+}
+
+// evictIdleLocked stops the running session that has been idle longest,
+// skipping any with a prompt in flight, queued prompts or a pending
+// permission prompt, and tells its channels. Returns false if none qualify.
+// Callers must hold b.mu.
+func (b *Bridge) evictIdleLocked() bool {
+	var victim *repoSession
+	for _, session := range b.repos {
+		if session.llm == nil || !session.llm.Running() || session.permissionPending || len(session.queue) > 0 {
+			continue
+		}
+		if busy, _, _ := session.busy.snapshot(); busy {
+			continue
+		}
+		if victim == nil || session.llm.LastActivity().Before(victim.llm.LastActivity()) {
+			victim = session
+		}
+	}
+	if victim == nil {
+		return false
+	}
+
+	idle := time.Since(victim.llm.LastActivity())
+	slog.Info("evicting idle llm to make room", "repo", victim.name, "user", victim.user.name, "idle", idle)
+	channels := make([]channelRef, len(victim.channels))
+	copy(channels, victim.channels)
+	b.stopSessionLocked(victim.key(), "evicted")
+
+	notice := fmt.Sprintf("LLM session for %s stopped after %v idle to make room for another session (max_active_sessions: %d). It restarts on the next message.",
+		repoLabel(victim.name, victim.user), idle.Round(time.Second), b.cfg.Defaults.MaxSessions)
+	go func() {
+		for _, ch := range channels {
+			b.reply(ch.provider, ch.channelID, notice)
+		}
+	}()
+	return true
+}
+
+// queueStartLocked adds a prompt to the pending start of its session,
+// queueing the start if there is none, and returns the start's position.
+// Callers must hold b.mu.
+func (b *Bridge) queueStartLocked(start pendingStart) int {
+	for i := range b.pendingStarts {
+		if b.pendingStarts[i].key() == start.key() {
+			b.pendingStarts[i].prompts = append(b.pendingStarts[i].prompts, start.prompts...)
+			return i + 1
+		}
+	}
+	b.pendingStarts = append(b.pendingStarts, start)
+	return len(b.pendingStarts)
+}
+
+// dropPendingStartsLocked removes the pending starts for a repo that is no
+// longer configured and tells the authors of their prompts. Callers must
+// hold b.mu.
+func (b *Bridge) dropPendingStartsLocked(repoName string) {
+	var dropped []queuedPrompt
+	kept := b.pendingStarts[:0]
+	for _, start := range b.pendingStarts {
+		if start.repoName == repoName {
+			dropped = append(dropped, start.prompts...)
+			continue
+		}
+		kept = append(kept, start)
+	}
+	b.pendingStarts = kept
+	if len(dropped) == 0 {
+		return
+	}
+
+	notice := fmt.Sprintf("Repo %s was removed; queued prompt not sent.", repoName)
+	go func() {
+		for _, p := range dropped {
+			b.reply(p.prov, p.channelID, notice)
+		}
+	}()
+}
+
+// startNotice tells the author their prompt is waiting for a session slot.
+func startNotice(position, limit int) string {
+	return fmt.Sprintf("All %d LLM sessions are busy; session start queued (position %d). Your prompt is sent once it starts.", limit, position)
+}
+
+// retryPendingStartsLocked tries the pending starts again in the
+// background, after a session stopped or went idle. Callers must hold b.mu.
+func (b *Bridge) retryPendingStartsLocked() {
+	if len(b.pendingStarts) > 0 {
+		go b.startPendingSessions()
+	}
+}
+
+// startPendingSessions starts queued sessions in order while the pool has
+// room, then sends each one's prompts.
+func (b *Bridge) startPendingSessions() {
+	for {
+		b.mu.Lock()
+		if len(b.pendingStarts) == 0 {
+			b.mu.Unlock()
+			return
+		}
+		next := b.pendingStarts[0]
+		var session *repoSession
+		repo, ok := b.cfg.Repos[next.repoName]
+		err := fmt.Errorf("repo %q is no longer configured", next.repoName)
+		if ok {
+			// The start was queued by getOrCreateSession, which created
+			// any per-user worktree first.
+			session, err = b.getOrCreateSessionLocked(next.ctx, next.repoName, repo, userWorktreeDir(repo, next.user), next.prov, next.channelID, next.user, true)
+		}
+		var full *poolFullError
+		if errors.As(err, &full) {
+			b.mu.Unlock()
+			return
+		}
+		b.pendingStarts = b.pendingStarts[1:]
+		if err == nil {
+			for _, p := range next.prompts {
+				b.addChannelToSession(session, p.prov, p.channelID)
+			}
+		}
+		b.mu.Unlock()
+
+		for _, p := range next.prompts {
+			if err != nil {
+				slog.Error("failed to start queued session", "error", err, "repo", next.repoName)
+				b.reply(p.prov, p.channelID, fmt.Sprintf("Error starting LLM: %v", err))
+				continue
+			}
+			p.msg.Content = session.merger.FormatMessage(p.msg.Source, p.text)
+			position, err := b.submit(session, p)
+			switch {
+			case err != nil:
+				slog.Error("send to llm failed", "error", err, "repo", next.repoName)
+				b.reply(p.prov, p.channelID, fmt.Sprintf("Error sending queued prompt from %s: %v", p.author, err))
+			case position > 0:
+				b.reply(p.prov, p.channelID, queueNotice(position))
+			}
+		}
+	}
+}
+
+// handlePoolFull queues the start of a session for a prompt from msg that
+// found the pool full, and tells its author their position in p's channel.
+func (b *Bridge) handlePoolFull(ctx context.Context, prov provider.Provider, msg provider.Message, repoName string, user sessionUser, p queuedPrompt, full *poolFullError) {
+	b.mu.Lock()
+	position := b.queueStartLocked(pendingStart{
+		ctx: ctx, repoName: repoName, prov: prov, channelID: p.channelID, user: user,
+		prompts: []queuedPrompt{p},
+	})
+	b.retryPendingStartsLocked()
+	b.mu.Unlock()
+
+	slog.Info("session start queued", "repo", repoName, "user", msg.Author, "position", position)
+	b.auditPrompt(prov, msg, repoName, p.text, audit.OutcomeQueued, fmt.Sprintf("waiting for a session slot, position %d", position))
+	b.reply(prov, p.channelID, startNotice(position, full.max))
+}
+
+// poolStatus describes pool usage for /status, or returns "" if sessions
+// are not capped.
+func (b *Bridge) poolStatus() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	limit := b.cfg.Defaults.MaxSessions
+	if limit <= 0 {
+		return ""
+	}
+	status := fmt.Sprintf("Sessions: %d/%d active", b.activeSessionsLocked(), limit)
+	if n := len(b.pendingStarts); n > 0 {
+		status += fmt.Sprintf(", %d waiting to start", n)
+	}
+	return status
+}
+
+// pendingPosition returns the position of the session with key among the
+// pending starts, or 0 if it is not waiting.
+func (b *Bridge) pendingPosition(key string) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for i := range b.pendingStarts {
+		if b.pendingStarts[i].key() == key {
+			return i + 1
+		}
+	}
+	return 0
+}
@@ -0,0 +1,240 @@
+package bridge
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anthropics/llm-bridge/internal/config"
+	"github.com/anthropics/llm-bridge/internal/provider"
+)
+
+// poolBridge returns a bridge with testConfig plus third-repo on
+// channel-789, capped at max sessions. LLMs it starts are recorded by
+// working directory.
+func poolBridge(t *testing.T, max int) (*Bridge, *provider.MockProvider, func(dir string) *mockLLM) {
+	t.Helper()
+	cfg := testConfig()
+	cfg.Repos["third-repo"] = config.RepoConfig{Provider: "discord", ChannelID: "channel-789", LLM: "claude", WorkingDir: "/tmp/third"}
+	cfg.Defaults.MaxSessions = max
+	tb := newTestBridge(t, withConfig(cfg))
+	return tb.b, tb.prov, tb.llmFor
+}
+
+// finishTurn marks a session's turn over, as readOutput does once output
+// goes quiet.
+func finishTurn(b *Bridge, key string) {
+	b.mu.Lock()
+	session := b.repos[key]
+	b.mu.Unlock()
+	session.busy.clear()
+}
+
+func TestPool_EvictsLeastRecentlyActiveIdleSession(t *testing.T) {
+	b, mockProv, llmFor := poolBridge(t, 2)
+
+	sendMessage(b, mockProv, "channel-123", "first")
+	sendMessage(b, mockProv, "channel-456", "second")
+	finishTurn(b, "test-repo")
+	finishTurn(b, "other-repo")
+	llmFor("/tmp/test").setLastActivity(time.Now().Add(-time.Hour))
+
+	sendMessage(b, mockProv, "channel-789", "third")
+
+	b.mu.Lock()
+	_, testRunning := b.repos["test-repo"]
+	_, otherRunning := b.repos["other-repo"]
+	b.mu.Unlock()
+	if testRunning || !otherRunning {
+		t.Fatalf("want the longest-idle session evicted, test-repo running=%v other-repo running=%v", testRunning, otherRunning)
+	}
+	if llmFor("/tmp/test").Running() {
+		t.Error("evicted LLM should be stopped")
+	}
+	if got := llmFor("/tmp/third").getSentMessages(); len(got) != 1 {
+		t.Errorf("third-repo LLM got %v, want the prompt", got)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for lastSent(mockProv, "channel-123") == "" && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if got := lastSent(mockProv, "channel-123"); !strings.Contains(got, "to make room for another session (max_active_sessions: 2)") {
+		t.Errorf("evicted session's channel got %q", got)
+	}
+	if got := b.metrics.sessionStops.Value("test-repo", "evicted"); got != 1 {
+		t.Errorf("evicted stops = %v, want 1", got)
+	}
+}
+
+func TestPool_QueuesStartWhenNoSessionIsIdle(t *testing.T) {
+	b, mockProv, llmFor := poolBridge(t, 1)
+	path := withAuditLog(t, b)
+
+	sendMessage(b, mockProv, "channel-123", "first")
+	sendMessage(b, mockProv, "channel-456", "second")
+	sendMessage(b, mockProv, "channel-456", "third")
+
+	if llmFor("/tmp/other") != nil {
+		t.Fatal("a session should not start while the only one is busy")
+	}
+	want := "All 1 LLM sessions are busy; session start queued (position 1). Your prompt is sent once it starts."
+	if got := lastSent(mockProv, "channel-456"); got != want {
+		t.Errorf("reply = %q, want %q", got, want)
+	}
+	recs := auditRecords(t, path)
+	if len(recs) != 3 || recs[1].Outcome != "queued" || recs[1].Detail != "waiting for a session slot, position 1" {
+		t.Errorf("audit = %+v, want the second prompt queued", recs)
+	}
+
+	sendMessage(b, mockProv, "channel-456", "/status")
+	if got := lastSent(mockProv, "channel-456"); got != "LLM: waiting to start (repo: other-repo, position 1)\nSessions: 1/1 active, 1 waiting to start" {
+		t.Errorf("/status = %q", got)
+	}
+
+	finishTurn(b, "test-repo")
+	b.startPendingSessions()
+
+	other := llmFor("/tmp/other")
+	if other == nil {
+		t.Fatal("queued session should start once a session is idle")
+	}
+	got := other.getSentMessages()
+	if len(got) != 1 || !strings.Contains(got[0].Content, "second") {
+		t.Errorf("other-repo LLM got %v, want the first queued prompt", got)
+	}
+	b.mu.Lock()
+	queued := len(b.repos["other-repo"].queue)
+	pending := len(b.pendingStarts)
+	b.mu.Unlock()
+	if queued != 1 || pending != 0 {
+		t.Errorf("queued prompts = %d, pending starts = %d; want the third prompt queued behind the second", queued, pending)
+	}
+}
+
+func TestPool_RemoveRepoFreesSlotAndDropsItsStarts(t *testing.T) {
+	b, mockProv, llmFor := poolBridge(t, 1)
+	b.cfgPath = filepath.Join(t.TempDir(), "llm-bridge.yaml")
+	writeReloadConfig(t, b.cfgPath, `repos:
+  test-repo: {provider: discord, channel_id: channel-123, working_dir: /tmp/test}
+  other-repo: {provider: discord, channel_id: channel-456, working_dir: /tmp/other}
+  third-repo: {provider: discord, channel_id: channel-789, working_dir: /tmp/third}
+`)
+
+	sendMessage(b, mockProv, "channel-123", "first")
+	sendMessage(b, mockProv, "channel-789", "second")
+	sendMessage(b, mockProv, "channel-456", "third")
+
+	if err := b.removeRepo("third-repo"); err != nil {
+		t.Fatalf("removeRepo(third-repo) error = %v", err)
+	}
+	b.mu.Lock()
+	pending := len(b.pendingStarts)
+	b.mu.Unlock()
+	if pending != 1 {
+		t.Errorf("pending starts = %d, want only other-repo's", pending)
+	}
+	deadline := time.Now().Add(time.Second)
+	for lastSent(mockProv, "channel-789") != "Repo third-repo was removed; queued prompt not sent." && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if got := lastSent(mockProv, "channel-789"); got != "Repo third-repo was removed; queued prompt not sent." {
+		t.Errorf("removed repo's channel got %q", got)
+	}
+
+	// Removing the busy repo frees its slot for the next pending start.
+	if err := b.removeRepo("test-repo"); err != nil {
+		t.Fatalf("removeRepo(test-repo) error = %v", err)
+	}
+	for llmFor("/tmp/other") == nil && time.Now().Before(deadline.Add(time.Second)) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	other := llmFor("/tmp/other")
+	if other == nil {
+		t.Fatal("queued session should start once the removed repo's session stops")
+	}
+	for len(other.getSentMessages()) == 0 && time.Now().Before(deadline.Add(time.Second)) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if got := other.getSentMessages(); len(got) != 1 || !strings.Contains(got[0].Content, "third") {
+		t.Errorf("other-repo LLM got %v, want the queued prompt", got)
+	}
+	if llmFor("/tmp/third") != nil {
+		t.Error("the removed repo's pending start should not run")
+	}
+}
+
+func TestPool_TerminalPromptWaitsForSlot(t *testing.T) {
+	b, mockProv, llmFor := poolBridge(t, 1)
+	path := withAuditLog(t, b)
+	term := provider.NewTerminal("terminal")
+	b.terminalRepoName = "test-repo"
+	sendMessage(b, mockProv, "channel-456", "first")
+
+	b.processTerminalMessage(context.Background(), term, provider.Message{ChannelID: "terminal", Content: "from terminal", Source: "terminal"})
+	if llmFor("/tmp/test") != nil {
+		t.Fatal("a session should not start while the only one is busy")
+	}
+	if got := b.pendingPosition("test-repo"); got != 1 {
+		t.Errorf("terminal session position = %d, want 1", got)
+	}
+	recs := auditRecords(t, path)
+	if len(recs) != 2 || recs[1].Outcome != "queued" || recs[1].Detail != "waiting for a session slot, position 1" {
+		t.Errorf("audit = %+v, want the terminal prompt queued", recs)
+	}
+
+	finishTurn(b, "other-repo")
+	b.startPendingSessions()
+	test := llmFor("/tmp/test")
+	if test == nil {
+		t.Fatal("queued terminal session should start once a session is idle")
+	}
+	if got := test.getSentMessages(); len(got) != 1 || got[0].Source != "terminal" || !strings.Contains(got[0].Content, "from terminal") {
+		t.Errorf("test-repo LLM got %v, want the terminal prompt", got)
+	}
+}
+
+func TestPool_QueuedStartsKeepTheirOrder(t *testing.T) {
+	b, mockProv, llmFor := poolBridge(t, 1)
+	sendMessage(b, mockProv, "channel-123", "first")
+	sendMessage(b, mockProv, "channel-456", "second")
+	sendMessage(b, mockProv, "channel-789", "third")
+	if got := lastSent(mockProv, "channel-789"); !strings.Contains(got, "position 2") {
+		t.Errorf("reply = %q, want position 2", got)
+	}
+
+	// Stopping a session makes room for the head of the queue only.
+	b.mu.Lock()
+	b.stopSessionLocked("test-repo", "stop")
+	b.mu.Unlock()
+	deadline := time.Now().Add(time.Second)
+	for b.pendingPosition("other-repo") != 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	if llmFor("/tmp/other") == nil || llmFor("/tmp/third") != nil {
+		t.Errorf("want other-repo started and third-repo still waiting")
+	}
+	if got := b.pendingPosition("third-repo"); got != 1 {
+		t.Errorf("third-repo position = %d, want 1", got)
+	}
+}
+
+func TestPool_Unlimited(t *testing.T) {
+	b, mockProv, llmFor := poolBridge(t, 0)
+
+	for _, ch := range []string{"channel-123", "channel-456", "channel-789"} {
+		sendMessage(b, mockProv, ch, "hi")
+	}
+
+	for _, dir := range []string{"/tmp/test", "/tmp/other", "/tmp/third"} {
+		if llmFor(dir) == nil {
+			t.Errorf("session in %s not started", dir)
+		}
+	}
+	if got := b.poolStatus(); got != "" {
+		t.Errorf("poolStatus() = %q, want none without a limit", got)
+	}
+}
@@ -0,0 +1,177 @@
+package bridge
+
+import (
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/anthropics/llm-bridge/internal/llm"
+	"github.com/anthropics/llm-bridge/internal/provider"
+)
+
+// queuePreviewLen caps how much of each prompt /queue shows.
+const queuePreviewLen = 60
+
+// queuedPrompt is a prompt waiting for the LLM to finish its current turn.
+type queuedPrompt struct {
+	msg       llm.Message
+	author    string
+	authorID  string
+	text      string // the prompt as typed, for /queue and transcripts
+	prov      provider.Provider
+	channelID string       // where to report send failures
+	replyTo   *replyTarget // reply-mode target, set once the prompt is sent
+}
+
+// submit sends a prompt to the session's LLM, or queues it if a turn is in
+// progress, a permission prompt awaits /approve or /deny, or other prompts
+// are already waiting, so prompts never interleave in the PTY (or answer the
+// permission prompt). It returns the prompt's queue position, or 0 if it was
+// sent.
+func (b *Bridge) submit(session *repoSession, p queuedPrompt) (int, error) {
+	b.mu.Lock()
+	if busy, _, _ := session.busy.snapshot(); busy || session.permissionPending || len(session.queue) > 0 {
+		session.queue = append(session.queue, p)
+		position := len(session.queue)
+		b.markStateChanged()
+		b.mu.Unlock()
+		return position, nil
+	}
+	b.beginPromptLocked(session, p)
+	b.mu.Unlock()
+
+	if err := b.sendPrompt(session, p); err != nil {
+		// Prompts that queued behind this one would otherwise wait for
+		// output that never comes.
+		b.dispatchQueued(session)
+		return 0, err
+	}
+	b.recordPrompt(session, p)
+	return 0, nil
+}
+
+// beginPromptLocked marks the session busy with a prompt about to be written
+// to the LLM, so prompts arriving during the write queue behind it. Callers
+// must hold b.mu.
+func (b *Bridge) beginPromptLocked(session *repoSession, p queuedPrompt) {
+	b.markBusy(session, p.author)
+	if p.replyTo != nil {
+		session.replyTo = p.replyTo
+	}
+}
+
+// sendPrompt writes a prompt begun with beginPromptLocked to the LLM, marking
+// the session idle again if that fails. Callers must not hold b.mu: the write
+// blocks while the LLM is not reading its input.
+func (b *Bridge) sendPrompt(session *repoSession, p queuedPrompt) error {
+	err := session.llm.Send(p.msg)
+	if err != nil {
+		b.mu.Lock()
+		session.busy.clear()
+		if p.replyTo != nil && session.replyTo == p.replyTo {
+			session.replyTo = nil
+		}
+		b.mu.Unlock()
+	}
+	return err
+}
+
+// dispatchQueued sends the next queued prompt once the LLM's turn is over.
+// A pending permission prompt holds the queue until it is answered. A prompt
+// that fails to send is reported to its channel and the next one is tried.
+func (b *Bridge) dispatchQueued(session *repoSession) {
+	for {
+		b.mu.Lock()
+		if len(session.queue) == 0 || session.permissionPending {
+			b.mu.Unlock()
+			return
+		}
+		if busy, _, _ := session.busy.snapshot(); busy {
+			b.mu.Unlock()
+			return
+		}
+		p := session.queue[0]
+		session.queue = session.queue[1:]
+		b.markStateChanged()
+		b.beginPromptLocked(session, p)
+		b.mu.Unlock()
+
+		if err := b.sendPrompt(session, p); err != nil {
+			slog.Error("send queued prompt to llm failed", "error", err, "repo", session.name)
+			if sendErr := p.prov.Send(p.channelID, fmt.Sprintf("Error sending queued prompt from %s: %v", p.author, err)); sendErr != nil {
+				slog.Warn("send error failed", "error", sendErr, "channel", p.channelID, "provider", p.prov.Name())
+			}
+			continue
+		}
+		b.recordPrompt(session, p)
+		return
+	}
+}
+
+// queueNotice tells the author their prompt is waiting.
+func queueNotice(position int) string {
+	return fmt.Sprintf("LLM is busy; prompt queued (position %d). Use /queue to view.", position)
+}
+
+// handleQueue implements /queue, /queue drop <n> and /queue clear for the
+// session author uses in the channel's repo.
+func (b *Bridge) handleQueue(channelID string, author sessionUser, args string) commandResult {
+	repoName, key, user := b.sessionKeyForChannel(channelID, author)
+	if repoName == "" {
+		return commandFailed("No repo configured")
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	session, ok := b.repos[key]
+	var queue []queuedPrompt
+	if ok {
+		queue = session.queue
+	}
+
+	fields := strings.Fields(args)
+	switch {
+	case len(fields) == 0:
+		if len(queue) == 0 {
+			return commandOK(fmt.Sprintf("No queued prompts for %s", repoLabel(repoName, user)))
+		}
+		var sb strings.Builder
+		fmt.Fprintf(&sb, "Queued prompts for %s:", repoLabel(repoName, user))
+		for i, p := range queue {
+			fmt.Fprintf(&sb, "\n  %d. %s: %s", i+1, p.author, truncateLine(p.text, queuePreviewLen))
+		}
+		return commandOK(sb.String())
+
+	case fields[0] == "drop" && len(fields) == 2:
+		n, err := strconv.Atoi(fields[1])
+		if err != nil || n < 1 || n > len(queue) {
+			return commandFailed(fmt.Sprintf("No queued prompt %s (%d queued)", fields[1], len(queue)))
+		}
+		dropped := queue[n-1]
+		session.queue = append(queue[:n-1:n-1], queue[n:]...)
+		b.markStateChanged()
+		return commandOK(fmt.Sprintf("Dropped prompt %d from %s", n, dropped.author))
+
+	case fields[0] == "clear" && len(fields) == 1:
+		if ok {
+			session.queue = nil
+			b.markStateChanged()
+		}
+		return commandOK(fmt.Sprintf("Cleared %d queued prompt(s)", len(queue)))
+	}
+
+	return commandFailed("Usage: /queue [drop <n> | clear]")
+}
+
+// truncateLine shortens s to one line of at most n characters.
+func truncateLine(s string, n int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	r := []rune(s)
+	return string(r[:n-1]) + "…"
+}
@@ -0,0 +1,334 @@
+package bridge
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anthropics/llm-bridge/internal/config"
+	"github.com/anthropics/llm-bridge/internal/llm"
+	"github.com/anthropics/llm-bridge/internal/provider"
+	"github.com/anthropics/llm-bridge/internal/router"
+)
+
+// endTurns finishes the current turn of every session, as readOutput does
+// once output goes quiet, sending the next queued prompt if any.
+func endTurns(b *Bridge) {
+	b.mu.Lock()
+	sessions := make([]*repoSession, 0, len(b.repos))
+	for _, s := range b.repos {
+		sessions = append(sessions, s)
+	}
+	b.mu.Unlock()
+
+	for _, s := range sessions {
+		s.busy.clear()
+		b.dispatchQueued(s)
+	}
+}
+
+func queueBridge(t *testing.T) (*Bridge, *mockLLM, *provider.MockProvider, *repoSession) {
+	t.Helper()
+	tb := newTestBridge(t, withRunningSession())
+	return tb.b, tb.llm, tb.prov, tb.session
+}
+
+func sendPrompt(b *Bridge, prov provider.Provider, author, content string) {
+	msg := provider.Message{ChannelID: "channel-123", Content: content, Author: author, AuthorID: "id-" + author, Source: "discord"}
+	b.handleLLMMessage(context.Background(), prov, msg, router.Parse(content))
+}
+
+func TestQueue_HoldsPromptsUntilTurnEnds(t *testing.T) {
+	b, mockLLM, mockProv, session := queueBridge(t)
+
+	sendPrompt(b, mockProv, "alice", "first")
+	sendPrompt(b, mockProv, "bob", "second")
+	sendPrompt(b, mockProv, "carol", "third")
+
+	if got := mockLLM.getSentMessages(); len(got) != 1 || got[0].Content != "first" {
+		t.Fatalf("only the first prompt should reach the LLM while busy, got %v", got)
+	}
+	sent := mockProv.GetSentMessages()
+	if len(sent) != 2 || !strings.Contains(sent[0].Content, "position 1") || !strings.Contains(sent[1].Content, "position 2") {
+		t.Errorf("queued prompts should be told their position, got %v", sent)
+	}
+
+	endTurns(b)
+	if got := mockLLM.getSentMessages(); len(got) != 2 || got[1].Content != "second" {
+		t.Fatalf("second prompt should be sent after the first turn, got %v", got)
+	}
+	if _, _, author := session.busy.snapshot(); author != "bob" {
+		t.Errorf("busy author = %q, want bob", author)
+	}
+
+	endTurns(b)
+	endTurns(b)
+	if got := mockLLM.getSentMessages(); len(got) != 3 || got[2].Content != "third" {
+		t.Errorf("prompts should be sent in order, got %v", got)
+	}
+	if len(session.queue) != 0 {
+		t.Errorf("queue should be empty, has %d", len(session.queue))
+	}
+}
+
+func TestQueue_HeldWhilePermissionPending(t *testing.T) {
+	b, mockLLM, mockProv, session := queueBridge(t)
+	permLLM := &permissionLLM{mockLLM: mockLLM}
+	session.llm = permLLM
+
+	sendPrompt(b, mockProv, "alice", "first")
+	b.mu.Lock()
+	session.permissionPending = true
+	b.mu.Unlock()
+
+	// The turn goes quiet while the LLM waits for an answer.
+	endTurns(b)
+	sendPrompt(b, mockProv, "bob", "second")
+	endTurns(b)
+	if got := mockLLM.getSentMessages(); len(got) != 1 {
+		t.Fatalf("prompts must not reach the LLM while a permission prompt is pending, got %v", got)
+	}
+	if got := lastSent(mockProv, "channel-123"); !strings.Contains(got, "position 1") {
+		t.Errorf("prompt should be queued, got %q", got)
+	}
+
+	if got := b.answerPermission("channel-123", sessionUser{}, true).response; got != "Approved" {
+		t.Fatalf("/approve = %q", got)
+	}
+	if busy, _, _ := session.busy.snapshot(); !busy {
+		t.Error("the turn should continue after /approve")
+	}
+	if got := mockLLM.getSentMessages(); len(got) != 1 {
+		t.Fatalf("queued prompt should wait for the approved turn to finish, got %v", got)
+	}
+
+	endTurns(b)
+	if got := mockLLM.getSentMessages(); len(got) != 2 || got[1].Content != "second" {
+		t.Errorf("queued prompt should be sent once the approved turn ends, got %v", got)
+	}
+	if got := permLLM.getAnswers(); len(got) != 1 || !got[0] {
+		t.Errorf("answers = %v", got)
+	}
+}
+
+func TestQueue_ReplyTargetSetWhenSent(t *testing.T) {
+	cfg := testConfig()
+	cfg.Defaults.OutputMode = config.OutputModeReply
+	b, _, mockProv, session := queueBridge(t)
+	b.cfg = cfg
+
+	first := provider.Message{ID: "m1", ChannelID: "channel-123", Content: "first", Author: "alice", Source: "discord"}
+	second := provider.Message{ID: "m2", ChannelID: "channel-123", Content: "second", Author: "bob", Source: "discord"}
+	b.handleLLMMessage(context.Background(), mockProv, first, router.Parse(first.Content))
+	b.handleLLMMessage(context.Background(), mockProv, second, router.Parse(second.Content))
+
+	if session.replyTo == nil || session.replyTo.messageID != "m1" {
+		t.Fatalf("reply target should stay on the prompt being answered, got %+v", session.replyTo)
+	}
+	endTurns(b)
+	if session.replyTo == nil || session.replyTo.messageID != "m2" {
+		t.Errorf("reply target should move to the queued prompt once sent, got %+v", session.replyTo)
+	}
+}
+
+func TestQueue_DispatchFailureNotifies(t *testing.T) {
+	b, mockLLM, mockProv, _ := queueBridge(t)
+
+	sendPrompt(b, mockProv, "alice", "first")
+	sendPrompt(b, mockProv, "bob", "second")
+	mockLLM.mu.Lock()
+	mockLLM.sendErr = errors.New("pty closed")
+	mockLLM.mu.Unlock()
+
+	endTurns(b)
+
+	sent := mockProv.GetSentMessages()
+	last := sent[len(sent)-1]
+	if !strings.Contains(last.Content, "queued prompt from bob") || !strings.Contains(last.Content, "pty closed") {
+		t.Errorf("failed dispatch should be reported, got %q", last.Content)
+	}
+}
+
+// stalledLLM is a mockLLM whose Send blocks until release is closed, like a
+// PTY write to an LLM that is not reading its input. The first Send fails
+// with failFirst, if set.
+type stalledLLM struct {
+	*mockLLM
+	sending   chan struct{}
+	release   chan struct{}
+	failFirst error
+}
+
+func (s *stalledLLM) Send(msg llm.Message) error {
+	s.sending <- struct{}{}
+	<-s.release
+	s.mu.Lock()
+	err := s.failFirst
+	s.failFirst = nil
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return s.mockLLM.Send(msg)
+}
+
+func TestQueue_SendDoesNotHoldBridgeLock(t *testing.T) {
+	b, mockLLM, mockProv, session := queueBridge(t)
+	stalled := &stalledLLM{mockLLM: mockLLM, sending: make(chan struct{}, 1), release: make(chan struct{})}
+	session.llm = stalled
+
+	done := make(chan struct{})
+	go func() {
+		sendPrompt(b, mockProv, "alice", "first")
+		close(done)
+	}()
+	<-stalled.sending
+
+	status := make(chan string)
+	go func() {
+		sendPrompt(b, mockProv, "bob", "second")
+		status <- b.getStatus("channel-123", sessionUser{}).response
+	}()
+	select {
+	case got := <-status:
+		if !strings.Contains(got, "busy") || !strings.Contains(got, "1 queued") {
+			t.Errorf("prompt should queue behind the one being written, status = %q", got)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("a stalled LLM write blocked the bridge")
+	}
+
+	close(stalled.release)
+	<-done
+	if got := mockLLM.getSentMessages(); len(got) != 1 || got[0].Content != "first" {
+		t.Errorf("sent = %v, want only the first prompt", got)
+	}
+}
+
+func TestQueue_SendFailureDispatchesQueued(t *testing.T) {
+	b, mockLLM, mockProv, session := queueBridge(t)
+	stalled := &stalledLLM{mockLLM: mockLLM, sending: make(chan struct{}, 2), release: make(chan struct{}), failFirst: errors.New("pty closed")}
+	session.llm = stalled
+
+	done := make(chan struct{})
+	go func() {
+		sendPrompt(b, mockProv, "alice", "first")
+		close(done)
+	}()
+	<-stalled.sending
+	sendPrompt(b, mockProv, "bob", "second")
+	close(stalled.release)
+	<-done
+
+	if got := mockLLM.getSentMessages(); len(got) != 1 || got[0].Content != "second" {
+		t.Errorf("queued prompt should be sent after the first failed, got %v", got)
+	}
+	if _, _, author := session.busy.snapshot(); author != "bob" {
+		t.Errorf("busy author = %q, want bob", author)
+	}
+}
+
+func TestQueue_Command(t *testing.T) {
+	b, _, mockProv, session := queueBridge(t)
+
+	if got := b.handleQueue("channel-123", sessionUser{}, "").response; got != "No queued prompts for test-repo" {
+		t.Errorf("empty queue = %q", got)
+	}
+
+	sendPrompt(b, mockProv, "alice", "first")
+	sendPrompt(b, mockProv, "bob", "second prompt\nwith two lines")
+	sendPrompt(b, mockProv, "carol", strings.Repeat("x", 100))
+
+	list := b.handleQueue("channel-123", sessionUser{}, "").response
+	if !strings.Contains(list, "1. bob: second prompt with two lines") {
+		t.Errorf("list should show author and one-line preview, got %q", list)
+	}
+	if !strings.Contains(list, "2. carol: "+strings.Repeat("x", queuePreviewLen-1)+"…") {
+		t.Errorf("long prompts should be truncated, got %q", list)
+	}
+
+	if got := b.handleQueue("channel-123", sessionUser{}, "drop 3").response; !strings.Contains(got, "No queued prompt 3") {
+		t.Errorf("drop out of range = %q", got)
+	}
+	if got := b.handleQueue("channel-123", sessionUser{}, "drop 1").response; got != "Dropped prompt 1 from bob" {
+		t.Errorf("drop = %q", got)
+	}
+	if len(session.queue) != 1 || session.queue[0].author != "carol" {
+		t.Errorf("queue after drop = %v", session.queue)
+	}
+
+	if got := b.handleQueue("channel-123", sessionUser{}, "clear").response; got != "Cleared 1 queued prompt(s)" {
+		t.Errorf("clear = %q", got)
+	}
+	if len(session.queue) != 0 {
+		t.Errorf("queue should be empty after clear")
+	}
+
+	if got := b.handleQueue("channel-123", sessionUser{}, "shuffle").response; !strings.HasPrefix(got, "Usage:") {
+		t.Errorf("bad args = %q", got)
+	}
+	if got := b.handleQueue("unknown-channel", sessionUser{}, "").response; got != "No repo configured" {
+		t.Errorf("unknown channel = %q", got)
+	}
+}
+
+func TestQueue_BridgeCommandsBypass(t *testing.T) {
+	b, mockLLM, mockProv, _ := queueBridge(t)
+
+	sendPrompt(b, mockProv, "alice", "first")
+	sendPrompt(b, mockProv, "bob", "second")
+
+	b.processMessage(context.Background(), mockProv, provider.Message{
+		ChannelID: "channel-123", Content: "/status", Author: "carol", AuthorID: "id-carol", Source: "discord",
+	})
+
+	sent := mockProv.GetSentMessages()
+	last := sent[len(sent)-1]
+	if !strings.Contains(last.Content, "busy") || !strings.Contains(last.Content, "1 queued") {
+		t.Errorf("/status should answer immediately with the queue length, got %q", last.Content)
+	}
+	if got := mockLLM.getSentMessages(); len(got) != 1 {
+		t.Errorf("commands must not reach the LLM, got %v", got)
+	}
+}
+
+func TestQueue_ReadOutputDispatchesWhenQuiet(t *testing.T) {
+	b, mockLLM, mockProv, session := queueBridge(t)
+	b.busyQuietPeriod = 0
+	pr, pw := io.Pipe()
+	mockLLM.SetOutput(pr)
+
+	sendPrompt(b, mockProv, "alice", "first")
+	sendPrompt(b, mockProv, "bob", "second")
+
+	done := make(chan struct{})
+	go func() {
+		b.readOutput(session, "test-repo")
+		close(done)
+	}()
+	_, _ = pw.Write([]byte("answer\n"))
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) && len(mockLLM.getSentMessages()) < 2 {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := mockLLM.getSentMessages(); len(got) != 2 || got[1].Content != "second" {
+		t.Errorf("queued prompt should be sent once output goes quiet, got %v", got)
+	}
+
+	_ = pw.Close()
+	<-done
+}
+
+func TestTruncateLine(t *testing.T) {
+	if got := truncateLine("  a\n b  ", 10); got != "a b" {
+		t.Errorf("got %q", got)
+	}
+	long := strings.Repeat("é", queuePreviewLen+5)
+	if got := truncateLine(long, queuePreviewLen); len([]rune(got)) != queuePreviewLen {
+		t.Errorf("truncated to %d runes, want %d", len([]rune(got)), queuePreviewLen)
+	}
+}
@@ -0,0 +1,67 @@
+package bridge
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/anthropics/llm-bridge/internal/authz"
+	"github.com/anthropics/llm-bridge/internal/provider"
+	"github.com/anthropics/llm-bridge/internal/router"
+)
+
+// reactionCommands maps each configured emoji to the bridge command it runs.
+func (b *Bridge) reactionCommands() map[string]string {
+	r := b.currentConfig().Defaults.Reactions
+	return map[string]string{
+		r.GetCancel():  "cancel",
+		r.GetRestart(): "restart",
+		r.GetAttach():  "last",
+		r.GetApprove(): "approve",
+		r.GetDeny():    "deny",
+	}
+}
+
+func (b *Bridge) handleReactions(ctx context.Context, prov provider.Provider, reactor provider.Reactor) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case reaction, ok := <-reactor.Reactions():
+			if !ok {
+				return
+			}
+			b.processReaction(prov, reaction)
+		}
+	}
+}
+
+// processReaction runs the bridge command bound to a reaction's emoji.
+// Reactions with unmapped emoji are ignored.
+func (b *Bridge) processReaction(prov provider.Provider, reaction provider.Reaction) {
+	if !b.currentConfig().Defaults.Reactions.GetReactionsEnabled() {
+		return
+	}
+
+	cmd, ok := b.reactionCommands()[reaction.Emoji]
+	if !ok {
+		return
+	}
+
+	route := router.Route{
+		Type:    router.RouteToBridge,
+		Command: cmd,
+		Raw:     "/" + cmd,
+	}
+	subject := authz.Subject{Provider: prov.Name(), UserID: reaction.UserID, RoleIDs: reaction.Roles}
+	if !b.authorize(prov, reaction.ChannelID, reaction.User, subject, route, b.repoForChannel(reaction.ChannelID)) {
+		return
+	}
+
+	author := sessionUser{id: reaction.UserID, name: reaction.User}
+	if b.refuseReadOnlyCommand(prov, reaction.ChannelID, author, route) {
+		return
+	}
+
+	slog.Info("reaction command", "command", cmd, "user", reaction.User, "channel", reaction.ChannelID, "message", reaction.MessageID)
+	b.handleBridgeCommand(prov, reaction.ChannelID, author, route)
+}
@@ -0,0 +1,265 @@
+package bridge
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/anthropics/llm-bridge/internal/provider"
+)
+
+// permissionLLM is a mockLLM that also answers permission prompts.
+type permissionLLM struct {
+	*mockLLM
+
+	mu      sync.Mutex
+	answers []bool
+}
+
+func (p *permissionLLM) IsPermissionPrompt(output string) bool {
+	return output == "Do you want to proceed?\n"
+}
+
+func (p *permissionLLM) RespondPermission(allow bool) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.answers = append(p.answers, allow)
+	return nil
+}
+
+func (p *permissionLLM) getAnswers() []bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]bool(nil), p.answers...)
+}
+
+func TestBridge_ProcessReaction_Cancel(t *testing.T) {
+	b := New(testConfig(), "")
+
+	mockLLM := newMockLLM("claude")
+	mockLLM.setRunning(true)
+	mockProv := provider.NewMockProvider("discord")
+	b.repos["test-repo"] = &repoSession{
+		name:     "test-repo",
+		llm:      mockLLM,
+		channels: []channelRef{{provider: mockProv, channelID: "channel-123"}},
+		merger:   NewMerger(2 * time.Second),
+	}
+
+	b.processReaction(mockProv, provider.Reaction{ChannelID: "channel-123", MessageID: "m1", Emoji: "🛑", User: "alice"})
+
+	msgs := mockProv.GetSentMessages()
+	if len(msgs) != 1 || msgs[0].Content != "Sent interrupt signal" {
+		t.Errorf("expected cancel response, got %v", msgs)
+	}
+}
+
+func TestBridge_ProcessReaction_Restart(t *testing.T) {
+	b := New(testConfig(), "")
+
+	mockLLM := newMockLLM("claude")
+	mockLLM.setRunning(true)
+	mockProv := provider.NewMockProvider("discord")
+	b.repos["test-repo"] = &repoSession{
+		name:     "test-repo",
+		llm:      mockLLM,
+		channels: []channelRef{{provider: mockProv, channelID: "channel-123"}},
+		merger:   NewMerger(2 * time.Second),
+	}
+
+	b.processReaction(mockProv, provider.Reaction{ChannelID: "channel-123", Emoji: "🔁"})
+
+	if _, ok := b.repos["test-repo"]; ok {
+		t.Error("restart reaction should remove the session")
+	}
+	if mockLLM.Running() {
+		t.Error("restart reaction should stop the LLM")
+	}
+}
+
+func TestBridge_ProcessReaction_Attach(t *testing.T) {
+	b := New(testConfig(), "")
+
+	mockProv := provider.NewMockProvider("discord")
+	session := &repoSession{
+		name:     "test-repo",
+		llm:      newMockLLM("claude"),
+		channels: []channelRef{{provider: mockProv, channelID: "channel-123"}},
+		merger:   NewMerger(2 * time.Second),
+	}
+	b.repos["test-repo"] = session
+	b.broadcastOutput(session, "the answer")
+
+	b.processReaction(mockProv, provider.Reaction{ChannelID: "channel-123", Emoji: "📎"})
+
+	files := mockProv.GetSentFiles()
+	if len(files) != 1 || string(files[0].Content) != "the answer" {
+		t.Errorf("expected last output as file, got %v", files)
+	}
+	// Only the original broadcast should be a text message
+	if msgs := mockProv.GetSentMessages(); len(msgs) != 1 {
+		t.Errorf("expected no text response to a successful re-send, got %v", msgs)
+	}
+}
+
+func TestBridge_ProcessReaction_ApproveAndDeny(t *testing.T) {
+	b := New(testConfig(), "")
+
+	pl := &permissionLLM{mockLLM: newMockLLM("claude")}
+	pl.setRunning(true)
+	mockProv := provider.NewMockProvider("discord")
+	session := &repoSession{
+		name:     "test-repo",
+		llm:      pl,
+		channels: []channelRef{{provider: mockProv, channelID: "channel-123"}},
+		merger:   NewMerger(2 * time.Second),
+	}
+	b.repos["test-repo"] = session
+
+	session.permissionPending = true
+	b.processReaction(mockProv, provider.Reaction{ChannelID: "channel-123", Emoji: "✅"})
+
+	session.permissionPending = true
+	b.processReaction(mockProv, provider.Reaction{ChannelID: "channel-123", Emoji: "❌"})
+
+	answers := pl.getAnswers()
+	if len(answers) != 2 || !answers[0] || answers[1] {
+		t.Errorf("answers = %v, want [true false]", answers)
+	}
+	msgs := mockProv.GetSentMessages()
+	if len(msgs) != 2 || msgs[0].Content != "Approved" || msgs[1].Content != "Denied" {
+		t.Errorf("unexpected responses: %v", msgs)
+	}
+}
+
+func TestBridge_ProcessReaction_UnmappedEmoji(t *testing.T) {
+	b := New(testConfig(), "")
+	mockProv := provider.NewMockProvider("discord")
+
+	b.processReaction(mockProv, provider.Reaction{ChannelID: "channel-123", Emoji: "👍"})
+
+	if msgs := mockProv.GetSentMessages(); len(msgs) != 0 {
+		t.Errorf("unmapped emoji should be ignored, got %v", msgs)
+	}
+}
+
+func TestBridge_ProcessReaction_Disabled(t *testing.T) {
+	cfg := testConfig()
+	disabled := false
+	cfg.Defaults.Reactions.Enabled = &disabled
+	b := New(cfg, "")
+	mockProv := provider.NewMockProvider("discord")
+
+	b.processReaction(mockProv, provider.Reaction{ChannelID: "channel-123", Emoji: "🛑"})
+
+	if msgs := mockProv.GetSentMessages(); len(msgs) != 0 {
+		t.Errorf("reactions should be ignored when disabled, got %v", msgs)
+	}
+}
+
+func TestBridge_ProcessReaction_CustomEmoji(t *testing.T) {
+	cfg := testConfig()
+	cfg.Defaults.Reactions.Cancel = "⏹️"
+	b := New(cfg, "")
+	mockProv := provider.NewMockProvider("discord")
+
+	b.processReaction(mockProv, provider.Reaction{ChannelID: "channel-123", Emoji: "🛑"})
+	if msgs := mockProv.GetSentMessages(); len(msgs) != 0 {
+		t.Errorf("default emoji should not trigger once overridden, got %v", msgs)
+	}
+
+	b.processReaction(mockProv, provider.Reaction{ChannelID: "channel-123", Emoji: "⏹️"})
+	msgs := mockProv.GetSentMessages()
+	if len(msgs) != 1 || msgs[0].Content != "LLM not running" {
+		t.Errorf("expected cancel response, got %v", msgs)
+	}
+}
+
+func TestBridge_HandleReactions_FromProvider(t *testing.T) {
+	b := New(testConfig(), "")
+	mockProv := provider.NewMockProvider("discord")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		b.handleReactions(ctx, mockProv, mockProv)
+		close(done)
+	}()
+
+	mockProv.SimulateReaction(provider.Reaction{ChannelID: "channel-123", Emoji: "🛑"})
+
+	deadline := time.Now().Add(time.Second)
+	for len(mockProv.GetSentMessages()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if msgs := mockProv.GetSentMessages(); len(msgs) != 1 {
+		t.Errorf("expected reaction to run a command, got %v", msgs)
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Error("handleReactions should exit on context cancel")
+	}
+}
+
+func TestBridge_AnswerPermission(t *testing.T) {
+	b := New(testConfig(), "")
+
+	if got := b.answerPermission("unknown", sessionUser{}, true).response; got != "No repo configured" {
+		t.Errorf("unknown channel: %q", got)
+	}
+	if got := b.answerPermission("channel-123", sessionUser{}, true).response; got != "LLM not running" {
+		t.Errorf("no session: %q", got)
+	}
+
+	plain := newMockLLM("claude")
+	plain.setRunning(true)
+	b.repos["test-repo"] = &repoSession{name: "test-repo", llm: plain, merger: NewMerger(2 * time.Second)}
+	if got := b.answerPermission("channel-123", sessionUser{}, true).response; got != "claude does not support permission prompts" {
+		t.Errorf("unsupported backend: %q", got)
+	}
+
+	pl := &permissionLLM{mockLLM: newMockLLM("claude")}
+	pl.setRunning(true)
+	b.repos["test-repo"] = &repoSession{name: "test-repo", llm: pl, merger: NewMerger(2 * time.Second)}
+	if got := b.answerPermission("channel-123", sessionUser{}, true).response; got != "No pending permission prompt" {
+		t.Errorf("nothing pending: %q", got)
+	}
+	if len(pl.getAnswers()) != 0 {
+		t.Error("should not answer when nothing is pending")
+	}
+}
+
+func TestBridge_ReadOutput_DetectsPermissionPrompt(t *testing.T) {
+	b := New(testConfig(), "")
+
+	pl := &permissionLLM{mockLLM: newMockLLM("claude")}
+	pl.setRunning(true)
+	pl.SetOutput(strings.NewReader("Editing main.go\nDo you want to proceed?\n"))
+	session := &repoSession{name: "test-repo", llm: pl, merger: NewMerger(2 * time.Second)}
+
+	b.readOutput(session, "test-repo")
+
+	b.mu.Lock()
+	pending := session.permissionPending
+	b.mu.Unlock()
+	if !pending {
+		t.Error("permission prompt in output should mark the session pending")
+	}
+}
+
+func TestBridge_ResendLastOutput_NoOutput(t *testing.T) {
+	b := New(testConfig(), "")
+	mockProv := provider.NewMockProvider("discord")
+
+	if got := b.resendLastOutput(mockProv, "channel-123", sessionUser{}).response; got != "No output to re-send" {
+		t.Errorf("resendLastOutput() = %q", got)
+	}
+	if got := b.resendLastOutput(mockProv, "unknown", sessionUser{}).response; got != "No repo configured" {
+		t.Errorf("resendLastOutput(unknown) = %q", got)
+	}
+}
@@ -0,0 +1,356 @@
+package bridge
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/anthropics/llm-bridge/internal/config"
+	"github.com/anthropics/llm-bridge/internal/webhook"
+)
+
+// defaultConfigPollInterval is how often the config file's modification time
+// is checked.
+const defaultConfigPollInterval = 5 * time.Second
+
+// currentConfig returns the config in effect. Reloads and runtime repo
+// changes replace it rather than modifying it, so callers may read the
+// result without holding b.mu.
+func (b *Bridge) currentConfig() *config.Config {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.cfg
+}
+
+// withReposLocked returns a copy of the config in effect whose Repos map
+// may be modified, for runtime changes to swap in as b.cfg. Callers must
+// hold b.mu.
+func (b *Bridge) withReposLocked() *config.Config {
+	cfg := *b.cfg
+	cfg.Repos = make(map[string]config.RepoConfig, len(b.cfg.Repos)+1)
+	maps.Copy(cfg.Repos, b.cfg.Repos)
+	return &cfg
+}
+
+// Reload asks the bridge to re-read its config file, as on SIGHUP. It never
+// blocks; the reload happens on the config watch loop.
+func (b *Bridge) Reload() {
+	select {
+	case b.reloadCh <- struct{}{}:
+	default:
+	}
+}
+
+// configWatchLoop reloads the config file when Reload is called or when the
+// file's modification time changes.
+func (b *Bridge) configWatchLoop(ctx context.Context) {
+	ticker := time.NewTicker(b.configPollInterval)
+	defer ticker.Stop()
+
+	lastMod, _ := b.configModTime()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-b.reloadCh:
+			if mod, ok := b.configModTime(); ok {
+				lastMod = mod
+			}
+			_ = b.reloadConfig(ctx)
+		case <-ticker.C:
+			// A missing file is usually an editor mid-save; wait for it.
+			mod, ok := b.configModTime()
+			if !ok || mod.Equal(lastMod) {
+				continue
+			}
+			lastMod = mod
+			_ = b.reloadConfig(ctx)
+		}
+	}
+}
+
+func (b *Bridge) configModTime() (time.Time, bool) {
+	info, err := os.Stat(b.cfgPath)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return info.ModTime(), true
+}
+
+// reloadConfig loads and applies the config file. A config that fails to
+// load or validate is reported and the current one is kept.
+func (b *Bridge) reloadConfig(ctx context.Context) error {
+	cfg, err := config.Load(b.cfgPath)
+	if err != nil {
+		slog.Error("config reload failed, keeping current config", "path", b.cfgPath, "error", err)
+		b.notifyAdmin(fmt.Sprintf("Config reload failed, keeping the current config: %v", err))
+		return err
+	}
+
+	changes := b.applyConfig(ctx, cfg)
+	if len(changes) == 0 {
+		slog.Debug("config reloaded, nothing changed", "path", b.cfgPath)
+		return nil
+	}
+	slog.Info("config reloaded", "path", b.cfgPath, "changes", strings.Join(changes, "; "))
+	b.notifyAdmin("Config reloaded:\n- " + strings.Join(changes, "\n- "))
+	return nil
+}
+
+// applyConfig makes cfg the bridge's config and brings the running bridge in
+// line with it: added repos start receiving messages, removed repos have
+// their sessions stopped, rate limits, roles and the idle timeout take
+// effect, and changed providers are restarted. Running sessions of changed
+// repos keep their settings until they next start. It returns a description
+// of each change.
+func (b *Bridge) applyConfig(ctx context.Context, cfg *config.Config) []string {
+	var changes []string
+	var removed []*repoSession
+
+	b.mu.Lock()
+	old := b.cfg
+	b.cfg = cfg
+
+	for _, name := range sortedKeys(old.Repos) {
+		repo := old.Repos[name]
+		next, ok := cfg.Repos[name]
+		switch {
+		case !ok:
+			b.unsubscribeRepoLocked(repo)
+			for key, session := range b.repos {
+				if session.name == name {
+					removed = append(removed, session)
+					delete(b.repos, key)
+					b.markStateChanged()
+				}
+			}
+			b.emit(webhook.RepoRemoved, name, map[string]any{"provider": repo.Provider, "channel": repo.ChannelID, "reason": "config reload"})
+			changes = append(changes, "removed repo "+name)
+		case !reflect.DeepEqual(repo, next):
+			if !reflect.DeepEqual(repo.AllChannels(), next.AllChannels()) {
+				b.unsubscribeRepoLocked(repo)
+				b.subscribeRepoLocked(next)
+			}
+			changes = append(changes, "updated repo "+name)
+		}
+	}
+	for _, name := range sortedKeys(cfg.Repos) {
+		if _, ok := old.Repos[name]; !ok {
+			b.subscribeRepoLocked(cfg.Repos[name])
+			changes = append(changes, "added repo "+name)
+		}
+	}
+
+	if !reflect.DeepEqual(old.Defaults.RateLimit, cfg.Defaults.RateLimit) {
+		b.setRateLimits(cfg.Defaults.RateLimit)
+		changes = append(changes, "rate limits updated")
+	}
+	if !reflect.DeepEqual(old.Authz, cfg.Authz) {
+		b.authz = newPolicy(cfg.Authz)
+		changes = append(changes, "authorization roles updated")
+	}
+	if oldIdle, idle := old.Defaults.GetIdleTimeoutDuration(), cfg.Defaults.GetIdleTimeoutDuration(); oldIdle != idle {
+		changes = append(changes, fmt.Sprintf("idle timeout %s -> %s", formatIdleTimeout(oldIdle), formatIdleTimeout(idle)))
+	}
+	if old.Defaults.MaxSessions != cfg.Defaults.MaxSessions {
+		changes = append(changes, fmt.Sprintf("max_active_sessions %d -> %d", old.Defaults.MaxSessions, cfg.Defaults.MaxSessions))
+	}
+	b.retryPendingStartsLocked() // a raised limit or removed repos may have made room
+	b.mu.Unlock()
+
+	for _, session := range removed {
+		b.stopRemovedSession(session)
+	}
+
+	changes = append(changes, b.applyProviderConfig(ctx, old.Providers, cfg.Providers)...)
+
+	// These are only read at startup.
+	var restartOnly []string
+	if old.Defaults.OutputThreshold != cfg.Defaults.OutputThreshold {
+		restartOnly = append(restartOnly, "output_threshold")
+	}
+	if old.Defaults.StateFile != cfg.Defaults.StateFile {
+		restartOnly = append(restartOnly, "state_file")
+	}
+	if !reflect.DeepEqual(old.Defaults.Audit, cfg.Defaults.Audit) {
+		restartOnly = append(restartOnly, "audit")
+	}
+	if old.AdminAPI != cfg.AdminAPI {
+		restartOnly = append(restartOnly, "admin_api")
+	}
+	if old.Metrics != cfg.Metrics {
+		restartOnly = append(restartOnly, "metrics")
+	}
+	if !reflect.DeepEqual(old.Webhooks, cfg.Webhooks) {
+		restartOnly = append(restartOnly, "webhooks")
+	}
+	if len(restartOnly) > 0 {
+		slog.Warn("config changes need a restart to take effect", "settings", restartOnly)
+		changes = append(changes, fmt.Sprintf("%s changed; restart to apply", strings.Join(restartOnly, ", ")))
+	}
+
+	return changes
+}
+
+// stopRemovedSession stops the session of a repo that is no longer
+// configured and tells its channels.
+func (b *Bridge) stopRemovedSession(session *repoSession) {
+	b.mu.Lock()
+	channels := make([]channelRef, len(session.channels))
+	copy(channels, session.channels)
+	b.mu.Unlock()
+
+	slog.Info("stopping llm of removed repo", "repo", session.name, "user", session.user.name)
+	if session.llm != nil {
+		_ = session.llm.Stop()
+	}
+	if session.cancelCtx != nil {
+		session.cancelCtx()
+	}
+	b.metrics.sessionStops.Inc(session.name, "removed")
+	b.emit(webhook.SessionStopped, session.name, map[string]any{"reason": "removed"})
+
+	notice := fmt.Sprintf("Repo %s was removed from the config; its LLM session was stopped.", repoLabel(session.name, session.user))
+	for _, ch := range channels {
+		b.reply(ch.provider, ch.channelID, notice)
+	}
+}
+
+// applyProviderConfig stops providers whose config was removed or changed
+// and starts those that were added or changed.
+func (b *Bridge) applyProviderConfig(ctx context.Context, old, cfg config.ProviderConfigs) []string {
+	var changes []string
+	restart := func(name string, wasOn, isOn bool, start func() error) {
+		if wasOn {
+			b.stopProvider(name)
+		}
+		if isOn {
+			if err := start(); err != nil {
+				slog.Error("restart provider failed", "provider", name, "error", err)
+				changes = append(changes, fmt.Sprintf("provider %s failed to start: %v", name, err))
+				b.rebindProvider(name)
+				return
+			}
+		}
+		b.rebindProvider(name)
+		switch {
+		case !isOn:
+			changes = append(changes, "stopped provider "+name)
+		case !wasOn:
+			changes = append(changes, "started provider "+name)
+		default:
+			changes = append(changes, "restarted provider "+name)
+		}
+	}
+
+	if old.Discord != cfg.Discord {
+		restart("discord", old.Discord.GetBotToken() != "", cfg.Discord.GetBotToken() != "", func() error { return b.startDiscord(ctx) })
+	}
+	if !reflect.DeepEqual(old.GitHub, cfg.GitHub) {
+		restart("github", old.GitHub.Enabled(), cfg.GitHub.Enabled(), func() error { return b.startGitHub(ctx) })
+	}
+
+	names := sortedKeys(old.Plugins)
+	for _, name := range sortedKeys(cfg.Plugins) {
+		if _, ok := old.Plugins[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		oldPlugin, wasOn := old.Plugins[name]
+		plugin, isOn := cfg.Plugins[name]
+		if wasOn && isOn && reflect.DeepEqual(oldPlugin, plugin) {
+			continue
+		}
+		restart(name, wasOn, isOn, func() error { return b.startPlugin(ctx, name, plugin) })
+	}
+	return changes
+}
+
+// stopProvider stops and forgets a provider along with its delivery queues.
+func (b *Bridge) stopProvider(name string) {
+	b.mu.Lock()
+	prov, ok := b.providers[name]
+	delete(b.providers, name)
+	b.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	if err := prov.Stop(); err != nil {
+		slog.Warn("stop provider failed", "provider", name, "error", err)
+	}
+	b.outboxMu.Lock()
+	for key, o := range b.outboxes {
+		if o.prov == prov {
+			delete(b.outboxes, key)
+		}
+	}
+	b.outboxMu.Unlock()
+	slog.Info("provider stopped", "provider", name)
+}
+
+// rebindProvider points sessions' channels and queued prompts at the
+// current instance of a provider, dropping channels of a provider that is
+// no longer running.
+func (b *Bridge) rebindProvider(name string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	prov, running := b.providers[name]
+	for _, session := range b.repos {
+		channels := session.channels[:0]
+		for _, ch := range session.channels {
+			if ch.provider.Name() == name {
+				if !running {
+					continue
+				}
+				ch.provider = prov
+			}
+			channels = append(channels, ch)
+		}
+		session.channels = channels
+
+		for i := range session.queue {
+			if running && session.queue[i].prov.Name() == name {
+				session.queue[i].prov = prov
+			}
+		}
+	}
+	b.markStateChanged()
+}
+
+// notifyAdmin sends an operator notice to the configured admin channel, if
+// any.
+func (b *Bridge) notifyAdmin(text string) {
+	b.mu.Lock()
+	ac := b.cfg.Defaults.AdminChannel
+	prov, ok := b.providers[ac.Provider]
+	b.mu.Unlock()
+
+	if !ac.Enabled() {
+		return
+	}
+	if !ok {
+		slog.Warn("admin channel provider not running", "provider", ac.Provider)
+		return
+	}
+	b.reply(prov, ac.ChannelID, text)
+}
+
+// sortedKeys returns the keys of m in order.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,365 @@
+package bridge
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/anthropics/llm-bridge/internal/config"
+	"github.com/anthropics/llm-bridge/internal/llm"
+	"github.com/anthropics/llm-bridge/internal/provider"
+)
+
+const reloadBaseConfig = `repos:
+  test-repo:
+    provider: discord
+    channel_id: channel-123
+    working_dir: /tmp/test
+  other-repo:
+    provider: discord
+    channel_id: channel-456
+    working_dir: /tmp/other
+defaults:
+  admin_channel:
+    provider: discord
+    channel_id: ops
+`
+
+// reloadBridge returns a bridge loaded from a config file in a temp dir, with
+// a running Discord mock and a running test-repo session.
+func reloadBridge(t *testing.T) (*Bridge, *provider.MockProvider, *mockLLM) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "llm-bridge.yaml")
+	writeReloadConfig(t, path, reloadBaseConfig)
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+	tb := newTestBridge(t, withConfig(cfg), withConfigPath(path), withRunningSession())
+	return tb.b, tb.prov, tb.llm
+}
+
+func writeReloadConfig(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+}
+
+func lastSent(p *provider.MockProvider, channelID string) string {
+	msgs := p.GetSentMessages()
+	for i := len(msgs) - 1; i >= 0; i-- {
+		if msgs[i].ChannelID == channelID {
+			return msgs[i].Content
+		}
+	}
+	return ""
+}
+
+func TestReload_AddAndRemoveRepos(t *testing.T) {
+	b, discord, llm := reloadBridge(t)
+	discord.Subscribe("channel-123")
+
+	writeReloadConfig(t, b.cfgPath, `repos:
+  other-repo:
+    provider: discord
+    channel_id: channel-456
+    working_dir: /tmp/other
+  new-repo:
+    provider: discord
+    channel_id: channel-789
+    working_dir: /tmp/new
+defaults:
+  admin_channel:
+    provider: discord
+    channel_id: ops
+`)
+	if err := b.reloadConfig(context.Background()); err != nil {
+		t.Fatalf("reloadConfig() error = %v", err)
+	}
+
+	if _, ok := b.cfg.Repos["new-repo"]; !ok {
+		t.Error("new-repo should be added")
+	}
+	if !discord.IsSubscribed("channel-789") {
+		t.Error("new repo's channel should be subscribed")
+	}
+	if _, ok := b.cfg.Repos["test-repo"]; ok {
+		t.Error("test-repo should be removed")
+	}
+	if discord.IsSubscribed("channel-123") {
+		t.Error("removed repo's channel should be unsubscribed")
+	}
+	if _, ok := b.repos["test-repo"]; ok || llm.Running() {
+		t.Error("removed repo's session should be stopped")
+	}
+	if got := lastSent(discord, "channel-123"); !strings.Contains(got, "test-repo was removed from the config") {
+		t.Errorf("removed repo's channel notice = %q", got)
+	}
+	got := lastSent(discord, "ops")
+	if !strings.Contains(got, "removed repo test-repo") || !strings.Contains(got, "added repo new-repo") {
+		t.Errorf("admin notice = %q", got)
+	}
+}
+
+// TestReload_WhileHandlingMessages reloads and changes repos at runtime while
+// prompts, commands and reactions are handled; run with -race.
+func TestReload_WhileHandlingMessages(t *testing.T) {
+	b, discord, _ := reloadBridge(t)
+	b.llmFactory = func(backend, workDir, claudePath string, resume bool) (llm.LLM, error) {
+		return newMockLLM("claude"), nil
+	}
+	b.repos["test-repo"].merger = NewMerger(2 * time.Second)
+	configs := []*config.Config{testConfig(), testConfig()}
+	configs[1].Defaults.OutputThreshold = 10
+	configs[1].Defaults.Reactions.Enabled = new(bool)
+	configs[1].Defaults.Delivery.MaxQueue = 5
+	for _, cfg := range configs {
+		cfg.Defaults.RateLimit.Enabled = new(bool)
+	}
+	b.setRateLimits(configs[0].Defaults.RateLimit)
+
+	ctx := context.Background()
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; ; i++ {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			b.applyConfig(ctx, configs[i%2])
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			_ = b.RuntimeAddRepo("extra-repo", config.RepoConfig{Provider: "discord", ChannelID: "channel-789", WorkingDir: "/tmp/extra"}, false)
+			_ = b.removeRepo("extra-repo")
+		}
+	}()
+
+	func() {
+		defer close(done)
+		for i := 0; i < 50; i++ {
+			for _, content := range []string{"hello", "/status", "/queue", "/list-repos", "/history"} {
+				b.processMessage(ctx, discord, provider.Message{ChannelID: "channel-123", Content: content, Author: "alice", AuthorID: "id-alice", Source: "discord"})
+			}
+			b.processReaction(discord, provider.Reaction{ChannelID: "channel-123", MessageID: "m1", Emoji: "🛑", User: "alice", UserID: "id-alice", Source: "discord"})
+			b.sendOutput(discord, "channel-456", "output")
+		}
+	}()
+	wg.Wait()
+}
+
+func TestReload_InvalidConfigKept(t *testing.T) {
+	b, discord, llm := reloadBridge(t)
+	old := b.cfg
+
+	writeReloadConfig(t, b.cfgPath, "defaults:\n  output_mode: thread\n")
+	if err := b.reloadConfig(context.Background()); err == nil {
+		t.Fatal("reloadConfig() should fail on an invalid config")
+	}
+
+	if b.cfg != old {
+		t.Error("old config should be kept")
+	}
+	if !llm.Running() {
+		t.Error("sessions should keep running")
+	}
+	if got := lastSent(discord, "ops"); !strings.Contains(got, "Config reload failed") || !strings.Contains(got, "invalid output_mode") {
+		t.Errorf("admin notice = %q", got)
+	}
+}
+
+func TestReload_NothingChanged(t *testing.T) {
+	b, discord, _ := reloadBridge(t)
+	if err := b.reloadConfig(context.Background()); err != nil {
+		t.Fatalf("reloadConfig() error = %v", err)
+	}
+	if got := discord.GetSentMessages(); len(got) != 0 {
+		t.Errorf("an unchanged config should not notify, got %+v", got)
+	}
+}
+
+func TestReload_RateLimitsAndIdleTimeout(t *testing.T) {
+	b, discord, _ := reloadBridge(t)
+	if b.userLimiter == nil {
+		t.Fatal("rate limiting should be on by default")
+	}
+
+	writeReloadConfig(t, b.cfgPath, reloadBaseConfig+"  idle_timeout: 1h\n  rate_limit:\n    enabled: false\n")
+	if err := b.reloadConfig(context.Background()); err != nil {
+		t.Fatalf("reloadConfig() error = %v", err)
+	}
+
+	if b.userLimiter != nil || b.channelLimiter != nil {
+		t.Error("disabling rate limits should remove the limiters")
+	}
+	if got := b.currentConfig().Defaults.GetIdleTimeoutDuration(); got != time.Hour {
+		t.Errorf("idle timeout = %v, want 1h", got)
+	}
+	got := lastSent(discord, "ops")
+	if !strings.Contains(got, "rate limits updated") || !strings.Contains(got, "idle timeout 10m0s -> 1h0m0s") {
+		t.Errorf("admin notice = %q", got)
+	}
+}
+
+func TestReload_RestartOnlySettings(t *testing.T) {
+	b, discord, _ := reloadBridge(t)
+
+	writeReloadConfig(t, b.cfgPath, reloadBaseConfig+"  output_threshold: 99\n")
+	if err := b.reloadConfig(context.Background()); err != nil {
+		t.Fatalf("reloadConfig() error = %v", err)
+	}
+	if got := lastSent(discord, "ops"); !strings.Contains(got, "output_threshold changed; restart to apply") {
+		t.Errorf("admin notice = %q", got)
+	}
+}
+
+func TestReload_Plugins(t *testing.T) {
+	b, discord, _ := reloadBridge(t)
+	var started []*provider.MockProvider
+	b.pluginFactory = func(name string, cfg config.PluginConfig, channelIDs []string) provider.Provider {
+		p := provider.NewMockProvider(name)
+		started = append(started, p)
+		return p
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	withPlugin := func(command string) string {
+		return reloadBaseConfig + "providers:\n  plugins:\n    slack:\n      command: " + command + "\n"
+	}
+
+	writeReloadConfig(t, b.cfgPath, withPlugin("slack-v1"))
+	if err := b.reloadConfig(ctx); err != nil {
+		t.Fatalf("reloadConfig() error = %v", err)
+	}
+	if len(started) != 1 || b.providers["slack"] != started[0] || !started[0].WasStartCalled() {
+		t.Fatal("added plugin should be started")
+	}
+
+	// A session with a slack channel follows the plugin across restarts.
+	session := b.repos["test-repo"]
+	session.channels = append(session.channels, channelRef{provider: started[0], channelID: "slack-1"})
+
+	writeReloadConfig(t, b.cfgPath, withPlugin("slack-v2"))
+	if err := b.reloadConfig(ctx); err != nil {
+		t.Fatalf("reloadConfig() error = %v", err)
+	}
+	if len(started) != 2 || !started[0].WasStopCalled() || b.providers["slack"] != started[1] {
+		t.Fatal("changed plugin should be restarted")
+	}
+	if session.channels[1].provider != started[1] {
+		t.Error("session channels should use the restarted plugin")
+	}
+
+	writeReloadConfig(t, b.cfgPath, reloadBaseConfig)
+	if err := b.reloadConfig(ctx); err != nil {
+		t.Fatalf("reloadConfig() error = %v", err)
+	}
+	if _, ok := b.providers["slack"]; ok || !started[1].WasStopCalled() {
+		t.Error("removed plugin should be stopped")
+	}
+	if len(session.channels) != 1 {
+		t.Errorf("channels of a stopped provider should be dropped, got %+v", session.channels)
+	}
+	if got := lastSent(discord, "ops"); !strings.Contains(got, "stopped provider slack") {
+		t.Errorf("admin notice = %q", got)
+	}
+}
+
+func TestReload_PluginStartFailure(t *testing.T) {
+	b, discord, _ := reloadBridge(t)
+	b.pluginFactory = func(name string, cfg config.PluginConfig, channelIDs []string) provider.Provider {
+		p := provider.NewMockProvider(name)
+		p.SetStartError(os.ErrNotExist)
+		return p
+	}
+
+	writeReloadConfig(t, b.cfgPath, reloadBaseConfig+"providers:\n  plugins:\n    slack:\n      command: missing\n")
+	if err := b.reloadConfig(context.Background()); err != nil {
+		t.Fatalf("reloadConfig() error = %v", err)
+	}
+	if _, ok := b.providers["slack"]; ok {
+		t.Error("a plugin that failed to start should not be registered")
+	}
+	if got := lastSent(discord, "ops"); !strings.Contains(got, "provider slack failed to start") {
+		t.Errorf("admin notice = %q", got)
+	}
+}
+
+func TestReload_WatchLoop(t *testing.T) {
+	b, _, _ := reloadBridge(t)
+	b.configPollInterval = 10 * time.Millisecond
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go b.configWatchLoop(ctx)
+
+	waitFor := func(what string, cond func() bool) {
+		t.Helper()
+		deadline := time.Now().Add(2 * time.Second)
+		for time.Now().Before(deadline) {
+			if cond() {
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		t.Fatalf("timed out waiting for %s", what)
+	}
+
+	// Reload (SIGHUP) re-reads the file even if its mtime is unchanged.
+	stat, err := os.Stat(b.cfgPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeReloadConfig(t, b.cfgPath, reloadBaseConfig+"  idle_timeout: 1h\n")
+	if err := os.Chtimes(b.cfgPath, stat.ModTime(), stat.ModTime()); err != nil {
+		t.Fatal(err)
+	}
+	b.Reload()
+	waitFor("signalled reload", func() bool {
+		return b.currentConfig().Defaults.GetIdleTimeoutDuration() == time.Hour
+	})
+
+	// A modified file is picked up by polling.
+	writeReloadConfig(t, b.cfgPath, reloadBaseConfig+"  idle_timeout: 2h\n")
+	future := time.Now().Add(time.Minute)
+	if err := os.Chtimes(b.cfgPath, future, future); err != nil {
+		t.Fatal(err)
+	}
+	waitFor("mtime reload", func() bool {
+		return b.currentConfig().Defaults.GetIdleTimeoutDuration() == 2*time.Hour
+	})
+}
+
+func TestReload_Authz(t *testing.T) {
+	b, discord, _ := reloadBridge(t)
+	if b.authz != nil {
+		t.Fatal("authorization should start disabled")
+	}
+
+	writeReloadConfig(t, b.cfgPath, reloadBaseConfig+"authz:\n  roles:\n    viewer:\n      users: [\"*\"]\n      commands: [status]\n")
+	if err := b.reloadConfig(context.Background()); err != nil {
+		t.Fatalf("reloadConfig() error = %v", err)
+	}
+	if b.authz == nil {
+		t.Fatal("reloaded roles should enable authorization")
+	}
+	if got := lastSent(discord, "ops"); !strings.Contains(got, "authorization roles updated") {
+		t.Errorf("admin notice = %q", got)
+	}
+}
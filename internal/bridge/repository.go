@@ -0,0 +1,139 @@
+package bridge
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+	"strings"
+
+	"github.com/anthropics/llm-bridge/internal/audit"
+	"github.com/anthropics/llm-bridge/internal/git"
+	"github.com/anthropics/llm-bridge/internal/provider"
+	"github.com/anthropics/llm-bridge/internal/router"
+)
+
+// RemoteResolver returns the GitHub "owner/name" of a working directory's
+// origin remote, or "" if it has none.
+type RemoteResolver func(dir string) (string, error)
+
+// resolveGitHubRemote is the default RemoteResolver.
+func resolveGitHubRemote(dir string) (string, error) {
+	url, err := git.RemoteURL(dir, "origin")
+	if err != nil {
+		return "", err
+	}
+	fullName, _ := git.GitHubFullName(url)
+	return fullName, nil
+}
+
+// processRepositoryMessage handles a message from a provider whose channels
+// are not configured per repo (e.g. GitHub comments). It is routed to the
+// repo whose origin remote matches msg.Repository, and its channel is then
+// remembered so commands and output for that channel reach the same repo.
+func (b *Bridge) processRepositoryMessage(ctx context.Context, prov provider.Provider, msg provider.Message) {
+	repoName := b.repoForRepository(msg.Repository)
+	if repoName == "" {
+		slog.Warn("no repo configured for repository", "repository", msg.Repository, "provider", prov.Name())
+		return
+	}
+
+	b.mu.Lock()
+	if b.repoChannels[msg.ChannelID] != repoName {
+		b.repoChannels[msg.ChannelID] = repoName
+		b.markStateChanged()
+	}
+	b.mu.Unlock()
+
+	route := b.parseMessage(msg.Content, repoName)
+	if route.Type == router.RouteToBridge && !b.authzEnabled() {
+		// Comments come from outside the team's chat, so commands such as
+		// /restart or /remove-repo need a role that grants them.
+		slog.Warn("bridge command refused without authz", "user", msg.Author, "command", route.Command, "repo", repoName, "provider", prov.Name())
+		b.auditRefused(prov, msg.ChannelID, msg.Author, msg.AuthorID, route, repoName, audit.OutcomeDenied, "bridge commands from "+prov.Name()+" need authz roles")
+		b.reply(prov, msg.ChannelID, "Bridge commands are disabled here unless authz roles are configured.")
+		return
+	}
+	if !b.authorize(prov, msg.ChannelID, msg.Author, messageSubject(prov, msg), route, repoName) {
+		return
+	}
+	route, ok := b.expandMacro(prov, msg, repoName, route)
+	if !ok {
+		return
+	}
+
+	switch route.Type {
+	case router.RouteToBridge:
+		b.handleBridgeCommand(prov, msg.ChannelID, messageAuthor(msg), route)
+	case router.RouteToLLM:
+		if b.isRateLimited(prov, msg) {
+			return
+		}
+		b.handleLLMMessage(ctx, prov, msg, route)
+	}
+}
+
+// authzEnabled reports whether authorization roles are configured.
+func (b *Bridge) authzEnabled() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.authz != nil
+}
+
+// repoForRepository returns the configured repo (in name order) whose origin
+// remote is fullName, compared case-insensitively as GitHub does. Remotes
+// are resolved outside b.mu and cached per working directory.
+func (b *Bridge) repoForRepository(fullName string) string {
+	b.mu.Lock()
+	dirs := make(map[string]string, len(b.cfg.Repos))
+	for name, repo := range b.cfg.Repos {
+		dirs[name] = repo.WorkingDir
+	}
+	b.mu.Unlock()
+
+	names := make([]string, 0, len(dirs))
+	for name := range dirs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if strings.EqualFold(b.remoteFullName(dirs[name]), fullName) {
+			return name
+		}
+	}
+	return ""
+}
+
+// remoteFullName returns the cached GitHub full name for dir, resolving it
+// on first use. Failures are cached as "" so they are not retried per message.
+func (b *Bridge) remoteFullName(dir string) string {
+	b.mu.Lock()
+	fullName, ok := b.repoRemotes[dir]
+	b.mu.Unlock()
+	if ok {
+		return fullName
+	}
+
+	fullName, err := b.remoteResolver(dir)
+	if err != nil {
+		slog.Debug("resolve git remote failed", "dir", dir, "error", err)
+	}
+
+	b.mu.Lock()
+	b.repoRemotes[dir] = fullName
+	b.mu.Unlock()
+	return fullName
+}
+
+// repositoryRepoForChannelLocked returns the repo a repository-routed
+// channel was last mapped to, or "" if none. Callers must hold b.mu.
+func (b *Bridge) repositoryRepoForChannelLocked(channelID string) string {
+	name, ok := b.repoChannels[channelID]
+	if !ok {
+		return ""
+	}
+	if _, ok := b.cfg.Repos[name]; !ok {
+		return ""
+	}
+	return name
+}
@@ -0,0 +1,126 @@
+package bridge
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/anthropics/llm-bridge/internal/config"
+	"github.com/anthropics/llm-bridge/internal/llm"
+	"github.com/anthropics/llm-bridge/internal/provider"
+)
+
+func githubMessage(repository, content string) provider.Message {
+	return provider.Message{
+		ID:          "1001",
+		ChannelID:   repository + "#42",
+		Content:     content,
+		Author:      "alice",
+		AuthorID:    "7",
+		Source:      "github",
+		MentionsBot: true,
+		Repository:  repository,
+	}
+}
+
+func repositoryTestBridge(t *testing.T) (*Bridge, *int) {
+	t.Helper()
+	b := newTestBridge(t).b
+	calls := 0
+	b.remoteResolver = func(dir string) (string, error) {
+		calls++
+		switch dir {
+		case "/tmp/test":
+			return "acme/widgets", nil
+		case "/tmp/other":
+			return "", errors.New("not a git repository")
+		}
+		return "", nil
+	}
+	return b, &calls
+}
+
+func TestBridge_RepoForRepository(t *testing.T) {
+	b, calls := repositoryTestBridge(t)
+
+	if got := b.repoForRepository("Acme/Widgets"); got != "test-repo" {
+		t.Errorf("repoForRepository() = %q, want test-repo (case-insensitive)", got)
+	}
+	if got := b.repoForRepository("acme/unknown"); got != "" {
+		t.Errorf("repoForRepository(unknown) = %q, want empty", got)
+	}
+	if *calls != 2 {
+		t.Errorf("resolver called %d times, want once per working dir", *calls)
+	}
+}
+
+func TestBridge_RepositoryMessage_RoutesByRemote(t *testing.T) {
+	b, _ := repositoryTestBridge(t)
+	mockLLM := newMockLLM("claude")
+	b.llmFactory = func(backend, workDir, claudePath string, resume bool) (llm.LLM, error) {
+		return mockLLM, nil
+	}
+	mockProv := provider.NewMockProvider("github")
+
+	b.processMessage(context.Background(), mockProv, githubMessage("acme/widgets", "why is CI red?"))
+
+	sent := mockLLM.getSentMessages()
+	if len(sent) != 1 || sent[0].Content != "why is CI red?" {
+		t.Fatalf("LLM messages = %v", sent)
+	}
+	if got := b.repoForChannel("acme/widgets#42"); got != "test-repo" {
+		t.Errorf("repoForChannel(issue) = %q, want test-repo", got)
+	}
+
+	b.mu.Lock()
+	channels := append([]channelRef(nil), b.repos["test-repo"].channels...)
+	b.mu.Unlock()
+	if len(channels) != 1 || channels[0].channelID != "acme/widgets#42" {
+		t.Errorf("session channels = %v, want the issue", channels)
+	}
+}
+
+func TestBridge_RepositoryMessage_BridgeCommand(t *testing.T) {
+	b, _ := repositoryTestBridge(t)
+	b.authz = newPolicy(config.AuthzConfig{
+		Roles: map[string]config.RoleConfig{"dev": {Users: []string{"github:7"}, Commands: []string{"status"}}},
+	})
+	mockProv := provider.NewMockProvider("github")
+
+	b.processMessage(context.Background(), mockProv, githubMessage("acme/widgets", "/status"))
+
+	msgs := mockProv.GetSentMessages()
+	if len(msgs) != 1 || msgs[0].ChannelID != "acme/widgets#42" || msgs[0].Content != "LLM: not running (repo: test-repo)" {
+		t.Errorf("unexpected status reply: %v", msgs)
+	}
+}
+
+func TestBridge_RepositoryMessage_BridgeCommandNeedsAuthz(t *testing.T) {
+	b, _ := repositoryTestBridge(t)
+	path := withAuditLog(t, b)
+	mockProv := provider.NewMockProvider("github")
+
+	b.processMessage(context.Background(), mockProv, githubMessage("acme/widgets", "/restart"))
+
+	msgs := mockProv.GetSentMessages()
+	if len(msgs) != 1 || msgs[0].Content != "Bridge commands are disabled here unless authz roles are configured." {
+		t.Errorf("unexpected reply: %v", msgs)
+	}
+	if recs := auditRecords(t, path); len(recs) != 1 || recs[0].Outcome != "denied" {
+		t.Errorf("audit = %+v, want one denied command", recs)
+	}
+}
+
+func TestBridge_RepositoryMessage_UnknownRepositoryIgnored(t *testing.T) {
+	b, _ := repositoryTestBridge(t)
+	mockProv := provider.NewMockProvider("github")
+
+	b.processMessage(context.Background(), mockProv, githubMessage("someone/else", "hello"))
+
+	if msgs := mockProv.GetSentMessages(); len(msgs) != 0 {
+		t.Errorf("expected no reply for an unmapped repository, got %v", msgs)
+	}
+	if got := b.repoForChannel("someone/else#42"); got != "" {
+		t.Errorf("repoForChannel() = %q, want empty", got)
+	}
+}
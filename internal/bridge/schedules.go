@@ -0,0 +1,314 @@
+package bridge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/anthropics/llm-bridge/internal/audit"
+	"github.com/anthropics/llm-bridge/internal/config"
+	"github.com/anthropics/llm-bridge/internal/cron"
+	"github.com/anthropics/llm-bridge/internal/llm"
+)
+
+// scheduleSource names scheduled runs as a prompt source and in the audit log.
+const scheduleSource = "schedule"
+
+// defaultScheduleInterval is how often the schedule loop checks for due
+// runs. Each minute is checked once however often the loop wakes.
+const defaultScheduleInterval = 15 * time.Second
+
+// errPreviousRunGoing skips a run while the schedule's last prompt is still
+// queued or being worked on.
+var errPreviousRunGoing = errors.New("previous run still going")
+
+// scheduleRun is the outcome of a schedule's most recent run, for /schedules.
+type scheduleRun struct {
+	at      time.Time
+	outcome string
+}
+
+// scheduleAuthor is the author a schedule's prompts are sent as.
+func scheduleAuthor(name string) string {
+	return scheduleSource + ":" + name
+}
+
+// scheduleLoop runs schedules as they come due, until ctx is done.
+func (b *Bridge) scheduleLoop(ctx context.Context) {
+	ticker := time.NewTicker(b.scheduleInterval)
+	defer ticker.Stop()
+
+	var last time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			loc := b.currentConfig().Defaults.Schedules.GetLocation()
+			now := time.Now().In(loc)
+			minute := time.Date(now.Year(), now.Month(), now.Day(), now.Hour(), now.Minute(), 0, 0, loc)
+			if minute.Equal(last) {
+				continue
+			}
+			last = minute
+			b.runDueSchedules(minute)
+		}
+	}
+}
+
+// runDueSchedules runs every unpaused schedule whose cron matches now, in
+// name order. Runs due during quiet hours are skipped.
+func (b *Bridge) runDueSchedules(now time.Time) {
+	cfg := b.currentConfig()
+	quiet, hasQuiet := cfg.Defaults.Schedules.GetQuietHours()
+
+	for _, name := range sortedKeys(cfg.Schedules) {
+		sc := cfg.Schedules[name]
+		spec, err := cron.Parse(sc.Cron)
+		if err != nil || !spec.Matches(now) || b.schedulePaused(name, sc) {
+			continue
+		}
+		if hasQuiet && quiet.Contains(now) {
+			slog.Info("scheduled run skipped in quiet hours", "schedule", name, "repo", sc.Repo, "quiet_hours", quiet.String())
+			b.recordScheduleRun(name, "skipped: quiet hours")
+			continue
+		}
+		b.runSchedule(name, sc)
+	}
+}
+
+// schedulePaused reports whether a schedule is paused: as set by /schedules,
+// or else as configured.
+func (b *Bridge) schedulePaused(name string, sc config.ScheduleConfig) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if paused, ok := b.schedulesPaused[name]; ok {
+		return paused
+	}
+	return sc.Paused
+}
+
+func (b *Bridge) recordScheduleRun(name, outcome string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.scheduleRuns[name] = scheduleRun{at: time.Now(), outcome: outcome}
+}
+
+// runSchedule sends a schedule's prompt to its repo's shared session,
+// starting the session if needed and announcing the run in the session's
+// channels. If the pool is full, the run waits for a session slot. A run is
+// skipped while the schedule's previous prompt is still waiting, queued or
+// being worked on. Returns the run's outcome, and the error if the prompt
+// was not sent or queued.
+func (b *Bridge) runSchedule(name string, sc config.ScheduleConfig) (string, error) {
+	author := scheduleAuthor(name)
+	position, starting, err := b.sendScheduled(name, sc, author)
+	if errors.Is(err, errPreviousRunGoing) {
+		slog.Info("scheduled run skipped, previous run still going", "schedule", name, "repo", sc.Repo)
+		b.recordScheduleRun(name, "skipped: "+err.Error())
+		return "skipped: " + err.Error(), err
+	}
+
+	outcome := "sent"
+	rec := audit.Record{
+		Kind:     audit.KindPrompt,
+		Provider: scheduleSource,
+		Author:   author,
+		Repo:     sc.Repo,
+		Prompt:   sc.Prompt,
+		Outcome:  audit.OutcomeOK,
+	}
+	switch {
+	case err != nil:
+		outcome = "failed: " + err.Error()
+		rec.Outcome, rec.Detail = audit.OutcomeFailed, err.Error()
+		slog.Error("scheduled run failed", "schedule", name, "repo", sc.Repo, "error", err)
+	case starting:
+		outcome = fmt.Sprintf("waiting for a session slot at position %d", position)
+		rec.Outcome, rec.Detail = audit.OutcomeQueued, fmt.Sprintf("waiting for a session slot, position %d", position)
+	case position > 0:
+		outcome = fmt.Sprintf("queued at position %d", position)
+		rec.Outcome, rec.Detail = audit.OutcomeQueued, fmt.Sprintf("position %d", position)
+	}
+	if err == nil {
+		slog.Info("scheduled run", "schedule", name, "repo", sc.Repo, "outcome", outcome)
+	}
+	b.audit(rec)
+	b.recordScheduleRun(name, outcome)
+	return outcome, err
+}
+
+// sendScheduled does the work of runSchedule. It returns the prompt's queue
+// position, or 0 if it was sent. starting is set if the pool was full and
+// position is that of the session among the pending starts.
+func (b *Bridge) sendScheduled(name string, sc config.ScheduleConfig, author string) (position int, starting bool, err error) {
+	b.mu.Lock()
+	repo, ok := b.cfg.Repos[sc.Repo]
+	prov, running := b.providers[repo.Provider]
+	ctx := b.scheduleCtx
+	user := sessionUserFor(repo, sessionUser{})
+	inFlight := b.scheduleInFlightLocked(sessionKey(sc.Repo, user), author)
+	b.mu.Unlock()
+
+	switch {
+	case !ok:
+		return 0, false, fmt.Errorf("unknown repo %q", sc.Repo)
+	case !running || repo.ChannelID == "":
+		return 0, false, fmt.Errorf("repo %q has no running channel to post output to", sc.Repo)
+	case inFlight:
+		return 0, false, errPreviousRunGoing
+	}
+
+	announcement := fmt.Sprintf("⏰ Scheduled run %s: %s", name, sc.Prompt)
+	prompt := queuedPrompt{
+		msg:       llm.Message{Source: scheduleSource},
+		author:    author,
+		text:      sc.Prompt,
+		prov:      prov,
+		channelID: repo.ChannelID,
+	}
+	session, err := b.getOrCreateSession(ctx, sc.Repo, repo, prov, repo.ChannelID, user)
+	var full *poolFullError
+	if errors.As(err, &full) {
+		b.mu.Lock()
+		position = b.queueStartLocked(pendingStart{
+			ctx: ctx, repoName: sc.Repo, prov: prov, channelID: repo.ChannelID, user: user,
+			prompts: []queuedPrompt{prompt},
+		})
+		b.retryPendingStartsLocked()
+		b.mu.Unlock()
+		b.reply(prov, repo.ChannelID, announcement)
+		b.reply(prov, repo.ChannelID, startNotice(position, full.max))
+		return position, true, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("start llm: %w", err)
+	}
+
+	b.mu.Lock()
+	channels := make([]channelRef, len(session.channels))
+	copy(channels, session.channels)
+	b.mu.Unlock()
+	if len(channels) == 0 {
+		return 0, false, fmt.Errorf("session of repo %q stopped", sc.Repo)
+	}
+	for _, ch := range channels {
+		b.reply(ch.provider, ch.channelID, announcement)
+	}
+
+	prompt.msg.Content = session.merger.FormatMessage(scheduleSource, sc.Prompt)
+	prompt.prov, prompt.channelID = channels[0].provider, channels[0].channelID
+	position, err = b.submit(session, prompt)
+	return position, false, err
+}
+
+// scheduleInFlightLocked reports whether author's prompt is waiting for the
+// session with key to start, is queued in it or is the one its LLM is
+// working on. Callers must hold b.mu.
+func (b *Bridge) scheduleInFlightLocked(key, author string) bool {
+	for i := range b.pendingStarts {
+		if b.pendingStarts[i].key() != key {
+			continue
+		}
+		for _, p := range b.pendingStarts[i].prompts {
+			if p.author == author {
+				return true
+			}
+		}
+	}
+	session, ok := b.repos[key]
+	if !ok || session.llm == nil || !session.llm.Running() {
+		return false
+	}
+	if busy, _, busyWith := session.busy.snapshot(); busy && busyWith == author {
+		return true
+	}
+	for _, p := range session.queue {
+		if p.author == author {
+			return true
+		}
+	}
+	return false
+}
+
+// handleSchedules runs /schedules: list schedules, or pause, resume or
+// trigger one by name.
+func (b *Bridge) handleSchedules(args string) commandResult {
+	fields := strings.Fields(args)
+	if len(fields) == 0 {
+		return commandOK(b.listSchedules())
+	}
+	if len(fields) != 2 {
+		return commandFailed("Usage: /schedules [pause|resume|trigger <name>]")
+	}
+
+	action, name := fields[0], fields[1]
+	sc, ok := b.currentConfig().Schedules[name]
+	if !ok {
+		return commandFailed(fmt.Sprintf("Error: unknown schedule %q", name))
+	}
+	switch action {
+	case "pause", "resume":
+		paused := action == "pause"
+		b.mu.Lock()
+		b.schedulesPaused[name] = paused
+		b.markStateChanged()
+		b.mu.Unlock()
+		if paused {
+			return commandOK(fmt.Sprintf("Paused schedule %s", name))
+		}
+		return commandOK(fmt.Sprintf("Resumed schedule %s", name))
+	case "trigger":
+		outcome, err := b.runSchedule(name, sc)
+		switch {
+		case errors.Is(err, errPreviousRunGoing):
+			return commandFailed(fmt.Sprintf("Skipped schedule %s: %v", name, err))
+		case err != nil:
+			return commandFailed(fmt.Sprintf("Failed to run schedule %s: %v", name, err))
+		}
+		return commandOK(fmt.Sprintf("Ran schedule %s: %s", name, outcome))
+	default:
+		return commandFailed("Usage: /schedules [pause|resume|trigger <name>]")
+	}
+}
+
+// listSchedules describes every schedule with its next and last run.
+func (b *Bridge) listSchedules() string {
+	cfg := b.currentConfig()
+	if len(cfg.Schedules) == 0 {
+		return "No schedules configured"
+	}
+	loc := cfg.Defaults.Schedules.GetLocation()
+	now := time.Now().In(loc)
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Schedules (%s", loc)
+	if quiet, ok := cfg.Defaults.Schedules.GetQuietHours(); ok {
+		fmt.Fprintf(&sb, ", quiet hours %s", quiet)
+	}
+	sb.WriteString("):")
+
+	for _, name := range sortedKeys(cfg.Schedules) {
+		sc := cfg.Schedules[name]
+		fmt.Fprintf(&sb, "\n  %s → %s [%s]", name, sc.Repo, sc.Cron)
+		if b.schedulePaused(name, sc) {
+			sb.WriteString(" paused")
+		} else if spec, err := cron.Parse(sc.Cron); err == nil {
+			if next := spec.Next(now); !next.IsZero() {
+				fmt.Fprintf(&sb, " next %s", next.Format("Mon Jan 2 15:04"))
+			}
+		}
+
+		b.mu.Lock()
+		last, ran := b.scheduleRuns[name]
+		b.mu.Unlock()
+		if ran {
+			fmt.Fprintf(&sb, "; last %s %s", last.at.In(loc).Format("Mon Jan 2 15:04"), last.outcome)
+		}
+		fmt.Fprintf(&sb, "\n    %s", truncateLine(sc.Prompt, queuePreviewLen))
+	}
+	return sb.String()
+}
@@ -0,0 +1,273 @@
+package bridge
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anthropics/llm-bridge/internal/audit"
+	"github.com/anthropics/llm-bridge/internal/config"
+	"github.com/anthropics/llm-bridge/internal/provider"
+	"github.com/anthropics/llm-bridge/internal/router"
+)
+
+// monday7am matches the test schedule "0 7 * * 1-5".
+var monday7am = time.Date(2026, 10, 19, 7, 0, 0, 0, time.Local)
+
+// scheduleBridge returns a bridge with a running Discord provider and a
+// weekday-morning schedule for test-repo.
+func scheduleBridge(t *testing.T) (*Bridge, *provider.MockProvider, *[]*mockLLM) {
+	t.Helper()
+	b, _, started := adminBridge(t)
+	b.cfg.Schedules = map[string]config.ScheduleConfig{
+		"nightly": {Repo: "test-repo", Cron: "0 7 * * 1-5", Prompt: "run the tests and summarise failures"},
+	}
+	return b, b.providers["discord"].(*provider.MockProvider), started
+}
+
+func TestSchedules_RunsWhenDue(t *testing.T) {
+	b, prov, started := scheduleBridge(t)
+
+	b.runDueSchedules(monday7am.Add(time.Minute))
+	if len(*started) != 0 {
+		t.Fatal("schedule should not run at a minute it does not match")
+	}
+
+	b.runDueSchedules(monday7am)
+	if len(*started) != 1 {
+		t.Fatalf("due schedule should start the session, started %d", len(*started))
+	}
+	msgs := (*started)[0].getSentMessages()
+	if len(msgs) != 1 || msgs[0].Source != scheduleSource || !strings.Contains(msgs[0].Content, "run the tests") {
+		t.Errorf("LLM got %+v", msgs)
+	}
+	if sent := prov.GetSentMessages(); len(sent) != 1 || sent[0].ChannelID != "channel-123" || !strings.Contains(sent[0].Content, "Scheduled run nightly") {
+		t.Errorf("channel got %+v", sent)
+	}
+	if run := b.scheduleRuns["nightly"]; run.outcome != "sent" {
+		t.Errorf("last run = %+v", run)
+	}
+}
+
+func TestSchedules_QuietHours(t *testing.T) {
+	b, _, started := scheduleBridge(t)
+	b.cfg.Defaults.Schedules.QuietHours = "22:00-07:30"
+
+	b.runDueSchedules(monday7am)
+	if len(*started) != 0 {
+		t.Error("run due in quiet hours should be skipped")
+	}
+	if run := b.scheduleRuns["nightly"]; run.outcome != "skipped: quiet hours" {
+		t.Errorf("last run = %+v", run)
+	}
+
+	// A manual trigger is not held back by quiet hours.
+	if got := b.handleSchedules("trigger nightly").response; got != "Ran schedule nightly: sent" {
+		t.Errorf("trigger = %q", got)
+	}
+}
+
+func TestSchedules_SkipsWhilePreviousRunGoing(t *testing.T) {
+	b, prov, started := scheduleBridge(t)
+
+	b.runDueSchedules(monday7am)
+	b.runDueSchedules(monday7am.AddDate(0, 0, 1))
+	if msgs := (*started)[0].getSentMessages(); len(msgs) != 1 {
+		t.Errorf("second run should be skipped while the first is going, LLM got %d prompts", len(msgs))
+	}
+	if run := b.scheduleRuns["nightly"]; run.outcome != "skipped: previous run still going" {
+		t.Errorf("last run = %+v", run)
+	}
+
+	// Another user's prompt in flight does not skip the run; it queues.
+	endTurns(b)
+	sendPrompt(b, prov, "alice", "something else")
+	if got := b.handleSchedules("trigger nightly").response; got != "Ran schedule nightly: queued at position 1" {
+		t.Errorf("trigger = %q", got)
+	}
+	if got := b.handleSchedules("trigger nightly").response; got != "Skipped schedule nightly: previous run still going" {
+		t.Errorf("trigger with run queued = %q", got)
+	}
+}
+
+func TestSchedules_WaitsForSessionSlot(t *testing.T) {
+	b, prov, started := scheduleBridge(t)
+	b.cfg.Defaults.MaxSessions = 1
+	b.cfg.Repos["other-repo"] = config.RepoConfig{Provider: "discord", ChannelID: "channel-456", WorkingDir: "/tmp/other"}
+	sendMessage(b, prov, "channel-456", "busy work")
+
+	if got := b.handleSchedules("trigger nightly").response; got != "Ran schedule nightly: waiting for a session slot at position 1" {
+		t.Fatalf("trigger with pool full = %q", got)
+	}
+	if got := lastSent(prov, "channel-123"); !strings.Contains(got, "session start queued (position 1)") {
+		t.Errorf("channel got %q, want the start notice", got)
+	}
+	if got := b.handleSchedules("trigger nightly").response; got != "Skipped schedule nightly: previous run still going" {
+		t.Errorf("trigger while waiting = %q", got)
+	}
+
+	finishTurn(b, "other-repo")
+	b.startPendingSessions()
+	if len(*started) != 2 {
+		t.Fatalf("started %d LLMs, want the schedule's session once a slot frees", len(*started))
+	}
+	msgs := (*started)[1].getSentMessages()
+	if len(msgs) != 1 || msgs[0].Source != scheduleSource || !strings.Contains(msgs[0].Content, "run the tests") {
+		t.Errorf("LLM got %+v", msgs)
+	}
+}
+
+func TestSchedules_PauseAndResume(t *testing.T) {
+	b, _, started := scheduleBridge(t)
+
+	if got := b.handleSchedules("pause nightly").response; got != "Paused schedule nightly" {
+		t.Errorf("pause = %q", got)
+	}
+	b.runDueSchedules(monday7am)
+	if len(*started) != 0 {
+		t.Error("paused schedule should not run")
+	}
+	if st := b.snapshotState(); !st.Schedules["nightly"] {
+		t.Errorf("pause should be saved, state = %v", st.Schedules)
+	}
+
+	if got := b.handleSchedules("resume nightly").response; got != "Resumed schedule nightly" {
+		t.Errorf("resume = %q", got)
+	}
+	b.runDueSchedules(monday7am)
+	if len(*started) != 1 {
+		t.Error("resumed schedule should run")
+	}
+}
+
+func TestSchedules_ConfiguredPaused(t *testing.T) {
+	b, _, started := scheduleBridge(t)
+	sc := b.cfg.Schedules["nightly"]
+	sc.Paused = true
+	b.cfg.Schedules["nightly"] = sc
+
+	b.runDueSchedules(monday7am)
+	if len(*started) != 0 {
+		t.Error("schedule configured paused should not run")
+	}
+	b.handleSchedules("resume nightly")
+	b.runDueSchedules(monday7am)
+	if len(*started) != 1 {
+		t.Error("/schedules resume should override the config")
+	}
+}
+
+func TestSchedules_Command(t *testing.T) {
+	b, _, _ := scheduleBridge(t)
+	b.cfg.Schedules["weekly"] = config.ScheduleConfig{Repo: "removed-repo", Cron: "@weekly", Prompt: "update dependencies", Paused: true}
+	b.cfg.Defaults.Schedules.QuietHours = "22:00-06:00"
+
+	list := b.handleSchedules("").response
+	for _, want := range []string{
+		"quiet hours 22:00-06:00",
+		"nightly → test-repo [0 7 * * 1-5] next ",
+		"    run the tests and summarise failures",
+		"weekly → removed-repo [@weekly] paused",
+	} {
+		if !strings.Contains(list, want) {
+			t.Errorf("list missing %q:\n%s", want, list)
+		}
+	}
+
+	b.handleSchedules("trigger nightly")
+	if list := b.handleSchedules("").response; !strings.Contains(list, "; last ") || !strings.Contains(list, " sent") {
+		t.Errorf("list should show the last run:\n%s", list)
+	}
+
+	for args, want := range map[string]string{
+		"pause nope":     `Error: unknown schedule "nope"`,
+		"explode weekly": "Usage: /schedules [pause|resume|trigger <name>]",
+		"pause":          "Usage: /schedules [pause|resume|trigger <name>]",
+	} {
+		if got := b.handleSchedules(args).response; got != want {
+			t.Errorf("/schedules %s = %q, want %q", args, got, want)
+		}
+	}
+
+	if got := b.handleSchedules("trigger weekly").response; got != `Failed to run schedule weekly: unknown repo "removed-repo"` {
+		t.Errorf("trigger weekly = %q", got)
+	}
+
+	if got := New(testConfig(), "").handleSchedules("").response; got != "No schedules configured" {
+		t.Errorf("no schedules = %q", got)
+	}
+}
+
+func TestSchedules_Audited(t *testing.T) {
+	b, prov, _ := scheduleBridge(t)
+	path := withAuditLog(t, b)
+
+	b.handleBridgeCommand(prov, "channel-123", sessionUser{id: "id-alice", name: "alice"}, router.Parse("/schedules trigger nightly"))
+
+	recs := auditRecords(t, path)
+	if len(recs) != 2 {
+		t.Fatalf("got %d records, want 2: %+v", len(recs), recs)
+	}
+	if r := recs[0]; r.Kind != audit.KindPrompt || r.Provider != scheduleSource || r.Author != "schedule:nightly" || r.Repo != "test-repo" || r.Outcome != audit.OutcomeOK {
+		t.Errorf("prompt record = %+v", r)
+	}
+	if r := recs[1]; r.Command != "schedules" || r.Args != "trigger nightly" || r.Author != "alice" || r.Outcome != audit.OutcomeOK {
+		t.Errorf("command record = %+v", r)
+	}
+}
+
+func TestSchedules_AuthorizedAgainstScheduleRepo(t *testing.T) {
+	b, prov, started := scheduleBridge(t)
+	b.authz = newPolicy(config.AuthzConfig{Roles: map[string]config.RoleConfig{
+		"dev": {Users: []string{"id-dana"}, Commands: []string{"schedules"}, Repos: []string{"other-repo"}, Prompt: true},
+	}})
+	send := func(content string) {
+		b.processMessage(context.Background(), prov, provider.Message{ChannelID: "channel-456", Content: content, Author: "dana", AuthorID: "id-dana", Source: "discord"})
+	}
+
+	// dana may use /schedules in other-repo's channel, but nightly belongs
+	// to test-repo.
+	for _, action := range []string{"pause", "resume", "trigger"} {
+		send("/schedules " + action + " nightly")
+		if got := lastSent(prov, "channel-456"); got != "Permission denied: your role (dev) may not use /schedules in test-repo." {
+			t.Errorf("%s denial = %q", action, got)
+		}
+	}
+	if b.schedulePaused("nightly", b.currentConfig().Schedules["nightly"]) {
+		t.Error("pause without permission in the schedule's repo should not pause it")
+	}
+	if len(*started) != 0 {
+		t.Fatal("trigger without permission in the schedule's repo should not run it")
+	}
+
+	// Listing is checked against the channel's repo.
+	send("/schedules")
+	if got := lastSent(prov, "channel-456"); !strings.Contains(got, "nightly") {
+		t.Errorf("list = %q", got)
+	}
+}
+
+func TestSchedules_TriggerNeedsPromptInScheduleRepo(t *testing.T) {
+	b, prov, started := scheduleBridge(t)
+	b.authz = newPolicy(config.AuthzConfig{Roles: map[string]config.RoleConfig{
+		"dev": {Users: []string{"id-dana"}, Commands: []string{"schedules"}},
+	}})
+	trigger := provider.Message{ChannelID: "channel-456", Content: "/schedules trigger nightly", Author: "dana", AuthorID: "id-dana", Source: "discord"}
+
+	b.processMessage(context.Background(), prov, trigger)
+	if len(*started) != 0 {
+		t.Fatal("trigger without prompt permission in the schedule's repo should not run it")
+	}
+	if got := lastSent(prov, "channel-456"); got != "Permission denied: your role (dev) may not send prompts in test-repo." {
+		t.Errorf("denial = %q", got)
+	}
+
+	b.authz = newPolicy(config.AuthzConfig{Roles: map[string]config.RoleConfig{
+		"dev": {Users: []string{"id-dana"}, Commands: []string{"schedules"}, Prompt: true},
+	}})
+	b.processMessage(context.Background(), prov, trigger)
+	if len(*started) != 1 {
+		t.Errorf("trigger with prompt permission in test-repo should run it, started %d", len(*started))
+	}
+}
@@ -0,0 +1,140 @@
+package bridge
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/anthropics/llm-bridge/internal/config"
+	"github.com/anthropics/llm-bridge/internal/provider"
+)
+
+// sessionUser is the author a per_user session belongs to. The zero value
+// stands for a repo's shared session.
+type sessionUser struct {
+	id   string // provider AuthorID
+	name string // display name, for status and addressing output
+}
+
+// messageAuthor returns the author of a message as a sessionUser.
+func messageAuthor(msg provider.Message) sessionUser {
+	return sessionUser{id: msg.AuthorID, name: msg.Author}
+}
+
+// sessionKey returns the b.repos key of a repo's session for a user.
+func sessionKey(repoName string, user sessionUser) string {
+	if user.id == "" {
+		return repoName
+	}
+	return repoName + "@" + user.id
+}
+
+// key returns the session's b.repos key.
+func (s *repoSession) key() string {
+	return sessionKey(s.name, s.user)
+}
+
+// sessionUserFor returns whose session an author uses in a repo: their own
+// in per_user repos, the shared one otherwise. Authors without an ID (the
+// terminal) always use the shared session.
+func sessionUserFor(repo config.RepoConfig, author sessionUser) sessionUser {
+	if !repo.PerUser() || author.id == "" {
+		return sessionUser{}
+	}
+	return author
+}
+
+// sessionKeyForChannel resolves the repo bound to a channel and the key of
+// the session author uses there. repoName is "" if no repo is bound.
+func (b *Bridge) sessionKeyForChannel(channelID string, author sessionUser) (repoName, key string, user sessionUser) {
+	repoName, repo, ok := b.repoConfigForChannel(channelID)
+	if !ok {
+		return "", "", sessionUser{}
+	}
+	user = sessionUserFor(repo, author)
+	if author.id != "" && b.isDMChannel(channelID) {
+		user = author // DMs always use the author's own session
+	}
+	return repoName, sessionKey(repoName, user), user
+}
+
+// repoLabel describes a session's repo (and user) for status messages.
+func repoLabel(repoName string, user sessionUser) string {
+	if user.id == "" {
+		return repoName
+	}
+	return fmt.Sprintf("%s, user: %s", repoName, user.name)
+}
+
+// repoActiveLocked reports whether any session of a repo, shared or
+// per-user, is running. Callers must hold b.mu.
+func (b *Bridge) repoActiveLocked(repoName string) bool {
+	for _, session := range b.repos {
+		if session.name == repoName && session.llm != nil && session.llm.Running() {
+			return true
+		}
+	}
+	return false
+}
+
+// userWorkingDir returns the directory a session runs in. Per-user sessions
+// of repos with user_worktrees get their own worktree next to the repo, on
+// branch llm-bridge/<repo>/<user>, created the first time it is needed and
+// reused afterwards. Callers must not hold b.mu: creating a worktree runs
+// git.
+func (b *Bridge) userWorkingDir(repoName string, repo config.RepoConfig, user sessionUser) (string, error) {
+	wtDir := userWorktreeDir(repo, user)
+	if wtDir == repo.WorkingDir {
+		return wtDir, nil
+	}
+
+	// Racing first prompts from one user must not both create it.
+	b.worktreeMu.Lock()
+	defer b.worktreeMu.Unlock()
+	if _, err := os.Stat(wtDir); err == nil {
+		return wtDir, nil
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return "", fmt.Errorf("check user worktree: %w", err)
+	}
+
+	branch := "llm-bridge/" + repoName + "/" + userSlug(user.id)
+	if err := b.addWorktree(repo.WorkingDir, wtDir, branch); err != nil {
+		return "", fmt.Errorf("create user worktree: %w", err)
+	}
+	return wtDir, nil
+}
+
+// userWorktreeDir returns the directory userWorkingDir uses for a session,
+// without creating it.
+func userWorktreeDir(repo config.RepoConfig, user sessionUser) string {
+	if user.id == "" || !repo.UserWorktrees {
+		return repo.WorkingDir
+	}
+	return filepath.Join(repo.WorkingDir+"-users", userSlug(user.id))
+}
+
+// userSlug makes an author ID safe for use in paths and branch names.
+func userSlug(id string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_':
+			return r
+		}
+		return '-'
+	}, id)
+}
+
+// addressOutput prefixes per-user session output with its owner so people
+// sharing a channel can tell whose answer it is. Providers that can mention
+// users do so; others get the display name.
+func addressOutput(prov provider.Provider, user sessionUser, content string) string {
+	if user.id == "" {
+		return content
+	}
+	if m, ok := prov.(provider.Mentioner); ok {
+		return m.Mention(user.id) + "\n" + content
+	}
+	return "@" + user.name + "\n" + content
+}
@@ -0,0 +1,253 @@
+package bridge
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/anthropics/llm-bridge/internal/config"
+	"github.com/anthropics/llm-bridge/internal/llm"
+	"github.com/anthropics/llm-bridge/internal/provider"
+)
+
+// perUserBridge returns a bridge whose test-repo runs per_user sessions and
+// an LLM factory that records the working dir of each LLM it creates.
+func perUserBridge(t *testing.T, mutate func(*config.RepoConfig)) (*Bridge, *[]*mockLLM, *[]string) {
+	t.Helper()
+	cfg := testConfig()
+	repo := cfg.Repos["test-repo"]
+	repo.SessionMode = config.SessionModePerUser
+	if mutate != nil {
+		mutate(&repo)
+	}
+	cfg.Repos["test-repo"] = repo
+	tb := newTestBridge(t, withConfig(cfg))
+	return tb.b, &tb.started, &tb.dirs
+}
+
+func userMessage(authorID, content string) provider.Message {
+	return provider.Message{
+		ChannelID: "channel-123",
+		Content:   content,
+		Author:    "user-" + authorID,
+		AuthorID:  authorID,
+		Source:    "discord",
+	}
+}
+
+func TestSessions_PerUserIsolation(t *testing.T) {
+	b, llms, _ := perUserBridge(t, nil)
+	mockProv := provider.NewMockProvider("discord")
+
+	b.processMessage(context.Background(), mockProv, userMessage("u1", "fix the bug"))
+	b.processMessage(context.Background(), mockProv, userMessage("u2", "write docs"))
+	endTurns(b)
+	b.processMessage(context.Background(), mockProv, userMessage("u1", "and add a test"))
+
+	if len(*llms) != 2 {
+		t.Fatalf("expected one LLM per user, got %d", len(*llms))
+	}
+	if got := (*llms)[0].getSentMessages(); len(got) != 2 {
+		t.Errorf("u1 session got %d messages, want 2", len(got))
+	}
+	if got := (*llms)[1].getSentMessages(); len(got) != 1 || got[0].Content != "write docs" {
+		t.Errorf("u2 session messages = %v", got)
+	}
+
+	b.mu.Lock()
+	_, shared := b.repos["test-repo"]
+	u1 := b.repos["test-repo@u1"]
+	b.mu.Unlock()
+	if shared {
+		t.Error("no shared session should exist in per_user mode")
+	}
+	if u1 == nil || u1.user.name != "user-u1" {
+		t.Fatalf("u1 session = %+v", u1)
+	}
+}
+
+func TestSessions_SharedModeUnchanged(t *testing.T) {
+	b := New(testConfig(), "")
+	created := 0
+	b.llmFactory = func(backend, workDir, claudePath string, resume bool) (llm.LLM, error) {
+		created++
+		return newMockLLM("claude"), nil
+	}
+	mockProv := provider.NewMockProvider("discord")
+
+	b.processMessage(context.Background(), mockProv, userMessage("u1", "a"))
+	b.processMessage(context.Background(), mockProv, userMessage("u2", "b"))
+
+	if created != 1 {
+		t.Errorf("shared repo created %d LLMs, want 1", created)
+	}
+}
+
+func TestSessions_OutputAddressedToOwner(t *testing.T) {
+	b, _, _ := perUserBridge(t, nil)
+	mockProv := provider.NewMockProvider("discord")
+
+	b.processMessage(context.Background(), mockProv, userMessage("u1", "hi"))
+	b.mu.Lock()
+	session := b.repos["test-repo@u1"]
+	b.mu.Unlock()
+
+	b.broadcastOutput(session, "answer")
+
+	msgs := mockProv.GetSentMessages()
+	if len(msgs) != 1 || msgs[0].Content != "@user-u1\nanswer" {
+		t.Errorf("expected output addressed to u1, got %+v", msgs)
+	}
+}
+
+func TestSessions_CommandsActOnOwnSession(t *testing.T) {
+	b, llms, _ := perUserBridge(t, nil)
+	mockProv := provider.NewMockProvider("discord")
+
+	b.processMessage(context.Background(), mockProv, userMessage("u1", "hi"))
+	b.processMessage(context.Background(), mockProv, userMessage("u2", "hi"))
+
+	u1 := sessionUser{id: "u1", name: "user-u1"}
+	u3 := sessionUser{id: "u3", name: "user-u3"}
+	if status := b.getStatus("channel-123", u1).response; !strings.Contains(status, "running (repo: test-repo, user: user-u1") {
+		t.Errorf("u1 status = %q", status)
+	}
+	if status := b.getStatus("channel-123", u3).response; status != "LLM: not running (repo: test-repo, user: user-u3)" {
+		t.Errorf("u3 status = %q", status)
+	}
+
+	b.processMessage(context.Background(), mockProv, userMessage("u1", "/restart"))
+	if (*llms)[0].Running() {
+		t.Error("u1's LLM should be stopped by u1's /restart")
+	}
+	if !(*llms)[1].Running() {
+		t.Error("u2's LLM should be unaffected by u1's /restart")
+	}
+}
+
+func TestSessions_IdleTimeoutPerUser(t *testing.T) {
+	b, llms, _ := perUserBridge(t, nil)
+	mockProv := provider.NewMockProvider("discord")
+
+	b.processMessage(context.Background(), mockProv, userMessage("u1", "hi"))
+	b.processMessage(context.Background(), mockProv, userMessage("u2", "hi"))
+	finishTurn(b, "test-repo@u1")
+	finishTurn(b, "test-repo@u2")
+	(*llms)[0].setLastActivity(time.Now().Add(-time.Hour))
+
+	b.checkIdleTimeouts(10 * time.Minute)
+
+	b.mu.Lock()
+	_, u1 := b.repos["test-repo@u1"]
+	_, u2 := b.repos["test-repo@u2"]
+	b.mu.Unlock()
+	if u1 || !u2 {
+		t.Errorf("after idle check: u1 present = %v, u2 present = %v; want only u2", u1, u2)
+	}
+
+	msgs := mockProv.GetSentMessages()
+	if len(msgs) != 1 || msgs[0].Content != "LLM session for user-u1 stopped due to idle timeout (10m0s)" {
+		t.Errorf("idle notice = %+v", msgs)
+	}
+}
+
+func TestSessions_UserWorktrees(t *testing.T) {
+	repoDir := filepath.Join(t.TempDir(), "repo")
+	b, _, dirs := perUserBridge(t, func(r *config.RepoConfig) {
+		r.WorkingDir = repoDir
+		r.UserWorktrees = true
+	})
+	var added []string
+	b.addWorktree = func(src, wtDir, branch string) error {
+		added = append(added, src+" "+wtDir+" "+branch)
+		return os.MkdirAll(wtDir, 0o755)
+	}
+	mockProv := provider.NewMockProvider("discord")
+
+	b.processMessage(context.Background(), mockProv, userMessage("u1", "hi"))
+	b.restartLLM("channel-123", sessionUser{id: "u1"})
+	b.processMessage(context.Background(), mockProv, userMessage("u1", "again"))
+
+	wantDir := filepath.Join(repoDir+"-users", "u1")
+	if len(added) != 1 || added[0] != repoDir+" "+wantDir+" llm-bridge/test-repo/u1" {
+		t.Errorf("worktrees added = %v, want one for u1 reused on restart", added)
+	}
+	if len(*dirs) != 2 || (*dirs)[0] != wantDir || (*dirs)[1] != wantDir {
+		t.Errorf("LLM working dirs = %v, want %s", *dirs, wantDir)
+	}
+}
+
+func TestSessions_UserWorktreeCreatedOutsideLock(t *testing.T) {
+	repoDir := filepath.Join(t.TempDir(), "repo")
+	b, llms, _ := perUserBridge(t, func(r *config.RepoConfig) {
+		r.WorkingDir = repoDir
+		r.UserWorktrees = true
+	})
+	var mu sync.Mutex
+	adds := 0
+	adding := make(chan struct{}, 2)
+	release := make(chan struct{})
+	b.addWorktree = func(src, wtDir, branch string) error {
+		adding <- struct{}{}
+		<-release
+		mu.Lock()
+		adds++
+		mu.Unlock()
+		return os.MkdirAll(wtDir, 0o755)
+	}
+	mockProv := provider.NewMockProvider("discord")
+
+	var wg sync.WaitGroup
+	for _, content := range []string{"first", "second"} {
+		wg.Add(1)
+		go func(content string) {
+			defer wg.Done()
+			b.processMessage(context.Background(), mockProv, userMessage("u1", content))
+		}(content)
+	}
+	<-adding
+
+	status := make(chan string)
+	go func() { status <- b.getStatus("channel-456", sessionUser{}).response }()
+	select {
+	case <-status:
+	case <-time.After(2 * time.Second):
+		t.Fatal("creating a user worktree blocked the bridge")
+	}
+
+	close(release)
+	wg.Wait()
+	if adds != 1 {
+		t.Errorf("worktree created %d times, want once for racing first prompts", adds)
+	}
+	if len(*llms) != 1 {
+		t.Errorf("started %d LLMs, want one session for u1", len(*llms))
+	}
+}
+
+func TestSessions_AuthorWithoutIDUsesShared(t *testing.T) {
+	b, _, _ := perUserBridge(t, nil)
+	mockProv := provider.NewMockProvider("terminal")
+
+	b.processMessage(context.Background(), mockProv, provider.Message{ChannelID: "channel-123", Content: "hi"})
+
+	b.mu.Lock()
+	_, shared := b.repos["test-repo"]
+	b.mu.Unlock()
+	if !shared {
+		t.Error("messages without an author ID should use the shared session")
+	}
+}
+
+func TestUserSlug(t *testing.T) {
+	if got := userSlug("123456789"); got != "123456789" {
+		t.Errorf("userSlug(id) = %q", got)
+	}
+	if got := userSlug("../a b@c"); got != "---a-b-c" {
+		t.Errorf("userSlug(unsafe) = %q", got)
+	}
+}
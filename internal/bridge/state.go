@@ -0,0 +1,248 @@
+package bridge
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"sort"
+	"time"
+
+	"github.com/anthropics/llm-bridge/internal/config"
+	"github.com/anthropics/llm-bridge/internal/llm"
+	"github.com/anthropics/llm-bridge/internal/state"
+)
+
+// statePath returns the state file path, or "" if the bridge has no config
+// file to keep it next to (e.g. in tests).
+func (b *Bridge) statePath() string {
+	if b.cfgPath == "" {
+		return ""
+	}
+	return config.ResolvePath(b.cfgPath, b.currentConfig().Defaults.GetStateFile())
+}
+
+// markStateChanged asks the state loop to rewrite the state file. It never
+// blocks, so it is safe under b.mu.
+func (b *Bridge) markStateChanged() {
+	select {
+	case b.stateDirty <- struct{}{}:
+	default:
+	}
+}
+
+// stateLoop writes the state file whenever it changes, until the bridge stops.
+func (b *Bridge) stateLoop() {
+	for {
+		select {
+		case <-b.stopCh:
+			return
+		case <-b.stateDirty:
+			b.saveState()
+		}
+	}
+}
+
+// saveState writes the current state file. Once the bridge is stopping it
+// does nothing, so the sessions Stop recorded as active are not erased as
+// they shut down.
+func (b *Bridge) saveState() {
+	path := b.statePath()
+	if path == "" {
+		return
+	}
+
+	b.stateMu.Lock()
+	defer b.stateMu.Unlock()
+
+	select {
+	case <-b.stopCh:
+		return
+	default:
+	}
+
+	if err := state.Save(path, b.snapshotState()); err != nil {
+		slog.Warn("save state failed", "path", path, "error", err)
+	}
+}
+
+// snapshotState captures running sessions (in key order), their channels and
+// queues, and users' selections.
+func (b *Bridge) snapshotState() *state.State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	st := &state.State{
+		SavedAt:      time.Now().UTC(),
+		TerminalRepo: b.terminalRepoName,
+		DMRepos:      maps.Clone(b.dmRepos),
+		DMChannels:   maps.Clone(b.dmChannels),
+		RepoChannels: maps.Clone(b.repoChannels),
+		LLMSessions:  maps.Clone(b.llmSessions),
+		Schedules:    maps.Clone(b.schedulesPaused),
+	}
+
+	keys := make([]string, 0, len(b.repos))
+	for key := range b.repos {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		session := b.repos[key]
+		if session.llm == nil || !session.llm.Running() {
+			continue
+		}
+		saved := state.Session{
+			Repo:     session.name,
+			UserID:   session.user.id,
+			UserName: session.user.name,
+			ID:       session.id,
+		}
+		for _, ch := range session.channels {
+			saved.Channels = append(saved.Channels, state.Channel{Provider: ch.provider.Name(), ChannelID: ch.channelID})
+		}
+		for _, p := range session.queue {
+			saved.Queue = append(saved.Queue, savePrompt(p))
+		}
+		st.Sessions = append(st.Sessions, saved)
+	}
+	return st
+}
+
+// savePrompt converts a queued prompt to its state form.
+func savePrompt(p queuedPrompt) state.Prompt {
+	saved := state.Prompt{
+		Author:    p.author,
+		AuthorID:  p.authorID,
+		Text:      p.text,
+		Content:   p.msg.Content,
+		Source:    p.msg.Source,
+		Provider:  p.prov.Name(),
+		ChannelID: p.channelID,
+	}
+	for _, a := range p.msg.Attachments {
+		saved.Attachments = append(saved.Attachments, state.Attachment{Path: a.Path, ContentType: a.ContentType})
+	}
+	if r := p.replyTo; r != nil {
+		saved.ReplyTo = &state.ReplyTo{Provider: r.provider, ChannelID: r.channelID, ThreadID: r.threadID, MessageID: r.messageID}
+	}
+	return saved
+}
+
+// restorePromptLocked rebuilds a queued prompt. It fails if the prompt's
+// provider is no longer configured. Callers must hold b.mu.
+func (b *Bridge) restorePromptLocked(saved state.Prompt) (queuedPrompt, bool) {
+	prov, ok := b.providers[saved.Provider]
+	if !ok {
+		return queuedPrompt{}, false
+	}
+	p := queuedPrompt{
+		msg:       llm.Message{Source: saved.Source, Content: saved.Content},
+		author:    saved.Author,
+		authorID:  saved.AuthorID,
+		text:      saved.Text,
+		prov:      prov,
+		channelID: saved.ChannelID,
+	}
+	for _, a := range saved.Attachments {
+		p.msg.Attachments = append(p.msg.Attachments, llm.Attachment{Path: a.Path, ContentType: a.ContentType})
+	}
+	if r := saved.ReplyTo; r != nil {
+		p.replyTo = &replyTarget{provider: r.Provider, channelID: r.ChannelID, threadID: r.ThreadID, messageID: r.MessageID}
+	}
+	return p, true
+}
+
+// resumeState restores users' selections from the state file and restarts
+// the sessions that were running when the bridge last stopped. Providers
+// must already be started.
+func (b *Bridge) resumeState(ctx context.Context) {
+	path := b.statePath()
+	if path == "" {
+		return
+	}
+	st, err := state.Load(path)
+	if err != nil {
+		slog.Warn("load state failed, starting fresh", "path", path, "error", err)
+		return
+	}
+
+	b.mu.Lock()
+	if _, ok := b.cfg.Repos[st.TerminalRepo]; ok {
+		b.terminalRepoName = st.TerminalRepo
+	}
+	for authorID, repoName := range st.DMRepos {
+		if _, ok := b.cfg.Repos[repoName]; ok {
+			b.dmRepos[authorID] = repoName
+		}
+	}
+	maps.Copy(b.dmChannels, st.DMChannels)
+	maps.Copy(b.repoChannels, st.RepoChannels)
+	maps.Copy(b.llmSessions, st.LLMSessions)
+	for name, paused := range st.Schedules {
+		if _, ok := b.cfg.Schedules[name]; ok {
+			b.schedulesPaused[name] = paused
+		}
+	}
+	b.mu.Unlock()
+
+	for _, saved := range st.Sessions {
+		b.resumeSession(ctx, saved)
+	}
+}
+
+// resumeSession restarts a saved session, continuing its LLM conversation,
+// reattaching its channels and queue, and telling the channels it is back.
+func (b *Bridge) resumeSession(ctx context.Context, saved state.Session) {
+	user := sessionUser{id: saved.UserID, name: saved.UserName}
+
+	b.mu.Lock()
+	repo, ok := b.cfg.Repos[saved.Repo]
+	var channels []channelRef
+	for _, ch := range saved.Channels {
+		if prov, ok := b.providers[ch.Provider]; ok {
+			channels = append(channels, channelRef{provider: prov, channelID: ch.ChannelID})
+		}
+	}
+	b.mu.Unlock()
+
+	if !ok || len(channels) == 0 {
+		slog.Info("not resuming session: repo or providers no longer configured", "repo", saved.Repo, "user", user.name)
+		return
+	}
+
+	session, err := b.getOrCreateSession(ctx, saved.Repo, repo, channels[0].provider, channels[0].channelID, user)
+	if err != nil {
+		slog.Error("resume session failed", "repo", saved.Repo, "user", user.name, "error", err)
+		for _, ch := range channels {
+			b.reply(ch.provider, ch.channelID, fmt.Sprintf("llm-bridge restarted but could not resume the LLM session for %s: %v", repoLabel(saved.Repo, user), err))
+		}
+		return
+	}
+
+	b.mu.Lock()
+	if saved.ID != "" {
+		session.id = saved.ID
+	}
+	for _, ch := range channels[1:] {
+		b.addChannelToSession(session, ch.provider, ch.channelID)
+	}
+	for _, p := range saved.Queue {
+		if prompt, ok := b.restorePromptLocked(p); ok {
+			session.queue = append(session.queue, prompt)
+		}
+	}
+	queued := len(session.queue)
+	b.mu.Unlock()
+
+	slog.Info("resumed llm session", "repo", saved.Repo, "user", user.name, "channels", len(channels), "queued", queued)
+	notice := fmt.Sprintf("llm-bridge restarted; resumed the LLM session for %s.", repoLabel(saved.Repo, user))
+	if queued > 0 {
+		notice += fmt.Sprintf(" %d queued prompt(s) will be sent.", queued)
+	}
+	for _, ch := range channels {
+		b.reply(ch.provider, ch.channelID, notice)
+	}
+	b.dispatchQueued(session)
+}
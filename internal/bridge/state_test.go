@@ -0,0 +1,295 @@
+package bridge
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anthropics/llm-bridge/internal/llm"
+	"github.com/anthropics/llm-bridge/internal/provider"
+	"github.com/anthropics/llm-bridge/internal/state"
+)
+
+// resumableLLM is a mockLLM whose conversations have IDs.
+type resumableLLM struct {
+	*mockLLM
+	id      string
+	resumed string
+}
+
+func (r *resumableLLM) SessionID() string { return r.id }
+
+func (r *resumableLLM) ResumeSession(id string) {
+	r.resumed = id
+	r.id = id
+}
+
+// stateBridge returns a bridge with a config path in a temp dir, so its
+// state file lives there too, and an LLM factory recording what it creates.
+func stateBridge(t *testing.T) (*Bridge, *provider.MockProvider, *[]*resumableLLM) {
+	t.Helper()
+	tb := newTestBridge(t, withConfigPath(filepath.Join(t.TempDir(), "llm-bridge.yaml")))
+
+	var created []*resumableLLM
+	tb.b.llmFactory = func(backend, workDir, claudePath string, resume bool) (llm.LLM, error) {
+		m := &resumableLLM{mockLLM: newMockLLM("claude"), id: "new-conversation"}
+		created = append(created, m)
+		return m, nil
+	}
+	return tb.b, tb.prov, &created
+}
+
+func TestState_Path(t *testing.T) {
+	if got := New(testConfig(), "").statePath(); got != "" {
+		t.Errorf("no config file should disable state, got %q", got)
+	}
+
+	b := New(testConfig(), "/etc/llm-bridge/llm-bridge.yaml")
+	if got := b.statePath(); got != "/etc/llm-bridge/llm-bridge.state.json" {
+		t.Errorf("default statePath() = %q", got)
+	}
+	b.cfg.Defaults.StateFile = "/var/lib/llm-bridge/state.json"
+	if got := b.statePath(); got != "/var/lib/llm-bridge/state.json" {
+		t.Errorf("absolute statePath() = %q", got)
+	}
+}
+
+func TestState_SaveSnapshot(t *testing.T) {
+	b, mockProv, _ := stateBridge(t)
+	ctx := context.Background()
+
+	b.processMessage(ctx, mockProv, provider.Message{ChannelID: "channel-123", Content: "first", Author: "alice", AuthorID: "u1", Source: "discord"})
+	b.processMessage(ctx, mockProv, provider.Message{ChannelID: "channel-123", Content: "second", Author: "bob", AuthorID: "u2", Source: "discord"})
+	b.mu.Lock()
+	b.terminalRepoName = "other-repo"
+	b.dmRepos["u1"] = "test-repo"
+	b.dmChannels["dm-1"] = "u1"
+	b.mu.Unlock()
+
+	b.saveState()
+
+	st, err := state.Load(b.statePath())
+	if err != nil {
+		t.Fatalf("load state: %v", err)
+	}
+	if st.TerminalRepo != "other-repo" || st.DMRepos["u1"] != "test-repo" || st.DMChannels["dm-1"] != "u1" {
+		t.Errorf("selections = %+v", st)
+	}
+	if st.LLMSessions["test-repo"] != "new-conversation" {
+		t.Errorf("LLM session IDs = %v", st.LLMSessions)
+	}
+	if len(st.Sessions) != 1 {
+		t.Fatalf("sessions = %+v", st.Sessions)
+	}
+	s := st.Sessions[0]
+	if s.Repo != "test-repo" || s.ID == "" || len(s.Channels) != 1 || s.Channels[0] != (state.Channel{Provider: "discord", ChannelID: "channel-123"}) {
+		t.Errorf("session = %+v", s)
+	}
+	if len(s.Queue) != 1 || s.Queue[0].Author != "bob" || s.Queue[0].Text != "second" || s.Queue[0].Provider != "discord" {
+		t.Errorf("queue = %+v", s.Queue)
+	}
+}
+
+func TestState_SkipsStoppedSessions(t *testing.T) {
+	b, _, _ := stateBridge(t)
+	stopped := newMockLLM("claude")
+	b.repos["test-repo"] = &repoSession{name: "test-repo", llm: stopped}
+
+	if got := b.snapshotState().Sessions; len(got) != 0 {
+		t.Errorf("stopped sessions should not be saved, got %+v", got)
+	}
+}
+
+func TestState_StopRecordsRunningSessions(t *testing.T) {
+	b, mockProv, _ := stateBridge(t)
+	b.processMessage(context.Background(), mockProv, provider.Message{ChannelID: "channel-123", Content: "hi", Author: "alice", Source: "discord"})
+
+	if err := b.Stop(); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+	// Saves after Stop must not erase the sessions it recorded.
+	b.saveState()
+
+	st, err := state.Load(b.statePath())
+	if err != nil {
+		t.Fatalf("load state: %v", err)
+	}
+	if len(st.Sessions) != 1 || st.Sessions[0].Repo != "test-repo" {
+		t.Errorf("sessions after Stop = %+v", st.Sessions)
+	}
+}
+
+// ctxLLM is a mockLLM that exits when its context is cancelled, like the
+// real backends.
+type ctxLLM struct {
+	*mockLLM
+	ctx context.Context
+}
+
+func (c *ctxLLM) Start(ctx context.Context) error {
+	c.ctx = ctx
+	if err := c.mockLLM.Start(ctx); err != nil {
+		return err
+	}
+	go func() {
+		<-ctx.Done()
+		_ = c.mockLLM.Stop()
+	}()
+	return nil
+}
+
+func TestState_ShutdownSavesSessionsBeforeCancelling(t *testing.T) {
+	b, mockProv, _ := stateBridge(t)
+	var created *ctxLLM
+	b.llmFactory = func(backend, workDir, claudePath string, resume bool) (llm.LLM, error) {
+		created = &ctxLLM{mockLLM: newMockLLM("claude")}
+		return created, nil
+	}
+
+	// Shutdown cancels the serve context first, as main does on SIGTERM.
+	ctx, cancel := context.WithCancel(context.Background())
+	b.processMessage(ctx, mockProv, provider.Message{ChannelID: "channel-123", Content: "hi", Author: "alice", Source: "discord"})
+	cancel()
+
+	if created == nil {
+		t.Fatal("no LLM was started")
+	}
+	if err := created.ctx.Err(); err != nil {
+		t.Fatalf("cancelling the serve context should not stop the LLM before Stop, got %v", err)
+	}
+	if err := b.Stop(); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+	if created.ctx.Err() == nil {
+		t.Error("Stop should cancel the LLM's context")
+	}
+
+	st, err := state.Load(b.statePath())
+	if err != nil {
+		t.Fatalf("load state: %v", err)
+	}
+	if len(st.Sessions) != 1 || st.Sessions[0].Repo != "test-repo" {
+		t.Errorf("sessions after shutdown = %+v, want the running session", st.Sessions)
+	}
+}
+
+func TestState_LoopSavesChanges(t *testing.T) {
+	b, _, _ := stateBridge(t)
+	go b.stateLoop()
+	defer b.Stop()
+
+	b.mu.Lock()
+	b.dmRepos["u9"] = "other-repo"
+	b.markStateChanged()
+	b.mu.Unlock()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if st, err := state.Load(b.statePath()); err == nil && st.DMRepos["u9"] == "other-repo" {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Error("state loop should write changes to the state file")
+}
+
+func TestState_Resume(t *testing.T) {
+	b, mockProv, created := stateBridge(t)
+	err := state.Save(b.statePath(), &state.State{
+		TerminalRepo: "other-repo",
+		DMRepos:      map[string]string{"u1": "test-repo", "u2": "deleted-repo"},
+		DMChannels:   map[string]string{"dm-1": "u1"},
+		LLMSessions:  map[string]string{"test-repo": "conv-1"},
+		Sessions: []state.Session{
+			{
+				Repo:     "test-repo",
+				ID:       "20261018-120000-abcd",
+				Channels: []state.Channel{{Provider: "discord", ChannelID: "channel-123"}, {Provider: "discord", ChannelID: "dm-1"}, {Provider: "gone", ChannelID: "x"}},
+				Queue: []state.Prompt{
+					{Author: "bob", Text: "queued one", Content: "queued one", Source: "discord", Provider: "discord", ChannelID: "channel-123"},
+					{Author: "carol", Text: "queued two", Content: "queued two", Source: "discord", Provider: "discord", ChannelID: "channel-123"},
+					{Author: "dave", Text: "lost", Content: "lost", Source: "gone", Provider: "gone", ChannelID: "x"},
+				},
+			},
+			{Repo: "deleted-repo", Channels: []state.Channel{{Provider: "discord", ChannelID: "c9"}}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("save state: %v", err)
+	}
+
+	b.resumeState(context.Background())
+
+	if b.terminalRepoName != "other-repo" || b.dmRepos["u1"] != "test-repo" || b.dmChannels["dm-1"] != "u1" {
+		t.Errorf("selections not restored: terminal=%q dm=%v", b.terminalRepoName, b.dmRepos)
+	}
+	if _, ok := b.dmRepos["u2"]; ok {
+		t.Error("selections of removed repos should be dropped")
+	}
+
+	if len(*created) != 1 {
+		t.Fatalf("expected one resumed session, got %d", len(*created))
+	}
+	m := (*created)[0]
+	if m.resumed != "conv-1" {
+		t.Errorf("LLM should resume conversation conv-1, resumed %q", m.resumed)
+	}
+
+	session := b.repos["test-repo"]
+	if session == nil {
+		t.Fatal("test-repo session should be running")
+	}
+	if session.id != "20261018-120000-abcd" {
+		t.Errorf("transcript session ID = %q, want the saved one", session.id)
+	}
+	if len(session.channels) != 2 {
+		t.Errorf("channels = %+v", session.channels)
+	}
+
+	if got := m.getSentMessages(); len(got) != 1 || got[0].Content != "queued one" {
+		t.Errorf("first queued prompt should be sent on resume, got %v", got)
+	}
+	if len(session.queue) != 1 || session.queue[0].author != "carol" {
+		t.Errorf("remaining queue = %+v", session.queue)
+	}
+
+	var notices int
+	for _, msg := range mockProv.GetSentMessages() {
+		if strings.Contains(msg.Content, "llm-bridge restarted; resumed the LLM session for test-repo") {
+			notices++
+			if !strings.Contains(msg.Content, "2 queued prompt(s)") {
+				t.Errorf("notice should mention the queue, got %q", msg.Content)
+			}
+		}
+	}
+	if notices != 2 {
+		t.Errorf("each attached channel should be told the bridge is back, got %d notices", notices)
+	}
+}
+
+func TestState_ResumeCorruptFile(t *testing.T) {
+	b, _, created := stateBridge(t)
+	if err := os.WriteFile(b.statePath(), []byte("{not json"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	b.resumeState(context.Background())
+	if len(*created) != 0 {
+		t.Error("a corrupt state file should not start sessions")
+	}
+}
+
+func TestGetOrCreateSession_ResumesConversation(t *testing.T) {
+	b, mockProv, created := stateBridge(t)
+	b.llmSessions["test-repo"] = "conv-7"
+
+	if _, err := b.getOrCreateSession(context.Background(), "test-repo", b.cfg.Repos["test-repo"], mockProv, "channel-123", sessionUser{}); err != nil {
+		t.Fatalf("getOrCreateSession: %v", err)
+	}
+	if got := (*created)[0].resumed; got != "conv-7" {
+		t.Errorf("session should resume the repo's last conversation, resumed %q", got)
+	}
+}
@@ -0,0 +1,113 @@
+package bridge
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/anthropics/llm-bridge/internal/config"
+	"github.com/anthropics/llm-bridge/internal/provider"
+)
+
+func TestBridge_RuntimeAddRepo_SubscribesChannel(t *testing.T) {
+	cfg := &config.Config{
+		Repos:    make(map[string]config.RepoConfig),
+		Defaults: config.NewDefaults(),
+	}
+	b := New(cfg, filepath.Join(t.TempDir(), "config.yaml"))
+
+	discord := provider.NewMockProvider("discord")
+	b.providers["discord"] = discord
+
+	repo := config.RepoConfig{Provider: "discord", ChannelID: "channel-999", WorkingDir: "/tmp/new"}
+	if err := b.RuntimeAddRepo("new-repo", repo, true); err != nil {
+		t.Fatalf("RuntimeAddRepo() error = %v", err)
+	}
+
+	if !discord.IsSubscribed("channel-999") {
+		t.Error("new repo's channel should be subscribed on its provider")
+	}
+}
+
+func TestBridge_RuntimeAddRepo_PersistFailureDoesNotSubscribe(t *testing.T) {
+	cfg := &config.Config{
+		Repos:    make(map[string]config.RepoConfig),
+		Defaults: config.NewDefaults(),
+	}
+	b := New(cfg, filepath.Join(t.TempDir(), "missing-dir", "config.yaml"))
+
+	discord := provider.NewMockProvider("discord")
+	b.providers["discord"] = discord
+
+	repo := config.RepoConfig{Provider: "discord", ChannelID: "channel-999", WorkingDir: "/tmp/new"}
+	if err := b.RuntimeAddRepo("new-repo", repo, true); err == nil {
+		t.Fatal("RuntimeAddRepo() should fail when the config cannot be written")
+	}
+
+	if discord.IsSubscribed("channel-999") {
+		t.Error("channel should not be subscribed when persistence fails")
+	}
+}
+
+func TestBridge_RuntimeAddRepo_ProviderNotRunning(t *testing.T) {
+	cfg := &config.Config{
+		Repos:    make(map[string]config.RepoConfig),
+		Defaults: config.NewDefaults(),
+	}
+	b := New(cfg, filepath.Join(t.TempDir(), "config.yaml"))
+
+	// No discord provider registered: adding a repo must still succeed
+	repo := config.RepoConfig{Provider: "discord", ChannelID: "channel-999", WorkingDir: "/tmp/new"}
+	if err := b.RuntimeAddRepo("new-repo", repo, true); err != nil {
+		t.Fatalf("RuntimeAddRepo() error = %v", err)
+	}
+}
+
+func TestBridge_HandleRemoveRepo_UnsubscribesChannel(t *testing.T) {
+	cfgPath := filepath.Join(t.TempDir(), "config.yaml")
+	content := `repos:
+  test-repo:
+    provider: discord
+    channel_id: "channel-123"
+    llm: claude
+    working_dir: /tmp/test
+`
+	if err := os.WriteFile(cfgPath, []byte(content), 0600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	b := New(testConfig(), cfgPath)
+	discord := provider.NewMockProvider("discord")
+	discord.Subscribe("channel-123")
+	discord.Subscribe("channel-456")
+	b.providers["discord"] = discord
+
+	b.handleRemoveRepo("test-repo")
+
+	if discord.IsSubscribed("channel-123") {
+		t.Error("removed repo's channel should be unsubscribed")
+	}
+	if !discord.IsSubscribed("channel-456") {
+		t.Error("other repos' channels should stay subscribed")
+	}
+}
+
+func TestBridge_HandleClone_SubscribesDiscordChannel(t *testing.T) {
+	dir := t.TempDir()
+	cfg := &config.Config{
+		Repos:    make(map[string]config.RepoConfig),
+		Defaults: config.NewDefaults(),
+	}
+	cfg.Defaults.BaseDir = dir
+	b := New(cfg, filepath.Join(dir, "config.yaml"))
+	b.cloneRepo = func(url, destDir string) error { return nil }
+
+	discord := provider.NewMockProvider("discord")
+	b.providers["discord"] = discord
+
+	b.handleClone("discord", "https://github.com/user/repo.git myrepo chan-new")
+
+	if !discord.IsSubscribed("chan-new") {
+		t.Error("cloned repo's channel should be subscribed immediately")
+	}
+}
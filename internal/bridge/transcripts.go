@@ -0,0 +1,224 @@
+package bridge
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/anthropics/llm-bridge/internal/config"
+	"github.com/anthropics/llm-bridge/internal/provider"
+	"github.com/anthropics/llm-bridge/internal/transcript"
+)
+
+const (
+	// defaultHistoryTurns is how many turns /history shows without an argument.
+	defaultHistoryTurns = 5
+	// maxHistoryTurns caps /history so replies stay readable.
+	maxHistoryTurns = 50
+	// historyOutputLen caps the output preview shown per turn.
+	historyOutputLen = 200
+)
+
+// newSessionID returns an ID for a new session that sorts by start time.
+func newSessionID() string {
+	var r [4]byte
+	_, _ = rand.Read(r[:])
+	return time.Now().UTC().Format("20060102-150405") + "-" + hex.EncodeToString(r[:])
+}
+
+// transcriptFor returns the transcript log of user's session in a repo
+// (the shared session if user has no ID), or nil if transcripts are
+// disabled, the repo is unknown or the bridge has no config file to keep
+// them next to (e.g. in tests). Each session gets its own directory under
+// the bridge's transcript dir, outside every working tree.
+func (b *Bridge) transcriptFor(repoName string, user sessionUser) *transcript.Log {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	tc := b.cfg.Defaults.Transcripts
+	if _, ok := b.cfg.Repos[repoName]; !ok || !tc.GetTranscriptsEnabled() || b.cfgPath == "" {
+		return nil
+	}
+	name := repoName
+	if user.id != "" {
+		name += "@" + userSlug(user.id)
+	}
+	dir := filepath.Join(config.ResolvePath(b.cfgPath, tc.GetDir()), name)
+	if log, ok := b.transcripts[dir]; ok {
+		return log
+	}
+	log := transcript.Open(dir, tc.GetRetention())
+	b.transcripts[dir] = log
+	return log
+}
+
+// record appends an entry for session to its repo's transcript.
+func (b *Bridge) record(session *repoSession, e transcript.Entry) {
+	log := b.transcriptFor(session.name, session.user)
+	if log == nil {
+		return
+	}
+	e.Session = session.id
+	e.Repo = session.name
+	e.User = session.user.id
+	if err := log.Append(e); err != nil {
+		slog.Warn("write transcript failed", "repo", session.name, "error", err)
+	}
+}
+
+// recordPrompt records a prompt once it has been sent to the LLM.
+func (b *Bridge) recordPrompt(session *repoSession, p queuedPrompt) {
+	var names []string
+	for _, a := range p.msg.Attachments {
+		names = append(names, filepath.Base(a.Path))
+	}
+	b.record(session, transcript.Entry{
+		Kind:        transcript.KindPrompt,
+		Author:      p.author,
+		AuthorID:    p.authorID,
+		Source:      p.msg.Source,
+		Content:     p.text,
+		Attachments: names,
+	})
+}
+
+// recordOutput records a chunk of output as it was broadcast.
+func (b *Bridge) recordOutput(session *repoSession, content string) {
+	b.record(session, transcript.Entry{Kind: transcript.KindOutput, Content: content})
+}
+
+// sessionEntries returns the transcript entries of the session author uses
+// in a repo, across restarts.
+func (b *Bridge) sessionEntries(repoName string, user sessionUser) ([]transcript.Entry, error) {
+	log := b.transcriptFor(repoName, user)
+	if log == nil {
+		return nil, nil
+	}
+	return log.Read(func(e transcript.Entry) bool {
+		return e.Repo == repoName && e.User == user.id
+	})
+}
+
+// handleHistory implements /history [n]: the last n prompts and the start
+// of the output that answered each.
+func (b *Bridge) handleHistory(channelID string, author sessionUser, args string) commandResult {
+	repoName, _, user := b.sessionKeyForChannel(channelID, author)
+	if repoName == "" {
+		return commandFailed("No repo configured")
+	}
+	if !b.currentConfig().Defaults.Transcripts.GetTranscriptsEnabled() {
+		return commandFailed("Transcripts are disabled")
+	}
+
+	n := defaultHistoryTurns
+	if args = strings.TrimSpace(args); args != "" {
+		v, err := strconv.Atoi(args)
+		if err != nil || v < 1 {
+			return commandFailed("Usage: /history [n]")
+		}
+		n = min(v, maxHistoryTurns)
+	}
+
+	entries, err := b.sessionEntries(repoName, user)
+	if err != nil {
+		return commandFailed(fmt.Sprintf("Read history failed: %v", err))
+	}
+	turns := transcript.Turns(entries)
+	if len(turns) == 0 {
+		return commandOK(fmt.Sprintf("No history for %s", repoLabel(repoName, user)))
+	}
+	if len(turns) > n {
+		turns = turns[len(turns)-n:]
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "History for %s (last %d):", repoLabel(repoName, user), len(turns))
+	for _, turn := range turns {
+		if turn.Prompt.Kind != "" {
+			fmt.Fprintf(&sb, "\n[%s] %s: %s", turn.Prompt.Time.Local().Format("2006-01-02 15:04"), turn.Prompt.Author, truncateLine(turn.Prompt.Content, historyOutputLen))
+		}
+		if turn.Output != "" {
+			fmt.Fprintf(&sb, "\n  → %s", truncateLine(turn.Output, historyOutputLen))
+		}
+	}
+	return commandOK(sb.String())
+}
+
+// handleExport implements /export [md|html|jsonl]: uploads the transcript of
+// the author's current session, or of their last one if none is running.
+func (b *Bridge) handleExport(prov provider.Provider, channelID string, author sessionUser, args string) commandResult {
+	format := strings.ToLower(strings.TrimSpace(args))
+	switch format {
+	case "":
+		format = "md"
+	case "md", "html", "jsonl":
+	default:
+		return commandFailed("Usage: /export [md|html|jsonl]")
+	}
+
+	repoName, key, user := b.sessionKeyForChannel(channelID, author)
+	if repoName == "" {
+		return commandFailed("No repo configured")
+	}
+	if !b.currentConfig().Defaults.Transcripts.GetTranscriptsEnabled() {
+		return commandFailed("Transcripts are disabled")
+	}
+	if !provider.CapabilitiesOf(prov).Files {
+		return commandFailed(fmt.Sprintf("%s does not support file uploads", prov.Name()))
+	}
+
+	entries, err := b.sessionEntries(repoName, user)
+	if err != nil {
+		return commandFailed(fmt.Sprintf("Read transcript failed: %v", err))
+	}
+
+	b.mu.Lock()
+	var sessionID string
+	if session, ok := b.repos[key]; ok {
+		sessionID = session.id
+	}
+	b.mu.Unlock()
+	if sessionID == "" && len(entries) > 0 {
+		sessionID = entries[len(entries)-1].Session
+	}
+
+	var current []transcript.Entry
+	for _, e := range entries {
+		if e.Session == sessionID {
+			current = append(current, e)
+		}
+	}
+	if len(current) == 0 {
+		return commandFailed(fmt.Sprintf("No transcript for %s", repoLabel(repoName, user)))
+	}
+
+	title := fmt.Sprintf("Transcript: %s, session %s", repoLabel(repoName, user), sessionID)
+	var data []byte
+	switch format {
+	case "md":
+		data = []byte(transcript.Markdown(title, current))
+	case "html":
+		page, err := transcript.HTML(title, current)
+		if err != nil {
+			return commandFailed(fmt.Sprintf("Export failed: %v", err))
+		}
+		data = []byte(page)
+	case "jsonl":
+		var sb strings.Builder
+		if err := transcript.WriteJSONL(&sb, current); err != nil {
+			return commandFailed(fmt.Sprintf("Export failed: %v", err))
+		}
+		data = []byte(sb.String())
+	}
+
+	filename := fmt.Sprintf("%s-%s.%s", strings.ReplaceAll(repoName, "/", "-"), sessionID, format)
+	if err := prov.SendFile(channelID, filename, data); err != nil {
+		return commandFailed(fmt.Sprintf("Export failed: %v", err))
+	}
+	return commandOK("")
+}
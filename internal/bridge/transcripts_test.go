@@ -0,0 +1,265 @@
+package bridge
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anthropics/llm-bridge/internal/config"
+	"github.com/anthropics/llm-bridge/internal/llm"
+	"github.com/anthropics/llm-bridge/internal/provider"
+	"github.com/anthropics/llm-bridge/internal/transcript"
+)
+
+// transcriptBridge returns a bridge whose test-repo and config file live in
+// temp dirs, with a running session that has an ID.
+func transcriptBridge(t *testing.T) (*Bridge, *provider.MockProvider, *repoSession) {
+	t.Helper()
+	cfg := testConfig()
+	repo := cfg.Repos["test-repo"]
+	repo.WorkingDir = t.TempDir()
+	cfg.Repos["test-repo"] = repo
+	tb := newTestBridge(t, withConfig(cfg), withConfigPath(filepath.Join(t.TempDir(), "llm-bridge.yaml")), withRunningSession())
+	return tb.b, tb.prov, tb.session
+}
+
+func readTranscript(t *testing.T, b *Bridge) []transcript.Entry {
+	t.Helper()
+	log := b.transcriptFor("test-repo", sessionUser{})
+	if log == nil {
+		t.Fatal("transcript log should be available")
+	}
+	entries, err := log.Read(nil)
+	if err != nil {
+		t.Fatalf("read transcript: %v", err)
+	}
+	return entries
+}
+
+func TestTranscripts_RecordPromptsAndOutput(t *testing.T) {
+	b, mockProv, session := transcriptBridge(t)
+
+	sendPrompt(b, mockProv, "alice", "fix the bug")
+	b.broadcastOutput(session, "on it\n")
+
+	entries := readTranscript(t, b)
+	if len(entries) != 2 {
+		t.Fatalf("got %d entries, want 2: %+v", len(entries), entries)
+	}
+	p := entries[0]
+	if p.Kind != transcript.KindPrompt || p.Session != "s1" || p.Repo != "test-repo" ||
+		p.Author != "alice" || p.AuthorID != "id-alice" || p.Source != "discord" || p.Content != "fix the bug" {
+		t.Errorf("prompt entry = %+v", p)
+	}
+	if o := entries[1]; o.Kind != transcript.KindOutput || o.Content != "on it\n" || o.Session != "s1" {
+		t.Errorf("output entry = %+v", o)
+	}
+
+	wantDir := filepath.Join(filepath.Dir(b.cfgPath), "llm-bridge.transcripts", "test-repo")
+	if got := b.transcriptFor("test-repo", sessionUser{}).Dir(); got != wantDir {
+		t.Errorf("transcript dir = %q, want %q", got, wantDir)
+	}
+	if entries, _ := os.ReadDir(b.cfg.Repos["test-repo"].WorkingDir); len(entries) != 0 {
+		t.Errorf("transcripts should stay out of the working tree, found %v", entries)
+	}
+}
+
+func TestTranscripts_QueuedPromptRecordedWhenSent(t *testing.T) {
+	b, mockProv, _ := transcriptBridge(t)
+
+	sendPrompt(b, mockProv, "alice", "first")
+	sendPrompt(b, mockProv, "bob", "second")
+	if got := readTranscript(t, b); len(got) != 1 {
+		t.Fatalf("queued prompt should not be recorded yet, got %+v", got)
+	}
+
+	endTurns(b)
+	got := readTranscript(t, b)
+	if len(got) != 2 || got[1].Author != "bob" {
+		t.Errorf("queued prompt should be recorded once sent, got %+v", got)
+	}
+}
+
+func TestTranscripts_AttachmentNames(t *testing.T) {
+	b, _, session := transcriptBridge(t)
+
+	b.recordPrompt(session, queuedPrompt{
+		author: "alice",
+		text:   "see log",
+		msg:    llm.Message{Source: "discord", Attachments: []llm.Attachment{{Path: "/repo/.llm-bridge/inbox/build.log"}}},
+	})
+
+	got := readTranscript(t, b)
+	if len(got) != 1 || len(got[0].Attachments) != 1 || got[0].Attachments[0] != "build.log" {
+		t.Errorf("entry = %+v", got)
+	}
+}
+
+func TestTranscripts_Disabled(t *testing.T) {
+	b, mockProv, _ := transcriptBridge(t)
+	disabled := false
+	b.cfg.Defaults.Transcripts.Enabled = &disabled
+
+	sendPrompt(b, mockProv, "alice", "hi")
+
+	if b.transcriptFor("test-repo", sessionUser{}) != nil {
+		t.Error("disabled transcripts should have no log")
+	}
+	if got := b.handleHistory("channel-123", sessionUser{}, "").response; got != "Transcripts are disabled" {
+		t.Errorf("/history = %q", got)
+	}
+}
+
+func TestTranscripts_NoConfigFile(t *testing.T) {
+	b := New(testConfig(), "")
+	if b.transcriptFor("test-repo", sessionUser{}) != nil {
+		t.Error("a bridge without a config file should have no log")
+	}
+	b, _, _ = transcriptBridge(t)
+	if b.transcriptFor("no-such-repo", sessionUser{}) != nil {
+		t.Error("unknown repo should have no log")
+	}
+}
+
+func TestTranscripts_History(t *testing.T) {
+	b, mockProv, session := transcriptBridge(t)
+
+	if got := b.handleHistory("channel-123", sessionUser{}, "").response; got != "No history for test-repo" {
+		t.Errorf("empty history = %q", got)
+	}
+
+	for _, prompt := range []string{"one", "two", "three"} {
+		sendPrompt(b, mockProv, "alice", prompt)
+		b.broadcastOutput(session, "answer to "+prompt+"\n")
+		endTurns(b)
+	}
+
+	got := b.handleHistory("channel-123", sessionUser{}, "2").response
+	if !strings.HasPrefix(got, "History for test-repo (last 2):") {
+		t.Errorf("header = %q", got)
+	}
+	if strings.Contains(got, "alice: one") || !strings.Contains(got, "alice: two") || !strings.Contains(got, "alice: three") {
+		t.Errorf("should show the last 2 turns, got %q", got)
+	}
+	if !strings.Contains(got, "→ answer to three") {
+		t.Errorf("should show output previews, got %q", got)
+	}
+
+	if got := b.handleHistory("channel-123", sessionUser{}, "zero").response; got != "Usage: /history [n]" {
+		t.Errorf("bad arg = %q", got)
+	}
+	if got := b.handleHistory("unknown", sessionUser{}, "").response; got != "No repo configured" {
+		t.Errorf("unknown channel = %q", got)
+	}
+}
+
+func TestTranscripts_HistoryPerUser(t *testing.T) {
+	b, _, _ := transcriptBridge(t)
+	repo := b.cfg.Repos["test-repo"]
+	repo.SessionMode = config.SessionModePerUser
+	b.cfg.Repos["test-repo"] = repo
+
+	alice := &repoSession{id: "a1", name: "test-repo", user: sessionUser{id: "id-alice", name: "alice"}}
+	b.recordPrompt(alice, queuedPrompt{author: "alice", text: "alice's prompt"})
+	b.recordPrompt(&repoSession{id: "b1", name: "test-repo", user: sessionUser{id: "id-bob", name: "bob"}}, queuedPrompt{author: "bob", text: "bob's prompt"})
+
+	got := b.handleHistory("channel-123", sessionUser{id: "id-alice", name: "alice"}, "").response
+	if !strings.Contains(got, "alice's prompt") || strings.Contains(got, "bob's prompt") {
+		t.Errorf("per-user history should only show the author's session, got %q", got)
+	}
+
+	// Each user's transcript has its own directory.
+	aliceDir := b.transcriptFor("test-repo", alice.user).Dir()
+	if filepath.Base(aliceDir) != "test-repo@id-alice" {
+		t.Errorf("alice's transcript dir = %q", aliceDir)
+	}
+	files, _ := os.ReadDir(aliceDir)
+	for _, f := range files {
+		data, _ := os.ReadFile(filepath.Join(aliceDir, f.Name()))
+		if strings.Contains(string(data), "bob's prompt") {
+			t.Errorf("bob's prompt written to alice's transcript %s", f.Name())
+		}
+	}
+}
+
+func TestTranscripts_Export(t *testing.T) {
+	b, mockProv, session := transcriptBridge(t)
+
+	// An earlier session of the same repo is not part of the export.
+	b.record(&repoSession{id: "s0", name: "test-repo"}, transcript.Entry{Kind: transcript.KindPrompt, Author: "old", Content: "earlier"})
+	sendPrompt(b, mockProv, "alice", "fix <it>")
+	b.broadcastOutput(session, "fixed\n")
+
+	for _, tt := range []struct {
+		args, filename, want string
+	}{
+		{"", "test-repo-s1.md", "## alice (discord)"},
+		{"html", "test-repo-s1.html", "fix &lt;it&gt;"},
+		{"JSONL", "test-repo-s1.jsonl", `"content":"fixed\n"`},
+	} {
+		if got := b.handleExport(mockProv, "channel-123", sessionUser{}, tt.args).response; got != "" {
+			t.Fatalf("/export %s = %q", tt.args, got)
+		}
+		files := mockProv.GetSentFiles()
+		f := files[len(files)-1]
+		if f.Filename != tt.filename || f.ChannelID != "channel-123" {
+			t.Errorf("/export %s sent %s to %s", tt.args, f.Filename, f.ChannelID)
+		}
+		if !strings.Contains(string(f.Content), tt.want) || strings.Contains(string(f.Content), "earlier") {
+			t.Errorf("/export %s content = %s", tt.args, f.Content)
+		}
+	}
+
+	if got := b.handleExport(mockProv, "channel-123", sessionUser{}, "pdf").response; got != "Usage: /export [md|html|jsonl]" {
+		t.Errorf("bad format = %q", got)
+	}
+
+	mockProv.SetCapabilities(provider.Capabilities{MaxMessageLength: 2000})
+	if got := b.handleExport(mockProv, "channel-123", sessionUser{}, "").response; got != "discord does not support file uploads" {
+		t.Errorf("no file support = %q", got)
+	}
+}
+
+func TestTranscripts_ExportLastSessionWhenStopped(t *testing.T) {
+	b, mockProv, session := transcriptBridge(t)
+	sendPrompt(b, mockProv, "alice", "hello")
+	b.broadcastOutput(session, "hi\n")
+	delete(b.repos, "test-repo")
+
+	if got := b.handleExport(mockProv, "channel-123", sessionUser{}, "md").response; got != "" {
+		t.Fatalf("/export = %q", got)
+	}
+	if files := mockProv.GetSentFiles(); len(files) != 1 || files[0].Filename != "test-repo-s1.md" {
+		t.Errorf("should export the last session, got %+v", files)
+	}
+
+	b2, mockProv2, _ := transcriptBridge(t)
+	delete(b2.repos, "test-repo")
+	if got := b2.handleExport(mockProv2, "channel-123", sessionUser{}, "").response; got != "No transcript for test-repo" {
+		t.Errorf("empty export = %q", got)
+	}
+}
+
+func TestNewSessionID(t *testing.T) {
+	a, b := newSessionID(), newSessionID()
+	if a == b {
+		t.Error("session IDs should be unique")
+	}
+	if _, err := time.Parse("20060102-150405", a[:15]); err != nil {
+		t.Errorf("session ID %q should start with its UTC start time: %v", a, err)
+	}
+}
+
+func TestTranscripts_ProcessMessageEndToEnd(t *testing.T) {
+	b, mockProv, _ := transcriptBridge(t)
+	b.processMessage(context.Background(), mockProv, provider.Message{
+		ChannelID: "channel-123", Content: "/history", Author: "alice", AuthorID: "id-alice", Source: "discord",
+	})
+	sent := mockProv.GetSentMessages()
+	if len(sent) == 0 || !strings.Contains(sent[len(sent)-1].Content, "No history for test-repo") {
+		t.Errorf("/history should be routed as a bridge command, got %+v", sent)
+	}
+}
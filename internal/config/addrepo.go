@@ -1,6 +1,7 @@
 package config
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"os"
@@ -11,7 +12,8 @@ import (
 // AddRepo adds a repository to the configuration file at cfgPath.
 // If the file doesn't exist, a new config is created.
 // If the name already exists, it is overwritten.
-// The repo is validated before writing.
+// The repo is validated before writing. Only the repos: section of an
+// existing file is rewritten, so ${ENV} references elsewhere stay unexpanded.
 func AddRepo(cfgPath string, name string, repo RepoConfig) error {
 	cfg, err := loadRaw(cfgPath)
 	if err != nil {
@@ -22,6 +24,18 @@ func AddRepo(cfgPath string, name string, repo RepoConfig) error {
 			Repos:    make(map[string]RepoConfig),
 			Defaults: NewDefaults(),
 		}
+		cfg.Repos[name] = repo
+		if err := cfg.Validate(); err != nil {
+			return fmt.Errorf("validate config: %w", err)
+		}
+		data, err := yaml.Marshal(cfg)
+		if err != nil {
+			return fmt.Errorf("marshal config: %w", err)
+		}
+		if err := os.WriteFile(cfgPath, data, 0600); err != nil {
+			return fmt.Errorf("write config: %w", err)
+		}
+		return nil
 	}
 
 	if cfg.Repos == nil {
@@ -33,48 +47,115 @@ func AddRepo(cfgPath string, name string, repo RepoConfig) error {
 		return fmt.Errorf("validate config: %w", err)
 	}
 
-	data, err := yaml.Marshal(cfg)
+	doc, err := readConfigNode(cfgPath)
 	if err != nil {
-		return fmt.Errorf("marshal config: %w", err)
+		return err
 	}
-
-	if err := os.WriteFile(cfgPath, data, 0600); err != nil {
-		return fmt.Errorf("write config: %w", err)
+	var value yaml.Node
+	if err := value.Encode(repo); err != nil {
+		return fmt.Errorf("marshal repo: %w", err)
 	}
-
-	return nil
+	repos := reposNode(doc)
+	if i := mappingIndex(repos, name); i >= 0 {
+		repos.Content[i+1] = &value
+	} else {
+		repos.Content = append(repos.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: name}, &value)
+	}
+	return writeConfigNode(cfgPath, doc)
 }
 
 // RemoveRepo removes a repository from the configuration file at cfgPath.
 // Returns an error if the config file doesn't exist or if the repo doesn't exist.
 // The function does NOT delete any files on disk - it only removes the config entry.
+// Like AddRepo, it rewrites only the repos: section.
 func RemoveRepo(cfgPath, name string) error {
 	cfg, err := loadRaw(cfgPath)
 	if err != nil {
 		return fmt.Errorf("load config: %w", err)
 	}
 
-	if cfg.Repos == nil {
-		return fmt.Errorf("repo %q not found", name)
-	}
-
 	if _, ok := cfg.Repos[name]; !ok {
 		return fmt.Errorf("repo %q not found", name)
 	}
 
-	delete(cfg.Repos, name)
-
 	// No validation required - empty repos map is valid
 	// (user can still add repos later via add-repo command)
 
-	data, err := yaml.Marshal(cfg)
+	doc, err := readConfigNode(cfgPath)
 	if err != nil {
-		return fmt.Errorf("marshal config: %w", err)
+		return err
 	}
+	repos := reposNode(doc)
+	i := mappingIndex(repos, name)
+	if i < 0 {
+		return fmt.Errorf("repo %q not found", name)
+	}
+	repos.Content = append(repos.Content[:i], repos.Content[i+2:]...)
+	return writeConfigNode(cfgPath, doc)
+}
 
-	if err := os.WriteFile(cfgPath, data, 0600); err != nil {
-		return fmt.Errorf("write config: %w", err)
+// readConfigNode parses the config file at cfgPath as written, without
+// expanding environment variables.
+func readConfigNode(cfgPath string) (*yaml.Node, error) {
+	data, err := os.ReadFile(cfgPath)
+	if err != nil {
+		return nil, fmt.Errorf("read config: %w", err)
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("parse config: %w", err)
+	}
+	if doc.Kind == 0 {
+		// An empty file.
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}}
+	}
+	if len(doc.Content) != 1 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, errors.New("parse config: top level is not a mapping")
+	}
+	return &doc, nil
+}
+
+// reposNode returns the repos: mapping of doc, adding an empty one if the
+// file has none.
+func reposNode(doc *yaml.Node) *yaml.Node {
+	root := doc.Content[0]
+	if i := mappingIndex(root, "repos"); i >= 0 {
+		repos := root.Content[i+1]
+		if repos.Kind != yaml.MappingNode {
+			// repos: with no value, or null.
+			*repos = yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		}
+		return repos
 	}
+	repos := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	root.Content = append(root.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "repos"}, repos)
+	return repos
+}
 
+// mappingIndex returns the index of key's key node in the mapping node m,
+// or -1 if it has none.
+func mappingIndex(m *yaml.Node, key string) int {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// writeConfigNode writes doc back to cfgPath.
+func writeConfigNode(cfgPath string, doc *yaml.Node) error {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(doc); err != nil {
+		return fmt.Errorf("marshal config: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return fmt.Errorf("marshal config: %w", err)
+	}
+	if err := os.WriteFile(cfgPath, buf.Bytes(), 0600); err != nil {
+		return fmt.Errorf("write config: %w", err)
+	}
 	return nil
 }
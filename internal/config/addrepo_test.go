@@ -1,6 +1,7 @@
 package config
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
 	"strings"
@@ -110,8 +111,8 @@ func TestAddRepo_DuplicateChannelID(t *testing.T) {
 	if err == nil {
 		t.Fatal("AddRepo() expected error for duplicate channel_id")
 	}
-	if !strings.Contains(err.Error(), "duplicate channel_id") {
-		t.Errorf("error = %q, want to contain %q", err.Error(), "duplicate channel_id")
+	if !errors.Is(err, ErrDuplicateChannel) {
+		t.Errorf("error = %q, want ErrDuplicateChannel", err.Error())
 	}
 
 	// Verify original file is unchanged.
@@ -606,3 +607,61 @@ func TestRemoveRepo_PreservesWorktrees(t *testing.T) {
 		t.Errorf("other-repo should have been removed")
 	}
 }
+
+func TestAddRepo_KeepsEnvReferences(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	t.Setenv("TEST_ADDREPO_WEBHOOK_SECRET", "expanded-webhook-secret")
+	t.Setenv("TEST_ADDREPO_DISCORD_TOKEN", "expanded-discord-token")
+
+	content := `# bridge config
+providers:
+  discord:
+    token: "${TEST_ADDREPO_DISCORD_TOKEN}"
+webhooks:
+  - url: https://hooks.example.com/llm-bridge
+    secret: "${TEST_ADDREPO_WEBHOOK_SECRET}"
+repos:
+  existing-repo:
+    provider: discord
+    channel_id: "111"
+    working_dir: /tmp/existing
+`
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("write test config: %v", err)
+	}
+
+	if err := AddRepo(path, "new-repo", RepoConfig{Provider: "discord", ChannelID: "222", WorkingDir: "/tmp/new"}); err != nil {
+		t.Fatalf("AddRepo() error = %v", err)
+	}
+	if err := RemoveRepo(path, "existing-repo"); err != nil {
+		t.Fatalf("RemoveRepo() error = %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile error = %v", err)
+	}
+	raw := string(data)
+	for _, secret := range []string{"expanded-webhook-secret", "expanded-discord-token"} {
+		if strings.Contains(raw, secret) {
+			t.Errorf("config file contains expanded secret %q:\n%s", secret, raw)
+		}
+	}
+	for _, want := range []string{"${TEST_ADDREPO_DISCORD_TOKEN}", "${TEST_ADDREPO_WEBHOOK_SECRET}", "# bridge config"} {
+		if !strings.Contains(raw, want) {
+			t.Errorf("config file lost %q:\n%s", want, raw)
+		}
+	}
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if _, ok := cfg.Repos["existing-repo"]; ok {
+		t.Error("existing-repo should be removed")
+	}
+	if cfg.Repos["new-repo"].ChannelID != "222" || cfg.Webhooks[0].Secret != "expanded-webhook-secret" {
+		t.Errorf("reloaded config = %+v", cfg)
+	}
+}
@@ -1,18 +1,133 @@
 package config
 
 import (
+	"errors"
 	"fmt"
+	"net"
+	"net/url"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 	"time"
 
 	"gopkg.in/yaml.v3"
+
+	"github.com/anthropics/llm-bridge/internal/adminapi"
+	"github.com/anthropics/llm-bridge/internal/cron"
+	"github.com/anthropics/llm-bridge/internal/macro"
+	"github.com/anthropics/llm-bridge/internal/router"
+	"github.com/anthropics/llm-bridge/internal/webhook"
 )
 
 type Config struct {
-	Repos     map[string]RepoConfig `yaml:"repos"`
-	Defaults  Defaults              `yaml:"defaults"`
-	Providers ProviderConfigs       `yaml:"providers"`
+	Repos     map[string]RepoConfig     `yaml:"repos"`
+	Defaults  Defaults                  `yaml:"defaults"`
+	Providers ProviderConfigs           `yaml:"providers"`
+	Webhooks  []WebhookConfig           `yaml:"webhooks,omitempty"`
+	Authz     AuthzConfig               `yaml:"authz,omitempty"`
+	AdminAPI  AdminAPIConfig            `yaml:"admin_api,omitempty"`
+	Metrics   MetricsConfig             `yaml:"metrics,omitempty"`
+	Schedules map[string]ScheduleConfig `yaml:"schedules,omitempty"`
+	Macros    map[string]MacroConfig    `yaml:"macros,omitempty"`
+}
+
+// MacroConfig is a user-defined /command that expands into a prompt, e.g.
+// /review <branch>. See docs/macros.md.
+type MacroConfig struct {
+	Description string            `yaml:"description,omitempty"` // shown in /help
+	Args        []string          `yaml:"args,omitempty"`        // parameter names, in positional order
+	Defaults    map[string]string `yaml:"defaults,omitempty"`    // values of optional parameters
+	Prompt      string            `yaml:"prompt"`                // template with {arg} and {args} placeholders
+}
+
+// Macro returns the macro as a validated template.
+func (m MacroConfig) Macro(name string) (*macro.Macro, error) {
+	return macro.New(name, m.Description, m.Prompt, m.Args, m.Defaults)
+}
+
+// MacrosFor returns the macros available in a repo: the top-level macros,
+// with the repo's own macros added or overriding them by name.
+func (c *Config) MacrosFor(repoName string) map[string]MacroConfig {
+	repoMacros := c.Repos[repoName].Macros
+	if len(repoMacros) == 0 {
+		return c.Macros
+	}
+	merged := make(map[string]MacroConfig, len(c.Macros)+len(repoMacros))
+	for name, m := range c.Macros {
+		merged[name] = m
+	}
+	for name, m := range repoMacros {
+		merged[name] = m
+	}
+	return merged
+}
+
+// ScheduleConfig sends a prompt to a repo's session on a cron schedule.
+type ScheduleConfig struct {
+	Repo   string `yaml:"repo"`
+	Cron   string `yaml:"cron"` // "minute hour day-of-month month day-of-week", e.g. "0 7 * * 1-5"
+	Prompt string `yaml:"prompt"`
+	Paused bool   `yaml:"paused,omitempty"` // start paused; /schedules resume enables it
+}
+
+// MetricsConfig serves Prometheus metrics over HTTP.
+type MetricsConfig struct {
+	Listen string `yaml:"listen"`         // host:port; empty disables the endpoint
+	Path   string `yaml:"path,omitempty"` // default: /metrics
+}
+
+// Enabled reports whether metrics should be served.
+func (m MetricsConfig) Enabled() bool {
+	return m.Listen != ""
+}
+
+// GetPath returns the metrics path, defaulting to /metrics.
+func (m MetricsConfig) GetPath() string {
+	if m.Path == "" {
+		return "/metrics"
+	}
+	return m.Path
+}
+
+// AdminAPIConfig enables the local HTTP API for operating the bridge.
+type AdminAPIConfig struct {
+	Listen string `yaml:"listen"` // loopback host:port or unix:<path>; empty disables the API
+	Token  string `yaml:"token"`  // bearer token every request must carry
+}
+
+// Enabled reports whether the admin API should be served.
+func (a AdminAPIConfig) Enabled() bool {
+	return a.Listen != ""
+}
+
+// AuthzConfig restricts bridge commands and prompts by role. With no roles
+// configured, everyone in a repo's channels may do everything.
+type AuthzConfig struct {
+	DefaultRole string                `yaml:"default_role,omitempty"` // role of users no role binds; empty denies them
+	Roles       map[string]RoleConfig `yaml:"roles,omitempty"`
+}
+
+// Enabled reports whether authorization checks apply.
+func (a AuthzConfig) Enabled() bool {
+	return len(a.Roles) > 0
+}
+
+// RoleConfig binds a role to users and grants it permissions.
+type RoleConfig struct {
+	Users    []string `yaml:"users,omitempty"`    // "provider:id" AuthorIDs (bare IDs are Discord's); "*" for everyone
+	RoleIDs  []string `yaml:"role_ids,omitempty"` // "provider:id" role IDs (bare IDs are Discord's)
+	Commands []string `yaml:"commands,omitempty"` // bridge commands without "/"; "*" for all
+	Repos    []string `yaml:"repos,omitempty"`    // repo name patterns, e.g. "app/*"; empty for all
+	Prompt   bool     `yaml:"prompt,omitempty"`   // may send prompts to the LLM; false for read-only roles
+}
+
+// WebhookConfig is an HTTP endpoint that receives bridge events as signed
+// JSON. See docs/webhooks.md.
+type WebhookConfig struct {
+	URL    string   `yaml:"url"`
+	Secret string   `yaml:"secret,omitempty"` // HMAC-SHA256 key for X-LLM-Bridge-Signature-256
+	Events []string `yaml:"events,omitempty"` // event types to send; empty for all
 }
 
 type RepoConfig struct {
@@ -23,6 +138,106 @@ type RepoConfig struct {
 	Worktrees  []WorktreeConfig `yaml:"worktrees,omitempty"`
 	GitRoot    string           `yaml:"git_root,omitempty"`
 	Branch     string           `yaml:"branch,omitempty"`
+
+	// SessionMode is "shared" (default: one LLM for everyone in the repo's
+	// channels) or "per_user" (one LLM per author).
+	SessionMode string `yaml:"session_mode,omitempty"`
+	// UserWorktrees gives each per_user session its own git worktree on a
+	// per-user branch, created on first use.
+	UserWorktrees bool `yaml:"user_worktrees,omitempty"`
+
+	// Channels mirrors the repo's sessions to more channels, on any
+	// provider, in addition to ChannelID.
+	Channels []ChannelConfig `yaml:"channels,omitempty"`
+
+	// Macros adds macros for this repo, overriding top-level macros of the
+	// same name. Worktrees inherit them.
+	Macros map[string]MacroConfig `yaml:"macros,omitempty"`
+
+	// IdleTimeout overrides defaults.idle_timeout for the repo's sessions,
+	// e.g. "2h" or "never". Worktrees inherit it.
+	IdleTimeout string `yaml:"idle_timeout,omitempty"`
+}
+
+// IdleTimeoutNever disables the idle timeout.
+const IdleTimeoutNever = "never"
+
+// parseIdleTimeout parses an idle_timeout value, returning 0 for "never".
+func parseIdleTimeout(s string) (time.Duration, error) {
+	if s == IdleTimeoutNever {
+		return 0, nil
+	}
+	return time.ParseDuration(s)
+}
+
+// GetIdleTimeout returns the repo's idle timeout, or def if it sets none.
+// Zero means the repo's sessions never time out.
+func (r RepoConfig) GetIdleTimeout(def time.Duration) time.Duration {
+	if r.IdleTimeout == "" {
+		return def
+	}
+	dur, err := parseIdleTimeout(r.IdleTimeout)
+	if err != nil {
+		return def
+	}
+	return dur
+}
+
+// ChannelConfig is a channel a repo's sessions are mirrored to.
+type ChannelConfig struct {
+	Provider  string `yaml:"provider"`
+	ChannelID string `yaml:"channel_id"`
+	Mode      string `yaml:"mode,omitempty"` // read-write (default), read-only or notify-only
+}
+
+// Channel modes for ChannelConfig.Mode.
+const (
+	ChannelModeReadWrite  = "read-write"  // prompts and output
+	ChannelModeReadOnly   = "read-only"   // output; prompts are refused
+	ChannelModeNotifyOnly = "notify-only" // session notices only; prompts are refused
+)
+
+// GetMode returns the channel's mode, defaulting to read-write.
+func (c ChannelConfig) GetMode() string {
+	if c.Mode == "" {
+		return ChannelModeReadWrite
+	}
+	return c.Mode
+}
+
+// AllChannels returns every channel of the repo: ChannelID (read-write, on
+// Provider) if set, then Channels.
+func (r RepoConfig) AllChannels() []ChannelConfig {
+	all := make([]ChannelConfig, 0, len(r.Channels)+1)
+	if r.ChannelID != "" {
+		all = append(all, ChannelConfig{Provider: r.Provider, ChannelID: r.ChannelID, Mode: ChannelModeReadWrite})
+	}
+	return append(all, r.Channels...)
+}
+
+// ChannelMode returns the mode of channelID in the repo, or false if the
+// channel is not one of the repo's.
+func (r RepoConfig) ChannelMode(channelID string) (string, bool) {
+	if channelID == "" {
+		return "", false
+	}
+	for _, ch := range r.AllChannels() {
+		if ch.ChannelID == channelID {
+			return ch.GetMode(), true
+		}
+	}
+	return "", false
+}
+
+// Session modes for RepoConfig.SessionMode.
+const (
+	SessionModeShared  = "shared"
+	SessionModePerUser = "per_user"
+)
+
+// PerUser reports whether the repo runs a separate session per author.
+func (r RepoConfig) PerUser() bool {
+	return r.SessionMode == SessionModePerUser
 }
 
 type WorktreeConfig struct {
@@ -33,13 +248,105 @@ type WorktreeConfig struct {
 }
 
 type Defaults struct {
-	LLM             string          `yaml:"llm"`
-	ClaudePath      string          `yaml:"claude_path"`
-	OutputThreshold int             `yaml:"output_threshold"`
-	IdleTimeout     string          `yaml:"idle_timeout"`
-	ResumeSession   *bool           `yaml:"resume_session"`
-	RateLimit       RateLimitConfig `yaml:"rate_limit"`
-	BaseDir         string          `yaml:"base_dir"`
+	LLM             string           `yaml:"llm"`
+	ClaudePath      string           `yaml:"claude_path"`
+	OutputThreshold int              `yaml:"output_threshold"`
+	IdleTimeout     string           `yaml:"idle_timeout"`
+	IdleWarning     string           `yaml:"idle_warning"`        // how long before an idle timeout to warn; "0" for no warning
+	MaxSessions     int              `yaml:"max_active_sessions"` // running LLMs across all repos; 0 for no limit
+	ResumeSession   *bool            `yaml:"resume_session"`
+	RateLimit       RateLimitConfig  `yaml:"rate_limit"`
+	BaseDir         string           `yaml:"base_dir"`
+	Attachments     AttachmentConfig `yaml:"attachments"`
+	Reactions       ReactionConfig   `yaml:"reactions"`
+	OutputMode      string           `yaml:"output_mode"` // "broadcast" (default) or "reply"
+	Delivery        DeliveryConfig   `yaml:"delivery"`
+	Transcripts     TranscriptConfig `yaml:"transcripts"`
+	StateFile       string           `yaml:"state_file"` // relative to the config file's directory
+	AdminChannel    AdminChannel     `yaml:"admin_channel"`
+	Audit           AuditConfig      `yaml:"audit"`
+	Schedules       ScheduleDefaults `yaml:"schedules"`
+}
+
+// ScheduleDefaults apply to every schedule.
+type ScheduleDefaults struct {
+	Timezone   string `yaml:"timezone"`    // IANA zone for cron times and quiet hours (default: local time)
+	QuietHours string `yaml:"quiet_hours"` // "HH:MM-HH:MM"; scheduled runs due then are skipped
+}
+
+// GetLocation returns the time zone schedules run in. Defaults to local time.
+func (s ScheduleDefaults) GetLocation() *time.Location {
+	if s.Timezone == "" {
+		return time.Local
+	}
+	loc, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		return time.Local // rejected at load time
+	}
+	return loc
+}
+
+// GetQuietHours returns the quiet hours window, if set.
+func (s ScheduleDefaults) GetQuietHours() (cron.Window, bool) {
+	if s.QuietHours == "" {
+		return cron.Window{}, false
+	}
+	w, err := cron.ParseWindow(s.QuietHours)
+	if err != nil {
+		return cron.Window{}, false // rejected at load time
+	}
+	return w, true
+}
+
+// AdminChannel is a channel for operator notices, such as a config reload
+// that failed validation.
+type AdminChannel struct {
+	Provider  string `yaml:"provider"`
+	ChannelID string `yaml:"channel_id"`
+}
+
+// Enabled reports whether an admin channel is configured.
+func (a AdminChannel) Enabled() bool {
+	return a.ChannelID != ""
+}
+
+// AuditConfig controls the hash-chained log of bridge commands and prompts.
+type AuditConfig struct {
+	Enabled *bool  `yaml:"enabled"` // enable/disable the audit log (default: true)
+	File    string `yaml:"file"`    // relative to the config file's directory (default: llm-bridge.audit.jsonl)
+}
+
+// GetAuditEnabled returns whether commands and prompts are audited.
+// Defaults to true if not explicitly set.
+func (a AuditConfig) GetAuditEnabled() bool {
+	if a.Enabled == nil {
+		return true
+	}
+	return *a.Enabled
+}
+
+// GetFile returns the audit log path, relative to the config file's
+// directory unless absolute. Defaults to "llm-bridge.audit.jsonl".
+func (a AuditConfig) GetFile() string {
+	if a.File == "" {
+		return "llm-bridge.audit.jsonl"
+	}
+	return a.File
+}
+
+// Output modes for Defaults.OutputMode.
+const (
+	OutputModeBroadcast = "broadcast" // post output as new messages
+	OutputModeReply     = "reply"     // post the first output after a prompt as a reply to it
+)
+
+// GetOutputMode returns how LLM output is posted.
+// Defaults to OutputModeBroadcast.
+func (d Defaults) GetOutputMode() string {
+	if d.OutputMode == "" {
+		return OutputModeBroadcast
+	}
+	return d.OutputMode
 }
 
 // NewDefaults returns the default values for the Defaults struct.
@@ -48,6 +355,7 @@ func NewDefaults() Defaults {
 		LLM:             "claude",
 		OutputThreshold: 1500,
 		IdleTimeout:     "10m",
+		IdleWarning:     "2m",
 	}
 }
 
@@ -105,6 +413,183 @@ func (r RateLimitConfig) GetChannelBurst() int {
 	return r.ChannelBurst
 }
 
+// DeliveryConfig bounds the per-channel queue that retries failed output sends.
+type DeliveryConfig struct {
+	MaxQueue    int `yaml:"max_queue"`    // pending messages per channel before new output is dropped (default: 100)
+	MaxAttempts int `yaml:"max_attempts"` // send attempts per message before it is dropped (default: 5)
+}
+
+// GetMaxQueue returns the per-channel delivery queue size.
+// Defaults to 100.
+func (d DeliveryConfig) GetMaxQueue() int {
+	if d.MaxQueue == 0 {
+		return 100
+	}
+	return d.MaxQueue
+}
+
+// GetMaxAttempts returns how many times a message is sent before giving up.
+// Defaults to 5.
+func (d DeliveryConfig) GetMaxAttempts() int {
+	if d.MaxAttempts == 0 {
+		return 5
+	}
+	return d.MaxAttempts
+}
+
+// AttachmentConfig controls how files uploaded to a repo channel are handed to the LLM.
+type AttachmentConfig struct {
+	Enabled      *bool    `yaml:"enabled"`       // enable/disable inbound attachments (default: true)
+	MaxBytes     int64    `yaml:"max_bytes"`     // per-file size cap in bytes (default: 8 MiB)
+	AllowedTypes []string `yaml:"allowed_types"` // MIME types or "type/*" wildcards (default: text, images, json, pdf, patches)
+	InboxDir     string   `yaml:"inbox_dir"`     // directory relative to working_dir (default: .llm-bridge/inbox)
+}
+
+// DefaultAttachmentTypes is the MIME allowlist used when allowed_types is unset.
+var DefaultAttachmentTypes = []string{
+	"text/*",
+	"image/png",
+	"image/jpeg",
+	"image/gif",
+	"image/webp",
+	"application/json",
+	"application/pdf",
+	"application/x-patch",
+	"application/x-diff",
+}
+
+// GetAttachmentsEnabled returns whether inbound attachments are delivered to the LLM.
+// Defaults to true if not explicitly set.
+func (a AttachmentConfig) GetAttachmentsEnabled() bool {
+	if a.Enabled == nil {
+		return true
+	}
+	return *a.Enabled
+}
+
+// GetMaxBytes returns the per-attachment size cap in bytes.
+// Defaults to 8 MiB.
+func (a AttachmentConfig) GetMaxBytes() int64 {
+	if a.MaxBytes == 0 {
+		return 8 << 20
+	}
+	return a.MaxBytes
+}
+
+// GetAllowedTypes returns the MIME allowlist for attachments.
+// Defaults to DefaultAttachmentTypes.
+func (a AttachmentConfig) GetAllowedTypes() []string {
+	if len(a.AllowedTypes) == 0 {
+		return DefaultAttachmentTypes
+	}
+	return a.AllowedTypes
+}
+
+// GetInboxDir returns the attachment inbox directory, relative to a repo's working_dir.
+// Defaults to ".llm-bridge/inbox".
+func (a AttachmentConfig) GetInboxDir() string {
+	if a.InboxDir == "" {
+		return filepath.Join(".llm-bridge", "inbox")
+	}
+	return a.InboxDir
+}
+
+// TranscriptConfig controls the per-repo JSONL record of prompts and output.
+type TranscriptConfig struct {
+	Enabled       *bool  `yaml:"enabled"`        // enable/disable transcripts (default: true)
+	Dir           string `yaml:"dir"`            // directory relative to the config file unless absolute (default: llm-bridge.transcripts)
+	RetentionDays int    `yaml:"retention_days"` // days of transcripts to keep (default: 30)
+}
+
+// GetTranscriptsEnabled returns whether prompts and output are recorded.
+// Defaults to true if not explicitly set.
+func (t TranscriptConfig) GetTranscriptsEnabled() bool {
+	if t.Enabled == nil {
+		return true
+	}
+	return *t.Enabled
+}
+
+// GetDir returns the transcript directory, relative to the config file's
+// directory unless absolute. It is kept outside repo working trees so the
+// LLM cannot read or commit other sessions' transcripts.
+// Defaults to "llm-bridge.transcripts".
+func (t TranscriptConfig) GetDir() string {
+	if t.Dir == "" {
+		return "llm-bridge.transcripts"
+	}
+	return t.Dir
+}
+
+// GetRetention returns how long transcripts are kept.
+// Defaults to 30 days.
+func (t TranscriptConfig) GetRetention() time.Duration {
+	days := t.RetentionDays
+	if days == 0 {
+		days = 30
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// ReactionConfig maps emoji reactions on the bridge's messages to actions.
+type ReactionConfig struct {
+	Enabled *bool  `yaml:"enabled"` // enable/disable reaction controls (default: true)
+	Cancel  string `yaml:"cancel"`  // runs /cancel (default: 🛑)
+	Restart string `yaml:"restart"` // runs /restart (default: 🔁)
+	Attach  string `yaml:"attach"`  // re-sends the last output as a file (default: 📎)
+	Approve string `yaml:"approve"` // approves a pending permission prompt (default: ✅)
+	Deny    string `yaml:"deny"`    // denies a pending permission prompt (default: ❌)
+}
+
+// GetReactionsEnabled returns whether reaction controls are enabled.
+// Defaults to true if not explicitly set.
+func (r ReactionConfig) GetReactionsEnabled() bool {
+	if r.Enabled == nil {
+		return true
+	}
+	return *r.Enabled
+}
+
+// GetCancel returns the emoji that cancels the LLM. Defaults to 🛑.
+func (r ReactionConfig) GetCancel() string {
+	if r.Cancel == "" {
+		return "🛑"
+	}
+	return r.Cancel
+}
+
+// GetRestart returns the emoji that restarts the LLM. Defaults to 🔁.
+func (r ReactionConfig) GetRestart() string {
+	if r.Restart == "" {
+		return "🔁"
+	}
+	return r.Restart
+}
+
+// GetAttach returns the emoji that re-sends the last output as a file. Defaults to 📎.
+func (r ReactionConfig) GetAttach() string {
+	if r.Attach == "" {
+		return "📎"
+	}
+	return r.Attach
+}
+
+// GetApprove returns the emoji that approves a permission prompt. Defaults to ✅.
+func (r ReactionConfig) GetApprove() string {
+	if r.Approve == "" {
+		return "✅"
+	}
+	return r.Approve
+}
+
+// GetDeny returns the emoji that denies a permission prompt. Defaults to ❌.
+func (r ReactionConfig) GetDeny() string {
+	if r.Deny == "" {
+		return "❌"
+	}
+	return r.Deny
+}
+
 // GetClaudePath returns the path to the Claude CLI binary.
 // Defaults to "claude" if not explicitly set.
 func (d Defaults) GetClaudePath() string {
@@ -123,17 +608,47 @@ func (d Defaults) GetResumeSession() bool {
 	return *d.ResumeSession
 }
 
-// GetIdleTimeoutDuration returns the idle timeout as a time.Duration.
-// Falls back to 10 minutes if the stored value is unparseable (defensive;
-// loadRaw validates this at load time).
+// GetIdleTimeoutDuration returns the idle timeout as a time.Duration, or 0
+// for "never". Falls back to 10 minutes if the stored value is unparseable
+// (defensive; loadRaw validates this at load time).
 func (d Defaults) GetIdleTimeoutDuration() time.Duration {
-	dur, err := time.ParseDuration(d.IdleTimeout)
+	dur, err := parseIdleTimeout(d.IdleTimeout)
 	if err != nil {
 		return 10 * time.Minute
 	}
 	return dur
 }
 
+// GetIdleWarningDuration returns how long before an idle timeout its
+// channels are warned, or 0 for no warning. Falls back to 2 minutes if the
+// stored value is unset or unparseable.
+func (d Defaults) GetIdleWarningDuration() time.Duration {
+	dur, err := time.ParseDuration(d.IdleWarning)
+	if err != nil {
+		return 2 * time.Minute
+	}
+	return dur
+}
+
+// GetStateFile returns where the bridge records active sessions to resume
+// after a restart, relative to the config file's directory unless absolute.
+// Defaults to "llm-bridge.state.json".
+func (d Defaults) GetStateFile() string {
+	if d.StateFile == "" {
+		return "llm-bridge.state.json"
+	}
+	return d.StateFile
+}
+
+// ResolvePath returns p relative to the directory of the config file at
+// cfgPath, or p itself if it is absolute.
+func ResolvePath(cfgPath, p string) string {
+	if filepath.IsAbs(p) {
+		return p
+	}
+	return filepath.Join(filepath.Dir(cfgPath), p)
+}
+
 // GetBaseDir returns the base directory for cloned repos.
 // Defaults to "." if not explicitly set.
 func (d Defaults) GetBaseDir() string {
@@ -144,13 +659,110 @@ func (d Defaults) GetBaseDir() string {
 }
 
 type ProviderConfigs struct {
-	Discord DiscordConfig `yaml:"discord"`
+	Discord DiscordConfig           `yaml:"discord"`
+	GitHub  GitHubConfig            `yaml:"github"`
+	Plugins map[string]PluginConfig `yaml:"plugins"` // keyed by provider name
+}
+
+// GitHubConfig enables answering issue and pull request comments that
+// mention the bot. See docs/github.md.
+type GitHubConfig struct {
+	WebhookSecret string `yaml:"webhook_secret"`
+	Token         string `yaml:"token"`        // enables the provider
+	Listen        string `yaml:"listen"`       // webhook server address (default: ":8080")
+	Path          string `yaml:"path"`         // webhook URL path (default: "/github/webhook")
+	APIURL        string `yaml:"api_url"`      // REST API base (default: "https://api.github.com")
+	Mention       string `yaml:"mention"`      // trigger in comments (default: "@llm-bridge")
+	QuietPeriod   string `yaml:"quiet_period"` // batch output until quiet this long (default: "10s")
+	ReplyWindow   string `yaml:"reply_window"` // post output this long after a mention (default: "10m")
+
+	// AllowedUsers lists GitHub logins accepted in addition to the
+	// repository's owners, members and collaborators.
+	AllowedUsers []string `yaml:"allowed_users,omitempty"`
+}
+
+// Enabled reports whether the GitHub provider should be started.
+func (g GitHubConfig) Enabled() bool {
+	return g.Token != ""
+}
+
+// GetListen returns the webhook listen address.
+// Defaults to ":8080".
+func (g GitHubConfig) GetListen() string {
+	if g.Listen == "" {
+		return ":8080"
+	}
+	return g.Listen
+}
+
+// GetPath returns the webhook URL path.
+// Defaults to "/github/webhook".
+func (g GitHubConfig) GetPath() string {
+	if g.Path == "" {
+		return "/github/webhook"
+	}
+	return g.Path
+}
+
+// GetAPIURL returns the REST API base URL.
+// Defaults to "https://api.github.com".
+func (g GitHubConfig) GetAPIURL() string {
+	if g.APIURL == "" {
+		return "https://api.github.com"
+	}
+	return g.APIURL
+}
+
+// GetMention returns the text that triggers the bot in a comment.
+// Defaults to "@llm-bridge".
+func (g GitHubConfig) GetMention() string {
+	if g.Mention == "" {
+		return "@llm-bridge"
+	}
+	return g.Mention
+}
+
+// GetQuietPeriod returns how long output must be quiet before it is posted.
+// Defaults to 10 seconds.
+func (g GitHubConfig) GetQuietPeriod() time.Duration {
+	dur, err := time.ParseDuration(g.QuietPeriod)
+	if err != nil || dur <= 0 {
+		return 10 * time.Second
+	}
+	return dur
+}
+
+// GetReplyWindow returns how long after a mention output is still posted.
+// Defaults to 10 minutes.
+func (g GitHubConfig) GetReplyWindow() time.Duration {
+	dur, err := time.ParseDuration(g.ReplyWindow)
+	if err != nil || dur <= 0 {
+		return 10 * time.Minute
+	}
+	return dur
+}
+
+// PluginConfig describes an out-of-process provider plugin. See docs/plugins.md.
+type PluginConfig struct {
+	Command string            `yaml:"command"`
+	Args    []string          `yaml:"args,omitempty"`
+	Env     map[string]string `yaml:"env,omitempty"`
+}
+
+// EnvList returns Env as sorted KEY=VALUE entries.
+func (p PluginConfig) EnvList() []string {
+	env := make([]string, 0, len(p.Env))
+	for k, v := range p.Env {
+		env = append(env, k+"="+v)
+	}
+	sort.Strings(env)
+	return env
 }
 
 type DiscordConfig struct {
 	BotToken      string `yaml:"bot_token"`
 	ApplicationID string `yaml:"application_id"`
-	PublicKey      string `yaml:"public_key"`
+	PublicKey     string `yaml:"public_key"`
 	TestChannelID string `yaml:"test_channel_id"`
 }
 
@@ -185,14 +797,51 @@ func (d DiscordConfig) GetTestChannelID() string {
 	return d.TestChannelID
 }
 
+// ErrDuplicateChannel is returned by Validate when two repo entries, mirror
+// channels or worktrees share a channel_id.
+var ErrDuplicateChannel = errors.New("duplicate channel_id")
+
 // Validate checks the config for structural integrity and channel_id uniqueness.
 // It covers both top-level repo channel_ids and worktree-level channel_ids.
 // Load() calls this before worktree expansion; worktree name conflicts with
 // existing repos are caught separately during expansion. AddRepo() also calls
 // it on the raw form to validate before writing.
 func (c *Config) Validate() error {
+	// An idle warning must come before the timeout it warns about.
+	warning := c.Defaults.GetIdleWarningDuration()
+	if timeout, err := parseIdleTimeout(c.Defaults.IdleTimeout); err == nil && warning > 0 && timeout > 0 && warning >= timeout {
+		return fmt.Errorf("invalid idle_warning %q: must be shorter than idle_timeout %q", c.Defaults.IdleWarning, c.Defaults.IdleTimeout)
+	}
+
 	// Check worktree field integrity on repos that still carry worktree definitions.
 	for name, repo := range c.Repos {
+		switch repo.SessionMode {
+		case "", SessionModeShared, SessionModePerUser:
+		default:
+			return fmt.Errorf("repo %q has invalid session_mode %q: must be %q or %q", name, repo.SessionMode, SessionModeShared, SessionModePerUser)
+		}
+		if repo.UserWorktrees && !repo.PerUser() {
+			return fmt.Errorf("repo %q sets user_worktrees without session_mode %q", name, SessionModePerUser)
+		}
+		if repo.IdleTimeout != "" {
+			timeout, err := parseIdleTimeout(repo.IdleTimeout)
+			if err != nil {
+				return fmt.Errorf("repo %q has invalid idle_timeout %q: must be a duration or %q", name, repo.IdleTimeout, IdleTimeoutNever)
+			}
+			if warning > 0 && timeout > 0 && warning >= timeout {
+				return fmt.Errorf("repo %q has invalid idle_timeout %q: must be longer than idle_warning %v", name, repo.IdleTimeout, warning)
+			}
+		}
+		for i, ch := range repo.Channels {
+			if ch.Provider == "" || ch.ChannelID == "" {
+				return fmt.Errorf("channels[%d] in repo %q needs a provider and channel_id", i, name)
+			}
+			switch ch.Mode {
+			case "", ChannelModeReadWrite, ChannelModeReadOnly, ChannelModeNotifyOnly:
+			default:
+				return fmt.Errorf("channels[%d] in repo %q has invalid mode %q: must be %q, %q or %q", i, name, ch.Mode, ChannelModeReadWrite, ChannelModeReadOnly, ChannelModeNotifyOnly)
+			}
+		}
 		for _, wt := range repo.Worktrees {
 			if wt.Name == "" {
 				return fmt.Errorf("worktree in repo %q has empty name", name)
@@ -206,21 +855,24 @@ func (c *Config) Validate() error {
 		}
 	}
 
-	// Check for duplicate channel IDs across all repo entries and worktree
-	// definitions. This catches collisions both after worktree expansion
-	// (Load path) and on raw configs (AddRepo path).
+	// Check for duplicate channel IDs across all repo entries, their mirror
+	// channels and worktree definitions. This catches collisions both after
+	// worktree expansion (Load path) and on raw configs (AddRepo path).
 	channelIDs := make(map[string]string) // channel_id -> source label
 	for name, repo := range c.Repos {
-		if repo.ChannelID != "" {
-			if existing, ok := channelIDs[repo.ChannelID]; ok {
+		for _, ch := range repo.AllChannels() {
+			if existing, ok := channelIDs[ch.ChannelID]; ok {
+				if existing == name {
+					return fmt.Errorf("%w %q in repo %q", ErrDuplicateChannel, ch.ChannelID, name)
+				}
 				// Sort names for deterministic error messages.
 				a, b := existing, name
 				if a > b {
 					a, b = b, a
 				}
-				return fmt.Errorf("duplicate channel_id %q in repos %q and %q", repo.ChannelID, a, b)
+				return fmt.Errorf("%w %q in repos %q and %q", ErrDuplicateChannel, ch.ChannelID, a, b)
 			}
-			channelIDs[repo.ChannelID] = name
+			channelIDs[ch.ChannelID] = name
 		}
 		for _, wt := range repo.Worktrees {
 			if wt.ChannelID != "" {
@@ -230,7 +882,7 @@ func (c *Config) Validate() error {
 					if a > b {
 						a, b = b, a
 					}
-					return fmt.Errorf("duplicate channel_id %q in repos %q and %q", wt.ChannelID, a, b)
+					return fmt.Errorf("%w %q in repos %q and %q", ErrDuplicateChannel, wt.ChannelID, a, b)
 				}
 				channelIDs[wt.ChannelID] = wtLabel
 			}
@@ -266,16 +918,26 @@ func loadRaw(path string) (*Config, error) {
 	if cfg.Defaults.IdleTimeout == "" {
 		cfg.Defaults.IdleTimeout = defaults.IdleTimeout
 	}
+	if cfg.Defaults.IdleWarning == "" {
+		cfg.Defaults.IdleWarning = defaults.IdleWarning
+	}
 
 	// Validate output_threshold is non-negative.
 	if cfg.Defaults.OutputThreshold < 0 {
 		return nil, fmt.Errorf("invalid output_threshold %d: must be non-negative", cfg.Defaults.OutputThreshold)
 	}
 
-	// Validate idle_timeout is a parseable duration.
-	if _, err := time.ParseDuration(cfg.Defaults.IdleTimeout); err != nil {
+	// Validate idle_timeout is a parseable duration or "never".
+	if _, err := parseIdleTimeout(cfg.Defaults.IdleTimeout); err != nil {
 		return nil, fmt.Errorf("invalid idle_timeout %q: %w", cfg.Defaults.IdleTimeout, err)
 	}
+	if d, err := time.ParseDuration(cfg.Defaults.IdleWarning); err != nil || d < 0 {
+		return nil, fmt.Errorf("invalid idle_warning %q: must be a non-negative duration", cfg.Defaults.IdleWarning)
+	}
+
+	if cfg.Defaults.MaxSessions < 0 {
+		return nil, fmt.Errorf("invalid max_active_sessions %d: must be non-negative", cfg.Defaults.MaxSessions)
+	}
 
 	// Validate rate limit values are non-negative.
 	rl := cfg.Defaults.RateLimit
@@ -297,9 +959,168 @@ func loadRaw(path string) (*Config, error) {
 		return nil, fmt.Errorf("invalid base_dir %q: must be absolute path, \".\", or empty", cfg.Defaults.BaseDir)
 	}
 
+	// Validate plugins: names must not shadow built-in providers.
+	for name, plugin := range cfg.Providers.Plugins {
+		if name == "discord" || name == "github" || name == "terminal" || name == "" {
+			return nil, fmt.Errorf("invalid plugin name %q: reserved", name)
+		}
+		if plugin.Command == "" {
+			return nil, fmt.Errorf("plugin %q has empty command", name)
+		}
+	}
+
+	// Validate github: webhooks must be signed, durations must parse.
+	if gh := cfg.Providers.GitHub; gh.Enabled() {
+		if gh.WebhookSecret == "" {
+			return nil, fmt.Errorf("providers.github.webhook_secret is required when a token is set")
+		}
+		for field, v := range map[string]string{"quiet_period": gh.QuietPeriod, "reply_window": gh.ReplyWindow} {
+			if v == "" {
+				continue
+			}
+			if dur, err := time.ParseDuration(v); err != nil || dur <= 0 {
+				return nil, fmt.Errorf("invalid providers.github.%s %q: must be a positive duration", field, v)
+			}
+		}
+	}
+
+	// Validate webhooks: absolute http(s) URLs, a signing secret and known
+	// event types.
+	for i, wh := range cfg.Webhooks {
+		u, err := url.Parse(wh.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("invalid webhooks[%d].url %q: must be an http or https URL", i, wh.URL)
+		}
+		if wh.Secret == "" {
+			return nil, fmt.Errorf("webhooks[%d].secret is required", i)
+		}
+		for _, ev := range wh.Events {
+			if !webhook.KnownEvent(ev) {
+				return nil, fmt.Errorf("invalid webhooks[%d] event %q: must be one of %v", i, ev, webhook.EventTypes)
+			}
+		}
+	}
+
+	// Validate output_mode.
+	switch cfg.Defaults.OutputMode {
+	case "", OutputModeBroadcast, OutputModeReply:
+	default:
+		return nil, fmt.Errorf("invalid output_mode %q: must be %q or %q", cfg.Defaults.OutputMode, OutputModeBroadcast, OutputModeReply)
+	}
+
+	// Validate delivery queue settings.
+	if cfg.Defaults.Delivery.MaxQueue < 0 {
+		return nil, fmt.Errorf("invalid delivery.max_queue %d: must be non-negative", cfg.Defaults.Delivery.MaxQueue)
+	}
+	if cfg.Defaults.Delivery.MaxAttempts < 0 {
+		return nil, fmt.Errorf("invalid delivery.max_attempts %d: must be non-negative", cfg.Defaults.Delivery.MaxAttempts)
+	}
+
+	// Validate attachment settings: inbox_dir must stay inside working_dir.
+	att := cfg.Defaults.Attachments
+	if att.MaxBytes < 0 {
+		return nil, fmt.Errorf("invalid attachments.max_bytes %d: must be non-negative", att.MaxBytes)
+	}
+	if att.InboxDir != "" && (filepath.IsAbs(att.InboxDir) || !filepath.IsLocal(att.InboxDir)) {
+		return nil, fmt.Errorf("invalid attachments.inbox_dir %q: must be a relative path inside working_dir", att.InboxDir)
+	}
+
+	// Validate transcript settings: dir must stay inside working_dir.
+	tr := cfg.Defaults.Transcripts
+	if tr.RetentionDays < 0 {
+		return nil, fmt.Errorf("invalid transcripts.retention_days %d: must be non-negative", tr.RetentionDays)
+	}
+	if tr.Dir != "" && (filepath.IsAbs(tr.Dir) || !filepath.IsLocal(tr.Dir)) {
+		return nil, fmt.Errorf("invalid transcripts.dir %q: must be a relative path inside working_dir", tr.Dir)
+	}
+
+	// Validate authz: the default role must exist and repo patterns must parse.
+	if az := cfg.Authz; az.DefaultRole != "" {
+		if _, ok := az.Roles[az.DefaultRole]; !ok {
+			return nil, fmt.Errorf("invalid authz.default_role %q: no such role", az.DefaultRole)
+		}
+	}
+	for name, role := range cfg.Authz.Roles {
+		for _, pattern := range role.Repos {
+			if _, err := filepath.Match(pattern, ""); err != nil {
+				return nil, fmt.Errorf("invalid authz.roles.%s repo pattern %q: %w", name, pattern, err)
+			}
+		}
+	}
+
+	// Validate admin_api: local only, and never without a token.
+	if api := cfg.AdminAPI; api.Enabled() {
+		if err := adminapi.CheckLoopback(api.Listen); err != nil {
+			return nil, fmt.Errorf("invalid admin_api.listen: %w", err)
+		}
+		if api.Token == "" {
+			return nil, fmt.Errorf("admin_api.token is required when admin_api.listen is set")
+		}
+	}
+
+	// Validate metrics: a TCP address and an absolute path.
+	if m := cfg.Metrics; m.Enabled() {
+		if _, _, err := net.SplitHostPort(m.Listen); err != nil {
+			return nil, fmt.Errorf("invalid metrics.listen %q: %w", m.Listen, err)
+		}
+		if !strings.HasPrefix(m.GetPath(), "/") {
+			return nil, fmt.Errorf("invalid metrics.path %q: must start with /", m.Path)
+		}
+	}
+
+	// Validate schedules: parseable cron specs, a repo and a prompt each.
+	for name, sc := range cfg.Schedules {
+		if name == "" || strings.ContainsAny(name, " \t\n") {
+			return nil, fmt.Errorf("invalid schedule name %q: must not be empty or contain spaces", name)
+		}
+		if sc.Repo == "" || strings.TrimSpace(sc.Prompt) == "" {
+			return nil, fmt.Errorf("schedules.%s: repo and prompt are required", name)
+		}
+		if _, err := cron.Parse(sc.Cron); err != nil {
+			return nil, fmt.Errorf("invalid schedules.%s.cron: %w", name, err)
+		}
+	}
+	if tz := cfg.Defaults.Schedules.Timezone; tz != "" {
+		if _, err := time.LoadLocation(tz); err != nil {
+			return nil, fmt.Errorf("invalid schedules.timezone %q: %w", tz, err)
+		}
+	}
+	if qh := cfg.Defaults.Schedules.QuietHours; qh != "" {
+		if _, err := cron.ParseWindow(qh); err != nil {
+			return nil, fmt.Errorf("invalid schedules.quiet_hours: %w", err)
+		}
+	}
+
+	// Validate macros: valid templates that do not shadow bridge commands.
+	if err := validateMacros("macros", cfg.Macros); err != nil {
+		return nil, err
+	}
+	for name, repo := range cfg.Repos {
+		if err := validateMacros("repos."+name+".macros", repo.Macros); err != nil {
+			return nil, err
+		}
+	}
+
+	// Validate admin_channel: a channel needs its provider.
+	if ac := cfg.Defaults.AdminChannel; ac.Enabled() && ac.Provider == "" {
+		return nil, fmt.Errorf("admin_channel.provider is required when admin_channel.channel_id is set")
+	}
+
 	return &cfg, nil
 }
 
+func validateMacros(field string, macros map[string]MacroConfig) error {
+	for name, m := range macros {
+		if router.BridgeCommands[name] {
+			return fmt.Errorf("invalid %s.%s: /%s is a bridge command", field, name, name)
+		}
+		if _, err := m.Macro(name); err != nil {
+			return fmt.Errorf("invalid %s.%s: %w", field, name, err)
+		}
+	}
+	return nil
+}
+
 // Load reads the config from path, applies defaults, validates, and
 // expands worktrees into top-level repo entries.
 func Load(path string) (*Config, error) {
@@ -338,6 +1159,11 @@ func Load(path string) (*Config, error) {
 				WorkingDir: wt.Path,
 				GitRoot:    repo.WorkingDir,
 				Branch:     wt.Branch,
+
+				SessionMode:   repo.SessionMode,
+				UserWorktrees: repo.UserWorktrees,
+				Macros:        repo.Macros,
+				IdleTimeout:   repo.IdleTimeout,
 			}
 		}
 	}
@@ -189,6 +189,7 @@ func TestDefaults_GetIdleTimeoutDuration(t *testing.T) {
 		{"valid hours", "1h", 1 * time.Hour},
 		{"invalid returns default", "invalid", 10 * time.Minute},
 		{"empty returns default", "", 10 * time.Minute},
+		{"never", "never", 0},
 	}
 
 	for _, tt := range tests {
@@ -1024,6 +1025,59 @@ func TestLoad_NegativeOutputThreshold(t *testing.T) {
 	}
 }
 
+func TestLoad_IdleWarning(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+
+	for _, tt := range []struct {
+		yaml    string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"repos: {}\n", 2 * time.Minute, false},
+		{"repos: {}\ndefaults:\n  idle_timeout: never\n  idle_warning: 5m\n", 5 * time.Minute, false},
+		{"repos: {}\ndefaults:\n  idle_warning: \"0\"\n", 0, false},
+		{"repos: {}\ndefaults:\n  idle_warning: -1m\n", 0, true},
+		{"repos: {}\ndefaults:\n  idle_warning: soon\n", 0, true},
+		{"repos: {}\ndefaults:\n  idle_timeout: 5m\n  idle_warning: 5m\n", 0, true},
+	} {
+		if err := os.WriteFile(path, []byte(tt.yaml), 0600); err != nil {
+			t.Fatalf("write test config: %v", err)
+		}
+		cfg, err := Load(path)
+		if tt.wantErr {
+			if err == nil || !strings.Contains(err.Error(), "invalid idle_warning") {
+				t.Errorf("Load(%q) error = %v, want invalid idle_warning", tt.yaml, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Load(%q): %v", tt.yaml, err)
+		}
+		if got := cfg.Defaults.GetIdleWarningDuration(); got != tt.want {
+			t.Errorf("Load(%q) idle warning = %v, want %v", tt.yaml, got, tt.want)
+		}
+	}
+}
+
+func TestLoad_NegativeMaxActiveSessions(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+
+	content := "repos: {}\ndefaults:\n  max_active_sessions: -1\n"
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("write test config: %v", err)
+	}
+
+	_, err := Load(path)
+	if err == nil {
+		t.Fatal("Load() expected error for negative max_active_sessions")
+	}
+	if !strings.Contains(err.Error(), "invalid max_active_sessions") {
+		t.Errorf("error = %q, want to contain %q", err.Error(), "invalid max_active_sessions")
+	}
+}
+
 func TestLoad_NegativeRateLimitValues(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -1284,3 +1338,809 @@ defaults:
 		t.Errorf("GetBaseDir() = %q, want %q", cfg.Defaults.GetBaseDir(), "/var/cloned-repos")
 	}
 }
+
+func TestAttachmentConfig_Defaults(t *testing.T) {
+	var a AttachmentConfig
+
+	if !a.GetAttachmentsEnabled() {
+		t.Error("GetAttachmentsEnabled() should default to true")
+	}
+	if got := a.GetMaxBytes(); got != 8<<20 {
+		t.Errorf("GetMaxBytes() = %d, want %d", got, 8<<20)
+	}
+	if got := a.GetAllowedTypes(); !reflect.DeepEqual(got, DefaultAttachmentTypes) {
+		t.Errorf("GetAllowedTypes() = %v, want defaults", got)
+	}
+	if got := a.GetInboxDir(); got != filepath.Join(".llm-bridge", "inbox") {
+		t.Errorf("GetInboxDir() = %q", got)
+	}
+}
+
+func TestLoad_AttachmentConfig(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+
+	content := `
+repos: {}
+defaults:
+  attachments:
+    enabled: false
+    max_bytes: 1024
+    allowed_types: ["text/*"]
+    inbox_dir: uploads
+`
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("write test config: %v", err)
+	}
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	att := cfg.Defaults.Attachments
+	if att.GetAttachmentsEnabled() {
+		t.Error("attachments should be disabled")
+	}
+	if att.GetMaxBytes() != 1024 {
+		t.Errorf("GetMaxBytes() = %d, want 1024", att.GetMaxBytes())
+	}
+	if !reflect.DeepEqual(att.GetAllowedTypes(), []string{"text/*"}) {
+		t.Errorf("GetAllowedTypes() = %v", att.GetAllowedTypes())
+	}
+	if att.GetInboxDir() != "uploads" {
+		t.Errorf("GetInboxDir() = %q, want uploads", att.GetInboxDir())
+	}
+}
+
+func TestLoad_InvalidAttachmentConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		wantErr string
+	}{
+		{"negative max_bytes", "defaults:\n  attachments:\n    max_bytes: -1\n", "invalid attachments.max_bytes"},
+		{"absolute inbox_dir", "defaults:\n  attachments:\n    inbox_dir: /tmp/inbox\n", "invalid attachments.inbox_dir"},
+		{"escaping inbox_dir", "defaults:\n  attachments:\n    inbox_dir: ../inbox\n", "invalid attachments.inbox_dir"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(path, []byte(tt.yaml), 0600); err != nil {
+				t.Fatalf("write test config: %v", err)
+			}
+			_, err := Load(path)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Load() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTranscriptConfig(t *testing.T) {
+	var tr TranscriptConfig
+	if !tr.GetTranscriptsEnabled() {
+		t.Error("GetTranscriptsEnabled() should default to true")
+	}
+	if got := tr.GetDir(); got != "llm-bridge.transcripts" {
+		t.Errorf("GetDir() = %q", got)
+	}
+	if got := tr.GetRetention(); got != 30*24*time.Hour {
+		t.Errorf("GetRetention() = %v, want 30 days", got)
+	}
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	content := "defaults:\n  transcripts:\n    enabled: false\n    dir: logs\n    retention_days: 7\n"
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("write test config: %v", err)
+	}
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	tr = cfg.Defaults.Transcripts
+	if tr.GetTranscriptsEnabled() || tr.GetDir() != "logs" || tr.GetRetention() != 7*24*time.Hour {
+		t.Errorf("loaded transcripts = %+v", tr)
+	}
+
+	for _, bad := range []string{"retention_days: -1", "dir: /var/log", "dir: ../logs"} {
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		if err := os.WriteFile(path, []byte("defaults:\n  transcripts:\n    "+bad+"\n"), 0600); err != nil {
+			t.Fatalf("write test config: %v", err)
+		}
+		if _, err := Load(path); err == nil || !strings.Contains(err.Error(), "invalid transcripts.") {
+			t.Errorf("%s: Load() error = %v", bad, err)
+		}
+	}
+}
+
+func TestDefaults_GetStateFile(t *testing.T) {
+	if got := (Defaults{}).GetStateFile(); got != "llm-bridge.state.json" {
+		t.Errorf("GetStateFile() = %q, want default", got)
+	}
+	if got := (Defaults{StateFile: "/var/lib/llm-bridge/state.json"}).GetStateFile(); got != "/var/lib/llm-bridge/state.json" {
+		t.Errorf("GetStateFile() = %q", got)
+	}
+}
+
+func TestResolvePath(t *testing.T) {
+	if got := ResolvePath("/etc/llm-bridge/llm-bridge.yaml", "state.json"); got != "/etc/llm-bridge/state.json" {
+		t.Errorf("relative: ResolvePath() = %q", got)
+	}
+	if got := ResolvePath("/etc/llm-bridge/llm-bridge.yaml", "/var/log/audit.jsonl"); got != "/var/log/audit.jsonl" {
+		t.Errorf("absolute: ResolvePath() = %q", got)
+	}
+}
+
+func TestAuditConfig(t *testing.T) {
+	var a AuditConfig
+	if !a.GetAuditEnabled() {
+		t.Error("GetAuditEnabled() should default to true")
+	}
+	if got := a.GetFile(); got != "llm-bridge.audit.jsonl" {
+		t.Errorf("GetFile() = %q, want default", got)
+	}
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("defaults:\n  audit:\n    enabled: false\n    file: /var/log/llm-bridge/audit.jsonl\n"), 0600); err != nil {
+		t.Fatalf("write test config: %v", err)
+	}
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if a := cfg.Defaults.Audit; a.GetAuditEnabled() || a.GetFile() != "/var/log/llm-bridge/audit.jsonl" {
+		t.Errorf("audit = %+v", a)
+	}
+}
+
+func TestLoad_Authz(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	content := `authz:
+  default_role: viewer
+  roles:
+    viewer:
+      users: ["*"]
+      commands: [status, history]
+    dev:
+      role_ids: ["111"]
+      commands: [status, cancel]
+      repos: ["app", "app/*"]
+      prompt: true
+`
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("write test config: %v", err)
+	}
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if !cfg.Authz.Enabled() || cfg.Authz.DefaultRole != "viewer" {
+		t.Errorf("authz = %+v", cfg.Authz)
+	}
+	dev := cfg.Authz.Roles["dev"]
+	if !dev.Prompt || !reflect.DeepEqual(dev.RoleIDs, []string{"111"}) || !reflect.DeepEqual(dev.Repos, []string{"app", "app/*"}) {
+		t.Errorf("dev role = %+v", dev)
+	}
+	if cfg.Authz.Roles["viewer"].Prompt {
+		t.Error("prompt should default to false")
+	}
+	if (AuthzConfig{}).Enabled() {
+		t.Error("authz without roles should be disabled")
+	}
+
+	for _, tt := range []struct{ yaml, wantErr string }{
+		{"authz:\n  default_role: nobody\n  roles:\n    viewer: {}\n", "invalid authz.default_role"},
+		{"authz:\n  roles:\n    dev:\n      repos: [\"[\"]\n", "invalid authz.roles.dev repo pattern"},
+	} {
+		if err := os.WriteFile(path, []byte(tt.yaml), 0600); err != nil {
+			t.Fatalf("write test config: %v", err)
+		}
+		if _, err := Load(path); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("Load() error = %v, want %q", err, tt.wantErr)
+		}
+	}
+}
+
+func TestLoad_AdminChannel(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("defaults:\n  admin_channel:\n    provider: discord\n    channel_id: ops\n"), 0600); err != nil {
+		t.Fatalf("write test config: %v", err)
+	}
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if ac := cfg.Defaults.AdminChannel; !ac.Enabled() || ac.Provider != "discord" || ac.ChannelID != "ops" {
+		t.Errorf("admin channel = %+v", ac)
+	}
+	if (AdminChannel{}).Enabled() {
+		t.Error("empty admin channel should be disabled")
+	}
+
+	if err := os.WriteFile(path, []byte("defaults:\n  admin_channel:\n    channel_id: ops\n"), 0600); err != nil {
+		t.Fatalf("write test config: %v", err)
+	}
+	if _, err := Load(path); err == nil || !strings.Contains(err.Error(), "admin_channel.provider") {
+		t.Errorf("missing provider: Load() error = %v", err)
+	}
+}
+
+func TestLoad_AdminAPI(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{"loopback", "admin_api:\n  listen: 127.0.0.1:9090\n  token: t\n", ""},
+		{"unix socket", "admin_api:\n  listen: unix:/run/llm-bridge/admin.sock\n  token: t\n", ""},
+		{"disabled", "repos: {}\n", ""},
+		{"all interfaces", "admin_api:\n  listen: 0.0.0.0:9090\n  token: t\n", "admin_api.listen"},
+		{"no token", "admin_api:\n  listen: 127.0.0.1:9090\n", "admin_api.token"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(path, []byte(tt.content), 0600); err != nil {
+				t.Fatalf("write test config: %v", err)
+			}
+			_, err := Load(path)
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("Load() error = %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("Load() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoad_Metrics(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		wantPath string
+		wantErr  string
+	}{
+		{"default path", "metrics:\n  listen: 0.0.0.0:9464\n", "/metrics", ""},
+		{"custom path", "metrics:\n  listen: 127.0.0.1:9464\n  path: /prom\n", "/prom", ""},
+		{"bad listen", "metrics:\n  listen: 9464\n", "", "metrics.listen"},
+		{"relative path", "metrics:\n  listen: :9464\n  path: prom\n", "", "metrics.path"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(path, []byte(tt.content), 0600); err != nil {
+				t.Fatalf("write test config: %v", err)
+			}
+			cfg, err := Load(path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Load() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			if !cfg.Metrics.Enabled() || cfg.Metrics.GetPath() != tt.wantPath {
+				t.Errorf("metrics = %+v, path %q", cfg.Metrics, cfg.Metrics.GetPath())
+			}
+		})
+	}
+}
+
+func TestLoad_Schedules(t *testing.T) {
+	valid := `defaults:
+  schedules:
+    timezone: Europe/Berlin
+    quiet_hours: "22:00-07:00"
+schedules:
+  nightly:
+    repo: app
+    cron: "0 7 * * 1-5"
+    prompt: run the test suite and summarise failures
+`
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(valid), 0600); err != nil {
+		t.Fatalf("write test config: %v", err)
+	}
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if sc := cfg.Schedules["nightly"]; sc.Repo != "app" || sc.Cron != "0 7 * * 1-5" || sc.Paused {
+		t.Errorf("schedule = %+v", sc)
+	}
+	if loc := cfg.Defaults.Schedules.GetLocation(); loc.String() != "Europe/Berlin" {
+		t.Errorf("location = %v", loc)
+	}
+	if w, ok := cfg.Defaults.Schedules.GetQuietHours(); !ok || w.String() != "22:00-07:00" {
+		t.Errorf("quiet hours = %v, %v", w, ok)
+	}
+
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{"bad cron", "schedules:\n  x:\n    repo: app\n    cron: \"0 7 * *\"\n    prompt: hi\n", "schedules.x.cron"},
+		{"no prompt", "schedules:\n  x:\n    repo: app\n    cron: \"@daily\"\n", "repo and prompt are required"},
+		{"spaced name", "schedules:\n  \"a b\":\n    repo: app\n    cron: \"@daily\"\n    prompt: hi\n", "invalid schedule name"},
+		{"bad timezone", "defaults:\n  schedules:\n    timezone: Mars/Olympus\n", "schedules.timezone"},
+		{"bad quiet hours", "defaults:\n  schedules:\n    quiet_hours: nights\n", "schedules.quiet_hours"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(path, []byte(tt.content), 0600); err != nil {
+				t.Fatalf("write test config: %v", err)
+			}
+			if _, err := Load(path); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Load() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestScheduleDefaults_Unset(t *testing.T) {
+	var s ScheduleDefaults
+	if s.GetLocation() != time.Local {
+		t.Error("location should default to local time")
+	}
+	if _, ok := s.GetQuietHours(); ok {
+		t.Error("no quiet hours by default")
+	}
+}
+
+func TestReactionConfig_Defaults(t *testing.T) {
+	var r ReactionConfig
+
+	if !r.GetReactionsEnabled() {
+		t.Error("GetReactionsEnabled() should default to true")
+	}
+	got := []string{r.GetCancel(), r.GetRestart(), r.GetAttach(), r.GetApprove(), r.GetDeny()}
+	want := []string{"🛑", "🔁", "📎", "✅", "❌"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("default emoji = %v, want %v", got, want)
+	}
+
+	r = ReactionConfig{Cancel: "x", Restart: "r", Attach: "a", Approve: "y", Deny: "n"}
+	got = []string{r.GetCancel(), r.GetRestart(), r.GetAttach(), r.GetApprove(), r.GetDeny()}
+	want = []string{"x", "r", "a", "y", "n"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("custom emoji = %v, want %v", got, want)
+	}
+}
+
+func TestLoad_OutputMode(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		want    string
+		wantErr string
+	}{
+		{"default", "repos: {}\n", OutputModeBroadcast, ""},
+		{"reply", "defaults:\n  output_mode: reply\n", OutputModeReply, ""},
+		{"invalid", "defaults:\n  output_mode: thread\n", "", "invalid output_mode"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(path, []byte(tt.yaml), 0600); err != nil {
+				t.Fatalf("write test config: %v", err)
+			}
+			cfg, err := Load(path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Load() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			if got := cfg.Defaults.GetOutputMode(); got != tt.want {
+				t.Errorf("GetOutputMode() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDeliveryConfig(t *testing.T) {
+	var d DeliveryConfig
+	if d.GetMaxQueue() != 100 || d.GetMaxAttempts() != 5 {
+		t.Errorf("defaults = %d, %d; want 100, 5", d.GetMaxQueue(), d.GetMaxAttempts())
+	}
+
+	for _, yml := range []string{
+		"defaults:\n  delivery:\n    max_queue: -1\n",
+		"defaults:\n  delivery:\n    max_attempts: -1\n",
+	} {
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		if err := os.WriteFile(path, []byte(yml), 0600); err != nil {
+			t.Fatalf("write test config: %v", err)
+		}
+		if _, err := Load(path); err == nil || !strings.Contains(err.Error(), "invalid delivery") {
+			t.Errorf("Load(%q) error = %v, want invalid delivery", yml, err)
+		}
+	}
+}
+
+func TestLoad_Plugins(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	content := `
+providers:
+  plugins:
+    slack:
+      command: /usr/local/bin/slack-plugin
+      args: ["--verbose"]
+      env:
+        B: "2"
+        A: "1"
+repos:
+  app:
+    provider: slack
+    channel_id: C1
+    working_dir: /tmp/app
+`
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("write test config: %v", err)
+	}
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	plugin, ok := cfg.Providers.Plugins["slack"]
+	if !ok {
+		t.Fatal("plugin slack not loaded")
+	}
+	if plugin.Command != "/usr/local/bin/slack-plugin" || !reflect.DeepEqual(plugin.Args, []string{"--verbose"}) {
+		t.Errorf("plugin = %+v", plugin)
+	}
+	if !reflect.DeepEqual(plugin.EnvList(), []string{"A=1", "B=2"}) {
+		t.Errorf("EnvList() = %v", plugin.EnvList())
+	}
+}
+
+func TestLoad_InvalidPlugins(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		wantErr string
+	}{
+		{"reserved name", "providers:\n  plugins:\n    discord:\n      command: x\n", "reserved"},
+		{"empty command", "providers:\n  plugins:\n    slack: {}\n", "empty command"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(path, []byte(tt.yaml), 0600); err != nil {
+				t.Fatalf("write test config: %v", err)
+			}
+			_, err := Load(path)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Load() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGitHubConfig_Defaults(t *testing.T) {
+	var g GitHubConfig
+	if g.Enabled() {
+		t.Error("Enabled() = true without a token")
+	}
+	if g.GetListen() != ":8080" || g.GetPath() != "/github/webhook" || g.GetAPIURL() != "https://api.github.com" || g.GetMention() != "@llm-bridge" {
+		t.Errorf("defaults = %q %q %q %q", g.GetListen(), g.GetPath(), g.GetAPIURL(), g.GetMention())
+	}
+	if g.GetQuietPeriod() != 10*time.Second || g.GetReplyWindow() != 10*time.Minute {
+		t.Errorf("durations = %v %v", g.GetQuietPeriod(), g.GetReplyWindow())
+	}
+
+	g = GitHubConfig{Token: "t", QuietPeriod: "3s", ReplyWindow: "1h", Mention: "@bot"}
+	if !g.Enabled() || g.GetQuietPeriod() != 3*time.Second || g.GetReplyWindow() != time.Hour || g.GetMention() != "@bot" {
+		t.Errorf("configured values not honored: %+v", g)
+	}
+}
+
+func TestLoad_InvalidGitHub(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		wantErr string
+	}{
+		{"missing secret", "providers:\n  github:\n    token: t\n", "webhook_secret is required"},
+		{"bad quiet period", "providers:\n  github:\n    token: t\n    webhook_secret: s\n    quiet_period: soon\n", "quiet_period"},
+		{"negative reply window", "providers:\n  github:\n    token: t\n    webhook_secret: s\n    reply_window: -1m\n", "reply_window"},
+		{"plugin shadows github", "providers:\n  plugins:\n    github:\n      command: x\n", "reserved"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(path, []byte(tt.yaml), 0600); err != nil {
+				t.Fatalf("write test config: %v", err)
+			}
+			_, err := Load(path)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Load() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoad_Webhooks(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	content := `
+webhooks:
+  - url: https://hooks.example.com/llm-bridge
+    secret: s3cret
+    events: [session.crashed, repo.cloned]
+  - url: http://localhost:9000/all
+    secret: other
+`
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("write test config: %v", err)
+	}
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(cfg.Webhooks) != 2 {
+		t.Fatalf("expected 2 webhooks, got %d", len(cfg.Webhooks))
+	}
+	if cfg.Webhooks[0].Secret != "s3cret" || !reflect.DeepEqual(cfg.Webhooks[0].Events, []string{"session.crashed", "repo.cloned"}) {
+		t.Errorf("webhook[0] = %+v", cfg.Webhooks[0])
+	}
+}
+
+func TestLoad_InvalidWebhooks(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		wantErr string
+	}{
+		{"relative url", "webhooks:\n  - url: /hook\n    secret: k\n", "must be an http or https URL"},
+		{"bad scheme", "webhooks:\n  - url: ftp://example.com/hook\n    secret: k\n", "must be an http or https URL"},
+		{"missing secret", "webhooks:\n  - url: https://example.com\n", "webhooks[0].secret is required"},
+		{"unknown event", "webhooks:\n  - url: https://example.com\n    secret: k\n    events: [session.exploded]\n", "session.exploded"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(path, []byte(tt.yaml), 0600); err != nil {
+				t.Fatalf("write test config: %v", err)
+			}
+			_, err := Load(path)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Load() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoad_SessionMode(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	content := `
+repos:
+  app:
+    provider: discord
+    channel_id: C1
+    working_dir: /tmp/app
+    session_mode: per_user
+    user_worktrees: true
+    worktrees:
+      - name: feature
+        path: /tmp/app-feature
+        channel_id: C2
+        branch: feature
+`
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("write test config: %v", err)
+	}
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if !cfg.Repos["app"].PerUser() || !cfg.Repos["app"].UserWorktrees {
+		t.Errorf("app = %+v", cfg.Repos["app"])
+	}
+	if !cfg.Repos["app/feature"].PerUser() || !cfg.Repos["app/feature"].UserWorktrees {
+		t.Errorf("worktree should inherit session settings: %+v", cfg.Repos["app/feature"])
+	}
+}
+
+func TestValidate_SessionMode(t *testing.T) {
+	tests := []struct {
+		name    string
+		repo    RepoConfig
+		wantErr string
+	}{
+		{"unknown mode", RepoConfig{SessionMode: "per_channel"}, "invalid session_mode"},
+		{"worktrees without per_user", RepoConfig{UserWorktrees: true}, "user_worktrees"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{Repos: map[string]RepoConfig{"app": tt.repo}}
+			err := cfg.Validate()
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRepoConfig_GetIdleTimeout(t *testing.T) {
+	tests := []struct {
+		timeout string
+		want    time.Duration
+	}{
+		{"", 10 * time.Minute},
+		{"2h", 2 * time.Hour},
+		{"never", 0},
+	}
+	for _, tt := range tests {
+		r := RepoConfig{IdleTimeout: tt.timeout}
+		if got := r.GetIdleTimeout(10 * time.Minute); got != tt.want {
+			t.Errorf("GetIdleTimeout() with %q = %v, want %v", tt.timeout, got, tt.want)
+		}
+	}
+
+	err := (&Config{Repos: map[string]RepoConfig{"app": {Provider: "discord", ChannelID: "1", IdleTimeout: "forever"}}}).Validate()
+	if err == nil || !strings.Contains(err.Error(), `repo "app" has invalid idle_timeout "forever"`) {
+		t.Errorf("Validate() error = %v, want invalid idle_timeout", err)
+	}
+
+	err = (&Config{Repos: map[string]RepoConfig{"app": {Provider: "discord", ChannelID: "1", IdleTimeout: "1m"}}, Defaults: NewDefaults()}).Validate()
+	if err == nil || !strings.Contains(err.Error(), `repo "app" has invalid idle_timeout "1m": must be longer than idle_warning 2m0s`) {
+		t.Errorf("Validate() error = %v, want idle_timeout longer than idle_warning", err)
+	}
+}
+
+func TestValidate_Channels(t *testing.T) {
+	mirror := func(provider, channelID, mode string) RepoConfig {
+		return RepoConfig{Provider: "discord", ChannelID: "1", Channels: []ChannelConfig{{Provider: provider, ChannelID: channelID, Mode: mode}}}
+	}
+	other := RepoConfig{Provider: "discord", ChannelID: "2", Channels: []ChannelConfig{{Provider: "slack", ChannelID: "C1", Mode: ChannelModeNotifyOnly}}}
+	tests := []struct {
+		name    string
+		repos   map[string]RepoConfig
+		wantErr string
+	}{
+		{"valid", map[string]RepoConfig{"app": mirror("slack", "C1", ChannelModeReadOnly)}, ""},
+		{"unknown mode", map[string]RepoConfig{"app": mirror("slack", "C1", "write-only")}, "invalid mode"},
+		{"no provider", map[string]RepoConfig{"app": mirror("", "C1", "")}, "needs a provider and channel_id"},
+		{"no channel", map[string]RepoConfig{"app": mirror("slack", "", "")}, "needs a provider and channel_id"},
+		{"same repo", map[string]RepoConfig{"app": mirror("discord", "1", "")}, `duplicate channel_id "1" in repo "app"`},
+		{"other repo's channel", map[string]RepoConfig{
+			"app":   mirror("slack", "C1", ""),
+			"other": {Provider: "slack", ChannelID: "C1"},
+		}, `duplicate channel_id "C1" in repos "app" and "other"`},
+		{"two mirrors", map[string]RepoConfig{
+			"app":   mirror("slack", "C1", ""),
+			"other": other,
+		}, `duplicate channel_id "C1" in repos "app" and "other"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := (&Config{Repos: tt.repos}).Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Validate() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRepoConfig_ChannelMode(t *testing.T) {
+	repo := RepoConfig{
+		Provider:  "discord",
+		ChannelID: "1",
+		Channels: []ChannelConfig{
+			{Provider: "slack", ChannelID: "C1"},
+			{Provider: "discord", ChannelID: "2", Mode: ChannelModeNotifyOnly},
+		},
+	}
+	for _, tt := range []struct {
+		channelID string
+		want      string
+		wantOK    bool
+	}{
+		{"1", ChannelModeReadWrite, true},
+		{"C1", ChannelModeReadWrite, true},
+		{"2", ChannelModeNotifyOnly, true},
+		{"3", "", false},
+		{"", "", false},
+	} {
+		if got, ok := repo.ChannelMode(tt.channelID); got != tt.want || ok != tt.wantOK {
+			t.Errorf("ChannelMode(%q) = %q, %v, want %q, %v", tt.channelID, got, ok, tt.want, tt.wantOK)
+		}
+	}
+	if got := len(repo.AllChannels()); got != 3 {
+		t.Errorf("AllChannels() has %d channels, want 3", got)
+	}
+	if got := len((RepoConfig{}).AllChannels()); got != 0 {
+		t.Errorf("AllChannels() of a repo without channels has %d, want 0", got)
+	}
+}
+
+func TestLoad_Macros(t *testing.T) {
+	valid := `macros:
+  review:
+    description: Review a branch
+    args: [branch, focus]
+    defaults:
+      focus: correctness
+    prompt: Review {branch} for {focus}.
+  triage:
+    args: [issue_url]
+    prompt: Triage {issue_url}.
+repos:
+  app:
+    provider: discord
+    channel_id: "1"
+    working_dir: /tmp/app
+    worktrees:
+      - name: feature
+        path: /tmp/app-feature
+        channel_id: "2"
+    macros:
+      review:
+        args: [branch]
+        prompt: Review {branch} against our style guide.
+  other:
+    provider: discord
+    channel_id: "3"
+    working_dir: /tmp/other
+`
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(valid), 0600); err != nil {
+		t.Fatalf("write test config: %v", err)
+	}
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	for _, tt := range []struct {
+		repo, macro, want string
+	}{
+		{"other", "review", "Review {branch} for {focus}."},
+		{"app", "review", "Review {branch} against our style guide."},
+		{"app/feature", "review", "Review {branch} against our style guide."},
+		{"app", "triage", "Triage {issue_url}."},
+	} {
+		if got := cfg.MacrosFor(tt.repo)[tt.macro].Prompt; got != tt.want {
+			t.Errorf("MacrosFor(%q)[%q].Prompt = %q, want %q", tt.repo, tt.macro, got, tt.want)
+		}
+	}
+	if _, ok := cfg.MacrosFor("unknown")["triage"]; !ok {
+		t.Error("top-level macros should apply to any repo")
+	}
+
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{"bridge command", "macros:\n  status:\n    prompt: hi\n", "/status is a bridge command"},
+		{"undeclared placeholder", "macros:\n  review:\n    prompt: Review {branch}\n", "invalid macros.review"},
+		{"bad name", "macros:\n  Review:\n    prompt: hi\n", "invalid macro name"},
+		{"repo macro", "repos:\n  app:\n    working_dir: /tmp/app\n    macros:\n      x:\n        prompt: \"\"\n", "invalid repos.app.macros.x"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(path, []byte(tt.content), 0600); err != nil {
+				t.Fatalf("write test config: %v", err)
+			}
+			if _, err := Load(path); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Load() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,223 @@
+// Package cron parses cron schedules and daily time windows such as quiet
+// hours.
+package cron
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule is a parsed five-field cron expression: minute, hour, day of
+// month, month and day of week.
+type Schedule struct {
+	minute, hour, dom, month, dow uint64 // bit i set if value i matches
+	domAny, dowAny                bool   // field was "*", for the day-matching rule
+}
+
+// field describes the range and names of one cron field.
+type field struct {
+	name     string
+	min, max int
+	names    map[string]int
+}
+
+var (
+	minuteField = field{name: "minute", min: 0, max: 59}
+	hourField   = field{name: "hour", min: 0, max: 23}
+	domField    = field{name: "day of month", min: 1, max: 31}
+	monthField  = field{name: "month", min: 1, max: 12, names: map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	// Day of week 7 is Sunday, like 0.
+	dowField = field{name: "day of week", min: 0, max: 7, names: map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+)
+
+var shortcuts = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// Parse parses a cron expression such as "0 7 * * 1-5". Fields accept *,
+// numbers, ranges (1-5), steps (*/15, 0-30/10), lists (1,3,5) and, for month
+// and day of week, three-letter names (jan, mon-fri). The shortcuts @hourly,
+// @daily, @weekly, @monthly and @yearly are also accepted.
+func Parse(spec string) (*Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	if expanded, ok := shortcuts[strings.ToLower(spec)]; ok {
+		spec = expanded
+	}
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron %q: want 5 fields (minute hour day-of-month month day-of-week), got %d", spec, len(fields))
+	}
+
+	s := &Schedule{domAny: fields[2] == "*", dowAny: fields[4] == "*"}
+	var err error
+	for i, dst := range []struct {
+		bits *uint64
+		f    field
+	}{
+		{&s.minute, minuteField},
+		{&s.hour, hourField},
+		{&s.dom, domField},
+		{&s.month, monthField},
+		{&s.dow, dowField},
+	} {
+		if *dst.bits, err = parseField(fields[i], dst.f); err != nil {
+			return nil, fmt.Errorf("cron %q: %w", spec, err)
+		}
+	}
+	if s.dow&(1<<7) != 0 {
+		s.dow |= 1
+	}
+	return s, nil
+}
+
+func parseField(expr string, f field) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(expr, ",") {
+		rangeExpr, stepExpr, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepExpr)
+			if err != nil || n < 1 {
+				return 0, fmt.Errorf("invalid %s step %q", f.name, stepExpr)
+			}
+			step = n
+		}
+
+		lo, hi := f.min, f.max
+		if rangeExpr != "*" {
+			loExpr, hiExpr, isRange := strings.Cut(rangeExpr, "-")
+			var err error
+			if lo, err = f.value(loExpr); err != nil {
+				return 0, err
+			}
+			hi = lo
+			if isRange {
+				if hi, err = f.value(hiExpr); err != nil {
+					return 0, err
+				}
+			} else if hasStep {
+				hi = f.max // "5/15" means 5, 20, 35, 50
+			}
+			if hi < lo {
+				return 0, fmt.Errorf("invalid %s range %q", f.name, rangeExpr)
+			}
+		}
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << v
+		}
+	}
+	return bits, nil
+}
+
+func (f field) value(s string) (int, error) {
+	if v, ok := f.names[strings.ToLower(s)]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil || v < f.min || v > f.max {
+		return 0, fmt.Errorf("invalid %s %q (want %d-%d)", f.name, s, f.min, f.max)
+	}
+	return v, nil
+}
+
+// Matches reports whether t, to the minute, is one of the schedule's times.
+// As in cron, when both day of month and day of week are restricted, a day
+// matching either one matches.
+func (s *Schedule) Matches(t time.Time) bool {
+	return s.minute&(1<<t.Minute()) != 0 &&
+		s.hour&(1<<t.Hour()) != 0 &&
+		s.month&(1<<int(t.Month())) != 0 &&
+		s.dayMatches(t)
+}
+
+func (s *Schedule) dayMatches(t time.Time) bool {
+	dom := s.dom&(1<<t.Day()) != 0
+	dow := s.dow&(1<<int(t.Weekday())) != 0
+	if s.domAny || s.dowAny {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+// Next returns the first matching minute after t, in t's location, or the
+// zero time if there is none within five years (e.g. "0 0 31 2 *").
+func (s *Schedule) Next(t time.Time) time.Time {
+	loc := t.Location()
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	for end := t.AddDate(5, 0, 0); t.Before(end); {
+		switch {
+		case s.month&(1<<int(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+		case !s.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+		case s.hour&(1<<t.Hour()) == 0:
+			t = t.Truncate(time.Hour).Add(time.Hour)
+		case s.minute&(1<<t.Minute()) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// Window is a daily span of wall-clock time, such as quiet hours. It may
+// wrap past midnight ("22:00-07:00").
+type Window struct {
+	start, end int // minutes since midnight; start inclusive, end exclusive
+}
+
+// ParseWindow parses "HH:MM-HH:MM".
+func ParseWindow(spec string) (Window, error) {
+	startExpr, endExpr, ok := strings.Cut(spec, "-")
+	if !ok {
+		return Window{}, fmt.Errorf("window %q: want HH:MM-HH:MM", spec)
+	}
+	start, err := parseClock(startExpr)
+	if err != nil {
+		return Window{}, fmt.Errorf("window %q: %w", spec, err)
+	}
+	end, err := parseClock(endExpr)
+	if err != nil {
+		return Window{}, fmt.Errorf("window %q: %w", spec, err)
+	}
+	if start == end {
+		return Window{}, fmt.Errorf("window %q: start and end are the same", spec)
+	}
+	return Window{start: start, end: end}, nil
+}
+
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q: want HH:MM", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// Contains reports whether t's wall-clock time falls in the window.
+func (w Window) Contains(t time.Time) bool {
+	m := t.Hour()*60 + t.Minute()
+	if w.start < w.end {
+		return m >= w.start && m < w.end
+	}
+	return m >= w.start || m < w.end
+}
+
+// String formats the window as HH:MM-HH:MM.
+func (w Window) String() string {
+	return fmt.Sprintf("%02d:%02d-%02d:%02d", w.start/60, w.start%60, w.end/60, w.end%60)
+}
@@ -0,0 +1,133 @@
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func mustParse(t *testing.T, spec string) *Schedule {
+	t.Helper()
+	s, err := Parse(spec)
+	if err != nil {
+		t.Fatalf("Parse(%q): %v", spec, err)
+	}
+	return s
+}
+
+func date(s string) time.Time {
+	t, err := time.Parse("2006-01-02 15:04", s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestParse_Invalid(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"a * * * *",
+		"* * * foo *",
+	} {
+		if _, err := Parse(spec); err == nil {
+			t.Errorf("Parse(%q) should fail", spec)
+		}
+	}
+}
+
+func TestSchedule_Matches(t *testing.T) {
+	tests := []struct {
+		spec string
+		at   string
+		want bool
+	}{
+		{"0 7 * * 1-5", "2026-10-19 07:00", true},  // Monday
+		{"0 7 * * 1-5", "2026-10-18 07:00", false}, // Sunday
+		{"0 7 * * 1-5", "2026-10-19 07:01", false},
+		{"0 7 * * mon-fri", "2026-10-23 07:00", true},
+		{"*/15 * * * *", "2026-10-18 13:45", true},
+		{"*/15 * * * *", "2026-10-18 13:46", false},
+		{"5/20 * * * *", "2026-10-18 13:25", true},
+		{"0 9,17 * * *", "2026-10-18 17:00", true},
+		{"0 0 * * 7", "2026-10-18 00:00", true}, // 7 is Sunday
+		{"0 0 1 jan *", "2027-01-01 00:00", true},
+		{"@hourly", "2026-10-18 13:00", true},
+		{"@daily", "2026-10-18 13:00", false},
+		// Both day fields restricted: either may match.
+		{"0 0 13 * 5", "2026-11-13 00:00", true}, // Friday the 13th
+		{"0 0 13 * 5", "2026-10-13 00:00", true}, // 13th, a Tuesday
+		{"0 0 13 * 5", "2026-10-16 00:00", true}, // Friday the 16th
+		{"0 0 13 * 5", "2026-10-15 00:00", false},
+	}
+	for _, tt := range tests {
+		if got := mustParse(t, tt.spec).Matches(date(tt.at)); got != tt.want {
+			t.Errorf("%q.Matches(%s) = %v, want %v", tt.spec, tt.at, got, tt.want)
+		}
+	}
+}
+
+func TestSchedule_Next(t *testing.T) {
+	tests := []struct {
+		spec, from, want string
+	}{
+		{"0 7 * * 1-5", "2026-10-17 08:00", "2026-10-19 07:00"}, // Saturday -> Monday
+		{"0 7 * * 1-5", "2026-10-19 06:59", "2026-10-19 07:00"},
+		{"0 7 * * 1-5", "2026-10-19 07:00", "2026-10-20 07:00"}, // strictly after
+		{"30 * * * *", "2026-10-18 23:45", "2026-10-19 00:30"},
+		{"0 0 1 * *", "2026-12-15 10:00", "2027-01-01 00:00"},
+		{"0 12 29 2 *", "2026-03-01 00:00", "2028-02-29 12:00"},
+	}
+	for _, tt := range tests {
+		if got := mustParse(t, tt.spec).Next(date(tt.from)); !got.Equal(date(tt.want)) {
+			t.Errorf("%q.Next(%s) = %s, want %s", tt.spec, tt.from, got.Format("2006-01-02 15:04"), tt.want)
+		}
+	}
+
+	if got := mustParse(t, "0 0 31 2 *").Next(date("2026-01-01 00:00")); !got.IsZero() {
+		t.Errorf("impossible schedule Next = %v, want zero", got)
+	}
+}
+
+func TestWindow(t *testing.T) {
+	overnight, err := ParseWindow("22:00-07:00")
+	if err != nil {
+		t.Fatal(err)
+	}
+	daytime, err := ParseWindow("09:30-17:00")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		w    Window
+		at   string
+		want bool
+	}{
+		{overnight, "2026-10-18 23:00", true},
+		{overnight, "2026-10-18 03:00", true},
+		{overnight, "2026-10-18 07:00", false},
+		{overnight, "2026-10-18 22:00", true},
+		{overnight, "2026-10-18 12:00", false},
+		{daytime, "2026-10-18 09:29", false},
+		{daytime, "2026-10-18 09:30", true},
+		{daytime, "2026-10-18 16:59", true},
+		{daytime, "2026-10-18 17:00", false},
+	}
+	for _, tt := range tests {
+		if got := tt.w.Contains(date(tt.at)); got != tt.want {
+			t.Errorf("%s.Contains(%s) = %v, want %v", tt.w, tt.at, got, tt.want)
+		}
+	}
+
+	for _, spec := range []string{"", "22:00", "25:00-07:00", "07:00-07:00", "7am-9am"} {
+		if _, err := ParseWindow(spec); err == nil {
+			t.Errorf("ParseWindow(%q) should fail", spec)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+// Package echoplugin is the reference llm-bridge provider plugin. It speaks
+// the protocol described in docs/plugins.md and echoes every message it is
+// asked to send back to the bridge as an incoming message, so it is useful
+// for testing the plugin host but not as a real chat integration.
+//
+// The special channel IDs "rate-limited" and "forbidden" make send fail with
+// a retryable and a permanent error respectively.
+package echoplugin
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+	"time"
+)
+
+type request struct {
+	ID     *int64          `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+}
+
+type rpcError struct {
+	Code    int            `json:"code"`
+	Message string         `json:"message"`
+	Data    map[string]any `json:"data,omitempty"`
+}
+
+type response struct {
+	JSONRPC string    `json:"jsonrpc"`
+	ID      *int64    `json:"id"`
+	Result  any       `json:"result,omitempty"`
+	Error   *rpcError `json:"error,omitempty"`
+}
+
+type notification struct {
+	JSONRPC string `json:"jsonrpc"`
+	Method  string `json:"method"`
+	Params  any    `json:"params"`
+}
+
+type sendParams struct {
+	ChannelID string `json:"channel_id"`
+	Content   string `json:"content"`
+	ReplyToID string `json:"reply_to_id"`
+	Filename  string `json:"filename"`
+}
+
+// Plugin holds the state of one echo plugin session.
+type Plugin struct {
+	out    *json.Encoder
+	outMu  sync.Mutex
+	logger *log.Logger
+	seq    int
+}
+
+// Run serves the plugin protocol on in/out until in is closed or the bridge
+// sends shutdown. Diagnostics go to logw (the bridge logs plugin stderr).
+func Run(in io.Reader, out, logw io.Writer) error {
+	p := &Plugin{
+		out:    json.NewEncoder(out),
+		logger: log.New(logw, "echo-plugin: ", 0),
+	}
+
+	reader := bufio.NewReader(in)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 {
+			var req request
+			if jerr := json.Unmarshal(line, &req); jerr != nil {
+				p.logger.Printf("invalid JSON: %v", jerr)
+			} else if req.Method == "shutdown" {
+				return nil
+			} else {
+				p.handle(req)
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("read: %w", err)
+		}
+	}
+}
+
+func (p *Plugin) handle(req request) {
+	switch req.Method {
+	case "initialize":
+		p.reply(req.ID, map[string]any{
+			"capabilities": map[string]any{
+				"max_message_length": 2000,
+				"files":              true,
+				"replies":            true,
+				"typing":             true,
+			},
+		}, nil)
+	case "send", "send_file":
+		var params sendParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			p.reply(req.ID, nil, &rpcError{Code: -32602, Message: "invalid params"})
+			return
+		}
+		switch params.ChannelID {
+		case "rate-limited":
+			p.reply(req.ID, nil, &rpcError{Code: -32000, Message: "rate limited", Data: map[string]any{"retry_after_ms": 1000}})
+			return
+		case "forbidden":
+			p.reply(req.ID, nil, &rpcError{Code: -32000, Message: "forbidden", Data: map[string]any{"permanent": true}})
+			return
+		}
+
+		content := params.Content
+		if req.Method == "send_file" {
+			data, err := base64.StdEncoding.DecodeString(params.Content)
+			if err != nil {
+				p.reply(req.ID, nil, &rpcError{Code: -32602, Message: "content is not base64"})
+				return
+			}
+			content = fmt.Sprintf("received file %s (%d bytes)", params.Filename, len(data))
+		}
+		p.reply(req.ID, map[string]any{}, nil)
+		p.echo(params.ChannelID, content, params.ReplyToID)
+	case "typing", "subscribe", "unsubscribe":
+		p.logger.Printf("%s %s", req.Method, req.Params)
+	default:
+		if req.ID != nil {
+			p.reply(req.ID, nil, &rpcError{Code: -32601, Message: "method not found"})
+		}
+	}
+}
+
+// echo sends content back to the bridge as a message from user "echo".
+func (p *Plugin) echo(channelID, content, replyToID string) {
+	p.seq++
+	p.write(notification{JSONRPC: "2.0", Method: "message", Params: map[string]any{
+		"id":          fmt.Sprintf("echo-%d", p.seq),
+		"channel_id":  channelID,
+		"content":     content,
+		"author":      "echo",
+		"author_id":   "echo",
+		"reply_to_id": replyToID,
+		"timestamp":   time.Now().UTC(),
+	}})
+}
+
+func (p *Plugin) reply(id *int64, result any, rerr *rpcError) {
+	if id == nil {
+		return
+	}
+	p.write(response{JSONRPC: "2.0", ID: id, Result: result, Error: rerr})
+}
+
+func (p *Plugin) write(v any) {
+	p.outMu.Lock()
+	defer p.outMu.Unlock()
+	if err := p.out.Encode(v); err != nil {
+		p.logger.Printf("write: %v", err)
+	}
+}
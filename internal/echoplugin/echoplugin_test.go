@@ -0,0 +1,97 @@
+package echoplugin
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+)
+
+// run feeds the given request lines to Run and returns the decoded output lines.
+func run(t *testing.T, lines ...string) []map[string]any {
+	t.Helper()
+	in := strings.NewReader(strings.Join(lines, "\n") + "\n")
+	var out strings.Builder
+	if err := Run(in, &out, io.Discard); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	var msgs []map[string]any
+	scanner := bufio.NewScanner(strings.NewReader(out.String()))
+	for scanner.Scan() {
+		var m map[string]any
+		if err := json.Unmarshal(scanner.Bytes(), &m); err != nil {
+			t.Fatalf("invalid output line %q: %v", scanner.Text(), err)
+		}
+		msgs = append(msgs, m)
+	}
+	return msgs
+}
+
+func TestRun_Initialize(t *testing.T) {
+	msgs := run(t, `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocol_version":1,"channels":["c"]}}`)
+	if len(msgs) != 1 {
+		t.Fatalf("expected 1 response, got %v", msgs)
+	}
+	caps := msgs[0]["result"].(map[string]any)["capabilities"].(map[string]any)
+	if caps["files"] != true || caps["max_message_length"] != float64(2000) {
+		t.Errorf("capabilities = %v", caps)
+	}
+}
+
+func TestRun_SendEchoes(t *testing.T) {
+	msgs := run(t, `{"jsonrpc":"2.0","id":2,"method":"send","params":{"channel_id":"c","content":"hi","reply_to_id":"m1"}}`)
+	if len(msgs) != 2 {
+		t.Fatalf("expected response and echo, got %v", msgs)
+	}
+	if msgs[0]["id"] != float64(2) || msgs[0]["error"] != nil {
+		t.Errorf("response = %v", msgs[0])
+	}
+	params := msgs[1]["params"].(map[string]any)
+	if msgs[1]["method"] != "message" || params["content"] != "hi" || params["reply_to_id"] != "m1" {
+		t.Errorf("echo = %v", msgs[1])
+	}
+}
+
+func TestRun_SendFile(t *testing.T) {
+	msgs := run(t, `{"jsonrpc":"2.0","id":3,"method":"send_file","params":{"channel_id":"c","filename":"a.md","content":"aGVsbG8="}}`)
+	if len(msgs) != 2 {
+		t.Fatalf("expected response and echo, got %v", msgs)
+	}
+	if got := msgs[1]["params"].(map[string]any)["content"]; got != "received file a.md (5 bytes)" {
+		t.Errorf("echo content = %v", got)
+	}
+}
+
+func TestRun_Errors(t *testing.T) {
+	msgs := run(t,
+		`{"jsonrpc":"2.0","id":4,"method":"send","params":{"channel_id":"rate-limited","content":"x"}}`,
+		`{"jsonrpc":"2.0","id":5,"method":"send","params":{"channel_id":"forbidden","content":"x"}}`,
+		`{"jsonrpc":"2.0","id":6,"method":"bogus"}`,
+	)
+	if len(msgs) != 3 {
+		t.Fatalf("expected 3 responses, got %v", msgs)
+	}
+	data := msgs[0]["error"].(map[string]any)["data"].(map[string]any)
+	if data["retry_after_ms"] != float64(1000) {
+		t.Errorf("rate-limited error data = %v", data)
+	}
+	data = msgs[1]["error"].(map[string]any)["data"].(map[string]any)
+	if data["permanent"] != true {
+		t.Errorf("forbidden error data = %v", data)
+	}
+	if code := msgs[2]["error"].(map[string]any)["code"]; code != float64(-32601) {
+		t.Errorf("unknown method code = %v", code)
+	}
+}
+
+func TestRun_ShutdownStops(t *testing.T) {
+	msgs := run(t,
+		`{"jsonrpc":"2.0","method":"shutdown"}`,
+		`{"jsonrpc":"2.0","id":7,"method":"initialize","params":{}}`,
+	)
+	if len(msgs) != 0 {
+		t.Errorf("nothing should be handled after shutdown, got %v", msgs)
+	}
+}
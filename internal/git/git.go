@@ -189,6 +189,69 @@ func CurrentBranch(dir string) (string, error) {
 	return branch, nil
 }
 
+// Exclude adds pattern to the info/exclude file of the repository containing
+// dir, so git ignores matching paths without touching the tracked .gitignore.
+// It does nothing if the pattern is already listed.
+func Exclude(dir, pattern string) error {
+	path, err := runGit(dir, "rev-parse", "--git-path", "info/exclude")
+	if err != nil {
+		return fmt.Errorf("exclude: %w", err)
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("exclude: %w", err)
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.TrimSpace(line) == pattern {
+			return nil
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("exclude: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("exclude: %w", err)
+	}
+	if len(data) > 0 && !strings.HasSuffix(string(data), "\n") {
+		pattern = "\n" + pattern
+	}
+	if _, err := f.WriteString(pattern + "\n"); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("exclude: %w", err)
+	}
+	return f.Close()
+}
+
+// RemoteURL returns the URL of the named remote (e.g. "origin") for the
+// repository containing dir.
+func RemoteURL(dir, remote string) (string, error) {
+	url, err := runGit(dir, "remote", "get-url", remote)
+	if err != nil {
+		return "", fmt.Errorf("remote url: %w", err)
+	}
+	return url, nil
+}
+
+// githubRemoteRe matches the "owner/name" part of GitHub remote URLs in
+// https, ssh and scp-like (git@github.com:owner/name) forms.
+var githubRemoteRe = regexp.MustCompile(`^(?:https://|ssh://git@|git://|git@)github\.com[:/]([A-Za-z0-9_.-]+)/([A-Za-z0-9_.-]+?)(?:\.git)?/?$`)
+
+// GitHubFullName extracts "owner/name" from a GitHub remote URL.
+// It returns false for remotes that are not on github.com.
+func GitHubFullName(remoteURL string) (string, bool) {
+	m := githubRemoteRe.FindStringSubmatch(strings.TrimSpace(remoteURL))
+	if m == nil {
+		return "", false
+	}
+	return m[1] + "/" + m[2], true
+}
+
 // IsGitRepo returns true if the given directory is inside a git work tree.
 func IsGitRepo(dir string) bool {
 	result, err := runGit(dir, "rev-parse", "--is-inside-work-tree")
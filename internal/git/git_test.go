@@ -591,3 +591,85 @@ func TestAddWorktree_InvalidRepo(t *testing.T) {
 		t.Error("AddWorktree() expected error for non-git directory")
 	}
 }
+
+func TestRemoteURL(t *testing.T) {
+	dir := setupTestRepo(t)
+
+	if _, err := RemoteURL(dir, "origin"); err == nil {
+		t.Error("RemoteURL() should fail when the remote does not exist")
+	}
+
+	cmd := exec.Command("git", "remote", "add", "origin", "git@github.com:acme/widgets.git")
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git remote add: %v\n%s", err, out)
+	}
+
+	url, err := RemoteURL(dir, "origin")
+	if err != nil {
+		t.Fatalf("RemoteURL() error = %v", err)
+	}
+	if url != "git@github.com:acme/widgets.git" {
+		t.Errorf("RemoteURL() = %q", url)
+	}
+}
+
+func TestGitHubFullName(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+		ok   bool
+	}{
+		{"https://github.com/acme/widgets.git", "acme/widgets", true},
+		{"https://github.com/acme/widgets", "acme/widgets", true},
+		{"git@github.com:acme/widgets.git", "acme/widgets", true},
+		{"ssh://git@github.com/acme/my.repo.git", "acme/my.repo", true},
+		{"https://gitlab.com/acme/widgets.git", "", false},
+		{"https://github.com/acme", "", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := GitHubFullName(tt.url)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("GitHubFullName(%q) = %q, %v; want %q, %v", tt.url, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestExclude(t *testing.T) {
+	dir := setupTestRepo(t)
+	if err := os.MkdirAll(filepath.Join(dir, ".llm-bridge", "inbox"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, ".llm-bridge", "inbox", "a.txt"), []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := Exclude(dir, "/.llm-bridge/inbox/"); err != nil {
+			t.Fatalf("Exclude() error = %v", err)
+		}
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, ".git", "info", "exclude"))
+	if err != nil {
+		t.Fatalf("read exclude: %v", err)
+	}
+	if n := strings.Count(string(data), "/.llm-bridge/inbox/\n"); n != 1 {
+		t.Errorf("exclude lists the pattern %d times, want once:\n%s", n, data)
+	}
+
+	out, err := runGit(dir, "status", "--porcelain", "--untracked-files=all")
+	if err != nil {
+		t.Fatalf("git status: %v", err)
+	}
+	if strings.Contains(out, ".llm-bridge") {
+		t.Errorf("excluded inbox still shows in git status: %q", out)
+	}
+}
+
+func TestExclude_NonGitDir(t *testing.T) {
+	if err := Exclude(t.TempDir(), "/x/"); err == nil {
+		t.Error("Exclude() outside a repo should fail")
+	}
+}
@@ -2,10 +2,13 @@ package llm
 
 import (
 	"context"
+	"crypto/rand"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
+	"regexp"
+	"strings"
 	"sync"
 	"syscall"
 	"time"
@@ -13,12 +16,19 @@ import (
 	"github.com/creack/pty"
 )
 
+// ansiEscape matches terminal control sequences in PTY output.
+var ansiEscape = regexp.MustCompile(`\x1b\[[0-9;?]*[ -/]*[@-~]|\x1b[@-_]`)
+
+// permissionPromptMarker is the phrase Claude uses when asking for tool permission.
+const permissionPromptMarker = "Do you want to"
+
 type Claude struct {
 	workingDir    string
 	resumeSession bool
 	claudePath    string
 
 	mu           sync.Mutex
+	sessionID    string // conversation to resume, or the one started
 	cmd          *exec.Cmd
 	ptmx         *os.File
 	running      bool
@@ -73,9 +83,16 @@ func (c *Claude) Start(ctx context.Context) error {
 		return nil
 	}
 
+	// With resume enabled every conversation gets an ID, so the bridge can
+	// continue it after an idle timeout or a restart.
 	args := []string{}
 	if c.resumeSession {
-		args = append(args, "--resume")
+		if c.sessionID != "" {
+			args = append(args, "--resume", c.sessionID)
+		} else {
+			c.sessionID = newSessionID()
+			args = append(args, "--session-id", c.sessionID)
+		}
 	}
 
 	c.cmd = exec.CommandContext(ctx, c.claudePath, args...)
@@ -151,10 +168,35 @@ func (c *Claude) Send(msg Message) error {
 	}
 
 	c.lastActivity = time.Now()
-	_, err := c.ptmx.WriteString(msg.Content + "\n")
+	_, err := c.ptmx.WriteString(formatClaudeInput(msg) + "\n")
 	return err
 }
 
+// formatClaudeInput renders a message as a single line of TUI input.
+// Attachments are listed by absolute path: Claude reads text files with its
+// file tools and renders image paths as images, so no upload step is needed.
+// Everything stays on one line because a newline submits the prompt.
+func formatClaudeInput(msg Message) string {
+	if len(msg.Attachments) == 0 {
+		return msg.Content
+	}
+
+	refs := make([]string, 0, len(msg.Attachments))
+	for _, a := range msg.Attachments {
+		if a.IsImage() {
+			refs = append(refs, a.Path+" (image)")
+		} else {
+			refs = append(refs, a.Path)
+		}
+	}
+
+	files := "[attached files: " + strings.Join(refs, ", ") + "]"
+	if msg.Content == "" {
+		return files
+	}
+	return msg.Content + " " + files
+}
+
 func (c *Claude) Output() io.Reader {
 	c.mu.Lock()
 	defer c.mu.Unlock()
@@ -189,3 +231,55 @@ func (c *Claude) UpdateActivity() {
 	defer c.mu.Unlock()
 	c.lastActivity = time.Now()
 }
+
+// SessionID returns the ID of the conversation started or resumed by the
+// last Start, or "" if resume is disabled.
+func (c *Claude) SessionID() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.sessionID
+}
+
+// ResumeSession makes the next Start continue conversation id instead of
+// starting a new one. It has no effect if resume is disabled.
+func (c *Claude) ResumeSession(id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.resumeSession {
+		c.sessionID = id
+	}
+}
+
+// newSessionID returns a random (version 4) UUID, the form Claude expects.
+func newSessionID() string {
+	var u [16]byte
+	_, _ = rand.Read(u[:])
+	u[6] = u[6]&0x0f | 0x40
+	u[8] = u[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", u[0:4], u[4:6], u[6:8], u[8:10], u[10:])
+}
+
+// IsPermissionPrompt reports whether output contains Claude's tool permission question.
+func (c *Claude) IsPermissionPrompt(output string) bool {
+	return strings.Contains(ansiEscape.ReplaceAllString(output, ""), permissionPromptMarker)
+}
+
+// RespondPermission answers a pending permission prompt. Claude's prompt is a
+// menu whose first option is "Yes"; Escape dismisses it as "No".
+func (c *Claude) RespondPermission(allow bool) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.running || c.ptmx == nil {
+		return fmt.Errorf("claude not running")
+	}
+
+	key := "\x1b"
+	if allow {
+		key = "1"
+	}
+
+	c.lastActivity = time.Now()
+	_, err := c.ptmx.WriteString(key)
+	return err
+}
@@ -2,6 +2,9 @@ package llm
 
 import (
 	"context"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 )
@@ -255,6 +258,65 @@ func TestClaude_WithResumeFlag(t *testing.T) {
 	}
 }
 
+// startArgs starts c with a stub that records its arguments and returns them.
+func startArgs(t *testing.T, c *Claude) string {
+	t.Helper()
+	dir := t.TempDir()
+	argsFile := filepath.Join(dir, "args")
+	stub := filepath.Join(dir, "claude-stub")
+	script := "#!/bin/sh\necho \"$@\" > " + argsFile + "\n"
+	if err := os.WriteFile(stub, []byte(script), 0755); err != nil {
+		t.Fatalf("write stub: %v", err)
+	}
+	c.claudePath = stub
+	c.workingDir = dir
+
+	if err := c.Start(context.Background()); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	defer func() { _ = c.Stop() }()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if data, err := os.ReadFile(argsFile); err == nil && len(data) > 0 {
+			return strings.TrimSpace(string(data))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("stub did not record its arguments")
+	return ""
+}
+
+func TestClaude_SessionIDs(t *testing.T) {
+	c := NewClaude(WithResume(true))
+	args := startArgs(t, c)
+	id := c.SessionID()
+	if len(id) != 36 || id[14] != '4' {
+		t.Errorf("SessionID() = %q, want a v4 UUID", id)
+	}
+	if args != "--session-id "+id {
+		t.Errorf("new conversation args = %q", args)
+	}
+
+	c = NewClaude(WithResume(true))
+	c.ResumeSession("0b9a3c4e-1111-4222-8333-444455556666")
+	if args := startArgs(t, c); args != "--resume 0b9a3c4e-1111-4222-8333-444455556666" {
+		t.Errorf("resumed conversation args = %q", args)
+	}
+	if c.SessionID() != "0b9a3c4e-1111-4222-8333-444455556666" {
+		t.Errorf("SessionID() = %q after resume", c.SessionID())
+	}
+
+	c = NewClaude(WithResume(false))
+	c.ResumeSession("ignored")
+	if args := startArgs(t, c); args != "" {
+		t.Errorf("resume disabled args = %q, want none", args)
+	}
+	if c.SessionID() != "" {
+		t.Errorf("SessionID() = %q with resume disabled", c.SessionID())
+	}
+}
+
 func TestClaude_Stop_DoubleStop(t *testing.T) {
 	c := NewClaude(WithClaudePath("cat"), WithResume(false))
 
@@ -327,3 +389,78 @@ func TestClaude_RestartAfterStop(t *testing.T) {
 		t.Error("Running() should be true after restart")
 	}
 }
+
+func TestFormatClaudeInput(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  Message
+		want string
+	}{
+		{"no attachments", Message{Content: "hello"}, "hello"},
+		{
+			"text and image",
+			Message{Content: "look", Attachments: []Attachment{
+				{Path: "/w/.llm-bridge/inbox/a.log", ContentType: "text/plain"},
+				{Path: "/w/.llm-bridge/inbox/b.png", ContentType: "image/png"},
+			}},
+			"look [attached files: /w/.llm-bridge/inbox/a.log, /w/.llm-bridge/inbox/b.png (image)]",
+		},
+		{
+			"attachment only",
+			Message{Attachments: []Attachment{{Path: "/w/x.diff", ContentType: "text/x-diff"}}},
+			"[attached files: /w/x.diff]",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := formatClaudeInput(tt.msg); got != tt.want {
+				t.Errorf("formatClaudeInput() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClaude_IsPermissionPrompt(t *testing.T) {
+	c := NewClaude()
+
+	tests := []struct {
+		output string
+		want   bool
+	}{
+		{"Do you want to make this edit to main.go?", true},
+		{"\x1b[1mDo you want to\x1b[0m proceed?", true},
+		{"Refactored the auth module.", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := c.IsPermissionPrompt(tt.output); got != tt.want {
+			t.Errorf("IsPermissionPrompt(%q) = %v, want %v", tt.output, got, tt.want)
+		}
+	}
+}
+
+func TestClaude_RespondPermission_NotRunning(t *testing.T) {
+	c := NewClaude()
+	if err := c.RespondPermission(true); err == nil {
+		t.Error("RespondPermission() should return error when not running")
+	}
+}
+
+func TestClaude_RespondPermission_Running(t *testing.T) {
+	c := NewClaude(WithClaudePath("cat"), WithResume(false))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	if err := c.Start(ctx); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	defer func() { _ = c.Stop() }()
+
+	if err := c.RespondPermission(true); err != nil {
+		t.Errorf("RespondPermission(true) error = %v", err)
+	}
+	if err := c.RespondPermission(false); err != nil {
+		t.Errorf("RespondPermission(false) error = %v", err)
+	}
+}
@@ -3,13 +3,27 @@ package llm
 import (
 	"context"
 	"io"
+	"strings"
 	"time"
 )
 
 // Message represents input from a source
 type Message struct {
-	Source  string // "discord", "terminal"
-	Content string
+	Source      string // "discord", "terminal"
+	Content     string
+	Attachments []Attachment // files saved to disk for the LLM to read
+}
+
+// Attachment is a file on local disk referenced by a message.
+// Backends decide how to present it (e.g. as a path the model can open).
+type Attachment struct {
+	Path        string // absolute path on disk
+	ContentType string // MIME type
+}
+
+// IsImage reports whether the attachment is an image.
+func (a Attachment) IsImage() bool {
+	return strings.HasPrefix(a.ContentType, "image/")
 }
 
 // LLM defines the interface for LLM backends
@@ -41,3 +55,23 @@ type LLM interface {
 	// Name returns the LLM backend name (e.g. "claude")
 	Name() string
 }
+
+// SessionResumer is implemented by backends whose conversations have IDs
+// that a later process can continue.
+type SessionResumer interface {
+	// SessionID returns the ID of the current conversation, or "" if unknown
+	SessionID() string
+
+	// ResumeSession makes the next Start continue conversation id
+	ResumeSession(id string)
+}
+
+// PermissionResponder is implemented by backends that pause for interactive
+// permission prompts (e.g. "Do you want to make this edit?").
+type PermissionResponder interface {
+	// IsPermissionPrompt reports whether a chunk of output asks for permission
+	IsPermissionPrompt(output string) bool
+
+	// RespondPermission answers the pending prompt
+	RespondPermission(allow bool) error
+}
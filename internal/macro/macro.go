@@ -0,0 +1,181 @@
+// Package macro expands prompt templates invoked as chat commands, such as
+// "/review <branch>".
+package macro
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ArgsPlaceholder is replaced by the invocation's arguments exactly as typed.
+const ArgsPlaceholder = "args"
+
+var (
+	namePattern        = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+	paramPattern       = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+	placeholderPattern = regexp.MustCompile(`\{([a-z_][a-z0-9_]*)\}`)
+)
+
+// Macro is a named prompt template. The template refers to parameters as
+// {name}, and to all arguments as typed as {args}.
+type Macro struct {
+	Name        string
+	Description string
+
+	params   []string
+	defaults map[string]string
+	template string
+}
+
+// New validates and returns a macro. Parameters without a default are
+// required. Every {placeholder} in template must be a parameter or {args};
+// other braces, such as {} or {"a": 1}, are left as they are.
+func New(name, description, template string, params []string, defaults map[string]string) (*Macro, error) {
+	if !namePattern.MatchString(name) {
+		return nil, fmt.Errorf("invalid macro name %q: use lowercase letters, digits, - and _", name)
+	}
+	if strings.TrimSpace(template) == "" {
+		return nil, fmt.Errorf("macro %q: prompt is required", name)
+	}
+
+	declared := make(map[string]bool, len(params))
+	for _, p := range params {
+		if !paramPattern.MatchString(p) || p == ArgsPlaceholder {
+			return nil, fmt.Errorf("macro %q: invalid argument name %q", name, p)
+		}
+		if declared[p] {
+			return nil, fmt.Errorf("macro %q: duplicate argument %q", name, p)
+		}
+		declared[p] = true
+	}
+	for p := range defaults {
+		if !declared[p] {
+			return nil, fmt.Errorf("macro %q: default for undeclared argument %q", name, p)
+		}
+	}
+	for _, m := range placeholderPattern.FindAllStringSubmatch(template, -1) {
+		if !declared[m[1]] && m[1] != ArgsPlaceholder {
+			return nil, fmt.Errorf("macro %q: prompt refers to undeclared argument {%s}", name, m[1])
+		}
+	}
+
+	return &Macro{
+		Name:        name,
+		Description: description,
+		params:      params,
+		defaults:    defaults,
+		template:    template,
+	}, nil
+}
+
+// Usage describes how to invoke the macro, e.g. "/review <branch> [focus=correctness]".
+func (m *Macro) Usage() string {
+	var sb strings.Builder
+	sb.WriteString("/" + m.Name)
+	for _, p := range m.params {
+		if def, ok := m.defaults[p]; ok {
+			fmt.Fprintf(&sb, " [%s=%s]", p, def)
+		} else {
+			fmt.Fprintf(&sb, " <%s>", p)
+		}
+	}
+	if len(m.params) == 0 && strings.Contains(m.template, "{"+ArgsPlaceholder+"}") {
+		sb.WriteString(" [text]")
+	}
+	return sb.String()
+}
+
+// Expand fills the template from input, the text after the command.
+// Arguments are words, or "quoted strings"; name=value sets a parameter by
+// name, and other words fill the remaining parameters in order. The last
+// parameter takes any extra words, so "/ask how does this work" needs no
+// quotes. Missing parameters take their default, if any.
+func (m *Macro) Expand(input string) (string, error) {
+	words, err := splitArgs(input)
+	if err != nil {
+		return "", err
+	}
+
+	declared := make(map[string]bool, len(m.params))
+	for _, p := range m.params {
+		declared[p] = true
+	}
+	values := make(map[string]string, len(m.params))
+	var positional []string
+	for _, w := range words {
+		if name, value, ok := strings.Cut(w, "="); ok && declared[name] {
+			values[name] = value
+			continue
+		}
+		positional = append(positional, w)
+	}
+
+	var open []string
+	for _, p := range m.params {
+		if _, ok := values[p]; !ok {
+			open = append(open, p)
+		}
+	}
+	for i, p := range open {
+		if len(positional) == 0 {
+			break
+		}
+		if i == len(open)-1 {
+			values[p] = strings.Join(positional, " ")
+			positional = nil
+			break
+		}
+		values[p], positional = positional[0], positional[1:]
+	}
+	if len(positional) > 0 && !strings.Contains(m.template, "{"+ArgsPlaceholder+"}") {
+		return "", fmt.Errorf("too many arguments")
+	}
+
+	for _, p := range m.params {
+		if _, ok := values[p]; ok {
+			continue
+		}
+		def, ok := m.defaults[p]
+		if !ok {
+			return "", fmt.Errorf("missing <%s>", p)
+		}
+		values[p] = def
+	}
+	values[ArgsPlaceholder] = strings.TrimSpace(input)
+
+	return placeholderPattern.ReplaceAllStringFunc(m.template, func(ph string) string {
+		return values[ph[1:len(ph)-1]]
+	}), nil
+}
+
+// splitArgs splits s into words, keeping "quoted strings" together. A quote
+// may start mid-word, as in focus="error handling".
+func splitArgs(s string) ([]string, error) {
+	var words []string
+	var cur strings.Builder
+	inWord, quoted := false, false
+	for _, r := range s {
+		switch {
+		case r == '"':
+			quoted = !quoted
+			inWord = true
+		case !quoted && (r == ' ' || r == '\t' || r == '\n'):
+			if inWord {
+				words = append(words, cur.String())
+				cur.Reset()
+				inWord = false
+			}
+		default:
+			cur.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quoted {
+		return nil, fmt.Errorf("unterminated quote")
+	}
+	if inWord {
+		words = append(words, cur.String())
+	}
+	return words, nil
+}
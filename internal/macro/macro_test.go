@@ -0,0 +1,108 @@
+package macro
+
+import (
+	"strings"
+	"testing"
+)
+
+func mustNew(t *testing.T, template string, params []string, defaults map[string]string) *Macro {
+	t.Helper()
+	m, err := New("test", "", template, params, defaults)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return m
+}
+
+func TestNew_Invalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		params   []string
+		defaults map[string]string
+		want     string
+	}{
+		{"Review", "x", nil, nil, "invalid macro name"},
+		{"re view", "x", nil, nil, "invalid macro name"},
+		{"review", " ", nil, nil, "prompt is required"},
+		{"review", "x", []string{"Branch"}, nil, "invalid argument name"},
+		{"review", "x", []string{"args"}, nil, "invalid argument name"},
+		{"review", "x", []string{"a", "a"}, nil, "duplicate argument"},
+		{"review", "x", []string{"a"}, map[string]string{"b": "1"}, "undeclared argument \"b\""},
+		{"review", "review {branch}", nil, nil, "undeclared argument {branch}"},
+	}
+	for _, tt := range tests {
+		_, err := New(tt.name, "", tt.template, tt.params, tt.defaults)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("New(%q, %q, %v, %v) error = %v, want %q", tt.name, tt.template, tt.params, tt.defaults, err, tt.want)
+		}
+	}
+}
+
+func TestExpand(t *testing.T) {
+	review := mustNew(t, "Review {branch} for {focus}.", []string{"branch", "focus"}, map[string]string{"focus": "correctness"})
+	ask := mustNew(t, "Question: {question}", []string{"question"}, nil)
+	free := mustNew(t, "Explain {args} briefly. {\"json\": true}", nil, nil)
+
+	tests := []struct {
+		m     *Macro
+		input string
+		want  string
+	}{
+		{review, "feature-x", "Review feature-x for correctness."},
+		{review, "feature-x security", "Review feature-x for security."},
+		{review, "feature-x error handling", "Review feature-x for error handling."},
+		{review, `focus="error handling" feature-x`, "Review feature-x for error handling."},
+		{review, "branch=main", "Review main for correctness."},
+		{review, `"my branch"`, "Review my branch for correctness."},
+		{review, "a=b", "Review a=b for correctness."},
+		{ask, "how does  this work?", "Question: how does this work?"},
+		{free, "  the router ", "Explain the router briefly. {\"json\": true}"},
+		{free, "", "Explain  briefly. {\"json\": true}"},
+	}
+	for _, tt := range tests {
+		got, err := tt.m.Expand(tt.input)
+		if err != nil {
+			t.Errorf("Expand(%q): %v", tt.input, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Expand(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestExpand_Errors(t *testing.T) {
+	review := mustNew(t, "Review {branch}", []string{"branch"}, nil)
+	fixed := mustNew(t, "Run the tests", nil, nil)
+
+	for _, tt := range []struct {
+		m     *Macro
+		input string
+		want  string
+	}{
+		{review, "", "missing <branch>"},
+		{review, `"unterminated`, "unterminated quote"},
+		{fixed, "extra", "too many arguments"},
+	} {
+		if _, err := tt.m.Expand(tt.input); err == nil || err.Error() != tt.want {
+			t.Errorf("Expand(%q) error = %v, want %q", tt.input, err, tt.want)
+		}
+	}
+}
+
+func TestUsage(t *testing.T) {
+	tests := []struct {
+		m    *Macro
+		want string
+	}{
+		{mustNew(t, "{branch} {focus}", []string{"branch", "focus"}, map[string]string{"focus": "bugs"}), "/test <branch> [focus=bugs]"},
+		{mustNew(t, "Explain {args}", nil, nil), "/test [text]"},
+		{mustNew(t, "Run the tests", nil, nil), "/test"},
+	}
+	for _, tt := range tests {
+		if got := tt.m.Usage(); got != tt.want {
+			t.Errorf("Usage() = %q, want %q", got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRegistry_WriteTo(t *testing.T) {
+	r := NewRegistry()
+	msgs := r.Counter("test_messages_total", "Messages received.", "provider")
+	msgs.Inc("discord")
+	msgs.Add(2, "terminal")
+	msgs.Inc("discord")
+
+	r.GaugeFunc("test_active", "Active sessions.", []string{"repo"}, func(set func(float64, ...string)) {
+		set(1, "app")
+		set(1, `we"ird`)
+		set(1, "app")
+	})
+
+	latency := r.Histogram("test_latency_seconds", "Latency.", []float64{0.5, 1})
+	latency.Observe(0.2)
+	latency.Observe(0.7)
+	latency.Observe(3)
+
+	var sb strings.Builder
+	if _, err := r.WriteTo(&sb); err != nil {
+		t.Fatal(err)
+	}
+	want := `# HELP test_messages_total Messages received.
+# TYPE test_messages_total counter
+test_messages_total{provider="discord"} 2
+test_messages_total{provider="terminal"} 2
+# HELP test_active Active sessions.
+# TYPE test_active gauge
+test_active{repo="app"} 2
+test_active{repo="we\"ird"} 1
+# HELP test_latency_seconds Latency.
+# TYPE test_latency_seconds histogram
+test_latency_seconds_bucket{le="0.5"} 1
+test_latency_seconds_bucket{le="1"} 2
+test_latency_seconds_bucket{le="+Inf"} 3
+test_latency_seconds_sum 3.9
+test_latency_seconds_count 3
+`
+	if got := sb.String(); got != want {
+		t.Errorf("WriteTo() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestCounter_Value(t *testing.T) {
+	c := NewRegistry().Counter("c_total", "C.", "a", "b")
+	c.Inc("x", "y")
+	if got := c.Value("x", "y"); got != 1 {
+		t.Errorf("Value(x, y) = %v, want 1", got)
+	}
+	if got := c.Value("x", "z"); got != 0 {
+		t.Errorf("Value(x, z) = %v, want 0", got)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("wrong number of label values should panic")
+		}
+	}()
+	c.Inc("x")
+}
+
+func TestHistogram_Count(t *testing.T) {
+	h := NewRegistry().Histogram("h_seconds", "H.", nil, "repo")
+	h.Observe(1, "app")
+	h.Observe(2, "app")
+	if got := h.Count("app"); got != 2 {
+		t.Errorf("Count(app) = %d, want 2", got)
+	}
+	if got := h.Count("other"); got != 0 {
+		t.Errorf("Count(other) = %d, want 0", got)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	r := NewRegistry()
+	r.Counter("up_total", "Up.").Inc()
+
+	rec := httptest.NewRecorder()
+	r.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "up_total 1\n") {
+		t.Errorf("GET = %d %q", rec.Code, rec.Body)
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("Content-Type = %q", ct)
+	}
+
+	rec = httptest.NewRecorder()
+	r.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/metrics", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST = %d, want 405", rec.Code)
+	}
+}
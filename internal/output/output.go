@@ -2,7 +2,9 @@ package output
 
 import (
 	"fmt"
+	"strings"
 	"time"
+	"unicode/utf8"
 )
 
 type Handler struct {
@@ -25,3 +27,42 @@ func (h *Handler) FormatFile(content string) (filename string, data []byte) {
 	data = []byte(content)
 	return
 }
+
+// Limit returns the attach threshold for a provider whose messages may be
+// at most maxLen bytes (0 means unlimited): the configured threshold, capped
+// at maxLen.
+func (h *Handler) Limit(maxLen int) int {
+	if maxLen > 0 && maxLen < h.threshold {
+		return maxLen
+	}
+	return h.threshold
+}
+
+// Split breaks content into chunks of at most maxLen bytes, preferring to
+// cut at newlines. A maxLen of 0 or less returns content as a single chunk.
+func Split(content string, maxLen int) []string {
+	if maxLen <= 0 || len(content) <= maxLen {
+		return []string{content}
+	}
+
+	var chunks []string
+	for len(content) > maxLen {
+		cut := strings.LastIndexByte(content[:maxLen], '\n') + 1
+		if cut <= 0 {
+			cut = maxLen
+			// Don't split a multi-byte UTF-8 sequence.
+			for cut > 0 && !utf8.RuneStart(content[cut]) {
+				cut--
+			}
+			if cut == 0 {
+				cut = maxLen
+			}
+		}
+		chunks = append(chunks, content[:cut])
+		content = content[cut:]
+	}
+	if content != "" {
+		chunks = append(chunks, content)
+	}
+	return chunks
+}
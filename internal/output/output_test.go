@@ -63,3 +63,48 @@ func TestFormatFile(t *testing.T) {
 		t.Errorf("data = %q, want %q", string(data), content)
 	}
 }
+
+func TestLimit(t *testing.T) {
+	h := NewHandler(1500)
+
+	tests := []struct {
+		maxLen int
+		want   int
+	}{
+		{0, 1500},
+		{2000, 1500},
+		{1000, 1000},
+	}
+	for _, tt := range tests {
+		if got := h.Limit(tt.maxLen); got != tt.want {
+			t.Errorf("Limit(%d) = %d, want %d", tt.maxLen, got, tt.want)
+		}
+	}
+}
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		maxLen  int
+		want    []string
+	}{
+		{"unlimited", "abcdef", 0, []string{"abcdef"}},
+		{"fits", "abc", 3, []string{"abc"}},
+		{"at newline", "ab\ncd\nef", 6, []string{"ab\ncd\n", "ef"}},
+		{"no newline", "abcdefg", 3, []string{"abc", "def", "g"}},
+		{"utf8 boundary", "aé", 2, []string{"a", "é"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Split(tt.content, tt.maxLen)
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("Split(%q, %d) = %q, want %q", tt.content, tt.maxLen, got, tt.want)
+			}
+			if strings.Join(got, "") != tt.content {
+				t.Errorf("chunks do not reassemble to the original content")
+			}
+		})
+	}
+}
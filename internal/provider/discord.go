@@ -3,20 +3,30 @@ package provider
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
 	"sync"
 
 	"github.com/bwmarrin/discordgo"
 )
 
+// maxTrackedMessages bounds how many sent message IDs are remembered for
+// mapping reactions back to their channel.
+const maxTrackedMessages = 1000
+
 type Discord struct {
-	token    string
-	channels map[string]bool
+	token string
+
+	mu        sync.Mutex
+	channels  map[string]bool // allowlist; guarded by mu
+	session   *discordgo.Session
+	messages  chan Message
+	reactions chan Reaction
+	stopped   bool
 
-	mu       sync.Mutex
-	session  *discordgo.Session
-	messages chan Message
-	stopped  bool
+	sent      map[string]string // message ID -> channel ID for messages we sent
+	sentOrder []string          // FIFO of message IDs for eviction
 }
 
 func NewDiscord(token string, channelIDs []string) *Discord {
@@ -25,9 +35,11 @@ func NewDiscord(token string, channelIDs []string) *Discord {
 		channels[id] = true
 	}
 	return &Discord{
-		token:    token,
-		channels: channels,
-		messages: make(chan Message, 100),
+		token:     token,
+		channels:  channels,
+		messages:  make(chan Message, 100),
+		reactions: make(chan Reaction, 100),
+		sent:      make(map[string]string),
 	}
 }
 
@@ -43,7 +55,9 @@ func (d *Discord) Start(ctx context.Context) error {
 	}
 
 	d.session.AddHandler(d.handleMessage)
-	d.session.Identify.Intents = discordgo.IntentsGuildMessages | discordgo.IntentsDirectMessages | discordgo.IntentMessageContent
+	d.session.AddHandler(d.handleReactionAdd)
+	d.session.Identify.Intents = discordgo.IntentsGuildMessages | discordgo.IntentsDirectMessages | discordgo.IntentMessageContent |
+		discordgo.IntentsGuildMessageReactions | discordgo.IntentsDirectMessageReactions
 
 	if err := d.session.Open(); err != nil {
 		return fmt.Errorf("open session: %w", err)
@@ -65,6 +79,7 @@ func (d *Discord) Stop() error {
 		_ = d.session.Close()
 	}
 	close(d.messages)
+	close(d.reactions)
 	return nil
 }
 
@@ -73,22 +88,24 @@ func (d *Discord) handleMessage(s *discordgo.Session, m *discordgo.MessageCreate
 		return
 	}
 
-	if !d.channels[m.ChannelID] {
-		return
+	msg := discordMessage(m.Message)
+	for _, id := range msg.Mentions {
+		if id == s.State.User.ID {
+			msg.MentionsBot = true
+		}
 	}
 
-	msg := Message{
-		ChannelID: m.ChannelID,
-		Content:   m.Content,
-		Author:    m.Author.Username,
-		AuthorID:  m.Author.ID,
-		Source:    "discord",
+	if parent := threadParent(s, m.ChannelID); parent != m.ChannelID {
+		msg.ThreadID = m.ChannelID
+		msg.ChannelID = parent
 	}
 
 	d.mu.Lock()
 	defer d.mu.Unlock()
 
-	if d.stopped {
+	// Guild messages must come from an allowlisted channel; DMs (no guild)
+	// are always delivered and routed per author by the bridge.
+	if d.stopped || (m.GuildID != "" && !d.channels[msg.ChannelID]) {
 		return
 	}
 
@@ -99,22 +116,247 @@ func (d *Discord) handleMessage(s *discordgo.Session, m *discordgo.MessageCreate
 	}
 }
 
+// Subscribe adds a channel to the allowlist so its messages are delivered.
+func (d *Discord) Subscribe(channelID string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.channels[channelID] = true
+}
+
+// Unsubscribe removes a channel from the allowlist.
+func (d *Discord) Unsubscribe(channelID string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.channels, channelID)
+}
+
+// threadParent returns the parent channel of a thread, or channelID itself
+// for any other channel. Threads are routed by their parent channel.
+func threadParent(s *discordgo.Session, channelID string) string {
+	if ch, err := s.State.Channel(channelID); err == nil && ch.IsThread() {
+		return ch.ParentID
+	}
+	return channelID
+}
+
+// handleReactionAdd forwards reactions on messages this bot sent.
+// Reactions on other messages, and the bot's own reactions, are ignored.
+func (d *Discord) handleReactionAdd(s *discordgo.Session, r *discordgo.MessageReactionAdd) {
+	if r.UserID == s.State.User.ID {
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.stopped {
+		return
+	}
+
+	channelID, ok := d.sent[r.MessageID]
+	if !ok {
+		return
+	}
+	// Like messages, reactions in a thread belong to its parent channel.
+	channelID = threadParent(s, channelID)
+
+	reaction := Reaction{
+		ChannelID: channelID,
+		MessageID: r.MessageID,
+		Emoji:     r.Emoji.Name,
+		UserID:    r.UserID,
+		User:      r.UserID,
+		Source:    "discord",
+	}
+	if r.Member != nil {
+		reaction.Roles = r.Member.Roles
+		if r.Member.User != nil {
+			reaction.User = r.Member.User.Username
+		}
+	}
+
+	select {
+	case d.reactions <- reaction:
+	default:
+		// Channel full, drop reaction
+	}
+}
+
+// trackSent remembers a sent message so reactions on it can be routed.
+func (d *Discord) trackSent(m *discordgo.Message) {
+	if m == nil {
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.sent[m.ID] = m.ChannelID
+	d.sentOrder = append(d.sentOrder, m.ID)
+	if len(d.sentOrder) > maxTrackedMessages {
+		delete(d.sent, d.sentOrder[0])
+		d.sentOrder = d.sentOrder[1:]
+	}
+}
+
+// discordMessage converts a discordgo message into a provider Message.
+func discordMessage(m *discordgo.Message) Message {
+	msg := Message{
+		ID:            m.ID,
+		ChannelID:     m.ChannelID,
+		Content:       m.Content,
+		Author:        m.Author.Username,
+		AuthorID:      m.Author.ID,
+		Source:        "discord",
+		Timestamp:     m.Timestamp,
+		DirectMessage: m.GuildID == "",
+	}
+	if m.Member != nil {
+		msg.Roles = m.Member.Roles
+	}
+	if m.MessageReference != nil {
+		msg.ReplyToID = m.MessageReference.MessageID
+	}
+	for _, u := range m.Mentions {
+		msg.Mentions = append(msg.Mentions, u.ID)
+	}
+	for _, a := range m.Attachments {
+		msg.Attachments = append(msg.Attachments, Attachment{
+			Filename:    a.Filename,
+			URL:         a.URL,
+			ContentType: a.ContentType,
+			Size:        int64(a.Size),
+		})
+	}
+	return msg
+}
+
+// Send posts a message, waiting out Discord rate limits. The bridge's
+// delivery queue uses TrySend instead.
 func (d *Discord) Send(channelID string, content string) error {
+	return d.send(channelID, content)
+}
+
+// TrySend posts a message, reporting a rate limit as a DeliveryError instead
+// of waiting it out.
+func (d *Discord) TrySend(channelID string, content string) error {
+	return d.send(channelID, content, discordgo.WithRetryOnRatelimit(false))
+}
+
+func (d *Discord) send(channelID string, content string, options ...discordgo.RequestOption) error {
 	if d.session == nil {
 		return fmt.Errorf("discord not connected")
 	}
-	_, err := d.session.ChannelMessageSend(channelID, content)
-	return err
+	m, err := d.session.ChannelMessageSend(channelID, content, options...)
+	if err != nil {
+		return deliveryError(err)
+	}
+	d.trackSent(m)
+	return nil
 }
 
+// SendFile uploads a file, waiting out Discord rate limits.
 func (d *Discord) SendFile(channelID string, filename string, content []byte) error {
+	return d.sendFile(channelID, filename, content)
+}
+
+// TrySendFile uploads a file, reporting a rate limit as a DeliveryError
+// instead of waiting it out.
+func (d *Discord) TrySendFile(channelID string, filename string, content []byte) error {
+	return d.sendFile(channelID, filename, content, discordgo.WithRetryOnRatelimit(false))
+}
+
+func (d *Discord) sendFile(channelID string, filename string, content []byte, options ...discordgo.RequestOption) error {
+	if d.session == nil {
+		return fmt.Errorf("discord not connected")
+	}
+	m, err := d.session.ChannelFileSend(channelID, filename, bytes.NewReader(content), options...)
+	if err != nil {
+		return deliveryError(err)
+	}
+	d.trackSent(m)
+	return nil
+}
+
+// Mention returns Discord's user mention markup.
+func (d *Discord) Mention(userID string) string {
+	return "<@" + userID + ">"
+}
+
+// Reply sends content as a reply to messageID, so Discord shows it linked
+// to the prompt that produced it. A rate limit fails the reply rather than
+// waiting, so the bridge falls back to its delivery queue.
+func (d *Discord) Reply(channelID, messageID, content string) error {
 	if d.session == nil {
 		return fmt.Errorf("discord not connected")
 	}
-	_, err := d.session.ChannelFileSend(channelID, filename, bytes.NewReader(content))
+	m, err := d.session.ChannelMessageSendReply(channelID, content, &discordgo.MessageReference{
+		MessageID: messageID,
+		ChannelID: channelID,
+	}, discordgo.WithRetryOnRatelimit(false))
+	if err != nil {
+		return deliveryError(err)
+	}
+	d.trackSent(m)
+	return nil
+}
+
+// deliveryError classifies a discordgo REST failure so the bridge knows
+// whether and when to retry it.
+func deliveryError(err error) error {
+	var rl *discordgo.RateLimitError
+	if errors.As(err, &rl) && rl.RateLimit != nil && rl.TooManyRequests != nil {
+		return &DeliveryError{Err: err, RetryAfter: rl.RetryAfter}
+	}
+	var re *discordgo.RESTError
+	if errors.As(err, &re) && re.Response != nil {
+		code := re.Response.StatusCode
+		if code >= 400 && code < 500 && code != http.StatusTooManyRequests {
+			return &DeliveryError{Err: err, Permanent: true}
+		}
+	}
 	return err
 }
 
+// CanView reports whether a user has Discord's View Channel permission in
+// channelID, from the gateway state or, failing that, the REST API.
+func (d *Discord) CanView(userID, channelID string) (bool, error) {
+	if d.session == nil {
+		return false, fmt.Errorf("discord not connected")
+	}
+	perms, err := d.session.UserChannelPermissions(userID, channelID)
+	if err != nil {
+		return false, err
+	}
+	return perms&discordgo.PermissionViewChannel != 0, nil
+}
+
+func (d *Discord) Typing(channelID string) error {
+	if d.session == nil {
+		return fmt.Errorf("discord not connected")
+	}
+	// Indicators are refreshed anyway; don't wait out a rate limit for one.
+	return d.session.ChannelTyping(channelID, discordgo.WithRetryOnRatelimit(false))
+}
+
+// Capabilities reports Discord's 2000-character message limit and the
+// optional features this provider implements.
+func (d *Discord) Capabilities() Capabilities {
+	return Capabilities{
+		MaxMessageLength: 2000,
+		Files:            true,
+		Reactions:        true,
+		Typing:           true,
+		Replies:          true,
+		Threads:          true,
+		Markdown:         MarkdownDiscord,
+	}
+}
+
 func (d *Discord) Messages() <-chan Message {
 	return d.messages
 }
+
+func (d *Discord) Reactions() <-chan Reaction {
+	return d.reactions
+}
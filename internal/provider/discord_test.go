@@ -1,7 +1,11 @@
 package provider
 
 import (
+	"fmt"
+	"net/http"
+	"reflect"
 	"testing"
+	"time"
 
 	"github.com/bwmarrin/discordgo"
 )
@@ -81,6 +85,17 @@ func TestDiscord_SendFile_NotConnected(t *testing.T) {
 	}
 }
 
+func TestDiscord_TrySend_NotConnected(t *testing.T) {
+	d := NewDiscord("token", []string{"channel-1"})
+
+	if err := d.TrySend("channel-1", "test"); err == nil {
+		t.Error("TrySend() should error when not connected")
+	}
+	if err := d.TrySendFile("channel-1", "test.md", []byte("content")); err == nil {
+		t.Error("TrySendFile() should error when not connected")
+	}
+}
+
 func TestDiscord_EmptyChannelList(t *testing.T) {
 	d := NewDiscord("token", []string{})
 	if len(d.channels) != 0 {
@@ -243,6 +258,7 @@ func TestDiscord_HandleMessage_BotMessage(t *testing.T) {
 	msg := &discordgo.MessageCreate{
 		Message: &discordgo.Message{
 			ChannelID: "allowed-channel",
+			GuildID:   "guild-1",
 			Content:   "bot message",
 			Author:    &discordgo.User{ID: "bot-id", Username: "BotUser"},
 		},
@@ -273,6 +289,7 @@ func TestDiscord_HandleMessage_DisallowedChannel(t *testing.T) {
 	msg := &discordgo.MessageCreate{
 		Message: &discordgo.Message{
 			ChannelID: "other-channel", // Not in allowed channels
+			GuildID:   "guild-1",
 			Content:   "hello",
 			Author:    &discordgo.User{ID: "user-id", Username: "TestUser"},
 		},
@@ -303,6 +320,7 @@ func TestDiscord_HandleMessage_Success(t *testing.T) {
 	msg := &discordgo.MessageCreate{
 		Message: &discordgo.Message{
 			ChannelID: "allowed-channel",
+			GuildID:   "guild-1",
 			Content:   "hello world",
 			Author:    &discordgo.User{ID: "user-123", Username: "TestUser"},
 		},
@@ -350,6 +368,7 @@ func TestDiscord_HandleMessage_Stopped(t *testing.T) {
 	msg := &discordgo.MessageCreate{
 		Message: &discordgo.Message{
 			ChannelID: "allowed-channel",
+			GuildID:   "guild-1",
 			Content:   "hello",
 			Author:    &discordgo.User{ID: "user-id", Username: "TestUser"},
 		},
@@ -378,6 +397,7 @@ func TestDiscord_HandleMessage_ChannelFull(t *testing.T) {
 	msg1 := &discordgo.MessageCreate{
 		Message: &discordgo.Message{
 			ChannelID: "allowed-channel",
+			GuildID:   "guild-1",
 			Content:   "first",
 			Author:    &discordgo.User{ID: "user-id", Username: "TestUser"},
 		},
@@ -388,6 +408,7 @@ func TestDiscord_HandleMessage_ChannelFull(t *testing.T) {
 	msg2 := &discordgo.MessageCreate{
 		Message: &discordgo.Message{
 			ChannelID: "allowed-channel",
+			GuildID:   "guild-1",
 			Content:   "second",
 			Author:    &discordgo.User{ID: "user-id", Username: "TestUser"},
 		},
@@ -412,3 +433,395 @@ func TestDiscord_HandleMessage_ChannelFull(t *testing.T) {
 		// Expected
 	}
 }
+
+func TestDiscord_HandleMessage_Attachments(t *testing.T) {
+	d := NewDiscord("token", []string{"allowed-channel"})
+
+	session := &discordgo.Session{
+		State: discordgo.NewState(),
+	}
+	session.State.User = &discordgo.User{ID: "bot-id"}
+	d.session = session
+
+	msg := &discordgo.MessageCreate{
+		Message: &discordgo.Message{
+			ChannelID: "allowed-channel",
+			GuildID:   "guild-1",
+			Content:   "see attached",
+			Author:    &discordgo.User{ID: "user-123", Username: "TestUser"},
+			Attachments: []*discordgo.MessageAttachment{
+				{Filename: "build.log", URL: "https://cdn.example/build.log", ContentType: "text/plain", Size: 42},
+				{Filename: "shot.png", URL: "https://cdn.example/shot.png", ContentType: "image/png", Size: 1024},
+			},
+		},
+	}
+
+	d.handleMessage(session, msg)
+
+	select {
+	case received := <-d.Messages():
+		if len(received.Attachments) != 2 {
+			t.Fatalf("len(Attachments) = %d, want 2", len(received.Attachments))
+		}
+		want := Attachment{Filename: "build.log", URL: "https://cdn.example/build.log", ContentType: "text/plain", Size: 42}
+		if received.Attachments[0] != want {
+			t.Errorf("Attachments[0] = %+v, want %+v", received.Attachments[0], want)
+		}
+		if received.Attachments[1].ContentType != "image/png" {
+			t.Errorf("Attachments[1].ContentType = %q, want image/png", received.Attachments[1].ContentType)
+		}
+	default:
+		t.Error("expected to receive message")
+	}
+}
+
+func TestDiscord_HandleReactionAdd_TrackedMessage(t *testing.T) {
+	d := NewDiscord("token", []string{"ch1"})
+
+	session := &discordgo.Session{State: discordgo.NewState()}
+	session.State.User = &discordgo.User{ID: "bot-id"}
+	d.session = session
+
+	d.trackSent(&discordgo.Message{ID: "msg-1", ChannelID: "ch1"})
+
+	d.handleReactionAdd(session, &discordgo.MessageReactionAdd{
+		MessageReaction: &discordgo.MessageReaction{
+			UserID:    "user-1",
+			MessageID: "msg-1",
+			ChannelID: "ch1",
+			Emoji:     discordgo.Emoji{Name: "🛑"},
+		},
+		Member: &discordgo.Member{User: &discordgo.User{ID: "user-1", Username: "alice"}, Roles: []string{"role-1"}},
+	})
+
+	select {
+	case r := <-d.Reactions():
+		want := Reaction{ChannelID: "ch1", MessageID: "msg-1", Emoji: "🛑", User: "alice", UserID: "user-1", Roles: []string{"role-1"}, Source: "discord"}
+		if !reflect.DeepEqual(r, want) {
+			t.Errorf("reaction = %+v, want %+v", r, want)
+		}
+	default:
+		t.Error("expected reaction on tracked message")
+	}
+}
+
+func TestDiscord_HandleReactionAdd_Ignored(t *testing.T) {
+	d := NewDiscord("token", []string{"ch1"})
+
+	session := &discordgo.Session{State: discordgo.NewState()}
+	session.State.User = &discordgo.User{ID: "bot-id"}
+	d.session = session
+
+	d.trackSent(&discordgo.Message{ID: "msg-1", ChannelID: "ch1"})
+
+	// Reaction on a message the bot did not send
+	d.handleReactionAdd(session, &discordgo.MessageReactionAdd{
+		MessageReaction: &discordgo.MessageReaction{UserID: "user-1", MessageID: "other", Emoji: discordgo.Emoji{Name: "🛑"}},
+	})
+	// The bot's own reaction
+	d.handleReactionAdd(session, &discordgo.MessageReactionAdd{
+		MessageReaction: &discordgo.MessageReaction{UserID: "bot-id", MessageID: "msg-1", Emoji: discordgo.Emoji{Name: "🛑"}},
+	})
+
+	select {
+	case r := <-d.Reactions():
+		t.Errorf("unexpected reaction %+v", r)
+	default:
+	}
+}
+
+func TestDiscord_TrackSent_Bounded(t *testing.T) {
+	d := NewDiscord("token", []string{"ch1"})
+
+	for i := 0; i < maxTrackedMessages+10; i++ {
+		d.trackSent(&discordgo.Message{ID: fmt.Sprintf("m%d", i), ChannelID: "ch1"})
+	}
+	d.trackSent(nil)
+
+	if len(d.sent) != maxTrackedMessages || len(d.sentOrder) != maxTrackedMessages {
+		t.Errorf("tracked %d/%d messages, want %d", len(d.sent), len(d.sentOrder), maxTrackedMessages)
+	}
+	if _, ok := d.sent["m0"]; ok {
+		t.Error("oldest message should have been evicted")
+	}
+	if _, ok := d.sent[fmt.Sprintf("m%d", maxTrackedMessages+9)]; !ok {
+		t.Error("newest message should be tracked")
+	}
+}
+
+func TestDiscord_Stop_ClosesReactionsChannel(t *testing.T) {
+	d := NewDiscord("token", []string{"ch1"})
+	ch := d.Reactions()
+
+	_ = d.Stop()
+
+	if _, open := <-ch; open {
+		t.Error("reactions channel should be closed after Stop()")
+	}
+}
+
+func TestDiscord_Typing_NotConnected(t *testing.T) {
+	d := NewDiscord("token", []string{"ch1"})
+	if err := d.Typing("ch1"); err == nil || err.Error() != "discord not connected" {
+		t.Errorf("Typing() error = %v, want 'discord not connected'", err)
+	}
+}
+
+func TestDiscord_SubscribeUnsubscribe(t *testing.T) {
+	d := NewDiscord("token", nil)
+
+	session := &discordgo.Session{State: discordgo.NewState()}
+	session.State.User = &discordgo.User{ID: "bot-id"}
+	d.session = session
+
+	msg := &discordgo.MessageCreate{
+		Message: &discordgo.Message{
+			ChannelID: "new-channel",
+			GuildID:   "guild-1",
+			Content:   "hello",
+			Author:    &discordgo.User{ID: "user-1", Username: "alice"},
+		},
+	}
+
+	d.handleMessage(session, msg)
+	select {
+	case <-d.Messages():
+		t.Fatal("unsubscribed channel should be ignored")
+	default:
+	}
+
+	d.Subscribe("new-channel")
+	d.handleMessage(session, msg)
+	select {
+	case received := <-d.Messages():
+		if received.ChannelID != "new-channel" {
+			t.Errorf("ChannelID = %q", received.ChannelID)
+		}
+	default:
+		t.Fatal("subscribed channel should be delivered")
+	}
+
+	d.Unsubscribe("new-channel")
+	d.handleMessage(session, msg)
+	select {
+	case <-d.Messages():
+		t.Error("channel should be ignored after Unsubscribe")
+	default:
+	}
+}
+
+func TestDiscord_HandleMessage_DirectMessage(t *testing.T) {
+	d := NewDiscord("token", []string{"allowed-channel"})
+
+	session := &discordgo.Session{State: discordgo.NewState()}
+	session.State.User = &discordgo.User{ID: "bot-id"}
+	d.session = session
+
+	// DMs have no guild and are delivered regardless of the allowlist
+	d.handleMessage(session, &discordgo.MessageCreate{
+		Message: &discordgo.Message{
+			ChannelID: "dm-channel",
+			Content:   "hello",
+			Author:    &discordgo.User{ID: "user-1", Username: "alice"},
+		},
+	})
+
+	select {
+	case received := <-d.Messages():
+		if !received.DirectMessage {
+			t.Error("DirectMessage should be true for a message without a guild")
+		}
+		if received.ChannelID != "dm-channel" {
+			t.Errorf("ChannelID = %q", received.ChannelID)
+		}
+	default:
+		t.Fatal("expected DM to be delivered")
+	}
+
+	// Guild messages are not DMs, and carry the author's guild roles
+	d.handleMessage(session, &discordgo.MessageCreate{
+		Message: &discordgo.Message{
+			ChannelID: "allowed-channel",
+			GuildID:   "guild-1",
+			Content:   "hello",
+			Author:    &discordgo.User{ID: "user-1", Username: "alice"},
+			Member:    &discordgo.Member{Roles: []string{"role-1", "role-2"}},
+		},
+	})
+	select {
+	case received := <-d.Messages():
+		if received.DirectMessage {
+			t.Error("guild message should not be a DM")
+		}
+		if !reflect.DeepEqual(received.Roles, []string{"role-1", "role-2"}) {
+			t.Errorf("Roles = %v", received.Roles)
+		}
+	default:
+		t.Fatal("expected guild message to be delivered")
+	}
+}
+
+func TestDiscord_Capabilities(t *testing.T) {
+	caps := CapabilitiesOf(NewDiscord("token", nil))
+	if caps.MaxMessageLength != 2000 || !caps.Files || !caps.Reactions || !caps.Typing {
+		t.Errorf("discord capabilities = %+v", caps)
+	}
+	if caps.Markdown != MarkdownDiscord {
+		t.Errorf("Markdown = %q, want %q", caps.Markdown, MarkdownDiscord)
+	}
+}
+
+func TestDiscord_HandleMessage_Metadata(t *testing.T) {
+	d := NewDiscord("token", []string{"allowed-channel"})
+
+	session := &discordgo.Session{State: discordgo.NewState()}
+	session.State.User = &discordgo.User{ID: "bot-id"}
+	d.session = session
+
+	sent := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	d.handleMessage(session, &discordgo.MessageCreate{
+		Message: &discordgo.Message{
+			ID:               "msg-1",
+			ChannelID:        "allowed-channel",
+			GuildID:          "guild-1",
+			Content:          "<@bot-id> <@user-2> look at this",
+			Author:           &discordgo.User{ID: "user-1", Username: "alice"},
+			Timestamp:        sent,
+			MessageReference: &discordgo.MessageReference{MessageID: "msg-0"},
+			Mentions:         []*discordgo.User{{ID: "bot-id"}, {ID: "user-2"}},
+		},
+	})
+
+	select {
+	case received := <-d.Messages():
+		if received.ID != "msg-1" || received.ReplyToID != "msg-0" {
+			t.Errorf("ID = %q, ReplyToID = %q", received.ID, received.ReplyToID)
+		}
+		if !received.Timestamp.Equal(sent) {
+			t.Errorf("Timestamp = %v, want %v", received.Timestamp, sent)
+		}
+		if len(received.Mentions) != 2 || received.Mentions[1] != "user-2" {
+			t.Errorf("Mentions = %v", received.Mentions)
+		}
+		if !received.MentionsBot {
+			t.Error("MentionsBot should be true")
+		}
+		if received.ThreadID != "" {
+			t.Errorf("ThreadID = %q, want empty", received.ThreadID)
+		}
+	default:
+		t.Fatal("expected message to be delivered")
+	}
+}
+
+func TestDiscord_HandleMessage_Thread(t *testing.T) {
+	d := NewDiscord("token", []string{"allowed-channel"})
+
+	session := &discordgo.Session{State: discordgo.NewState()}
+	session.State.User = &discordgo.User{ID: "bot-id"}
+	d.session = session
+	if err := session.State.GuildAdd(&discordgo.Guild{ID: "guild-1"}); err != nil {
+		t.Fatalf("GuildAdd: %v", err)
+	}
+	if err := session.State.ChannelAdd(&discordgo.Channel{
+		ID:       "thread-1",
+		GuildID:  "guild-1",
+		ParentID: "allowed-channel",
+		Type:     discordgo.ChannelTypeGuildPublicThread,
+	}); err != nil {
+		t.Fatalf("ChannelAdd: %v", err)
+	}
+
+	d.handleMessage(session, &discordgo.MessageCreate{
+		Message: &discordgo.Message{
+			ID:        "msg-1",
+			ChannelID: "thread-1",
+			GuildID:   "guild-1",
+			Content:   "in a thread",
+			Author:    &discordgo.User{ID: "user-1", Username: "alice"},
+		},
+	})
+
+	select {
+	case received := <-d.Messages():
+		if received.ChannelID != "allowed-channel" || received.ThreadID != "thread-1" {
+			t.Errorf("ChannelID = %q, ThreadID = %q; want parent channel and thread", received.ChannelID, received.ThreadID)
+		}
+	default:
+		t.Fatal("thread message in an allowlisted channel should be delivered")
+	}
+}
+
+func TestDiscord_Reply_NotConnected(t *testing.T) {
+	d := NewDiscord("token", nil)
+	if err := d.Reply("channel", "msg", "hi"); err == nil {
+		t.Error("Reply() should fail when not connected")
+	}
+}
+
+func TestDiscord_Mention(t *testing.T) {
+	var m Mentioner = NewDiscord("token", nil)
+	if got := m.Mention("42"); got != "<@42>" {
+		t.Errorf("Mention() = %q, want <@42>", got)
+	}
+}
+
+func TestDeliveryError_Classification(t *testing.T) {
+	rateLimited := &discordgo.RateLimitError{RateLimit: &discordgo.RateLimit{
+		TooManyRequests: &discordgo.TooManyRequests{RetryAfter: 3 * time.Second},
+		URL:             "https://discord.test/channels/1/messages",
+	}}
+	if got := RetryAfter(deliveryError(rateLimited)); got != 3*time.Second {
+		t.Errorf("RetryAfter(rate limit) = %v, want 3s", got)
+	}
+
+	forbidden := &discordgo.RESTError{Response: &http.Response{StatusCode: http.StatusForbidden, Status: "403 Forbidden"}}
+	if !IsPermanent(deliveryError(forbidden)) {
+		t.Error("403 should be permanent")
+	}
+
+	unavailable := &discordgo.RESTError{Response: &http.Response{StatusCode: http.StatusBadGateway, Status: "502 Bad Gateway"}}
+	err := deliveryError(unavailable)
+	if IsPermanent(err) || RetryAfter(err) != 0 {
+		t.Errorf("502 should be retried without a hint, got %v", err)
+	}
+
+	plain := fmt.Errorf("connection reset")
+	if deliveryError(plain) != plain {
+		t.Error("unrecognised errors should be returned unchanged")
+	}
+}
+
+func TestDiscord_HandleReactionAdd_Thread(t *testing.T) {
+	d := NewDiscord("token", []string{"allowed-channel"})
+
+	session := &discordgo.Session{State: discordgo.NewState()}
+	session.State.User = &discordgo.User{ID: "bot-id"}
+	d.session = session
+	if err := session.State.GuildAdd(&discordgo.Guild{ID: "guild-1"}); err != nil {
+		t.Fatalf("GuildAdd: %v", err)
+	}
+	if err := session.State.ChannelAdd(&discordgo.Channel{
+		ID:       "thread-1",
+		GuildID:  "guild-1",
+		ParentID: "allowed-channel",
+		Type:     discordgo.ChannelTypeGuildPublicThread,
+	}); err != nil {
+		t.Fatalf("ChannelAdd: %v", err)
+	}
+
+	// The bridge replied inside the thread.
+	d.trackSent(&discordgo.Message{ID: "msg-1", ChannelID: "thread-1"})
+	d.handleReactionAdd(session, &discordgo.MessageReactionAdd{
+		MessageReaction: &discordgo.MessageReaction{UserID: "user-1", MessageID: "msg-1", ChannelID: "thread-1", Emoji: discordgo.Emoji{Name: "🛑"}},
+	})
+
+	select {
+	case r := <-d.Reactions():
+		if r.ChannelID != "allowed-channel" {
+			t.Errorf("ChannelID = %q, want the thread's parent channel", r.ChannelID)
+		}
+	default:
+		t.Fatal("reaction on a tracked thread message should be delivered")
+	}
+}
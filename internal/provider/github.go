@@ -0,0 +1,493 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// maxGitHubCommentLength is GitHub's limit on a comment body.
+	maxGitHubCommentLength = 65536
+	// maxWebhookBody bounds how much of a webhook request is read.
+	maxWebhookBody = 5 << 20
+	// maxCommentFailures is how many times a comment is retried before it is dropped.
+	maxCommentFailures = 3
+)
+
+// GitHubOptions configures the GitHub provider.
+type GitHubOptions struct {
+	WebhookSecret string        // shared secret for X-Hub-Signature-256
+	Token         string        // token used to post comments
+	Listen        string        // address for the webhook server; empty to not listen (tests)
+	Path          string        // webhook URL path
+	APIURL        string        // REST API base URL
+	Mention       string        // trigger, e.g. "@llm-bridge"
+	QuietPeriod   time.Duration // output is batched into one comment until it has been quiet this long
+	ReplyWindow   time.Duration // how long after a mention (or its last output) output is still posted
+
+	// AllowedUsers are logins whose comments are accepted even though they
+	// are not owners, members or collaborators of the repository.
+	AllowedUsers []string
+}
+
+// trustedAssociations are the comment author_association values accepted
+// without AllowedUsers: people with write access or org membership.
+var trustedAssociations = map[string]bool{
+	"OWNER":        true,
+	"MEMBER":       true,
+	"COLLABORATOR": true,
+}
+
+// GitHub receives issue and pull request comments that mention the bot via
+// webhooks and answers them with comments. Channel IDs have the form
+// "owner/name#number".
+//
+// Output is not posted per chunk: it is batched per issue and posted once
+// the LLM has been quiet for QuietPeriod. Only issues with a recent mention
+// receive output, so a repo's other conversations do not spill into GitHub.
+type GitHub struct {
+	opts    GitHubOptions
+	client  *http.Client
+	mention *regexp.Regexp
+	server  *http.Server
+
+	mu       sync.Mutex
+	messages chan Message
+	threads  map[string]*githubThread
+	stopped  bool
+}
+
+// githubThread buffers output for one issue or pull request.
+type githubThread struct {
+	postMu sync.Mutex // serializes flushes so comments stay in order
+
+	// Guarded by GitHub.mu.
+	chunks   []string // complete comment bodies waiting to be posted
+	buf      strings.Builder
+	timer    *time.Timer
+	expires  time.Time
+	failures int
+}
+
+// NewGitHub creates a GitHub provider.
+func NewGitHub(opts GitHubOptions) *GitHub {
+	mention := regexp.MustCompile(`(?i)(^|\s)` + regexp.QuoteMeta(opts.Mention) + `\b`)
+	return &GitHub{
+		opts:     opts,
+		client:   &http.Client{Timeout: 30 * time.Second},
+		mention:  mention,
+		messages: make(chan Message, 100),
+		threads:  make(map[string]*githubThread),
+	}
+}
+
+func (g *GitHub) Name() string {
+	return "github"
+}
+
+// Start begins serving webhooks on opts.Listen.
+func (g *GitHub) Start(ctx context.Context) error {
+	if g.opts.Listen == "" {
+		return nil
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(g.opts.Path, g)
+	g.server = &http.Server{
+		Addr:              g.opts.Listen,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	ln, err := net.Listen("tcp", g.opts.Listen)
+	if err != nil {
+		return fmt.Errorf("listen: %w", err)
+	}
+	go func() {
+		if err := g.server.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("github webhook server failed", "error", err)
+		}
+	}()
+	return nil
+}
+
+// Stop shuts down the webhook server and posts any buffered output.
+func (g *GitHub) Stop() error {
+	g.mu.Lock()
+	if g.stopped {
+		g.mu.Unlock()
+		return nil
+	}
+	g.stopped = true
+	close(g.messages)
+	channels := make([]string, 0, len(g.threads))
+	for ch, t := range g.threads {
+		if t.timer != nil {
+			t.timer.Stop()
+		}
+		channels = append(channels, ch)
+	}
+	g.mu.Unlock()
+
+	var err error
+	if g.server != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		err = g.server.Shutdown(ctx)
+	}
+
+	for _, ch := range channels {
+		g.flush(ch)
+	}
+	return err
+}
+
+// githubWebhook is the subset of an issue_comment event the provider uses.
+type githubWebhook struct {
+	Action string `json:"action"`
+	Issue  struct {
+		Number int `json:"number"`
+	} `json:"issue"`
+	Comment struct {
+		ID                int64     `json:"id"`
+		Body              string    `json:"body"`
+		CreatedAt         time.Time `json:"created_at"`
+		AuthorAssociation string    `json:"author_association"`
+		User              struct {
+			Login string `json:"login"`
+			ID    int64  `json:"id"`
+			Type  string `json:"type"`
+		} `json:"user"`
+	} `json:"comment"`
+	Repository struct {
+		FullName string `json:"full_name"`
+	} `json:"repository"`
+}
+
+// ServeHTTP handles a GitHub webhook delivery.
+func (g *GitHub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxWebhookBody))
+	if err != nil {
+		http.Error(w, "read body", http.StatusBadRequest)
+		return
+	}
+	if !g.validSignature(r.Header.Get("X-Hub-Signature-256"), body) {
+		slog.Warn("github webhook signature mismatch", "remote", r.RemoteAddr)
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+
+	switch r.Header.Get("X-GitHub-Event") {
+	case "ping":
+		w.WriteHeader(http.StatusOK)
+		return
+	case "issue_comment":
+	default:
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	var hook githubWebhook
+	if err := json.Unmarshal(body, &hook); err != nil {
+		http.Error(w, "invalid payload", http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+
+	if msg, ok := g.commentMessage(hook); ok {
+		g.deliver(msg)
+	}
+}
+
+// validSignature checks an X-Hub-Signature-256 header against body.
+func (g *GitHub) validSignature(header string, body []byte) bool {
+	if g.opts.WebhookSecret == "" {
+		return false
+	}
+	sig, ok := strings.CutPrefix(header, "sha256=")
+	if !ok {
+		return false
+	}
+	got, err := hex.DecodeString(sig)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(g.opts.WebhookSecret))
+	mac.Write(body)
+	return hmac.Equal(got, mac.Sum(nil))
+}
+
+// commentMessage converts a newly created comment that mentions the bot
+// into a Message with the mention removed. Comments from users who are not
+// trusted (see trustedCommenter) are ignored.
+func (g *GitHub) commentMessage(hook githubWebhook) (Message, bool) {
+	c := hook.Comment
+	if hook.Action != "created" || c.User.Type == "Bot" || hook.Repository.FullName == "" {
+		return Message{}, false
+	}
+	if !g.mention.MatchString(c.Body) {
+		return Message{}, false
+	}
+	if !g.trustedCommenter(c.User.Login, c.AuthorAssociation) {
+		slog.Warn("github comment from untrusted user ignored", "user", c.User.Login, "association", c.AuthorAssociation, "repository", hook.Repository.FullName)
+		return Message{}, false
+	}
+
+	content := strings.TrimSpace(g.mention.ReplaceAllString(c.Body, "$1"))
+	return Message{
+		ID:          strconv.FormatInt(c.ID, 10),
+		ChannelID:   fmt.Sprintf("%s#%d", hook.Repository.FullName, hook.Issue.Number),
+		Content:     content,
+		Author:      c.User.Login,
+		AuthorID:    strconv.FormatInt(c.User.ID, 10),
+		Source:      "github",
+		MentionsBot: true,
+		Timestamp:   c.CreatedAt,
+		Repository:  hook.Repository.FullName,
+	}, true
+}
+
+// trustedCommenter reports whether a comment author may prompt the bot:
+// repository owners, organization members and collaborators, plus logins in
+// AllowedUsers. Anyone can comment on a public repository, so everyone else
+// is ignored.
+func (g *GitHub) trustedCommenter(login, association string) bool {
+	if trustedAssociations[association] {
+		return true
+	}
+	for _, allowed := range g.opts.AllowedUsers {
+		if strings.EqualFold(allowed, login) {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *GitHub) deliver(msg Message) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.stopped {
+		return
+	}
+
+	t := g.threadLocked(msg.ChannelID)
+	t.expires = time.Now().Add(g.opts.ReplyWindow)
+
+	select {
+	case g.messages <- msg:
+	default:
+		// Channel full, drop message
+	}
+}
+
+// threadLocked returns the thread for channelID, creating it if needed.
+// Callers must hold g.mu.
+func (g *GitHub) threadLocked(channelID string) *githubThread {
+	t, ok := g.threads[channelID]
+	if !ok {
+		t = &githubThread{}
+		g.threads[channelID] = t
+	}
+	return t
+}
+
+// Send buffers content for the issue's next comment. Output for issues
+// without a recent mention is discarded.
+func (g *GitHub) Send(channelID string, content string) error {
+	if _, _, err := parseGitHubChannel(channelID); err != nil {
+		return &DeliveryError{Err: err, Permanent: true}
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	t, ok := g.threads[channelID]
+	now := time.Now()
+	if !ok || now.After(t.expires) {
+		if ok && len(t.chunks) == 0 && t.buf.Len() == 0 {
+			delete(g.threads, channelID)
+		}
+		slog.Debug("github output outside reply window discarded", "channel", channelID)
+		return nil
+	}
+	t.expires = now.Add(g.opts.ReplyWindow)
+
+	if t.buf.Len() > 0 && t.buf.Len()+len(content)+1 > maxGitHubCommentLength {
+		t.chunks = append(t.chunks, t.buf.String())
+		t.buf.Reset()
+	}
+	if t.buf.Len() > 0 && !strings.HasSuffix(t.buf.String(), "\n") {
+		t.buf.WriteByte('\n')
+	}
+	t.buf.WriteString(content)
+
+	delay := g.opts.QuietPeriod
+	if len(t.chunks) > 0 {
+		delay = 0
+	}
+	g.scheduleLocked(channelID, t, delay)
+	return nil
+}
+
+// SendFile posts the file inline as a fenced block, since issue comments
+// cannot carry attachments.
+func (g *GitHub) SendFile(channelID string, filename string, content []byte) error {
+	return g.Send(channelID, fmt.Sprintf("**%s**\n\n```\n%s\n```", filename, content))
+}
+
+// scheduleLocked arranges for the thread to be flushed after delay.
+// Callers must hold g.mu.
+func (g *GitHub) scheduleLocked(channelID string, t *githubThread, delay time.Duration) {
+	if g.stopped {
+		return
+	}
+	if t.timer == nil {
+		t.timer = time.AfterFunc(delay, func() { g.flush(channelID) })
+		return
+	}
+	t.timer.Reset(delay)
+}
+
+// flush posts everything buffered for a channel. Failed comments are put
+// back and retried, up to maxCommentFailures times.
+func (g *GitHub) flush(channelID string) {
+	g.mu.Lock()
+	t, ok := g.threads[channelID]
+	g.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	t.postMu.Lock()
+	defer t.postMu.Unlock()
+
+	g.mu.Lock()
+	bodies := t.chunks
+	if t.buf.Len() > 0 {
+		bodies = append(bodies, t.buf.String())
+	}
+	t.chunks = nil
+	t.buf.Reset()
+	g.mu.Unlock()
+
+	for i, body := range bodies {
+		err := g.postComment(channelID, body)
+		if err == nil {
+			g.mu.Lock()
+			t.failures = 0
+			g.mu.Unlock()
+			continue
+		}
+
+		g.mu.Lock()
+		t.failures++
+		if IsPermanent(err) || t.failures >= maxCommentFailures {
+			slog.Error("github comment dropped", "channel", channelID, "error", err, "bytes", len(body))
+			t.failures = 0
+			g.mu.Unlock()
+			continue
+		}
+		slog.Warn("github comment failed, will retry", "channel", channelID, "error", err)
+		t.chunks = append(bodies[i:len(bodies):len(bodies)], t.chunks...)
+		g.scheduleLocked(channelID, t, max(RetryAfter(err), g.opts.QuietPeriod))
+		g.mu.Unlock()
+		return
+	}
+}
+
+// postComment creates an issue comment through the REST API.
+func (g *GitHub) postComment(channelID, body string) error {
+	repo, number, err := parseGitHubChannel(channelID)
+	if err != nil {
+		return &DeliveryError{Err: err, Permanent: true}
+	}
+
+	payload, err := json.Marshal(map[string]string{"body": body})
+	if err != nil {
+		return fmt.Errorf("encode comment: %w", err)
+	}
+	url := fmt.Sprintf("%s/repos/%s/issues/%d/comments", strings.TrimSuffix(g.opts.APIURL, "/"), repo, number)
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("build request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+g.opts.Token)
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("post comment: %w", err)
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+
+	if resp.StatusCode == http.StatusCreated {
+		return nil
+	}
+	return githubDeliveryError(resp, respBody)
+}
+
+// githubDeliveryError classifies a failed REST response. Rate limits carry
+// Retry-After (secondary limits) or X-RateLimit-Reset (primary limits).
+func githubDeliveryError(resp *http.Response, body []byte) error {
+	err := fmt.Errorf("github API: %s: %s", resp.Status, strings.TrimSpace(string(body)))
+
+	if secs, perr := strconv.Atoi(resp.Header.Get("Retry-After")); perr == nil {
+		return &DeliveryError{Err: err, RetryAfter: time.Duration(secs) * time.Second}
+	}
+	if resp.Header.Get("X-RateLimit-Remaining") == "0" {
+		if reset, perr := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); perr == nil {
+			return &DeliveryError{Err: err, RetryAfter: time.Until(time.Unix(reset, 0))}
+		}
+	}
+	if resp.StatusCode >= 400 && resp.StatusCode < 500 && resp.StatusCode != http.StatusTooManyRequests {
+		return &DeliveryError{Err: err, Permanent: true}
+	}
+	return err
+}
+
+// parseGitHubChannel splits "owner/name#number".
+func parseGitHubChannel(channelID string) (string, int, error) {
+	repo, num, ok := strings.Cut(channelID, "#")
+	if !ok || strings.Count(repo, "/") != 1 {
+		return "", 0, fmt.Errorf("invalid github channel %q: want owner/name#number", channelID)
+	}
+	n, err := strconv.Atoi(num)
+	if err != nil || n <= 0 {
+		return "", 0, fmt.Errorf("invalid github channel %q: bad issue number", channelID)
+	}
+	return repo, n, nil
+}
+
+// Capabilities reports GitHub's comment size limit and markdown dialect.
+func (g *GitHub) Capabilities() Capabilities {
+	return Capabilities{
+		MaxMessageLength: maxGitHubCommentLength,
+		Markdown:         MarkdownGitHub,
+	}
+}
+
+func (g *GitHub) Messages() <-chan Message {
+	return g.messages
+}
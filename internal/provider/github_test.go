@@ -0,0 +1,353 @@
+package provider
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeGitHubAPI records comments posted to it. statuses are returned for the
+// first requests in order; after that every request succeeds.
+type fakeGitHubAPI struct {
+	mu       sync.Mutex
+	comments []fakeComment
+	statuses []int
+	headers  http.Header
+}
+
+type fakeComment struct {
+	path string
+	body string
+	auth string
+}
+
+func (f *fakeGitHubAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if len(f.statuses) > 0 {
+		status := f.statuses[0]
+		f.statuses = f.statuses[1:]
+		for k, v := range f.headers {
+			w.Header()[k] = v
+		}
+		w.WriteHeader(status)
+		return
+	}
+
+	var payload struct {
+		Body string `json:"body"`
+	}
+	data, _ := io.ReadAll(r.Body)
+	_ = json.Unmarshal(data, &payload)
+	f.comments = append(f.comments, fakeComment{path: r.URL.Path, body: payload.Body, auth: r.Header.Get("Authorization")})
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (f *fakeGitHubAPI) posted() []fakeComment {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]fakeComment(nil), f.comments...)
+}
+
+func newTestGitHub(t *testing.T, api http.Handler) *GitHub {
+	t.Helper()
+	srv := httptest.NewServer(api)
+	t.Cleanup(srv.Close)
+	g := NewGitHub(GitHubOptions{
+		WebhookSecret: "s3cret",
+		Token:         "tok",
+		APIURL:        srv.URL,
+		Mention:       "@llm-bridge",
+		QuietPeriod:   20 * time.Millisecond,
+		ReplyWindow:   time.Minute,
+	})
+	t.Cleanup(func() { _ = g.Stop() })
+	return g
+}
+
+func signBody(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func commentPayload(body, userType string) []byte {
+	return associationPayload(body, userType, "MEMBER")
+}
+
+func associationPayload(body, userType, association string) []byte {
+	return []byte(`{"action":"created","issue":{"number":42},` +
+		`"comment":{"id":1001,"body":` + jsonQuote(body) + `,"created_at":"2026-01-02T03:04:05Z",` +
+		`"author_association":"` + association + `",` +
+		`"user":{"login":"alice","id":7,"type":"` + userType + `"}},` +
+		`"repository":{"full_name":"acme/widgets"}}`)
+}
+
+func jsonQuote(s string) string {
+	b, _ := json.Marshal(s)
+	return string(b)
+}
+
+func postWebhook(g *GitHub, event string, body []byte, signature string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/github/webhook", strings.NewReader(string(body)))
+	req.Header.Set("X-GitHub-Event", event)
+	req.Header.Set("X-Hub-Signature-256", signature)
+	rec := httptest.NewRecorder()
+	g.ServeHTTP(rec, req)
+	return rec
+}
+
+func waitForComments(t *testing.T, api *fakeGitHubAPI, n int) []fakeComment {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if c := api.posted(); len(c) >= n {
+			return c
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	c := api.posted()
+	t.Fatalf("expected %d comments, got %d: %+v", n, len(c), c)
+	return nil
+}
+
+func TestGitHub_Webhook_RejectsBadSignature(t *testing.T) {
+	g := newTestGitHub(t, &fakeGitHubAPI{})
+	body := commentPayload("@llm-bridge hi", "User")
+
+	for name, sig := range map[string]string{
+		"missing":    "",
+		"wrong":      signBody("other", body),
+		"not hex":    "sha256=zz",
+		"bad prefix": strings.Replace(signBody("s3cret", body), "sha256=", "sha1=", 1),
+	} {
+		if rec := postWebhook(g, "issue_comment", body, sig); rec.Code != http.StatusUnauthorized {
+			t.Errorf("%s signature: status = %d, want 401", name, rec.Code)
+		}
+	}
+	select {
+	case msg := <-g.Messages():
+		t.Errorf("unexpected message %+v", msg)
+	default:
+	}
+}
+
+func TestGitHub_Webhook_Ping(t *testing.T) {
+	g := newTestGitHub(t, &fakeGitHubAPI{})
+	body := []byte(`{"zen":"hi"}`)
+	if rec := postWebhook(g, "ping", body, signBody("s3cret", body)); rec.Code != http.StatusOK {
+		t.Errorf("ping status = %d, want 200", rec.Code)
+	}
+}
+
+func TestGitHub_Webhook_MentionedComment(t *testing.T) {
+	g := newTestGitHub(t, &fakeGitHubAPI{})
+	body := commentPayload("@LLM-Bridge why is CI red?", "User")
+
+	if rec := postWebhook(g, "issue_comment", body, signBody("s3cret", body)); rec.Code != http.StatusAccepted {
+		t.Fatalf("status = %d, want 202", rec.Code)
+	}
+
+	select {
+	case msg := <-g.Messages():
+		if msg.ChannelID != "acme/widgets#42" || msg.Repository != "acme/widgets" {
+			t.Errorf("routing fields = %q / %q", msg.ChannelID, msg.Repository)
+		}
+		if msg.Content != "why is CI red?" {
+			t.Errorf("Content = %q, want mention stripped", msg.Content)
+		}
+		if msg.ID != "1001" || msg.Author != "alice" || msg.AuthorID != "7" || msg.Source != "github" || !msg.MentionsBot {
+			t.Errorf("metadata = %+v", msg)
+		}
+		if msg.Timestamp.IsZero() {
+			t.Error("Timestamp not set")
+		}
+	default:
+		t.Fatal("expected a message")
+	}
+}
+
+func TestGitHub_Webhook_IgnoresUnmentionedAndBots(t *testing.T) {
+	g := newTestGitHub(t, &fakeGitHubAPI{})
+
+	for _, body := range [][]byte{
+		commentPayload("just a normal comment", "User"),
+		commentPayload("email@llm-bridge.example is not a mention", "User"),
+		commentPayload("@llm-bridge loop", "Bot"),
+		[]byte(strings.Replace(string(commentPayload("@llm-bridge hi", "User")), `"created"`, `"edited"`, 1)),
+	} {
+		if rec := postWebhook(g, "issue_comment", body, signBody("s3cret", body)); rec.Code != http.StatusAccepted {
+			t.Errorf("status = %d, want 202", rec.Code)
+		}
+	}
+	select {
+	case msg := <-g.Messages():
+		t.Errorf("unexpected message %+v", msg)
+	default:
+	}
+}
+
+func TestGitHub_Webhook_OnlyTrustedCommenters(t *testing.T) {
+	for _, tt := range []struct {
+		association string
+		allowed     []string
+		want        bool
+	}{
+		{"OWNER", nil, true},
+		{"MEMBER", nil, true},
+		{"COLLABORATOR", nil, true},
+		{"CONTRIBUTOR", nil, false},
+		{"NONE", nil, false},
+		{"", nil, false},
+		{"NONE", []string{"Alice"}, true},
+		{"NONE", []string{"bob"}, false},
+	} {
+		g := newTestGitHub(t, &fakeGitHubAPI{})
+		g.opts.AllowedUsers = tt.allowed
+		body := associationPayload("@llm-bridge hi", "User", tt.association)
+		postWebhook(g, "issue_comment", body, signBody("s3cret", body))
+
+		select {
+		case <-g.Messages():
+			if !tt.want {
+				t.Errorf("association %q, allowed %v: comment should be ignored", tt.association, tt.allowed)
+			}
+		default:
+			if tt.want {
+				t.Errorf("association %q, allowed %v: comment should be accepted", tt.association, tt.allowed)
+			}
+		}
+	}
+}
+
+func TestGitHub_Send_BatchesUntilQuiet(t *testing.T) {
+	api := &fakeGitHubAPI{}
+	g := newTestGitHub(t, api)
+	body := commentPayload("@llm-bridge go", "User")
+	postWebhook(g, "issue_comment", body, signBody("s3cret", body))
+
+	for _, chunk := range []string{"one", "two", "three"} {
+		if err := g.Send("acme/widgets#42", chunk); err != nil {
+			t.Fatalf("Send() error = %v", err)
+		}
+	}
+
+	comments := waitForComments(t, api, 1)
+	time.Sleep(50 * time.Millisecond)
+	if got := api.posted(); len(got) != 1 {
+		t.Fatalf("expected one batched comment, got %d", len(got))
+	}
+	if comments[0].body != "one\ntwo\nthree" {
+		t.Errorf("body = %q", comments[0].body)
+	}
+	if comments[0].path != "/repos/acme/widgets/issues/42/comments" || comments[0].auth != "Bearer tok" {
+		t.Errorf("request = %+v", comments[0])
+	}
+}
+
+func TestGitHub_Send_OutsideReplyWindowDiscarded(t *testing.T) {
+	api := &fakeGitHubAPI{}
+	g := newTestGitHub(t, api)
+
+	if err := g.Send("acme/widgets#42", "unprompted"); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	_ = g.Stop()
+	if got := api.posted(); len(got) != 0 {
+		t.Errorf("expected nothing posted without a mention, got %+v", got)
+	}
+}
+
+func TestGitHub_Send_InvalidChannel(t *testing.T) {
+	g := newTestGitHub(t, &fakeGitHubAPI{})
+	if err := g.Send("not-a-channel", "x"); !IsPermanent(err) {
+		t.Errorf("Send() error = %v, want permanent", err)
+	}
+}
+
+func TestGitHub_Send_RetriesRateLimit(t *testing.T) {
+	api := &fakeGitHubAPI{statuses: []int{http.StatusForbidden}, headers: http.Header{"Retry-After": {"0"}}}
+	g := newTestGitHub(t, api)
+	body := commentPayload("@llm-bridge go", "User")
+	postWebhook(g, "issue_comment", body, signBody("s3cret", body))
+
+	if err := g.Send("acme/widgets#42", "answer"); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	comments := waitForComments(t, api, 1)
+	if comments[0].body != "answer" {
+		t.Errorf("body = %q", comments[0].body)
+	}
+}
+
+func TestGitHub_Stop_FlushesBuffered(t *testing.T) {
+	api := &fakeGitHubAPI{}
+	g := newTestGitHub(t, api)
+	g.opts.QuietPeriod = time.Hour
+	body := commentPayload("@llm-bridge go", "User")
+	postWebhook(g, "issue_comment", body, signBody("s3cret", body))
+
+	_ = g.Send("acme/widgets#42", "pending")
+	if err := g.Stop(); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+	if got := api.posted(); len(got) != 1 || got[0].body != "pending" {
+		t.Errorf("expected buffered output flushed on stop, got %+v", got)
+	}
+}
+
+func TestGitHubDeliveryError(t *testing.T) {
+	tests := []struct {
+		status    int
+		header    http.Header
+		permanent bool
+		retry     bool
+	}{
+		{http.StatusForbidden, http.Header{"Retry-After": {"30"}}, false, true},
+		{http.StatusForbidden, http.Header{"X-Ratelimit-Remaining": {"0"}, "X-Ratelimit-Reset": {"9999999999"}}, false, true},
+		{http.StatusNotFound, nil, true, false},
+		{http.StatusUnprocessableEntity, nil, true, false},
+		{http.StatusBadGateway, nil, false, false},
+	}
+	for _, tt := range tests {
+		resp := &http.Response{StatusCode: tt.status, Status: http.StatusText(tt.status), Header: tt.header}
+		if resp.Header == nil {
+			resp.Header = http.Header{}
+		}
+		err := githubDeliveryError(resp, nil)
+		if IsPermanent(err) != tt.permanent {
+			t.Errorf("%d: permanent = %v, want %v", tt.status, IsPermanent(err), tt.permanent)
+		}
+		if (RetryAfter(err) > 0) != tt.retry {
+			t.Errorf("%d: RetryAfter = %v", tt.status, RetryAfter(err))
+		}
+	}
+}
+
+func TestParseGitHubChannel(t *testing.T) {
+	repo, n, err := parseGitHubChannel("acme/widgets#7")
+	if err != nil || repo != "acme/widgets" || n != 7 {
+		t.Errorf("parseGitHubChannel() = %q, %d, %v", repo, n, err)
+	}
+	for _, bad := range []string{"acme/widgets", "widgets#7", "acme/widgets#x", "acme/widgets#0", "a/b/c#1"} {
+		if _, _, err := parseGitHubChannel(bad); err == nil {
+			t.Errorf("parseGitHubChannel(%q) expected error", bad)
+		}
+	}
+}
+
+func TestGitHub_Capabilities(t *testing.T) {
+	caps := CapabilitiesOf(NewGitHub(GitHubOptions{Mention: "@bot"}))
+	if caps.MaxMessageLength != 65536 || caps.Markdown != MarkdownGitHub || caps.Files {
+		t.Errorf("capabilities = %+v", caps)
+	}
+}
@@ -12,18 +12,25 @@ type MockProvider struct {
 
 	mu          sync.Mutex
 	messages    chan Message
+	reactions   chan Reaction
 	sentMsgs    []SentMessage
 	sentFiles   []SentFile
+	typing      []string
+	subscribed  map[string]bool
+	caps        Capabilities
 	startCalled bool
 	stopCalled  bool
 	startErr    error
 	sendErr     error
+	sendErrs    []error // one-shot errors returned before sendErr
 	stopped     bool
+	canView     func(userID, channelID string) bool // nil: everyone can view everything
 }
 
 type SentMessage struct {
 	ChannelID string
 	Content   string
+	ReplyToID string // set when sent with Reply
 }
 
 type SentFile struct {
@@ -34,9 +41,12 @@ type SentFile struct {
 
 func NewMockProvider(name string) *MockProvider {
 	return &MockProvider{
-		name:      name,
-		channelID: "mock-channel",
-		messages:  make(chan Message, 100),
+		name:       name,
+		channelID:  "mock-channel",
+		messages:   make(chan Message, 100),
+		reactions:  make(chan Reaction, 100),
+		subscribed: make(map[string]bool),
+		caps:       DefaultCapabilities(),
 	}
 }
 
@@ -60,33 +70,90 @@ func (m *MockProvider) Stop() error {
 	m.stopCalled = true
 	m.stopped = true
 	close(m.messages)
+	close(m.reactions)
 	return nil
 }
 
 func (m *MockProvider) Send(channelID string, content string) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	if m.sendErr != nil {
-		return m.sendErr
+	if err := m.nextSendErr(); err != nil {
+		return err
 	}
 	m.sentMsgs = append(m.sentMsgs, SentMessage{ChannelID: channelID, Content: content})
 	return nil
 }
 
 func (m *MockProvider) SendFile(channelID string, filename string, content []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.nextSendErr(); err != nil {
+		return err
+	}
+	m.sentFiles = append(m.sentFiles, SentFile{ChannelID: channelID, Filename: filename, Content: content})
+	return nil
+}
+
+func (m *MockProvider) Reply(channelID, messageID, content string) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	if m.sendErr != nil {
 		return m.sendErr
 	}
-	m.sentFiles = append(m.sentFiles, SentFile{ChannelID: channelID, Filename: filename, Content: content})
+	m.sentMsgs = append(m.sentMsgs, SentMessage{ChannelID: channelID, Content: content, ReplyToID: messageID})
 	return nil
 }
 
+func (m *MockProvider) Typing(channelID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.typing = append(m.typing, channelID)
+	return nil
+}
+
+// CanView implements AccessChecker; see SetCanView.
+func (m *MockProvider) CanView(userID, channelID string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.canView == nil {
+		return true, nil
+	}
+	return m.canView(userID, channelID), nil
+}
+
+// SetCanView decides which channels users can view.
+func (m *MockProvider) SetCanView(f func(userID, channelID string) bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.canView = f
+}
+
+func (m *MockProvider) Subscribe(channelID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.subscribed[channelID] = true
+}
+
+func (m *MockProvider) Unsubscribe(channelID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.subscribed, channelID)
+}
+
+func (m *MockProvider) Capabilities() Capabilities {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.caps
+}
+
 func (m *MockProvider) Messages() <-chan Message {
 	return m.messages
 }
 
+func (m *MockProvider) Reactions() <-chan Reaction {
+	return m.reactions
+}
+
 // Test helpers
 
 func (m *MockProvider) SimulateMessage(msg Message) {
@@ -102,6 +169,19 @@ func (m *MockProvider) SimulateMessage(msg Message) {
 	}
 }
 
+func (m *MockProvider) SimulateReaction(r Reaction) {
+	m.mu.Lock()
+	stopped := m.stopped
+	m.mu.Unlock()
+	if stopped {
+		return
+	}
+	select {
+	case m.reactions <- r:
+	default:
+	}
+}
+
 func (m *MockProvider) GetSentMessages() []SentMessage {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -118,12 +198,49 @@ func (m *MockProvider) GetSentFiles() []SentFile {
 	return result
 }
 
+func (m *MockProvider) GetTypingChannels() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	result := make([]string, len(m.typing))
+	copy(result, m.typing)
+	return result
+}
+
+func (m *MockProvider) IsSubscribed(channelID string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.subscribed[channelID]
+}
+
 func (m *MockProvider) SetStartError(err error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	m.startErr = err
 }
 
+func (m *MockProvider) SetCapabilities(caps Capabilities) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.caps = caps
+}
+
+// QueueSendErrors makes the next len(errs) Send/SendFile calls fail with errs in order.
+func (m *MockProvider) QueueSendErrors(errs ...error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sendErrs = append(m.sendErrs, errs...)
+}
+
+// nextSendErr returns the error for the next send. Callers must hold m.mu.
+func (m *MockProvider) nextSendErr() error {
+	if len(m.sendErrs) > 0 {
+		err := m.sendErrs[0]
+		m.sendErrs = m.sendErrs[1:]
+		return err
+	}
+	return m.sendErr
+}
+
 func (m *MockProvider) SetSendError(err error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -2,6 +2,7 @@ package provider
 
 import (
 	"context"
+	"errors"
 	"testing"
 )
 
@@ -161,3 +162,88 @@ func TestMockProvider_SimulateMessage_ChannelFull(t *testing.T) {
 		// Expected
 	}
 }
+
+func TestMockProvider_SimulateReaction(t *testing.T) {
+	m := NewMockProvider("test")
+	m.SimulateReaction(Reaction{ChannelID: "ch", Emoji: "🛑"})
+
+	select {
+	case r := <-m.Reactions():
+		if r.Emoji != "🛑" {
+			t.Errorf("Emoji = %q", r.Emoji)
+		}
+	default:
+		t.Error("expected simulated reaction")
+	}
+
+	_ = m.Stop()
+	// Should not panic after stop
+	m.SimulateReaction(Reaction{ChannelID: "ch", Emoji: "🛑"})
+}
+
+func TestMockProvider_Typing(t *testing.T) {
+	m := NewMockProvider("test")
+	_ = m.Typing("ch1")
+	_ = m.Typing("ch2")
+
+	got := m.GetTypingChannels()
+	if len(got) != 2 || got[0] != "ch1" || got[1] != "ch2" {
+		t.Errorf("GetTypingChannels() = %v", got)
+	}
+}
+
+func TestMockProvider_Subscribe(t *testing.T) {
+	m := NewMockProvider("test")
+	m.Subscribe("ch1")
+	if !m.IsSubscribed("ch1") {
+		t.Error("ch1 should be subscribed")
+	}
+	m.Unsubscribe("ch1")
+	if m.IsSubscribed("ch1") {
+		t.Error("ch1 should be unsubscribed")
+	}
+}
+
+func TestCapabilitiesOf(t *testing.T) {
+	m := NewMockProvider("mock")
+	if got := CapabilitiesOf(m); got != DefaultCapabilities() {
+		t.Errorf("mock default capabilities = %+v, want %+v", got, DefaultCapabilities())
+	}
+
+	m.SetCapabilities(Capabilities{MaxMessageLength: 10})
+	if got := CapabilitiesOf(m); got.MaxMessageLength != 10 || got.Files {
+		t.Errorf("CapabilitiesOf after SetCapabilities = %+v", got)
+	}
+
+	var plain struct{ Provider }
+	if got := CapabilitiesOf(plain); got != DefaultCapabilities() {
+		t.Errorf("provider without capabilities = %+v, want defaults", got)
+	}
+}
+
+func TestMockProvider_Reply(t *testing.T) {
+	m := NewMockProvider("mock")
+	if err := m.Reply("ch", "msg-1", "answer"); err != nil {
+		t.Fatalf("Reply() error = %v", err)
+	}
+	msgs := m.GetSentMessages()
+	if len(msgs) != 1 || msgs[0].ReplyToID != "msg-1" || msgs[0].Content != "answer" {
+		t.Errorf("sent = %+v", msgs)
+	}
+}
+
+func TestMockProvider_QueueSendErrors(t *testing.T) {
+	m := NewMockProvider("mock")
+	errBusy := errors.New("busy")
+	m.QueueSendErrors(errBusy)
+
+	if err := m.Send("ch", "a"); err != errBusy {
+		t.Errorf("first Send() error = %v, want %v", err, errBusy)
+	}
+	if err := m.Send("ch", "b"); err != nil {
+		t.Errorf("second Send() error = %v, want nil", err)
+	}
+	if msgs := m.GetSentMessages(); len(msgs) != 1 || msgs[0].Content != "b" {
+		t.Errorf("sent = %+v", msgs)
+	}
+}
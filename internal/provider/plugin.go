@@ -0,0 +1,458 @@
+package provider
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+// PluginProtocolVersion is the plugin protocol version sent in initialize.
+// See docs/plugins.md.
+const PluginProtocolVersion = 1
+
+const (
+	pluginInitTimeout     = 10 * time.Second
+	pluginCallTimeout     = 30 * time.Second
+	pluginShutdownTimeout = 5 * time.Second
+)
+
+var errPluginExited = errors.New("plugin exited")
+
+// Plugin is a provider implemented by an external executable that speaks
+// JSON-RPC 2.0 over stdin/stdout, one message per line.
+type Plugin struct {
+	name     string
+	command  string
+	args     []string
+	env      []string // extra KEY=VALUE entries
+	channels []string
+
+	cmd   *exec.Cmd
+	stdin io.WriteCloser
+	done  chan struct{} // closed when the plugin's stdout ends
+
+	writeMu sync.Mutex // serializes writes to stdin
+
+	mu       sync.Mutex
+	nextID   int64
+	pending  map[int64]chan rpcResponse
+	messages chan Message
+	caps     Capabilities
+	stopped  bool
+}
+
+// NewPlugin creates a plugin provider named name that runs command with args.
+// env entries ("KEY=VALUE") are added to the bridge's environment.
+func NewPlugin(name, command string, args, env, channelIDs []string) *Plugin {
+	return &Plugin{
+		name:     name,
+		command:  command,
+		args:     args,
+		env:      env,
+		channels: channelIDs,
+		done:     make(chan struct{}),
+		pending:  make(map[int64]chan rpcResponse),
+		messages: make(chan Message, 100),
+	}
+}
+
+// rpcRequest is an outgoing request (ID set) or notification (ID nil).
+type rpcRequest struct {
+	JSONRPC string `json:"jsonrpc"`
+	ID      *int64 `json:"id,omitempty"`
+	Method  string `json:"method"`
+	Params  any    `json:"params,omitempty"`
+}
+
+// rpcErrorReply answers a request from the plugin with an error.
+type rpcErrorReply struct {
+	JSONRPC string    `json:"jsonrpc"`
+	ID      *int64    `json:"id"`
+	Error   *rpcError `json:"error"`
+}
+
+// rpcResponse is a response to a request.
+type rpcResponse struct {
+	Result json.RawMessage
+	Error  *rpcError
+}
+
+// rpcIncoming is any line read from the plugin.
+type rpcIncoming struct {
+	ID     *int64          `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+	Result json.RawMessage `json:"result"`
+	Error  *rpcError       `json:"error"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    *struct {
+		RetryAfterMS int64 `json:"retry_after_ms"`
+		Permanent    bool  `json:"permanent"`
+	} `json:"data,omitempty"`
+}
+
+func (e *rpcError) Error() string {
+	return fmt.Sprintf("plugin error %d: %s", e.Code, e.Message)
+}
+
+// pluginCapabilities is the wire form of Capabilities.
+type pluginCapabilities struct {
+	MaxMessageLength int    `json:"max_message_length"`
+	Files            bool   `json:"files"`
+	Replies          bool   `json:"replies"`
+	Typing           bool   `json:"typing"`
+	Threads          bool   `json:"threads"`
+	Markdown         string `json:"markdown"`
+}
+
+// pluginMessage is the params of a "message" notification.
+type pluginMessage struct {
+	ID            string    `json:"id"`
+	ChannelID     string    `json:"channel_id"`
+	Content       string    `json:"content"`
+	Author        string    `json:"author"`
+	AuthorID      string    `json:"author_id"`
+	Roles         []string  `json:"roles"`
+	ReplyToID     string    `json:"reply_to_id"`
+	ThreadID      string    `json:"thread_id"`
+	Mentions      []string  `json:"mentions"`
+	MentionsBot   bool      `json:"mentions_bot"`
+	DirectMessage bool      `json:"direct_message"`
+	Timestamp     time.Time `json:"timestamp"`
+	Attachments   []struct {
+		Filename    string `json:"filename"`
+		URL         string `json:"url"`
+		ContentType string `json:"content_type"`
+		Size        int64  `json:"size"`
+	} `json:"attachments"`
+}
+
+func (p *Plugin) Name() string {
+	return p.name
+}
+
+// Start launches the plugin process and performs the initialize handshake.
+func (p *Plugin) Start(ctx context.Context) error {
+	cmd := exec.Command(p.command, p.args...)
+	cmd.Env = append(os.Environ(), p.env...)
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return fmt.Errorf("stdin pipe: %w", err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("stdout pipe: %w", err)
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return fmt.Errorf("stderr pipe: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("start plugin %s: %w", p.name, err)
+	}
+	p.cmd = cmd
+	p.stdin = stdin
+
+	go p.logStderr(stderr)
+	go p.readLoop(stdout)
+
+	initCtx, cancel := context.WithTimeout(ctx, pluginInitTimeout)
+	defer cancel()
+
+	var result struct {
+		Capabilities pluginCapabilities `json:"capabilities"`
+	}
+	params := map[string]any{"protocol_version": PluginProtocolVersion, "channels": p.channels}
+	if err := p.call(initCtx, "initialize", params, &result); err != nil {
+		_ = p.Stop()
+		return fmt.Errorf("initialize plugin %s: %w", p.name, err)
+	}
+
+	c := result.Capabilities
+	p.mu.Lock()
+	p.caps = Capabilities{
+		MaxMessageLength: c.MaxMessageLength,
+		Files:            c.Files,
+		Replies:          c.Replies,
+		Typing:           c.Typing,
+		Threads:          c.Threads,
+		Markdown:         MarkdownDialect(c.Markdown),
+	}
+	p.mu.Unlock()
+	return nil
+}
+
+// Stop asks the plugin to shut down, closes its stdin and kills it if it
+// has not exited within pluginShutdownTimeout.
+func (p *Plugin) Stop() error {
+	p.mu.Lock()
+	if p.stopped {
+		p.mu.Unlock()
+		return nil
+	}
+	p.stopped = true
+	close(p.messages)
+	p.mu.Unlock()
+
+	if p.cmd == nil {
+		return nil
+	}
+
+	_ = p.notify("shutdown", nil)
+	_ = p.stdin.Close()
+
+	select {
+	case <-p.done:
+	case <-time.After(pluginShutdownTimeout):
+		slog.Warn("plugin did not exit, killing", "plugin", p.name)
+		_ = p.cmd.Process.Kill()
+	}
+	// The exit status of a plugin we asked to stop is not interesting.
+	_ = p.cmd.Wait()
+	return nil
+}
+
+func (p *Plugin) Send(channelID string, content string) error {
+	return p.call(context.Background(), "send", map[string]string{
+		"channel_id": channelID,
+		"content":    content,
+	}, nil)
+}
+
+func (p *Plugin) SendFile(channelID string, filename string, content []byte) error {
+	return p.call(context.Background(), "send_file", map[string]string{
+		"channel_id": channelID,
+		"filename":   filename,
+		"content":    base64.StdEncoding.EncodeToString(content),
+	}, nil)
+}
+
+// Reply sends content with reply_to_id set. Only used when the plugin
+// declares the replies capability.
+func (p *Plugin) Reply(channelID, messageID, content string) error {
+	return p.call(context.Background(), "send", map[string]string{
+		"channel_id":  channelID,
+		"content":     content,
+		"reply_to_id": messageID,
+	}, nil)
+}
+
+func (p *Plugin) Typing(channelID string) error {
+	return p.notify("typing", map[string]string{"channel_id": channelID})
+}
+
+func (p *Plugin) Subscribe(channelID string) {
+	if err := p.notify("subscribe", map[string]string{"channel_id": channelID}); err != nil {
+		slog.Warn("plugin subscribe failed", "plugin", p.name, "channel", channelID, "error", err)
+	}
+}
+
+func (p *Plugin) Unsubscribe(channelID string) {
+	if err := p.notify("unsubscribe", map[string]string{"channel_id": channelID}); err != nil {
+		slog.Warn("plugin unsubscribe failed", "plugin", p.name, "channel", channelID, "error", err)
+	}
+}
+
+// Capabilities reports what the plugin declared in its initialize result.
+func (p *Plugin) Capabilities() Capabilities {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.caps
+}
+
+func (p *Plugin) Messages() <-chan Message {
+	return p.messages
+}
+
+// call sends a request and waits for its response. A non-nil result is
+// filled from the response's result. Plugin errors become DeliveryErrors
+// carrying the plugin's retry hints.
+func (p *Plugin) call(ctx context.Context, method string, params, result any) error {
+	p.mu.Lock()
+	p.nextID++
+	id := p.nextID
+	ch := make(chan rpcResponse, 1)
+	p.pending[id] = ch
+	p.mu.Unlock()
+
+	defer func() {
+		p.mu.Lock()
+		delete(p.pending, id)
+		p.mu.Unlock()
+	}()
+
+	if err := p.write(method, rpcRequest{JSONRPC: "2.0", ID: &id, Method: method, Params: params}); err != nil {
+		return err
+	}
+
+	timer := time.NewTimer(pluginCallTimeout)
+	defer timer.Stop()
+
+	select {
+	case resp := <-ch:
+		if resp.Error != nil {
+			return pluginDeliveryError(resp.Error)
+		}
+		if result != nil && len(resp.Result) > 0 {
+			if err := json.Unmarshal(resp.Result, result); err != nil {
+				return fmt.Errorf("decode %s result: %w", method, err)
+			}
+		}
+		return nil
+	case <-p.done:
+		return errPluginExited
+	case <-timer.C:
+		return fmt.Errorf("plugin %s: %s timed out", p.name, method)
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (p *Plugin) notify(method string, params any) error {
+	return p.write(method, rpcRequest{JSONRPC: "2.0", Method: method, Params: params})
+}
+
+// write sends one JSON-RPC message as a line on the plugin's stdin.
+func (p *Plugin) write(method string, v any) error {
+	if p.stdin == nil {
+		return fmt.Errorf("plugin %s not started", p.name)
+	}
+	select {
+	case <-p.done:
+		return errPluginExited
+	default:
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("encode %s: %w", method, err)
+	}
+	data = append(data, '\n')
+
+	p.writeMu.Lock()
+	defer p.writeMu.Unlock()
+	if _, err := p.stdin.Write(data); err != nil {
+		return fmt.Errorf("write to plugin %s: %w", p.name, err)
+	}
+	return nil
+}
+
+// readLoop dispatches responses and notifications until stdout closes.
+func (p *Plugin) readLoop(stdout io.Reader) {
+	defer close(p.done)
+
+	reader := bufio.NewReader(stdout)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 {
+			p.handleLine(line)
+		}
+		if err != nil {
+			if err != io.EOF {
+				slog.Warn("plugin read error", "plugin", p.name, "error", err)
+			}
+			slog.Info("plugin output ended", "plugin", p.name)
+			return
+		}
+	}
+}
+
+func (p *Plugin) handleLine(line []byte) {
+	var in rpcIncoming
+	if err := json.Unmarshal(line, &in); err != nil {
+		slog.Warn("plugin sent invalid JSON", "plugin", p.name, "error", err)
+		return
+	}
+
+	switch {
+	case in.Method == "" && in.ID != nil:
+		p.mu.Lock()
+		ch, ok := p.pending[*in.ID]
+		p.mu.Unlock()
+		if ok {
+			ch <- rpcResponse{Result: in.Result, Error: in.Error}
+		}
+	case in.Method == "message" && in.ID == nil:
+		p.handleMessage(in.Params)
+	case in.ID != nil:
+		// Requests from plugins are not part of the protocol.
+		_ = p.write(in.Method, rpcErrorReply{JSONRPC: "2.0", ID: in.ID, Error: &rpcError{Code: -32601, Message: "method not found"}})
+		slog.Warn("plugin sent unsupported request", "plugin", p.name, "method", in.Method)
+	default:
+		slog.Warn("plugin sent unknown notification", "plugin", p.name, "method", in.Method)
+	}
+}
+
+func (p *Plugin) handleMessage(params json.RawMessage) {
+	var pm pluginMessage
+	if err := json.Unmarshal(params, &pm); err != nil || pm.ChannelID == "" {
+		slog.Warn("plugin sent invalid message", "plugin", p.name, "error", err)
+		return
+	}
+
+	msg := Message{
+		ID:            pm.ID,
+		ChannelID:     pm.ChannelID,
+		Content:       pm.Content,
+		Author:        pm.Author,
+		AuthorID:      pm.AuthorID,
+		Roles:         pm.Roles,
+		Source:        p.name,
+		ReplyToID:     pm.ReplyToID,
+		ThreadID:      pm.ThreadID,
+		Mentions:      pm.Mentions,
+		MentionsBot:   pm.MentionsBot,
+		DirectMessage: pm.DirectMessage,
+		Timestamp:     pm.Timestamp,
+	}
+	for _, a := range pm.Attachments {
+		msg.Attachments = append(msg.Attachments, Attachment{
+			Filename:    a.Filename,
+			URL:         a.URL,
+			ContentType: a.ContentType,
+			Size:        a.Size,
+		})
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.stopped {
+		return
+	}
+	select {
+	case p.messages <- msg:
+	default:
+		// Channel full, drop message
+	}
+}
+
+func (p *Plugin) logStderr(stderr io.Reader) {
+	scanner := bufio.NewScanner(stderr)
+	for scanner.Scan() {
+		slog.Info("plugin stderr", "plugin", p.name, "line", scanner.Text())
+	}
+}
+
+// pluginDeliveryError converts a plugin error response into a DeliveryError.
+func pluginDeliveryError(e *rpcError) error {
+	de := &DeliveryError{Err: e}
+	if e.Data != nil {
+		de.RetryAfter = time.Duration(e.Data.RetryAfterMS) * time.Millisecond
+		de.Permanent = e.Data.Permanent
+	}
+	return de
+}
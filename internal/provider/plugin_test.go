@@ -0,0 +1,218 @@
+package provider
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anthropics/llm-bridge/internal/echoplugin"
+)
+
+// TestMain lets the test binary double as the reference echo plugin: when
+// LLM_BRIDGE_ECHO_PLUGIN is set it serves the plugin protocol instead of
+// running tests. LLM_BRIDGE_BARE_PLUGIN serves a plugin that declares no
+// capabilities at all.
+func TestMain(m *testing.M) {
+	if os.Getenv("LLM_BRIDGE_ECHO_PLUGIN") == "1" {
+		if err := echoplugin.Run(os.Stdin, os.Stdout, os.Stderr); err != nil {
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	if os.Getenv("LLM_BRIDGE_BARE_PLUGIN") == "1" {
+		runBarePlugin()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// runBarePlugin answers initialize with empty capabilities and every other
+// request with an empty result.
+func runBarePlugin() {
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		var req struct {
+			ID     *int64 `json:"id"`
+			Method string `json:"method"`
+		}
+		if json.Unmarshal(scanner.Bytes(), &req) != nil || req.ID == nil {
+			continue
+		}
+		result := `{}`
+		if req.Method == "initialize" {
+			result = `{"capabilities":{}}`
+		}
+		fmt.Printf("{\"jsonrpc\":\"2.0\",\"id\":%d,\"result\":%s}\n", *req.ID, result)
+	}
+}
+
+func startEchoPlugin(t *testing.T) *Plugin {
+	t.Helper()
+	p := NewPlugin("echo", os.Args[0], []string{"-test.run=^$"}, []string{"LLM_BRIDGE_ECHO_PLUGIN=1"}, []string{"chan-1"})
+	if err := p.Start(context.Background()); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	t.Cleanup(func() { _ = p.Stop() })
+	return p
+}
+
+func receive(t *testing.T, p *Plugin) Message {
+	t.Helper()
+	select {
+	case msg, ok := <-p.Messages():
+		if !ok {
+			t.Fatal("messages channel closed")
+		}
+		return msg
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for plugin message")
+	}
+	return Message{}
+}
+
+func TestPlugin_Initialize(t *testing.T) {
+	p := startEchoPlugin(t)
+
+	if p.Name() != "echo" {
+		t.Errorf("Name() = %q, want echo", p.Name())
+	}
+	caps := CapabilitiesOf(p)
+	if caps.MaxMessageLength != 2000 || !caps.Files || !caps.Replies || !caps.Typing {
+		t.Errorf("capabilities = %+v", caps)
+	}
+}
+
+func TestPlugin_TypingNotDeclared(t *testing.T) {
+	p := NewPlugin("bare", os.Args[0], []string{"-test.run=^$"}, []string{"LLM_BRIDGE_BARE_PLUGIN=1"}, []string{"chan-1"})
+	if err := p.Start(context.Background()); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	t.Cleanup(func() { _ = p.Stop() })
+
+	// The bridge only sends typing notifications to providers whose
+	// capabilities include typing.
+	if caps := CapabilitiesOf(p); caps.Typing || caps.Files || caps.Replies {
+		t.Errorf("capabilities of a plugin declaring none = %+v", caps)
+	}
+}
+
+func TestPlugin_SendRoundTrip(t *testing.T) {
+	p := startEchoPlugin(t)
+
+	if err := p.Send("chan-1", "hello"); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	msg := receive(t, p)
+	if msg.ChannelID != "chan-1" || msg.Content != "hello" || msg.AuthorID != "echo" {
+		t.Errorf("echoed message = %+v", msg)
+	}
+	if msg.Source != "echo" {
+		t.Errorf("Source = %q, want plugin name", msg.Source)
+	}
+	if msg.ID == "" || msg.Timestamp.IsZero() {
+		t.Errorf("expected ID and Timestamp, got %+v", msg)
+	}
+
+	if err := p.Reply("chan-1", "msg-9", "answer"); err != nil {
+		t.Fatalf("Reply() error = %v", err)
+	}
+	if msg := receive(t, p); msg.ReplyToID != "msg-9" {
+		t.Errorf("ReplyToID = %q, want msg-9", msg.ReplyToID)
+	}
+}
+
+func TestPlugin_SendFile(t *testing.T) {
+	p := startEchoPlugin(t)
+
+	if err := p.SendFile("chan-1", "out.md", []byte("12345")); err != nil {
+		t.Fatalf("SendFile() error = %v", err)
+	}
+	if msg := receive(t, p); msg.Content != "received file out.md (5 bytes)" {
+		t.Errorf("echoed content = %q", msg.Content)
+	}
+}
+
+func TestPlugin_Errors(t *testing.T) {
+	p := startEchoPlugin(t)
+
+	err := p.Send("rate-limited", "x")
+	if err == nil || RetryAfter(err) != time.Second {
+		t.Errorf("rate-limited Send() error = %v, RetryAfter = %v", err, RetryAfter(err))
+	}
+
+	err = p.Send("forbidden", "x")
+	if err == nil || !IsPermanent(err) {
+		t.Errorf("forbidden Send() error = %v, want permanent", err)
+	}
+}
+
+func TestPlugin_Notifications(t *testing.T) {
+	p := startEchoPlugin(t)
+
+	if err := p.Typing("chan-1"); err != nil {
+		t.Errorf("Typing() error = %v", err)
+	}
+	p.Subscribe("chan-2")
+	p.Unsubscribe("chan-2")
+
+	// The plugin still answers requests after notifications.
+	if err := p.Send("chan-1", "still here"); err != nil {
+		t.Errorf("Send() after notifications error = %v", err)
+	}
+}
+
+func TestPlugin_StopClosesMessages(t *testing.T) {
+	p := startEchoPlugin(t)
+
+	if err := p.Stop(); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+	if _, ok := <-p.Messages(); ok {
+		t.Error("messages channel should be closed after Stop")
+	}
+	if err := p.Stop(); err != nil {
+		t.Errorf("second Stop() error = %v", err)
+	}
+	if err := p.Send("chan-1", "late"); err == nil {
+		t.Error("Send() after Stop should fail")
+	}
+}
+
+func TestPlugin_StartFailures(t *testing.T) {
+	p := NewPlugin("missing", "/nonexistent/llm-bridge-plugin", nil, nil, nil)
+	if err := p.Start(context.Background()); err == nil {
+		t.Error("Start() with a missing executable should fail")
+	}
+
+	// A process that exits without answering initialize.
+	p = NewPlugin("quits", os.Args[0], []string{"-test.run=^$"}, nil, nil)
+	err := p.Start(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "initialize") {
+		t.Errorf("Start() error = %v, want initialize failure", err)
+	}
+}
+
+func TestPlugin_NotStarted(t *testing.T) {
+	p := NewPlugin("echo", "unused", nil, nil, nil)
+	if err := p.Send("c", "x"); err == nil {
+		t.Error("Send() before Start should fail")
+	}
+	if err := p.Stop(); err != nil {
+		t.Errorf("Stop() before Start error = %v", err)
+	}
+}
+
+func TestPlugin_MessageRoles(t *testing.T) {
+	p := NewPlugin("chat", "unused", nil, nil, nil)
+	p.handleMessage([]byte(`{"channel_id":"chan-1","content":"hi","author_id":"U1","roles":["admins","devs"]}`))
+
+	msg := receive(t, p)
+	if len(msg.Roles) != 2 || msg.Roles[0] != "admins" || msg.Roles[1] != "devs" {
+		t.Errorf("Roles = %v, want [admins devs]", msg.Roles)
+	}
+}
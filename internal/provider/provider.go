@@ -2,15 +2,43 @@ package provider
 
 import (
 	"context"
+	"errors"
+	"time"
 )
 
 // Message represents a chat message
 type Message struct {
-	ChannelID string
-	Content   string
-	Author    string // display name (for logging/UI)
-	AuthorID  string // stable unique identifier (for rate limiting)
-	Source    string // provider name
+	ID          string // provider message ID (may be empty)
+	ChannelID   string
+	Content     string
+	Author      string       // display name (for logging/UI)
+	AuthorID    string       // stable unique identifier (for rate limiting)
+	Roles       []string     // author's role IDs on the chat service, for authorization (may be empty)
+	Source      string       // provider name
+	Attachments []Attachment // files uploaded with the message (may be empty)
+	ReplyToID   string       // ID of the message this one replies to, if any
+	ThreadID    string       // thread the message was posted in; ChannelID is then the thread's parent
+	Mentions    []string     // IDs of users mentioned in the message
+	MentionsBot bool         // true if the bot itself was mentioned
+	Timestamp   time.Time    // when the message was sent (zero if unknown)
+
+	// Repository is the full name ("owner/name") of the code repository the
+	// message was posted in, for providers whose channels are not configured
+	// per repo (e.g. GitHub comments). The bridge routes by git remote.
+	Repository string
+
+	// DirectMessage is true for private messages to the bot. DMs are not
+	// bound to a repo channel; the bridge routes them per author.
+	DirectMessage bool
+}
+
+// Attachment describes a file uploaded alongside a chat message.
+// The provider only reports metadata; the bridge decides whether to download it.
+type Attachment struct {
+	Filename    string
+	URL         string // download URL
+	ContentType string // MIME type reported by the chat service (may be empty)
+	Size        int64  // size in bytes reported by the chat service
 }
 
 // Provider defines the interface for chat providers
@@ -33,3 +61,144 @@ type Provider interface {
 	// Messages returns a channel of incoming messages
 	Messages() <-chan Message
 }
+
+// DeliveryError annotates a Send or SendFile failure with hints for retrying it.
+type DeliveryError struct {
+	Err        error
+	RetryAfter time.Duration // wait requested by the chat service (0 if none)
+	Permanent  bool          // retrying will not help, e.g. missing permissions
+}
+
+func (e *DeliveryError) Error() string { return e.Err.Error() }
+func (e *DeliveryError) Unwrap() error { return e.Err }
+
+// RetryAfter returns the wait requested by a DeliveryError in err's chain, or 0.
+func RetryAfter(err error) time.Duration {
+	var de *DeliveryError
+	if errors.As(err, &de) {
+		return de.RetryAfter
+	}
+	return 0
+}
+
+// IsPermanent reports whether err is a DeliveryError that should not be retried.
+func IsPermanent(err error) bool {
+	var de *DeliveryError
+	return errors.As(err, &de) && de.Permanent
+}
+
+// TrySender is implemented by providers whose Send and SendFile wait out
+// rate limits inside the request. The bridge's delivery queue sends through
+// these variants instead, which return a DeliveryError with RetryAfter so
+// the queue can retry in order without blocking output.
+type TrySender interface {
+	// TrySend is Send, failing instead of waiting when rate limited
+	TrySend(channelID string, content string) error
+
+	// TrySendFile is SendFile, failing instead of waiting when rate limited
+	TrySendFile(channelID string, filename string, content []byte) error
+}
+
+// Reaction is an emoji reaction a user added to a message the provider sent.
+type Reaction struct {
+	ChannelID string
+	MessageID string   // ID of the bot message that was reacted to
+	Emoji     string   // unicode emoji (or custom emoji name)
+	User      string   // display name
+	UserID    string   // stable unique identifier
+	Roles     []string // user's role IDs on the chat service, for authorization (may be empty)
+	Source    string   // provider name
+}
+
+// Reactor is implemented by providers that report reactions on their own messages.
+type Reactor interface {
+	// Reactions returns a channel of reactions added to messages this provider sent
+	Reactions() <-chan Reaction
+}
+
+// Replier is implemented by providers that can answer a specific message.
+type Replier interface {
+	// Reply sends content to channelID as a reply to messageID
+	Reply(channelID, messageID, content string) error
+}
+
+// Mentioner is implemented by providers that can mention (notify) a user
+// in message text.
+type Mentioner interface {
+	// Mention returns the markup that mentions the user with the given AuthorID
+	Mention(userID string) string
+}
+
+// AccessChecker is implemented by providers that can tell whether a user
+// may read a channel, so DMs can be limited to repos the user can see.
+type AccessChecker interface {
+	// CanView reports whether the user with the given AuthorID can read channelID
+	CanView(userID, channelID string) (bool, error)
+}
+
+// Typer is implemented by providers that can show a "typing" indicator.
+type Typer interface {
+	// Typing shows the indicator in a channel. Indicators expire on their
+	// own, so callers refresh them while work is in progress.
+	Typing(channelID string) error
+}
+
+// Subscriber is implemented by providers that only deliver messages from an
+// allowlist of channels. The allowlist can change while the provider runs.
+type Subscriber interface {
+	// Subscribe starts delivering messages from a channel
+	Subscribe(channelID string)
+
+	// Unsubscribe stops delivering messages from a channel
+	Unsubscribe(channelID string)
+}
+
+// MarkdownDialect names the formatting syntax a provider renders.
+type MarkdownDialect string
+
+const (
+	MarkdownNone    MarkdownDialect = ""        // plain text, no formatting
+	MarkdownDiscord MarkdownDialect = "discord" // Discord-flavored markdown
+	MarkdownGitHub  MarkdownDialect = "github"  // GitHub-flavored markdown
+)
+
+// Capabilities describes what a provider supports, so the bridge can adapt
+// its output instead of assuming Discord everywhere. A feature is only
+// reported when the provider implements it, not merely when the underlying
+// chat service has it.
+type Capabilities struct {
+	MaxMessageLength int             // longest single message in bytes; 0 means unlimited
+	Files            bool            // can upload file attachments
+	Edits            bool            // can edit messages it already sent
+	Threads          bool            // can create or reply in threads
+	Buttons          bool            // can render interactive buttons
+	Reactions        bool            // reports reactions on its messages
+	Typing           bool            // can show a typing indicator
+	Replies          bool            // can reply to a specific message
+	Markdown         MarkdownDialect // formatting syntax rendered by the service
+}
+
+// CapabilityProvider is implemented by providers that describe their capabilities.
+type CapabilityProvider interface {
+	// Capabilities returns what the provider's chat service supports
+	Capabilities() Capabilities
+}
+
+// DefaultCapabilities is assumed for providers that do not implement
+// CapabilityProvider. It matches the behavior the bridge had before
+// capabilities existed: Discord-sized messages with file uploads.
+func DefaultCapabilities() Capabilities {
+	return Capabilities{
+		MaxMessageLength: 2000,
+		Files:            true,
+		Markdown:         MarkdownDiscord,
+	}
+}
+
+// CapabilitiesOf returns p's capabilities, falling back to DefaultCapabilities.
+func CapabilitiesOf(p Provider) Capabilities {
+	if cp, ok := p.(CapabilityProvider); ok {
+		return cp.Capabilities()
+	}
+	return DefaultCapabilities()
+}
@@ -6,7 +6,9 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"strconv"
 	"sync"
+	"time"
 )
 
 // Terminal provides local stdin/stdout as a provider
@@ -40,6 +42,7 @@ func (t *Terminal) Start(ctx context.Context) error {
 
 func (t *Terminal) readLoop(ctx context.Context) {
 	scanner := bufio.NewScanner(t.reader)
+	var seq int
 	for scanner.Scan() {
 		select {
 		case <-ctx.Done():
@@ -47,7 +50,10 @@ func (t *Terminal) readLoop(ctx context.Context) {
 		default:
 		}
 
+		seq++
 		msg := Message{
+			ID:        strconv.Itoa(seq),
+			Timestamp: time.Now(),
 			ChannelID: t.channelID,
 			Content:   scanner.Text(),
 			Author:    "terminal",
@@ -91,6 +97,12 @@ func (t *Terminal) SendFile(channelID string, filename string, content []byte) e
 	return err
 }
 
+// Capabilities reports an unlimited plain-text stream. Files are printed
+// inline rather than uploaded, so the bridge should not prefer them.
+func (t *Terminal) Capabilities() Capabilities {
+	return Capabilities{}
+}
+
 func (t *Terminal) Messages() <-chan Message {
 	return t.messages
 }
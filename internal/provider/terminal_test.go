@@ -102,9 +102,24 @@ func TestTerminal_ReadLoop(t *testing.T) {
 		if msg.Source != "terminal" {
 			t.Errorf("source = %q, want %q", msg.Source, "terminal")
 		}
+		if msg.ID != "1" {
+			t.Errorf("ID = %q, want %q", msg.ID, "1")
+		}
+		if msg.Timestamp.IsZero() {
+			t.Error("Timestamp should be set")
+		}
 	default:
 		t.Error("expected message on channel")
 	}
+
+	select {
+	case msg := <-term.Messages():
+		if msg.ID != "2" {
+			t.Errorf("second message ID = %q, want %q", msg.ID, "2")
+		}
+	default:
+		t.Error("expected second message on channel")
+	}
 }
 
 func TestTerminal_Stop(t *testing.T) {
@@ -323,3 +338,10 @@ done:
 		}
 	}
 }
+
+func TestTerminal_Capabilities(t *testing.T) {
+	caps := CapabilitiesOf(NewTerminal("term"))
+	if caps.MaxMessageLength != 0 || caps.Files || caps.Markdown != MarkdownNone {
+		t.Errorf("terminal capabilities = %+v, want unlimited plain text without files", caps)
+	}
+}
@@ -9,6 +9,7 @@ type RouteType int
 const (
 	RouteToLLM RouteType = iota
 	RouteToBridge
+	RouteToMacro // a user-defined command that expands into a prompt
 )
 
 const LLMCommandPrefix = "::"
@@ -31,9 +32,23 @@ var BridgeCommands = map[string]bool{
 	"remove-repo":  true,
 	"clone":        true,
 	"add-worktree": true,
+	"last":         true,
+	"approve":      true,
+	"deny":         true,
+	"queue":        true,
+	"history":      true,
+	"export":       true,
+	"schedules":    true,
+	"keepalive":    true,
 }
 
 func Parse(content string) Route {
+	return ParseWithMacros(content, nil)
+}
+
+// ParseWithMacros is Parse with user-defined commands: a /command named in
+// macros routes to RouteToMacro. Bridge commands take precedence.
+func ParseWithMacros(content string, macros map[string]bool) Route {
 	content = strings.TrimSpace(content)
 
 	if strings.HasPrefix(content, "/") {
@@ -46,6 +61,14 @@ func Parse(content string) Route {
 				Raw:     content,
 			}
 		}
+		if macros[cmd] {
+			return Route{
+				Type:    RouteToMacro,
+				Command: cmd,
+				Args:    args,
+				Raw:     content,
+			}
+		}
 		return Route{
 			Type: RouteToLLM,
 			Raw:  content,
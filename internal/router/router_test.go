@@ -17,6 +17,12 @@ func TestParse_BridgeCommands(t *testing.T) {
 		{"restart", "/restart", "restart", RouteToBridge},
 		{"help", "/help", "help", RouteToBridge},
 		{"select", "/select", "select", RouteToBridge},
+		{"last", "/last", "last", RouteToBridge},
+		{"approve", "/approve", "approve", RouteToBridge},
+		{"deny", "/deny", "deny", RouteToBridge},
+		{"queue", "/queue drop 2", "queue", RouteToBridge},
+		{"history", "/history 10", "history", RouteToBridge},
+		{"export", "/export html", "export", RouteToBridge},
 		{"status with args", "/status repo1", "status", RouteToBridge},
 		{"uppercase normalized", "/STATUS", "status", RouteToBridge},
 	}
@@ -192,3 +198,26 @@ func TestParse_EdgeCases(t *testing.T) {
 		})
 	}
 }
+
+func TestParseWithMacros(t *testing.T) {
+	macros := map[string]bool{"review": true, "status": true}
+	tests := []struct {
+		input   string
+		wantTyp RouteType
+		wantCmd string
+		wantArg string
+	}{
+		{"/review feature-x", RouteToMacro, "review", "feature-x"},
+		{"/Review", RouteToMacro, "review", ""},
+		{"/status", RouteToBridge, "status", ""}, // bridge commands win
+		{"::review", RouteToLLM, "", ""},
+		{"/commit", RouteToLLM, "", ""},
+		{"review this", RouteToLLM, "", ""},
+	}
+	for _, tt := range tests {
+		route := ParseWithMacros(tt.input, macros)
+		if route.Type != tt.wantTyp || route.Command != tt.wantCmd || route.Args != tt.wantArg {
+			t.Errorf("ParseWithMacros(%q) = %+v, want type %v command %q args %q", tt.input, route, tt.wantTyp, tt.wantCmd, tt.wantArg)
+		}
+	}
+}
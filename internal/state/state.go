@@ -0,0 +1,134 @@
+// Package state persists what the bridge was doing (active sessions, their
+// channels and queued prompts, and users' repo selections) so that a
+// restarted bridge can pick up where it left off.
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Version is the current state file format.
+const Version = 1
+
+// State is the content of the state file.
+type State struct {
+	Version      int               `json:"version"`
+	SavedAt      time.Time         `json:"saved_at"`
+	TerminalRepo string            `json:"terminal_repo,omitempty"`
+	DMRepos      map[string]string `json:"dm_repos,omitempty"`      // author ID -> repo selected in DMs
+	DMChannels   map[string]string `json:"dm_channels,omitempty"`   // DM channel ID -> author ID
+	RepoChannels map[string]string `json:"repo_channels,omitempty"` // repository-routed channel ID -> repo
+	LLMSessions  map[string]string `json:"llm_sessions,omitempty"`  // session key -> LLM conversation ID
+	Sessions     []Session         `json:"sessions,omitempty"`      // sessions running when saved
+	Schedules    map[string]bool   `json:"schedules,omitempty"`     // schedule name -> paused, as set by /schedules
+}
+
+// Session is a session that was running when the state was saved.
+type Session struct {
+	Repo     string    `json:"repo"`
+	UserID   string    `json:"user_id,omitempty"` // owner of a per_user session
+	UserName string    `json:"user_name,omitempty"`
+	ID       string    `json:"id"` // transcript session ID
+	Channels []Channel `json:"channels"`
+	Queue    []Prompt  `json:"queue,omitempty"`
+}
+
+// Channel is a provider channel attached to a session.
+type Channel struct {
+	Provider  string `json:"provider"`
+	ChannelID string `json:"channel_id"`
+}
+
+// Prompt is a prompt that was waiting for the LLM.
+type Prompt struct {
+	Author      string       `json:"author"`
+	AuthorID    string       `json:"author_id,omitempty"`
+	Text        string       `json:"text"`    // as typed
+	Content     string       `json:"content"` // as it will be sent to the LLM
+	Source      string       `json:"source"`
+	Attachments []Attachment `json:"attachments,omitempty"`
+	Provider    string       `json:"provider"`
+	ChannelID   string       `json:"channel_id"`
+	ReplyTo     *ReplyTo     `json:"reply_to,omitempty"`
+}
+
+// Attachment is a file saved for a queued prompt.
+type Attachment struct {
+	Path        string `json:"path"`
+	ContentType string `json:"content_type"`
+}
+
+// ReplyTo is the message a queued prompt's output replies to in reply mode.
+type ReplyTo struct {
+	Provider  string `json:"provider"`
+	ChannelID string `json:"channel_id"`
+	ThreadID  string `json:"thread_id,omitempty"`
+	MessageID string `json:"message_id"`
+}
+
+// Load reads the state file at path. A missing file yields an empty State.
+func Load(path string) (*State, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &State{Version: Version}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read state: %w", err)
+	}
+
+	var st State
+	if err := json.Unmarshal(data, &st); err != nil {
+		return nil, fmt.Errorf("parse state: %w", err)
+	}
+	if st.Version > Version {
+		return nil, fmt.Errorf("state version %d is newer than supported version %d", st.Version, Version)
+	}
+	return &st, nil
+}
+
+// Save writes st to path atomically: readers see either the old file or the
+// complete new one, even if the bridge dies mid-write.
+func Save(path string, st *State) error {
+	st.Version = Version
+	data, err := json.MarshalIndent(st, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode state: %w", err)
+	}
+
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("create state dir: %w", err)
+	}
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("create temp state: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // no-op once renamed
+
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write state: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("sync state: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("close state: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("replace state: %w", err)
+	}
+
+	// Persist the rename itself; not all platforms support syncing a directory.
+	if d, err := os.Open(dir); err == nil {
+		_ = d.Sync()
+		d.Close()
+	}
+	return nil
+}
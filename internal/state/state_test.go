@@ -0,0 +1,100 @@
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoad_Missing(t *testing.T) {
+	st, err := Load(filepath.Join(t.TempDir(), "state.json"))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if st.Version != Version || len(st.Sessions) != 0 {
+		t.Errorf("missing file should load as empty state, got %+v", st)
+	}
+}
+
+func TestSaveLoad_RoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "state.json")
+	want := &State{
+		SavedAt:      time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC),
+		TerminalRepo: "app",
+		DMRepos:      map[string]string{"u1": "app"},
+		DMChannels:   map[string]string{"dm-1": "u1"},
+		RepoChannels: map[string]string{"octo/app#7": "app"},
+		LLMSessions:  map[string]string{"app": "0b9a3c4e-1111-4222-8333-444455556666"},
+		Sessions: []Session{{
+			Repo:     "app",
+			UserID:   "u1",
+			UserName: "alice",
+			ID:       "20261018-120000-abcd",
+			Channels: []Channel{{Provider: "discord", ChannelID: "c1"}},
+			Queue: []Prompt{{
+				Author: "bob", AuthorID: "u2", Text: "next", Content: "next", Source: "discord",
+				Attachments: []Attachment{{Path: "/repo/.llm-bridge/inbox/a.png", ContentType: "image/png"}},
+				Provider:    "discord", ChannelID: "c1",
+				ReplyTo: &ReplyTo{Provider: "discord", ChannelID: "c1", MessageID: "m1"},
+			}},
+		}},
+	}
+
+	if err := Save(path, want); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	got, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("round trip mismatch:\ngot  %+v\nwant %+v", got, want)
+	}
+	if got.Version != Version {
+		t.Errorf("Version = %d, want %d", got.Version, Version)
+	}
+}
+
+func TestSave_ReplacesAtomically(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "state.json")
+
+	if err := Save(path, &State{TerminalRepo: "one"}); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if err := Save(path, &State{TerminalRepo: "two"}); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	st, err := Load(path)
+	if err != nil || st.TerminalRepo != "two" {
+		t.Errorf("Load() = %+v, %v", st, err)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		var names []string
+		for _, e := range entries {
+			names = append(names, e.Name())
+		}
+		t.Errorf("temp files left behind: %v", names)
+	}
+}
+
+func TestLoad_Errors(t *testing.T) {
+	dir := t.TempDir()
+
+	bad := filepath.Join(dir, "bad.json")
+	_ = os.WriteFile(bad, []byte("{"), 0o600)
+	if _, err := Load(bad); err == nil || !strings.Contains(err.Error(), "parse state") {
+		t.Errorf("corrupt file error = %v", err)
+	}
+
+	future := filepath.Join(dir, "future.json")
+	_ = os.WriteFile(future, []byte(`{"version": 99}`), 0o600)
+	if _, err := Load(future); err == nil || !strings.Contains(err.Error(), "newer") {
+		t.Errorf("future version error = %v", err)
+	}
+}
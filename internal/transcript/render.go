@@ -0,0 +1,101 @@
+package transcript
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"strings"
+	"time"
+)
+
+// timeFormat is how entry times are shown in rendered transcripts.
+const timeFormat = "2006-01-02 15:04:05 MST"
+
+// Markdown renders entries as a Markdown document titled title. Prompts
+// become headed sections and output is fenced as it was printed.
+func Markdown(title string, entries []Entry) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# %s\n", title)
+	for _, turn := range Turns(entries) {
+		sb.WriteString("\n")
+		if turn.Prompt.Kind != "" {
+			fmt.Fprintf(&sb, "## %s · %s\n\n", promptHeading(turn.Prompt), turn.Prompt.Time.Format(timeFormat))
+			sb.WriteString(turn.Prompt.Content)
+			sb.WriteString("\n")
+			if len(turn.Prompt.Attachments) > 0 {
+				fmt.Fprintf(&sb, "\nAttachments: %s\n", strings.Join(turn.Prompt.Attachments, ", "))
+			}
+		}
+		if turn.Output != "" {
+			fence := codeFence(turn.Output)
+			fmt.Fprintf(&sb, "\n%s\n%s\n%s\n", fence, strings.TrimRight(turn.Output, "\n"), fence)
+		}
+	}
+	return sb.String()
+}
+
+// codeFence returns a backtick fence longer than any run of backticks in s.
+func codeFence(s string) string {
+	longest, run := 0, 0
+	for _, r := range s {
+		if r == '`' {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+	return strings.Repeat("`", max(3, longest+1))
+}
+
+var htmlTemplate = template.Must(template.New("transcript").Funcs(template.FuncMap{
+	"heading": promptHeading,
+	"time":    func(t time.Time) string { return t.Format(timeFormat) },
+	"join":    strings.Join,
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; max-width: 60em; margin: 2em auto; }
+pre { background: #f6f8fa; padding: 1em; overflow-x: auto; white-space: pre-wrap; }
+.prompt { white-space: pre-wrap; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+{{range .Turns}}<section>
+{{if .Prompt.Kind}}<h2>{{heading .Prompt}} · {{time .Prompt.Time}}</h2>
+<div class="prompt">{{.Prompt.Content}}</div>
+{{if .Prompt.Attachments}}<p>Attachments: {{join .Prompt.Attachments ", "}}</p>
+{{end}}{{end}}{{if .Output}}<pre>{{.Output}}</pre>
+{{end}}</section>
+{{end}}</body>
+</html>
+`))
+
+// HTML renders entries as a standalone HTML page titled title.
+func HTML(title string, entries []Entry) (string, error) {
+	var buf bytes.Buffer
+	err := htmlTemplate.Execute(&buf, struct {
+		Title string
+		Turns []Turn
+	}{title, Turns(entries)})
+	if err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// promptHeading names who sent a prompt and from where.
+func promptHeading(e Entry) string {
+	author := e.Author
+	if author == "" {
+		author = "unknown"
+	}
+	if e.Source == "" {
+		return author
+	}
+	return fmt.Sprintf("%s (%s)", author, e.Source)
+}
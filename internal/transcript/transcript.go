@@ -0,0 +1,217 @@
+// Package transcript records what goes in and out of LLM sessions as JSONL
+// files, one per day, and removes files older than a retention period.
+package transcript
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Entry kinds.
+const (
+	KindPrompt = "prompt" // input sent to the LLM
+	KindOutput = "output" // a chunk of LLM output as broadcast
+)
+
+// dayFormat names transcript files: <dir>/2006-01-02.jsonl.
+const dayFormat = "2006-01-02"
+
+// maxLineBytes bounds a single JSONL line when reading back.
+const maxLineBytes = 16 << 20
+
+// Entry is one line of a transcript.
+type Entry struct {
+	Time        time.Time `json:"time"`
+	Session     string    `json:"session"`
+	Repo        string    `json:"repo"`
+	User        string    `json:"user,omitempty"` // owner of a per_user session
+	Kind        string    `json:"kind"`
+	Author      string    `json:"author,omitempty"`
+	AuthorID    string    `json:"author_id,omitempty"`
+	Source      string    `json:"source,omitempty"`
+	Content     string    `json:"content"`
+	Attachments []string  `json:"attachments,omitempty"` // file names sent with a prompt
+}
+
+// Log appends entries to daily files in a directory. It is safe for
+// concurrent use.
+type Log struct {
+	dir       string
+	retention time.Duration // files older than this are removed; 0 keeps all
+	now       func() time.Time
+
+	mu     sync.Mutex
+	pruned string // day of the last prune
+}
+
+// Open returns a Log writing to dir, which is created on first append.
+func Open(dir string, retention time.Duration) *Log {
+	return &Log{dir: dir, retention: retention, now: time.Now}
+}
+
+// Dir returns the directory the log writes to.
+func (l *Log) Dir() string {
+	return l.dir
+}
+
+// Append writes e to the current day's file, setting Time if unset. The
+// first append of each day also removes expired files.
+func (l *Log) Append(e Entry) error {
+	if e.Time.IsZero() {
+		e.Time = l.now().UTC()
+	}
+	line, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("encode entry: %w", err)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	day := e.Time.UTC().Format(dayFormat)
+	if day != l.pruned {
+		l.pruned = day
+		if err := l.pruneLocked(); err != nil {
+			slog.Warn("prune transcripts failed", "dir", l.dir, "error", err)
+		}
+	}
+
+	if err := os.MkdirAll(l.dir, 0o700); err != nil {
+		return fmt.Errorf("create transcript dir: %w", err)
+	}
+	f, err := os.OpenFile(filepath.Join(l.dir, day+".jsonl"), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("open transcript: %w", err)
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return fmt.Errorf("write transcript: %w", err)
+	}
+	return f.Close()
+}
+
+// Read returns the entries for which match returns true, oldest first.
+// Lines that do not parse are skipped.
+func (l *Log) Read(match func(Entry) bool) ([]Entry, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	files, err := l.filesLocked()
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []Entry
+	for _, name := range files {
+		f, err := os.Open(filepath.Join(l.dir, name))
+		if err != nil {
+			return nil, fmt.Errorf("open transcript: %w", err)
+		}
+		scanner := bufio.NewScanner(f)
+		scanner.Buffer(make([]byte, 0, 64*1024), maxLineBytes)
+		for scanner.Scan() {
+			var e Entry
+			if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+				continue
+			}
+			if match == nil || match(e) {
+				entries = append(entries, e)
+			}
+		}
+		err = scanner.Err()
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("read transcript %s: %w", name, err)
+		}
+	}
+	return entries, nil
+}
+
+// filesLocked returns the transcript file names in date order.
+func (l *Log) filesLocked() ([]string, error) {
+	dirEntries, err := os.ReadDir(l.dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("list transcripts: %w", err)
+	}
+	var files []string
+	for _, de := range dirEntries {
+		if de.IsDir() || !strings.HasSuffix(de.Name(), ".jsonl") {
+			continue
+		}
+		if _, err := time.Parse(dayFormat, strings.TrimSuffix(de.Name(), ".jsonl")); err != nil {
+			continue
+		}
+		files = append(files, de.Name())
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// pruneLocked removes files for days entirely older than the retention period.
+func (l *Log) pruneLocked() error {
+	if l.retention <= 0 {
+		return nil
+	}
+	files, err := l.filesLocked()
+	if err != nil {
+		return err
+	}
+	cutoff := l.now().UTC().Add(-l.retention)
+	for _, name := range files {
+		day, _ := time.Parse(dayFormat, strings.TrimSuffix(name, ".jsonl"))
+		if day.Add(24 * time.Hour).After(cutoff) {
+			break
+		}
+		if err := os.Remove(filepath.Join(l.dir, name)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// Turn is a prompt and the output that followed it. Output produced before
+// any prompt (e.g. a startup banner) forms a turn with a zero Prompt.
+type Turn struct {
+	Prompt Entry
+	Output string
+}
+
+// Turns groups entries into turns.
+func Turns(entries []Entry) []Turn {
+	var turns []Turn
+	for _, e := range entries {
+		switch e.Kind {
+		case KindPrompt:
+			turns = append(turns, Turn{Prompt: e})
+		case KindOutput:
+			if len(turns) == 0 {
+				turns = append(turns, Turn{})
+			}
+			turns[len(turns)-1].Output += e.Content
+		}
+	}
+	return turns
+}
+
+// WriteJSONL writes entries one JSON object per line.
+func WriteJSONL(w io.Writer, entries []Entry) error {
+	enc := json.NewEncoder(w)
+	for _, e := range entries {
+		if err := enc.Encode(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,172 @@
+package transcript
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLog_AppendAndRead(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "transcripts")
+	l := Open(dir, 0)
+
+	day1 := time.Date(2026, 10, 17, 23, 0, 0, 0, time.UTC)
+	day2 := day1.Add(2 * time.Hour)
+	entries := []Entry{
+		{Time: day1, Session: "s1", Repo: "r", Kind: KindPrompt, Author: "alice", Content: "hi"},
+		{Time: day1, Session: "s1", Repo: "r", Kind: KindOutput, Content: "hello\n"},
+		{Time: day2, Session: "s2", Repo: "r", Kind: KindPrompt, Author: "bob", Content: "next"},
+	}
+	for _, e := range entries {
+		if err := l.Append(e); err != nil {
+			t.Fatalf("Append: %v", err)
+		}
+	}
+
+	for _, name := range []string{"2026-10-17.jsonl", "2026-10-18.jsonl"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("expected daily file %s: %v", name, err)
+		}
+	}
+
+	all, err := l.Read(nil)
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	if len(all) != 3 || all[0].Content != "hi" || all[2].Content != "next" {
+		t.Errorf("Read(nil) = %+v", all)
+	}
+
+	s1, err := l.Read(func(e Entry) bool { return e.Session == "s1" })
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	if len(s1) != 2 {
+		t.Errorf("filtered read got %d entries, want 2", len(s1))
+	}
+}
+
+func TestLog_ReadMissingDir(t *testing.T) {
+	l := Open(filepath.Join(t.TempDir(), "none"), 0)
+	entries, err := l.Read(nil)
+	if err != nil || len(entries) != 0 {
+		t.Errorf("Read on missing dir = %v, %v", entries, err)
+	}
+}
+
+func TestLog_SkipsBadLinesAndForeignFiles(t *testing.T) {
+	dir := t.TempDir()
+	_ = os.WriteFile(filepath.Join(dir, "2026-10-18.jsonl"), []byte("not json\n{\"kind\":\"prompt\",\"content\":\"ok\"}\n"), 0o644)
+	_ = os.WriteFile(filepath.Join(dir, "notes.jsonl"), []byte("{\"kind\":\"prompt\",\"content\":\"foreign\"}\n"), 0o644)
+
+	entries, err := Open(dir, 0).Read(nil)
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Content != "ok" {
+		t.Errorf("entries = %+v", entries)
+	}
+}
+
+func TestLog_Prune(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"2026-09-01.jsonl", "2026-10-10.jsonl", "2026-10-17.jsonl", "keep.txt"} {
+		_ = os.WriteFile(filepath.Join(dir, name), []byte("{}\n"), 0o644)
+	}
+
+	l := Open(dir, 7*24*time.Hour)
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	l.now = func() time.Time { return now }
+	if err := l.Append(Entry{Kind: KindPrompt, Content: "x"}); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+
+	for name, want := range map[string]bool{
+		"2026-09-01.jsonl": false,
+		"2026-10-10.jsonl": false,
+		"2026-10-17.jsonl": true,
+		"2026-10-18.jsonl": true,
+		"keep.txt":         true,
+	} {
+		_, err := os.Stat(filepath.Join(dir, name))
+		if got := err == nil; got != want {
+			t.Errorf("%s exists = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestTurns(t *testing.T) {
+	turns := Turns([]Entry{
+		{Kind: KindOutput, Content: "banner\n"},
+		{Kind: KindPrompt, Content: "one"},
+		{Kind: KindOutput, Content: "a"},
+		{Kind: KindOutput, Content: "b"},
+		{Kind: KindPrompt, Content: "two"},
+	})
+	if len(turns) != 3 {
+		t.Fatalf("got %d turns, want 3", len(turns))
+	}
+	if turns[0].Prompt.Kind != "" || turns[0].Output != "banner\n" {
+		t.Errorf("leading output turn = %+v", turns[0])
+	}
+	if turns[1].Prompt.Content != "one" || turns[1].Output != "ab" {
+		t.Errorf("turn 1 = %+v", turns[1])
+	}
+	if turns[2].Output != "" {
+		t.Errorf("turn 2 should have no output yet, got %q", turns[2].Output)
+	}
+}
+
+func sampleEntries() []Entry {
+	at := time.Date(2026, 10, 18, 13, 40, 0, 0, time.UTC)
+	return []Entry{
+		{Time: at, Kind: KindPrompt, Author: "alice", Source: "discord", Content: "fix <the> bug", Attachments: []string{"log.txt"}},
+		{Time: at, Kind: KindOutput, Content: "done, see ```diff```\n"},
+	}
+}
+
+func TestMarkdown(t *testing.T) {
+	md := Markdown("Transcript: repo", sampleEntries())
+	for _, want := range []string{
+		"# Transcript: repo\n",
+		"## alice (discord) · 2026-10-18 13:40:00 UTC",
+		"fix <the> bug",
+		"Attachments: log.txt",
+		"````\ndone, see ```diff```\n````",
+	} {
+		if !strings.Contains(md, want) {
+			t.Errorf("markdown missing %q:\n%s", want, md)
+		}
+	}
+}
+
+func TestHTML(t *testing.T) {
+	page, err := HTML("Transcript: <repo>", sampleEntries())
+	if err != nil {
+		t.Fatalf("HTML: %v", err)
+	}
+	for _, want := range []string{
+		"<title>Transcript: &lt;repo&gt;</title>",
+		"fix &lt;the&gt; bug",
+		"Attachments: log.txt",
+		"<pre>done, see ```diff```\n</pre>",
+	} {
+		if !strings.Contains(page, want) {
+			t.Errorf("html missing %q:\n%s", want, page)
+		}
+	}
+}
+
+func TestWriteJSONL(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteJSONL(&buf, sampleEntries()); err != nil {
+		t.Fatalf("WriteJSONL: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[0], `"author":"alice"`) {
+		t.Errorf("jsonl = %q", buf.String())
+	}
+}
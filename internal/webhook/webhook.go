@@ -0,0 +1,274 @@
+// Package webhook delivers bridge activity events to HTTP endpoints as
+// signed JSON, retrying failed deliveries in the background.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Event types emitted by the bridge.
+const (
+	SessionStarted  = "session.started"
+	SessionStopped  = "session.stopped"
+	SessionIdle     = "session.idle_timeout"
+	SessionCrashed  = "session.crashed"
+	RepoCloned      = "repo.cloned"
+	RepoRemoved     = "repo.removed"
+	WorktreeCreated = "worktree.created"
+	RateLimitHit    = "rate_limit.hit"
+	CommandExecuted = "command.executed"
+)
+
+// EventTypes lists every event type, for validating sink filters.
+var EventTypes = []string{
+	SessionStarted, SessionStopped, SessionIdle, SessionCrashed,
+	RepoCloned, RepoRemoved, WorktreeCreated,
+	RateLimitHit, CommandExecuted,
+}
+
+// KnownEvent reports whether name is one of EventTypes.
+func KnownEvent(name string) bool {
+	for _, t := range EventTypes {
+		if t == name {
+			return true
+		}
+	}
+	return false
+}
+
+const (
+	// SignatureHeader carries "sha256=" + hex HMAC-SHA256 of the body.
+	SignatureHeader = "X-LLM-Bridge-Signature-256"
+	// EventHeader carries the event type.
+	EventHeader = "X-LLM-Bridge-Event"
+	// DeliveryHeader carries the event ID, the same on every retry.
+	DeliveryHeader = "X-LLM-Bridge-Delivery"
+
+	defaultQueueSize   = 256
+	defaultMaxAttempts = 5
+	defaultBackoff     = time.Second
+	maxBackoff         = time.Minute
+)
+
+// Event is the JSON body posted to sinks.
+type Event struct {
+	ID   string         `json:"id"`
+	Type string         `json:"type"`
+	Time time.Time      `json:"time"`
+	Repo string         `json:"repo,omitempty"`
+	Data map[string]any `json:"data,omitempty"`
+}
+
+// Sink is an endpoint that receives events.
+type Sink struct {
+	URL    string
+	Secret string   // HMAC key that signs every delivery
+	Events []string // event types to send; empty for all
+}
+
+func (s Sink) wants(eventType string) bool {
+	if len(s.Events) == 0 {
+		return true
+	}
+	for _, t := range s.Events {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// Dispatcher fans events out to sinks. Each sink has its own queue and
+// worker so a slow or failing endpoint does not hold up the others.
+// Emit never blocks; events for a sink whose queue is full are dropped.
+type Dispatcher struct {
+	client      *http.Client
+	maxAttempts int
+	backoff     time.Duration
+
+	workers []*sinkWorker
+	wg      sync.WaitGroup
+	done    chan struct{} // closed by Close to cut retry waits short
+
+	mu     sync.RWMutex
+	closed bool
+}
+
+type sinkWorker struct {
+	sink  Sink
+	queue chan Event
+}
+
+// NewDispatcher starts a worker per sink.
+func NewDispatcher(sinks []Sink) *Dispatcher {
+	d := &Dispatcher{
+		client:      &http.Client{Timeout: 10 * time.Second},
+		maxAttempts: defaultMaxAttempts,
+		backoff:     defaultBackoff,
+		done:        make(chan struct{}),
+	}
+	for _, s := range sinks {
+		w := &sinkWorker{sink: s, queue: make(chan Event, defaultQueueSize)}
+		d.workers = append(d.workers, w)
+		d.wg.Add(1)
+		go d.run(w)
+	}
+	return d
+}
+
+// Emit queues an event for every sink that wants it, filling in ID and Time
+// if unset.
+func (d *Dispatcher) Emit(ev Event) {
+	if ev.ID == "" {
+		ev.ID = newID()
+	}
+	if ev.Time.IsZero() {
+		ev.Time = time.Now().UTC()
+	}
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if d.closed {
+		return
+	}
+	for _, w := range d.workers {
+		if !w.sink.wants(ev.Type) {
+			continue
+		}
+		select {
+		case w.queue <- ev:
+		default:
+			slog.Warn("webhook queue full, event dropped", "url", w.sink.URL, "event", ev.Type)
+		}
+	}
+}
+
+// Close stops accepting events and waits for queued ones to be delivered,
+// until ctx is done. Deliveries still waiting to retry are abandoned.
+func (d *Dispatcher) Close(ctx context.Context) error {
+	d.mu.Lock()
+	if !d.closed {
+		d.closed = true
+		close(d.done)
+		for _, w := range d.workers {
+			close(w.queue)
+		}
+	}
+	d.mu.Unlock()
+
+	finished := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(finished)
+	}()
+	select {
+	case <-finished:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (d *Dispatcher) run(w *sinkWorker) {
+	defer d.wg.Done()
+	for ev := range w.queue {
+		d.deliver(w.sink, ev)
+	}
+}
+
+// deliver posts ev to sink, retrying with exponential backoff (or the
+// endpoint's Retry-After) up to maxAttempts. 4xx responses other than 408
+// and 429 are not retried.
+func (d *Dispatcher) deliver(sink Sink, ev Event) {
+	body, err := json.Marshal(ev)
+	if err != nil {
+		slog.Error("encode webhook event failed", "event", ev.Type, "error", err)
+		return
+	}
+
+	backoff := d.backoff
+	for attempt := 1; ; attempt++ {
+		wait, retry, err := d.post(sink, ev, body)
+		if err == nil {
+			return
+		}
+		if !retry || attempt >= d.maxAttempts {
+			slog.Error("webhook delivery failed", "url", sink.URL, "event", ev.Type, "id", ev.ID, "attempts", attempt, "error", err)
+			return
+		}
+		if wait < backoff {
+			wait = backoff
+		}
+		slog.Warn("webhook delivery failed, will retry", "url", sink.URL, "event", ev.Type, "attempt", attempt, "error", err)
+
+		select {
+		case <-time.After(wait):
+		case <-d.done:
+			// Closing: make one last attempt without waiting.
+			if _, _, err := d.post(sink, ev, body); err != nil {
+				slog.Error("webhook delivery abandoned", "url", sink.URL, "event", ev.Type, "id", ev.ID, "error", err)
+			}
+			return
+		}
+		backoff = min(backoff*2, maxBackoff)
+	}
+}
+
+// post makes one delivery attempt. It reports how long the endpoint asked
+// to wait and whether the failure is worth retrying.
+func (d *Dispatcher) post(sink Sink, ev Event, body []byte) (time.Duration, bool, error) {
+	req, err := http.NewRequest(http.MethodPost, sink.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, false, fmt.Errorf("build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "llm-bridge")
+	req.Header.Set(EventHeader, ev.Type)
+	req.Header.Set(DeliveryHeader, ev.ID)
+	req.Header.Set(SignatureHeader, Sign(sink.Secret, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, true, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return 0, false, nil
+	}
+
+	var wait time.Duration
+	if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+		wait = time.Duration(secs) * time.Second
+	}
+	retry := resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusRequestTimeout
+	return wait, retry, fmt.Errorf("unexpected status %s", resp.Status)
+}
+
+// Sign returns the signature header value for body: "sha256=" followed by
+// the hex HMAC-SHA256 of body keyed with secret.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func newID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
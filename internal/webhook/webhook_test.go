@@ -0,0 +1,195 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recorder is a test endpoint that fails the first `failures` requests
+// with `status` and records the rest.
+type recorder struct {
+	mu       sync.Mutex
+	failures int
+	status   int
+	attempts int
+	got      []*http.Request
+	bodies   [][]byte
+}
+
+func (r *recorder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.attempts++
+	if r.failures > 0 {
+		r.failures--
+		w.WriteHeader(r.status)
+		return
+	}
+	r.got = append(r.got, req)
+	r.bodies = append(r.bodies, body)
+}
+
+func (r *recorder) snapshot() ([]*http.Request, [][]byte, int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]*http.Request(nil), r.got...), append([][]byte(nil), r.bodies...), r.attempts
+}
+
+func newTestDispatcher(t *testing.T, sinks ...Sink) *Dispatcher {
+	t.Helper()
+	d := NewDispatcher(sinks)
+	d.backoff = time.Millisecond
+	return d
+}
+
+func closeDispatcher(t *testing.T, d *Dispatcher) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := d.Close(ctx); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+}
+
+func TestDispatcher_DeliversSignedEvent(t *testing.T) {
+	rec := &recorder{}
+	srv := httptest.NewServer(rec)
+	defer srv.Close()
+
+	d := newTestDispatcher(t, Sink{URL: srv.URL, Secret: "k"})
+	d.Emit(Event{Type: SessionStarted, Repo: "app", Data: map[string]any{"llm": "claude"}})
+	closeDispatcher(t, d)
+
+	reqs, bodies, _ := rec.snapshot()
+	if len(reqs) != 1 {
+		t.Fatalf("expected 1 delivery, got %d", len(reqs))
+	}
+	if got := reqs[0].Header.Get(SignatureHeader); got != Sign("k", bodies[0]) {
+		t.Errorf("signature = %q, want %q", got, Sign("k", bodies[0]))
+	}
+	if reqs[0].Header.Get(EventHeader) != SessionStarted {
+		t.Errorf("event header = %q", reqs[0].Header.Get(EventHeader))
+	}
+
+	var ev Event
+	if err := json.Unmarshal(bodies[0], &ev); err != nil {
+		t.Fatalf("decode body: %v", err)
+	}
+	if ev.ID == "" || ev.Time.IsZero() || ev.Repo != "app" || ev.Data["llm"] != "claude" {
+		t.Errorf("event = %+v", ev)
+	}
+	if reqs[0].Header.Get(DeliveryHeader) != ev.ID {
+		t.Errorf("delivery header = %q, want event ID %q", reqs[0].Header.Get(DeliveryHeader), ev.ID)
+	}
+}
+
+func TestDispatcher_RetriesServerErrors(t *testing.T) {
+	rec := &recorder{failures: 2, status: http.StatusBadGateway}
+	srv := httptest.NewServer(rec)
+	defer srv.Close()
+
+	d := newTestDispatcher(t, Sink{URL: srv.URL, Secret: "k"})
+	d.Emit(Event{Type: SessionCrashed})
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if reqs, _, _ := rec.snapshot(); len(reqs) == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("event was not redelivered")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	closeDispatcher(t, d)
+
+	if _, _, attempts := rec.snapshot(); attempts != 3 {
+		t.Errorf("attempts = %d, want 3", attempts)
+	}
+}
+
+func TestDispatcher_DoesNotRetryClientErrors(t *testing.T) {
+	rec := &recorder{failures: 1, status: http.StatusBadRequest}
+	srv := httptest.NewServer(rec)
+	defer srv.Close()
+
+	d := newTestDispatcher(t, Sink{URL: srv.URL, Secret: "k"})
+	d.Emit(Event{Type: SessionCrashed})
+	closeDispatcher(t, d)
+
+	if reqs, _, attempts := rec.snapshot(); attempts != 1 || len(reqs) != 0 {
+		t.Errorf("attempts = %d, deliveries = %d; want 1 failed attempt", attempts, len(reqs))
+	}
+}
+
+func TestDispatcher_GivesUpAfterMaxAttempts(t *testing.T) {
+	rec := &recorder{failures: 100, status: http.StatusServiceUnavailable}
+	srv := httptest.NewServer(rec)
+	defer srv.Close()
+
+	d := newTestDispatcher(t, Sink{URL: srv.URL, Secret: "k"})
+	d.maxAttempts = 3
+	d.Emit(Event{Type: SessionCrashed})
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if _, _, attempts := rec.snapshot(); attempts >= 3 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expected 3 attempts")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	closeDispatcher(t, d)
+
+	if _, _, attempts := rec.snapshot(); attempts != 3 {
+		t.Errorf("attempts = %d, want 3", attempts)
+	}
+}
+
+func TestDispatcher_FiltersEvents(t *testing.T) {
+	rec := &recorder{}
+	srv := httptest.NewServer(rec)
+	defer srv.Close()
+
+	d := newTestDispatcher(t, Sink{URL: srv.URL, Secret: "k", Events: []string{RepoCloned}})
+	d.Emit(Event{Type: SessionStarted})
+	d.Emit(Event{Type: RepoCloned})
+	closeDispatcher(t, d)
+
+	reqs, _, _ := rec.snapshot()
+	if len(reqs) != 1 || reqs[0].Header.Get(EventHeader) != RepoCloned {
+		t.Errorf("expected only %s, got %d deliveries", RepoCloned, len(reqs))
+	}
+}
+
+func TestDispatcher_EmitAfterCloseIsDropped(t *testing.T) {
+	rec := &recorder{}
+	srv := httptest.NewServer(rec)
+	defer srv.Close()
+
+	d := newTestDispatcher(t, Sink{URL: srv.URL, Secret: "k"})
+	closeDispatcher(t, d)
+	d.Emit(Event{Type: SessionStarted}) // must not panic
+
+	if reqs, _, _ := rec.snapshot(); len(reqs) != 0 {
+		t.Errorf("expected no deliveries after Close, got %d", len(reqs))
+	}
+}
+
+func TestKnownEvent(t *testing.T) {
+	if !KnownEvent(CommandExecuted) {
+		t.Error("KnownEvent(command.executed) = false")
+	}
+	if KnownEvent("session.exploded") {
+		t.Error("KnownEvent(session.exploded) = true")
+	}
+}